@@ -0,0 +1,151 @@
+// db/assessments.go
+package db
+
+import (
+	"context"
+	"electronic-diary/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func (s *MongoStore) GetAssessmentsByDisciplineID(disciplineID, termID primitive.ObjectID) ([]models.Assessment, error) {
+	return s.findAssessments(context.Background(), disciplineID, termID)
+}
+
+func (s *MongoStore) findAssessments(ctx context.Context, disciplineID, termID primitive.ObjectID) ([]models.Assessment, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "date", Value: 1}, {Key: "_id", Value: 1}})
+	cursor, err := s.assessmentsCol.Find(ctx, bson.M{"disciplineId": disciplineID, "termId": termID}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var assessments []models.Assessment
+	err = cursor.All(ctx, &assessments)
+	return assessments, err
+}
+
+func (s *MongoStore) GetAssessmentByID(id primitive.ObjectID) (*models.Assessment, error) {
+	var assessment models.Assessment
+	err := findOne(s.assessmentsCol, bson.M{"_id": id}, &assessment)
+	return &assessment, err
+}
+
+func (s *MongoStore) CreateAssessment(assessment *models.Assessment) error {
+	if assessment.ID.IsZero() {
+		assessment.ID = primitive.NewObjectID()
+	}
+	_, err := s.assessmentsCol.InsertOne(context.Background(), assessment)
+	return err
+}
+
+// DeleteAssessment удаляет работу вместе с баллами студентов.
+func (s *MongoStore) DeleteAssessment(id primitive.ObjectID, audit []models.AuditEntry) error {
+	assessment, err := s.GetAssessmentByID(id)
+	if err != nil {
+		return err
+	}
+	return s.inTransaction(nil, func(ctx context.Context) error {
+		if _, err := s.assessmentMarksCol.DeleteMany(ctx, bson.M{"assessmentId": id}); err != nil {
+			return err
+		}
+		if _, err := s.assessmentsCol.DeleteOne(ctx, bson.M{"_id": id}); err != nil {
+			return err
+		}
+		if err := s.recomputeScores(ctx, assessment.DisciplineID, assessment.TermID); err != nil {
+			return err
+		}
+		return s.appendAudit(ctx, audit)
+	})
+}
+
+func (s *MongoStore) GetMarksByAssessment(assessmentID primitive.ObjectID) ([]models.AssessmentMark, error) {
+	return s.findAssessmentMarks(context.Background(), bson.M{"assessmentId": assessmentID})
+}
+
+func (s *MongoStore) GetMarksByStudent(studentID primitive.ObjectID) ([]models.AssessmentMark, error) {
+	return s.findAssessmentMarks(context.Background(), bson.M{"studentId": studentID})
+}
+
+func (s *MongoStore) findAssessmentMarks(ctx context.Context, filter bson.M) ([]models.AssessmentMark, error) {
+	cursor, err := s.assessmentMarksCol.Find(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var marks []models.AssessmentMark
+	err = cursor.All(ctx, &marks)
+	return marks, err
+}
+
+// SaveAssessmentMarks заменяет все баллы за работу: студенты, которых нет
+// в points, остаются без оценки. Затем пересчитывает Score по дисциплине.
+func (s *MongoStore) SaveAssessmentMarks(assessmentID primitive.ObjectID, points map[primitive.ObjectID]float64, audit []models.AuditEntry) error {
+	assessment, err := s.GetAssessmentByID(assessmentID)
+	if err != nil {
+		return err
+	}
+
+	writes := []mongo.WriteModel{mongo.NewDeleteManyModel().SetFilter(bson.M{"assessmentId": assessmentID})}
+	for studentID, p := range points {
+		writes = append(writes, mongo.NewInsertOneModel().SetDocument(models.AssessmentMark{
+			ID:           primitive.NewObjectID(),
+			AssessmentID: assessmentID,
+			StudentID:    studentID,
+			Points:       p,
+		}))
+	}
+	return s.inTransaction(nil, func(ctx context.Context) error {
+		if _, err := s.assessmentMarksCol.BulkWrite(ctx, writes); err != nil {
+			return err
+		}
+		if err := s.recomputeScores(ctx, assessment.DisciplineID, assessment.TermID); err != nil {
+			return err
+		}
+		return s.appendAudit(ctx, audit)
+	})
+}
+
+// recomputeScores выводит Score всех студентов дисциплины за период из
+// баллов за работы. Если работ в периоде нет, оценки ведутся вручную и не
+// трогаются.
+func (s *MongoStore) recomputeScores(ctx context.Context, disciplineID, termID primitive.ObjectID) error {
+	assessments, err := s.findAssessments(ctx, disciplineID, termID)
+	if err != nil || len(assessments) == 0 {
+		return err
+	}
+	ids := make([]primitive.ObjectID, len(assessments))
+	for i, a := range assessments {
+		ids[i] = a.ID
+	}
+	marks, err := s.findAssessmentMarks(ctx, bson.M{"assessmentId": bson.M{"$in": ids}})
+	if err != nil {
+		return err
+	}
+	scores := scoreAssessments(assessments, marks)
+
+	rows, err := s.findDisciplineData(ctx, bson.M{"disciplineId": disciplineID, "termId": termID})
+	if err != nil {
+		return err
+	}
+
+	var writes []mongo.WriteModel
+	for _, row := range rows {
+		score := scores[row.StudentID]
+		if row.Score == score {
+			continue
+		}
+		writes = append(writes, mongo.NewUpdateOneModel().
+			SetFilter(bson.M{"_id": row.ID}).
+			SetUpdate(bson.M{"$set": bson.M{"score": score}}))
+	}
+	if len(writes) == 0 {
+		return nil
+	}
+	_, err = s.studentDisciplineDataCol.BulkWrite(ctx, writes)
+	return err
+}
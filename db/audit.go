@@ -0,0 +1,71 @@
+// db/audit.go
+package db
+
+import (
+	"context"
+	"electronic-diary/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// appendAudit пишет журнал с контекстом ctx — транзакции, в которой
+// сохраняется само изменение.
+func (s *MongoStore) appendAudit(ctx context.Context, entries []models.AuditEntry) error {
+	if len(entries) == 0 {
+		return nil
+	}
+	docs := make([]interface{}, len(entries))
+	for i, e := range entries {
+		if e.ID.IsZero() {
+			e.ID = primitive.NewObjectID()
+		}
+		docs[i] = e
+	}
+	_, err := s.auditCol.InsertMany(ctx, docs)
+	return err
+}
+
+func (s *MongoStore) GetAudit(filter models.AuditFilter) ([]models.AuditEntry, error) {
+	q := bson.M{}
+	if filter.ActorID != nil {
+		q["actorId"] = *filter.ActorID
+	}
+	if filter.StudentID != nil {
+		q["studentId"] = *filter.StudentID
+	}
+	if filter.DisciplineID != nil {
+		q["disciplineId"] = *filter.DisciplineID
+	}
+	if filter.Field != "" {
+		q["field"] = filter.Field
+	}
+	at := bson.M{}
+	if !filter.From.IsZero() {
+		at["$gte"] = filter.From
+	}
+	if !filter.To.IsZero() {
+		at["$lt"] = filter.To
+	}
+	if len(at) > 0 {
+		q["at"] = at
+	}
+
+	opts := options.Find().SetSort(bson.D{{Key: "at", Value: -1}, {Key: "_id", Value: -1}})
+	if filter.Limit > 0 {
+		opts.SetLimit(int64(filter.Limit))
+	}
+	ctx := context.Background()
+	cursor, err := s.auditCol.Find(ctx, q, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var entries []models.AuditEntry
+	if err := cursor.All(ctx, &entries); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
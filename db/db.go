@@ -3,35 +3,114 @@ package db
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"log"
 	"time"
-	
+
+	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
-var DB *mongo.Database
+// MongoStore — реализация Store поверх MongoDB.
+type MongoStore struct {
+	client *mongo.Client
+	db     *mongo.Database
 
-func StudentDisciplineDataCol() *mongo.Collection {
-	return studentDisciplineDataCol
+	groupsCol                *mongo.Collection
+	studentsCol              *mongo.Collection
+	disciplinesCol           *mongo.Collection
+	studentDisciplineDataCol *mongo.Collection
+	lessonsCol               *mongo.Collection
+	attendanceCol            *mongo.Collection
+	assessmentsCol           *mongo.Collection
+	assessmentMarksCol       *mongo.Collection
+	gradingScalesCol         *mongo.Collection
+	usersCol                 *mongo.Collection
+	sessionsCol              *mongo.Collection
+	auditCol                 *mongo.Collection
+	resetSnapshotsCol        *mongo.Collection
+	yearsCol                 *mongo.Collection
+	termsCol                 *mongo.Collection
 }
 
-func Connect() {
+// NewMongoStore подключается к MongoDB по uri и открывает базу database.
+func NewMongoStore(uri, database string) (*MongoStore, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	client, err := mongo.Connect(ctx, options.Client().ApplyURI("mongodb://localhost:27017"))
+	client, err := mongo.Connect(ctx, options.Client().ApplyURI(uri))
 	if err != nil {
-		log.Fatal("Не удалось подключиться к MongoDB:", err)
+		return nil, fmt.Errorf("не удалось подключиться к MongoDB: %w", err)
 	}
 
 	// Проверим подключение
 	err = client.Ping(ctx, nil)
 	if err != nil {
-		log.Fatal("Не удалось пингануть MongoDB:", err)
+		client.Disconnect(context.Background())
+		return nil, fmt.Errorf("не удалось пингануть MongoDB: %w", err)
+	}
+
+	s := &MongoStore{client: client, db: client.Database(database)}
+	s.groupsCol = s.db.Collection("groups")
+	s.studentsCol = s.db.Collection("students")
+	s.disciplinesCol = s.db.Collection("disciplines")
+	s.studentDisciplineDataCol = s.db.Collection("studentDisciplineData")
+	s.lessonsCol = s.db.Collection("lessons")
+	s.attendanceCol = s.db.Collection("attendance")
+	s.assessmentsCol = s.db.Collection("assessments")
+	s.assessmentMarksCol = s.db.Collection("assessmentMarks")
+	s.gradingScalesCol = s.db.Collection("gradingScales")
+	s.usersCol = s.db.Collection("users")
+	s.sessionsCol = s.db.Collection("sessions")
+	s.auditCol = s.db.Collection("audit")
+	s.resetSnapshotsCol = s.db.Collection("resetSnapshots")
+	s.yearsCol = s.db.Collection("academicYears")
+	s.termsCol = s.db.Collection("terms")
+
+	if err := s.ensureGroupSlugs(); err != nil {
+		log.Printf("Не удалось проставить slug группам: %v", err)
 	}
+	if err := s.ensureIndexes(); err != nil {
+		log.Printf("Не удалось создать уникальные индексы групп и записей (возможно, в базе есть дубликаты): %v", err)
+	}
+	if err := s.ensureUserIndexes(); err != nil {
+		log.Printf("Не удалось создать индексы пользователей: %v", err)
+	}
+	s.warnStandalone(ctx)
 
-	DB = client.Database("electronic_diary")
-	log.Println("✅ Подключились к MongoDB: electronic_diary")
+	log.Println("✅ Подключились к MongoDB:", database)
+	return s, nil
 }
 
+// warnStandalone предупреждает, если MongoDB — одиночный сервер, а не
+// набор реплик: без транзакций сохранение нескольких документов при сбое
+// может записаться частично (см. inTransaction).
+func (s *MongoStore) warnStandalone(ctx context.Context) {
+	var hello struct {
+		SetName string `bson:"setName"`
+		Msg     string `bson:"msg"`
+	}
+	if err := s.client.Database("admin").RunCommand(ctx, bson.D{{Key: "hello", Value: 1}}).Decode(&hello); err != nil {
+		log.Printf("Не удалось узнать, запущена ли MongoDB набором реплик: %v", err)
+		return
+	}
+	// У mongos нет setName, но транзакции он поддерживает
+	if hello.SetName == "" && hello.Msg != "isdbgrid" {
+		log.Println("⚠️ MongoDB запущена без набора реплик: транзакций нет, и сохранения из нескольких документов при сбое могут записаться частично. Запустите mongod с --replSet (см. README)")
+	}
+}
+
+func (s *MongoStore) Close() error {
+	return s.client.Disconnect(context.Background())
+}
+
+// findOne декодирует один документ, превращая mongo.ErrNoDocuments в ErrNotFound.
+func findOne(col *mongo.Collection, filter interface{}, out interface{}) error {
+	err := col.FindOne(context.Background(), filter).Decode(out)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return ErrNotFound
+	}
+	return err
+}
@@ -0,0 +1,73 @@
+// db/grading.go
+package db
+
+import (
+	"context"
+	"electronic-diary/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func (s *MongoStore) GetGradingScales() ([]models.GradingScale, error) {
+	ctx := context.Background()
+	opts := options.Find().SetSort(bson.D{{Key: "_id", Value: 1}})
+	cursor, err := s.gradingScalesCol.Find(ctx, bson.M{}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var scales []models.GradingScale
+	err = cursor.All(ctx, &scales)
+	return scales, err
+}
+
+func (s *MongoStore) GetGradingScaleByID(id primitive.ObjectID) (*models.GradingScale, error) {
+	var scale models.GradingScale
+	err := findOne(s.gradingScalesCol, bson.M{"_id": id}, &scale)
+	return &scale, err
+}
+
+func (s *MongoStore) CreateGradingScale(scale *models.GradingScale) error {
+	if scale.ID.IsZero() {
+		scale.ID = primitive.NewObjectID()
+	}
+	_, err := s.gradingScalesCol.InsertOne(context.Background(), scale)
+	return err
+}
+
+func (s *MongoStore) DeleteGradingScale(id primitive.ObjectID) error {
+	ctx := context.Background()
+	unset := bson.M{"$unset": bson.M{"gradingScaleId": ""}}
+	if _, err := s.groupsCol.UpdateMany(ctx, bson.M{"gradingScaleId": id}, unset); err != nil {
+		return err
+	}
+	if _, err := s.disciplinesCol.UpdateMany(ctx, bson.M{"gradingScaleId": id}, unset); err != nil {
+		return err
+	}
+	res, err := s.gradingScalesCol.DeleteOne(ctx, bson.M{"_id": id})
+	if err == nil && res.DeletedCount == 0 {
+		return ErrNotFound
+	}
+	return err
+}
+
+func (s *MongoStore) SetGroupGradingScale(groupID primitive.ObjectID, scaleID *primitive.ObjectID) error {
+	_, err := s.groupsCol.UpdateOne(context.Background(), bson.M{"_id": groupID}, gradingScaleUpdate(scaleID))
+	return err
+}
+
+func (s *MongoStore) SetDisciplineGradingScale(disciplineID primitive.ObjectID, scaleID *primitive.ObjectID) error {
+	_, err := s.disciplinesCol.UpdateOne(context.Background(), bson.M{"_id": disciplineID}, gradingScaleUpdate(scaleID))
+	return err
+}
+
+// gradingScaleUpdate назначает шкалу или снимает её, если scaleID == nil.
+func gradingScaleUpdate(scaleID *primitive.ObjectID) bson.M {
+	if scaleID == nil {
+		return bson.M{"$unset": bson.M{"gradingScaleId": ""}}
+	}
+	return bson.M{"$set": bson.M{"gradingScaleId": *scaleID}}
+}
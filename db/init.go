@@ -3,70 +3,82 @@ package db
 
 import (
 	"context"
-	"log"
-
+	"crypto/rand"
 	"electronic-diary/models"
-	"go.mongodb.org/mongo-driver/bson"
-	"go.mongodb.org/mongo-driver/mongo"
-	"go.mongodb.org/mongo-driver/bson/primitive"
-)
-
-var (
-	groupsCol               *mongo.Collection
-	studentsCol             *mongo.Collection
-	disciplinesCol          *mongo.Collection
-	studentDisciplineDataCol *mongo.Collection
+	"encoding/hex"
+	"log"
+	"time"
 )
 
-func InitCollections() {
-	groupsCol = DB.Collection("groups")
-	studentsCol = DB.Collection("students")
-	disciplinesCol = DB.Collection("disciplines")
-	studentDisciplineDataCol = DB.Collection("studentDisciplineData")
+// EnsureGradingScales создаёт встроенные шкалы оценок, которых ещё нет
+// в хранилище. Шкалы нужны всегда, поэтому вызывается и без --seed.
+func EnsureGradingScales(s Store) error {
+	scales, err := s.GetGradingScales()
+	if err != nil {
+		return err
+	}
+	have := make(map[string]bool, len(scales))
+	for _, sc := range scales {
+		have[sc.Key] = true
+	}
+	for _, sc := range models.BuiltinScales() {
+		if have[sc.Key] {
+			continue
+		}
+		if err := s.CreateGradingScale(&sc); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
-func SeedData() {
-	ctx := context.Background()
+// EnsureTerm заводит учебный год и текущий семестр, пока периодов нет,
+// и относит к нему данные, созданные до появления периодов. Вызывается
+// до SeedData: начальные записи студентов создаются уже в периоде.
+func EnsureTerm(s Store) error {
+	terms, err := s.GetTerms()
+	if err != nil || len(terms) > 0 {
+		return err
+	}
+	year, term := models.DefaultTerm(time.Now())
+	created, err := s.CreateAcademicYear(year.Name)
+	if err != nil {
+		return err
+	}
+	term.YearID = created.ID
+	if err := s.CreateTerm(&term); err != nil {
+		return err
+	}
+	log.Printf("📅 Создан период %s, %s", created.Name, term.Name)
+	return s.AssignLegacyTerm(term.ID)
+}
 
+// SeedData наполняет пустое хранилище начальными группами, студентами
+// и дисциплинами. Если группы уже есть — ничего не делает.
+func SeedData(s Store) error {
 	// Проверим, есть ли уже группы
-	count, _ := groupsCol.CountDocuments(ctx, bson.M{})
-	if count > 0 {
+	groups, err := s.GetGroups(true)
+	if err != nil {
+		return err
+	}
+	if len(groups) > 0 {
 		log.Println("📚 Данные уже существуют — пропускаем инициализацию.")
-		return
+		return nil
 	}
 
 	log.Println("🌱 Инициализация начальных данных...")
 
 	// === 1. Создаём группы ===
-	backendGroup := models.Group{Name: "Backend"}
-	frontendGroup := models.Group{Name: "Frontend"}
-
-	backendResult, _ := groupsCol.InsertOne(ctx, backendGroup)
-	frontendResult, _ := groupsCol.InsertOne(ctx, frontendGroup)
-
-	backendID := backendResult.InsertedID.(primitive.ObjectID)
-	frontendID := frontendResult.InsertedID.(primitive.ObjectID)
-
-	// === 2. Создаём студентов ===
-	// Backend студенты
-backendNames := []string{
-    "Цицкиев Рустам", "Дзауров Увейс", "Цуроев Абдул-Малик",
-}
-// Frontend студенты
-frontendNames := []string{
-	"Цулоев Али", "Манкиев Магомед", "Котиев Магомед", "Яндиев Хамзат", "Костоев Алсбек", "Ислам Богатырев",
-}
-
-var students []interface{}
-for _, name := range backendNames {
-    students = append(students, models.Student{Name: name, GroupID: backendID, Comments: ""})
-}
-for _, name := range frontendNames {
-    students = append(students, models.Student{Name: name, GroupID: frontendID, Comments: ""})
-}
-	studentsCol.InsertMany(ctx, students)
+	backendGroup, err := s.CreateGroup("Backend")
+	if err != nil {
+		return err
+	}
+	frontendGroup, err := s.CreateGroup("Frontend")
+	if err != nil {
+		return err
+	}
 
-	// === 3. Создаём дисциплины ===
+	// === 2. Создаём дисциплины ===
 	backendDisciplines := []string{
 		"GO",
 		"Node.js",
@@ -82,64 +94,92 @@ for _, name := range frontendNames {
 		"Web-компоненты",
 	}
 
-	var disciplines []interface{}
 	for _, name := range backendDisciplines {
-		disciplines = append(disciplines, models.Discipline{Name: name, GroupID: backendID})
+		if _, err := s.CreateDiscipline(backendGroup.ID, name); err != nil {
+			return err
+		}
 	}
 	for _, name := range frontendDisciplines {
-		disciplines = append(disciplines, models.Discipline{Name: name, GroupID: frontendID})
+		if _, err := s.CreateDiscipline(frontendGroup.ID, name); err != nil {
+			return err
+		}
 	}
-	disciplinesCol.InsertMany(ctx, disciplines)
 
-	// === 4. Получим всех студентов и дисциплины для связи ===
-	var allStudents []models.Student
-	var allDisciplines []models.Discipline
-
-	cursor, err := studentsCol.Find(ctx, bson.M{})
-		if err != nil {
-    log.Fatal(err)
-		}
-		defer cursor.Close(ctx)
-		cursor.All(ctx, &allStudents)
+	// === 3. Создаём студентов — записи StudentDisciplineData заводятся вместе с ними ===
+	// Backend студенты
+	backendNames := []string{
+		"Цицкиев Рустам", "Дзауров Увейс", "Цуроев Абдул-Малик",
+	}
+	// Frontend студенты
+	frontendNames := []string{
+		"Цулоев Али", "Манкиев Магомед", "Котиев Магомед", "Яндиев Хамзат", "Костоев Алсбек", "Ислам Богатырев",
+	}
 
-	cursor1, err1 := disciplinesCol.Find(ctx, bson.M{})
-		if err1 != nil {
-    log.Fatal(err1)
+	for _, name := range backendNames {
+		if _, err := s.CreateStudent(name, backendGroup.ID); err != nil {
+			return err
 		}
-		defer cursor1.Close(ctx)
-		cursor1.All(ctx, &allDisciplines)
-
-	// === 5. Создаём StudentDisciplineData ===
-	var dataEntries []interface{}
-	for _, student := range allStudents {
-		for _, disc := range allDisciplines {
-			if disc.GroupID == student.GroupID {
-				dataEntries = append(dataEntries, models.StudentDisciplineData{
-					StudentID:       student.ID,
-					DisciplineID:    disc.ID,
-					Score:           0,
-					TotalClasses:    0,
-					AttendedClasses: 0,
-				})
-			}
+	}
+	for _, name := range frontendNames {
+		if _, err := s.CreateStudent(name, frontendGroup.ID); err != nil {
+			return err
 		}
 	}
-	studentDisciplineDataCol.InsertMany(ctx, dataEntries)
 
 	log.Println("✅ Начальные данные успешно созданы!")
-
-	
+	return nil
 }
 
-func ResetData() {
+func (s *MongoStore) Reset() error {
 	ctx := context.Background()
-	
-	// Удаляем все коллекции
-	groupsCol.Drop(ctx)
-	studentsCol.Drop(ctx)
-	disciplinesCol.Drop(ctx)
-	studentDisciplineDataCol.Drop(ctx)
+
+	// Удаляем базу целиком — коллекции создадутся заново при первой записи
+	if err := s.db.Drop(ctx); err != nil {
+		return err
+	}
+	// Индексы пропали вместе с базой
+	if err := s.ensureUserIndexes(); err != nil {
+		return err
+	}
+	if err := s.ensureIndexes(); err != nil {
+		return err
+	}
 
 	log.Println("Все данные удалены.")
+	return nil
+}
+
+// EnsureAdmin создаёт администратора login, пока в хранилище нет ни одного
+// пользователя. Пустой password заменяется случайным и выводится в лог.
+func EnsureAdmin(s Store, login, password string) error {
+	users, err := s.GetUsers()
+	if err != nil {
+		return err
+	}
+	if len(users) > 0 {
+		return nil
+	}
 
+	generated := password == ""
+	if generated {
+		buf := make([]byte, 8)
+		if _, err := rand.Read(buf); err != nil {
+			return err
+		}
+		password = hex.EncodeToString(buf)
+	}
+
+	admin := models.User{Login: login, Name: "Администратор", Role: models.RoleAdmin}
+	if err := admin.SetPassword(password); err != nil {
+		return err
+	}
+	if err := s.CreateUser(&admin); err != nil {
+		return err
+	}
+	if generated {
+		log.Printf("🔑 Создан администратор %q с паролем %s — смените его после входа", login, password)
+	} else {
+		log.Printf("🔑 Создан администратор %q", login)
+	}
+	return nil
 }
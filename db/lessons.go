@@ -0,0 +1,140 @@
+// db/lessons.go
+package db
+
+import (
+	"context"
+	"electronic-diary/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func (s *MongoStore) GetLessonsByGroupID(groupID, termID primitive.ObjectID) ([]models.Lesson, error) {
+	ctx := context.Background()
+	opts := options.Find().SetSort(bson.D{{Key: "date", Value: -1}, {Key: "_id", Value: -1}})
+	cursor, err := s.lessonsCol.Find(ctx, bson.M{"groupId": groupID, "termId": termID}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var lessons []models.Lesson
+	err = cursor.All(ctx, &lessons)
+	return lessons, err
+}
+
+func (s *MongoStore) GetLessonByID(id primitive.ObjectID) (*models.Lesson, error) {
+	var lesson models.Lesson
+	err := findOne(s.lessonsCol, bson.M{"_id": id}, &lesson)
+	return &lesson, err
+}
+
+func (s *MongoStore) CreateLesson(lesson *models.Lesson) error {
+	if lesson.ID.IsZero() {
+		lesson.ID = primitive.NewObjectID()
+	}
+	_, err := s.lessonsCol.InsertOne(context.Background(), lesson)
+	return err
+}
+
+// DeleteLesson удаляет занятие вместе с отметками.
+func (s *MongoStore) DeleteLesson(id primitive.ObjectID, audit []models.AuditEntry) error {
+	lesson, err := s.GetLessonByID(id)
+	if err != nil {
+		return err
+	}
+	return s.inTransaction(nil, func(ctx context.Context) error {
+		if _, err := s.attendanceCol.DeleteMany(ctx, bson.M{"lessonId": id}); err != nil {
+			return err
+		}
+		if _, err := s.lessonsCol.DeleteOne(ctx, bson.M{"_id": id}); err != nil {
+			return err
+		}
+		if err := s.recomputeAttendance(ctx, lesson.DisciplineID, lesson.TermID); err != nil {
+			return err
+		}
+		return s.appendAudit(ctx, audit)
+	})
+}
+
+func (s *MongoStore) GetAttendanceByLesson(lessonID primitive.ObjectID) ([]models.AttendanceMark, error) {
+	return s.findAttendance(context.Background(), bson.M{"lessonId": lessonID})
+}
+
+func (s *MongoStore) findAttendance(ctx context.Context, filter bson.M) ([]models.AttendanceMark, error) {
+	cursor, err := s.attendanceCol.Find(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var marks []models.AttendanceMark
+	err = cursor.All(ctx, &marks)
+	return marks, err
+}
+
+// SaveAttendance записывает отметки занятия (по одной на студента)
+// и пересчитывает счётчики посещаемости по дисциплине.
+func (s *MongoStore) SaveAttendance(lessonID primitive.ObjectID, marks map[primitive.ObjectID]models.AttendanceStatus, audit []models.AuditEntry) error {
+	lesson, err := s.GetLessonByID(lessonID)
+	if err != nil {
+		return err
+	}
+
+	var writes []mongo.WriteModel
+	for studentID, status := range marks {
+		writes = append(writes, mongo.NewUpdateOneModel().
+			SetFilter(bson.M{"lessonId": lessonID, "studentId": studentID}).
+			SetUpdate(bson.M{"$set": bson.M{"status": status}}).
+			SetUpsert(true))
+	}
+	return s.inTransaction(nil, func(ctx context.Context) error {
+		if len(writes) > 0 {
+			if _, err := s.attendanceCol.BulkWrite(ctx, writes); err != nil {
+				return err
+			}
+		}
+		if err := s.recomputeAttendance(ctx, lesson.DisciplineID, lesson.TermID); err != nil {
+			return err
+		}
+		return s.appendAudit(ctx, audit)
+	})
+}
+
+// recomputeAttendance выводит TotalClasses/AttendedClasses всех студентов
+// дисциплины за период из отметок журнала. ctx — контекст транзакции,
+// если пересчёт идёт в ней.
+func (s *MongoStore) recomputeAttendance(ctx context.Context, disciplineID, termID primitive.ObjectID) error {
+	lessonIDs, err := s.lessonsCol.Distinct(ctx, "_id", bson.M{"disciplineId": disciplineID, "termId": termID})
+	if err != nil {
+		return err
+	}
+	marks, err := s.findAttendance(ctx, bson.M{"lessonId": bson.M{"$in": lessonIDs}})
+	if err != nil {
+		return err
+	}
+	counts := tallyAttendance(marks)
+
+	rows, err := s.findDisciplineData(ctx, bson.M{"disciplineId": disciplineID, "termId": termID})
+	if err != nil {
+		return err
+	}
+
+	var writes []mongo.WriteModel
+	for _, row := range rows {
+		c := counts[row.StudentID]
+		if row.TotalClasses == c.total && row.AttendedClasses == c.attended {
+			continue
+		}
+		writes = append(writes, mongo.NewUpdateOneModel().
+			SetFilter(bson.M{"_id": row.ID}).
+			SetUpdate(bson.M{"$set": bson.M{"totalClasses": c.total, "attendedClasses": c.attended}}))
+	}
+	if len(writes) == 0 {
+		return nil
+	}
+	_, err = s.studentDisciplineDataCol.BulkWrite(ctx, writes)
+	return err
+}
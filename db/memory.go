@@ -0,0 +1,1387 @@
+// db/memory.go
+package db
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"electronic-diary/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// MemoryStore — хранилище в памяти процесса. Данные живут до перезапуска;
+// подходит для локального запуска без MongoDB и для тестов обработчиков.
+type MemoryStore struct {
+	mu sync.RWMutex
+
+	groups      map[primitive.ObjectID]models.Group
+	students    map[primitive.ObjectID]models.Student
+	disciplines map[primitive.ObjectID]models.Discipline
+	data        map[primitive.ObjectID]models.StudentDisciplineData
+	lessons     map[primitive.ObjectID]models.Lesson
+	attendance  map[primitive.ObjectID]models.AttendanceMark
+	assessments map[primitive.ObjectID]models.Assessment
+	marks       map[primitive.ObjectID]models.AssessmentMark
+	scales      map[primitive.ObjectID]models.GradingScale
+	users       map[primitive.ObjectID]models.User
+	sessions    map[string]models.Session
+	audit       []models.AuditEntry
+	snapshots   map[primitive.ObjectID]models.ResetSnapshot
+	years       map[primitive.ObjectID]models.AcademicYear
+	terms       map[primitive.ObjectID]models.Term
+}
+
+func NewMemoryStore() *MemoryStore {
+	s := &MemoryStore{}
+	s.clear()
+	return s
+}
+
+func (s *MemoryStore) clear() {
+	s.groups = make(map[primitive.ObjectID]models.Group)
+	s.students = make(map[primitive.ObjectID]models.Student)
+	s.disciplines = make(map[primitive.ObjectID]models.Discipline)
+	s.data = make(map[primitive.ObjectID]models.StudentDisciplineData)
+	s.lessons = make(map[primitive.ObjectID]models.Lesson)
+	s.attendance = make(map[primitive.ObjectID]models.AttendanceMark)
+	s.assessments = make(map[primitive.ObjectID]models.Assessment)
+	s.marks = make(map[primitive.ObjectID]models.AssessmentMark)
+	s.scales = make(map[primitive.ObjectID]models.GradingScale)
+	s.users = make(map[primitive.ObjectID]models.User)
+	s.sessions = make(map[string]models.Session)
+	s.audit = nil
+	s.snapshots = make(map[primitive.ObjectID]models.ResetSnapshot)
+	s.years = make(map[primitive.ObjectID]models.AcademicYear)
+	s.terms = make(map[primitive.ObjectID]models.Term)
+}
+
+func (s *MemoryStore) Reset() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.clear()
+	return nil
+}
+
+func (s *MemoryStore) Close() error {
+	return nil
+}
+
+func (s *MemoryStore) GetGroups(includeArchived bool) ([]models.Group, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var groups []models.Group
+	for _, g := range s.groups {
+		if includeArchived || !g.Archived {
+			groups = append(groups, g)
+		}
+	}
+	sort.Slice(groups, func(i, j int) bool { return groups[i].Name < groups[j].Name })
+	return groups, nil
+}
+
+func (s *MemoryStore) GetGroupByID(id primitive.ObjectID) (*models.Group, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	g, ok := s.groups[id]
+	if !ok {
+		return &models.Group{}, ErrNotFound
+	}
+	return &g, nil
+}
+
+func (s *MemoryStore) GetGroupBySlug(slug string) (*models.Group, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, g := range s.groups {
+		if g.Slug == slug {
+			return &g, nil
+		}
+	}
+	return &models.Group{}, ErrNotFound
+}
+
+// slugTaken вызывается под блокировкой.
+func (s *MemoryStore) slugTaken(exceptID primitive.ObjectID) func(string) (bool, error) {
+	return func(slug string) (bool, error) {
+		for _, g := range s.groups {
+			if g.Slug == slug && g.ID != exceptID {
+				return true, nil
+			}
+		}
+		return false, nil
+	}
+}
+
+func (s *MemoryStore) CreateGroup(name string) (*models.Group, error) {
+	name, err := cleanName(name)
+	if err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	slug, _ := uniqueSlug(name, s.slugTaken(primitive.NilObjectID))
+	group := models.Group{ID: primitive.NewObjectID(), Name: name, Slug: slug}
+	s.groups[group.ID] = group
+	return &group, nil
+}
+
+func (s *MemoryStore) RenameGroup(id primitive.ObjectID, name string) (*models.Group, error) {
+	name, err := cleanName(name)
+	if err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	group, ok := s.groups[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	group.Name = name
+	group.Slug, _ = uniqueSlug(name, s.slugTaken(id))
+	s.groups[id] = group
+	return &group, nil
+}
+
+func (s *MemoryStore) SetGroupArchived(id primitive.ObjectID, archived bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	group, ok := s.groups[id]
+	if !ok {
+		return ErrNotFound
+	}
+	group.Archived = archived
+	s.groups[id] = group
+	return nil
+}
+
+func (s *MemoryStore) GetStudentsByGroupID(groupID primitive.ObjectID) ([]models.Student, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.studentsOf(groupID), nil
+}
+
+// studentsOf вызывается под блокировкой.
+func (s *MemoryStore) studentsOf(groupID primitive.ObjectID) []models.Student {
+	var students []models.Student
+	for _, st := range s.students {
+		if st.GroupID == groupID {
+			students = append(students, st)
+		}
+	}
+	sort.Slice(students, func(i, j int) bool { return lessID(students[i].ID, students[j].ID) })
+	return students
+}
+
+func (s *MemoryStore) GetStudentByID(id primitive.ObjectID) (*models.Student, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	st, ok := s.students[id]
+	if !ok {
+		return &models.Student{}, ErrNotFound
+	}
+	return &st, nil
+}
+
+func (s *MemoryStore) CreateStudent(name string, groupID primitive.ObjectID) (*models.Student, error) {
+	name, err := cleanName(name)
+	if err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	student := models.Student{ID: primitive.NewObjectID(), Name: name, GroupID: groupID}
+	s.students[student.ID] = student
+	s.syncStudentDisciplineData(student.ID, groupID)
+	return &student, nil
+}
+
+func (s *MemoryStore) RenameStudent(studentID primitive.ObjectID, name string) error {
+	name, err := cleanName(name)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	st, ok := s.students[studentID]
+	if !ok {
+		return ErrNotFound
+	}
+	st.Name = name
+	st.Version++
+	s.students[studentID] = st
+	return nil
+}
+
+func (s *MemoryStore) MoveStudent(studentID, groupID primitive.ObjectID) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	st, ok := s.students[studentID]
+	if !ok {
+		return ErrNotFound
+	}
+	st.GroupID = groupID
+	st.Version++
+	s.students[studentID] = st
+	s.syncStudentDisciplineData(studentID, groupID)
+	return nil
+}
+
+func (s *MemoryStore) DeleteStudent(studentID primitive.ObjectID) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for id, d := range s.data {
+		if d.StudentID == studentID {
+			delete(s.data, id)
+		}
+	}
+	for id, m := range s.attendance {
+		if m.StudentID == studentID {
+			delete(s.attendance, id)
+		}
+	}
+	for id, m := range s.marks {
+		if m.StudentID == studentID {
+			delete(s.marks, id)
+		}
+	}
+	delete(s.students, studentID)
+	return nil
+}
+
+// syncStudentDisciplineData вызывается под блокировкой.
+func (s *MemoryStore) syncStudentDisciplineData(studentID, groupID primitive.ObjectID) {
+	for _, term := range s.openTerms() {
+		s.syncStudentTerm(studentID, groupID, term.ID)
+	}
+}
+
+// syncStudentTerm вызывается под блокировкой.
+func (s *MemoryStore) syncStudentTerm(studentID, groupID, termID primitive.ObjectID) {
+	stale, missing := planStudentSync(s.disciplinesOf(groupID, true), s.dataOf(studentID, termID))
+	for _, id := range stale {
+		delete(s.data, id)
+	}
+	for _, discID := range missing {
+		id := primitive.NewObjectID()
+		s.data[id] = models.StudentDisciplineData{ID: id, StudentID: studentID, DisciplineID: discID, TermID: termID}
+	}
+}
+
+func (s *MemoryStore) UpdateStudent(studentID primitive.ObjectID, version int, patch StudentPatch, audit []models.AuditEntry) error {
+	var name string
+	if patch.Name != nil {
+		var err error
+		if name, err = cleanName(*patch.Name); err != nil {
+			return err
+		}
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	st, ok := s.students[studentID]
+	if !ok {
+		return ErrNotFound
+	}
+	if st.Version != version {
+		return ErrConflict
+	}
+	if patch.Name != nil {
+		st.Name = name
+	}
+	if patch.GroupID != nil {
+		st.GroupID = *patch.GroupID
+	}
+	if patch.Comments != nil {
+		st.Comments = *patch.Comments
+	}
+	st.Version++
+	s.students[studentID] = st
+	if patch.GroupID != nil {
+		s.syncStudentDisciplineData(studentID, *patch.GroupID)
+	}
+	s.appendAudit(audit)
+	return nil
+}
+
+func (s *MemoryStore) GetDisciplinesByGroupID(groupID primitive.ObjectID) ([]models.Discipline, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.disciplinesOf(groupID, false), nil
+}
+
+func (s *MemoryStore) GetAllDisciplinesByGroupID(groupID primitive.ObjectID) ([]models.Discipline, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.disciplinesOf(groupID, true), nil
+}
+
+// disciplinesOf вызывается под блокировкой.
+func (s *MemoryStore) disciplinesOf(groupID primitive.ObjectID, includeRetired bool) []models.Discipline {
+	var list []models.Discipline
+	for _, d := range s.disciplines {
+		if d.GroupID == groupID && (includeRetired || !d.Retired) {
+			list = append(list, d)
+		}
+	}
+	sortDisciplines(list)
+	return list
+}
+
+func (s *MemoryStore) GetDisciplineByID(id primitive.ObjectID) (*models.Discipline, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	d, ok := s.disciplines[id]
+	if !ok {
+		return &models.Discipline{}, ErrNotFound
+	}
+	return &d, nil
+}
+
+func (s *MemoryStore) CreateDiscipline(groupID primitive.ObjectID, name string) (*models.Discipline, error) {
+	name, err := cleanName(name)
+	if err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	discipline := models.Discipline{
+		ID:       primitive.NewObjectID(),
+		Name:     name,
+		GroupID:  groupID,
+		Position: nextPosition(s.disciplinesOf(groupID, true)),
+	}
+	s.disciplines[discipline.ID] = discipline
+	s.syncDisciplineData(discipline.ID, groupID)
+	return &discipline, nil
+}
+
+// syncDisciplineData вызывается под блокировкой.
+func (s *MemoryStore) syncDisciplineData(disciplineID, groupID primitive.ObjectID) {
+	for _, term := range s.openTerms() {
+		have := make(map[primitive.ObjectID]bool)
+		for _, d := range s.data {
+			if d.DisciplineID == disciplineID && d.TermID == term.ID {
+				have[d.StudentID] = true
+			}
+		}
+		for _, st := range s.studentsOf(groupID) {
+			if !have[st.ID] {
+				id := primitive.NewObjectID()
+				s.data[id] = models.StudentDisciplineData{ID: id, StudentID: st.ID, DisciplineID: disciplineID, TermID: term.ID}
+			}
+		}
+	}
+}
+
+func (s *MemoryStore) RenameDiscipline(id primitive.ObjectID, name string) error {
+	name, err := cleanName(name)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	d, ok := s.disciplines[id]
+	if !ok {
+		return ErrNotFound
+	}
+	d.Name = name
+	s.disciplines[id] = d
+	return nil
+}
+
+func (s *MemoryStore) MoveDiscipline(id primitive.ObjectID, offset int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	discipline, ok := s.disciplines[id]
+	if !ok {
+		return ErrNotFound
+	}
+	for discID, position := range moveInList(s.disciplinesOf(discipline.GroupID, false), id, offset) {
+		d := s.disciplines[discID]
+		d.Position = position
+		s.disciplines[discID] = d
+	}
+	return nil
+}
+
+func (s *MemoryStore) SetDisciplineRetired(id primitive.ObjectID, retired bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	d, ok := s.disciplines[id]
+	if !ok {
+		return ErrNotFound
+	}
+	d.Retired = retired
+	s.disciplines[id] = d
+	if !retired {
+		s.syncDisciplineData(d.ID, d.GroupID)
+	}
+	return nil
+}
+
+func (s *MemoryStore) SetDisciplineTeacher(id primitive.ObjectID, teacherID *primitive.ObjectID) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	d, ok := s.disciplines[id]
+	if !ok {
+		return ErrNotFound
+	}
+	d.TeacherID = copyID(teacherID)
+	s.disciplines[id] = d
+	return nil
+}
+
+func (s *MemoryStore) GetAcademicYears() ([]models.AcademicYear, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var years []models.AcademicYear
+	for _, y := range s.years {
+		years = append(years, y)
+	}
+	sort.Slice(years, func(i, j int) bool { return years[i].Name < years[j].Name })
+	return years, nil
+}
+
+func (s *MemoryStore) CreateAcademicYear(name string) (*models.AcademicYear, error) {
+	name, err := cleanName(name)
+	if err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	year := models.AcademicYear{ID: primitive.NewObjectID(), Name: name}
+	s.years[year.ID] = year
+	return &year, nil
+}
+
+func (s *MemoryStore) GetTerms() ([]models.Term, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var terms []models.Term
+	for _, t := range s.terms {
+		terms = append(terms, t)
+	}
+	sortTerms(terms)
+	return terms, nil
+}
+
+// openTerms вызывается под блокировкой.
+func (s *MemoryStore) openTerms() []models.Term {
+	var terms []models.Term
+	for _, t := range s.terms {
+		if !t.Closed {
+			terms = append(terms, t)
+		}
+	}
+	return terms
+}
+
+// termClosed вызывается под блокировкой.
+func (s *MemoryStore) termClosed(id primitive.ObjectID) bool {
+	return s.terms[id].Closed
+}
+
+func (s *MemoryStore) GetTermByID(id primitive.ObjectID) (*models.Term, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	t, ok := s.terms[id]
+	if !ok {
+		return &models.Term{}, ErrNotFound
+	}
+	return &t, nil
+}
+
+func (s *MemoryStore) CreateTerm(term *models.Term) error {
+	name, err := cleanName(term.Name)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.years[term.YearID]; !ok {
+		return ErrNotFound
+	}
+	term.ID = primitive.NewObjectID()
+	term.Name = name
+	s.terms[term.ID] = *term
+	for _, st := range s.students {
+		s.syncStudentTerm(st.ID, st.GroupID, term.ID)
+	}
+	return nil
+}
+
+func (s *MemoryStore) SetTermClosed(id primitive.ObjectID, closed bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	term, ok := s.terms[id]
+	if !ok {
+		return ErrNotFound
+	}
+	term.Closed = closed
+	s.terms[id] = term
+	return nil
+}
+
+func (s *MemoryStore) AssignLegacyTerm(termID primitive.ObjectID) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	type record struct{ student, discipline primitive.ObjectID }
+	legacy := make(map[record]bool)
+	for _, d := range s.data {
+		if d.TermID.IsZero() {
+			legacy[record{d.StudentID, d.DisciplineID}] = true
+		}
+	}
+	for id, d := range s.data {
+		if d.TermID == termID && legacy[record{d.StudentID, d.DisciplineID}] {
+			delete(s.data, id)
+		}
+	}
+	for id, d := range s.data {
+		if d.TermID.IsZero() {
+			d.TermID = termID
+			s.data[id] = d
+		}
+	}
+	for id, l := range s.lessons {
+		if l.TermID.IsZero() {
+			l.TermID = termID
+			s.lessons[id] = l
+		}
+	}
+	for id, a := range s.assessments {
+		if a.TermID.IsZero() {
+			a.TermID = termID
+			s.assessments[id] = a
+		}
+	}
+	return nil
+}
+
+func (s *MemoryStore) GetStudentDisciplineData(studentID, termID primitive.ObjectID) ([]models.StudentDisciplineData, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.dataOf(studentID, termID), nil
+}
+
+// dataOf вызывается под блокировкой.
+func (s *MemoryStore) dataOf(studentID, termID primitive.ObjectID) []models.StudentDisciplineData {
+	var list []models.StudentDisciplineData
+	for _, d := range s.data {
+		if d.StudentID == studentID && d.TermID == termID {
+			list = append(list, d)
+		}
+	}
+	sort.Slice(list, func(i, j int) bool { return lessID(list[i].ID, list[j].ID) })
+	return list
+}
+
+func (s *MemoryStore) GetDisciplineDataFor(studentID, disciplineID, termID primitive.ObjectID) (*models.StudentDisciplineData, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, d := range s.data {
+		if d.StudentID == studentID && d.DisciplineID == disciplineID && d.TermID == termID {
+			return &d, nil
+		}
+	}
+	return &models.StudentDisciplineData{}, ErrNotFound
+}
+
+func (s *MemoryStore) GetDisciplineDataByID(id primitive.ObjectID) (*models.StudentDisciplineData, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	d, ok := s.data[id]
+	if !ok {
+		return &models.StudentDisciplineData{}, ErrNotFound
+	}
+	return &d, nil
+}
+
+func (s *MemoryStore) InsertDisciplineData(data *models.StudentDisciplineData, audit []models.AuditEntry) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if data.ID.IsZero() {
+		data.ID = primitive.NewObjectID()
+	}
+	s.data[data.ID] = *data
+	auditNewRows(audit, []models.StudentDisciplineData{*data})
+	s.appendAudit(audit)
+	return nil
+}
+
+func (s *MemoryStore) SaveStudentSheet(studentID primitive.ObjectID, version int, comments string, rows []models.StudentDisciplineData, audit []models.AuditEntry) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Проверяем всё до первой записи, чтобы не оставить половину изменений
+	st, ok := s.students[studentID]
+	if !ok {
+		return ErrNotFound
+	}
+	if st.Version != version {
+		return ErrConflict
+	}
+	rows = append([]models.StudentDisciplineData(nil), rows...)
+	for i := range rows {
+		rows[i].StudentID = studentID
+	}
+	if err := s.checkDataRows(rows); err != nil {
+		return err
+	}
+
+	st.Comments = comments
+	st.Version++
+	s.students[studentID] = st
+	s.writeDataRows(rows)
+	auditNewRows(audit, rows)
+	s.appendAudit(audit)
+	return nil
+}
+
+func (s *MemoryStore) SaveDisciplineData(rows []models.StudentDisciplineData, audit []models.AuditEntry) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.checkDataRows(rows); err != nil {
+		return err
+	}
+	s.writeDataRows(rows)
+	auditNewRows(audit, rows)
+	s.appendAudit(audit)
+	return nil
+}
+
+// checkDataRows сверяет версии строк с сохранёнными; вызывается под
+// блокировкой.
+func (s *MemoryStore) checkDataRows(rows []models.StudentDisciplineData) error {
+	type key struct{ student, discipline, term primitive.ObjectID }
+	existing := make(map[key]bool, len(s.data))
+	for _, d := range s.data {
+		existing[key{d.StudentID, d.DisciplineID, d.TermID}] = true
+	}
+	seen := make(map[primitive.ObjectID]bool, len(rows))
+	for _, row := range rows {
+		if row.ID.IsZero() {
+			k := key{row.StudentID, row.DisciplineID, row.TermID}
+			if existing[k] {
+				return ErrConflict
+			}
+			existing[k] = true
+			continue
+		}
+		d, ok := s.data[row.ID]
+		if !ok || seen[row.ID] || d.StudentID != row.StudentID || d.Version != row.Version {
+			return ErrConflict
+		}
+		seen[row.ID] = true
+	}
+	return nil
+}
+
+// writeDataRows пишет проверенные checkDataRows строки, обновляя их ID
+// и Version на месте; вызывается под блокировкой.
+func (s *MemoryStore) writeDataRows(rows []models.StudentDisciplineData) {
+	for i := range rows {
+		if rows[i].ID.IsZero() {
+			rows[i].ID, rows[i].Version = primitive.NewObjectID(), 1
+		} else {
+			rows[i].Version++
+		}
+		s.data[rows[i].ID] = rows[i]
+	}
+}
+
+func (s *MemoryStore) DeleteDisciplineData(dataID primitive.ObjectID, audit []models.AuditEntry) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.data[dataID]; !ok {
+		return ErrNotFound
+	}
+	delete(s.data, dataID)
+	s.appendAudit(audit)
+	return nil
+}
+
+func (s *MemoryStore) ResetDynamicData(snapshot *models.ResetSnapshot, audit func(*models.ResetSnapshot) []models.AuditEntry) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	scope := snapshot.Scope
+	snapshot.ID = primitive.NewObjectID()
+	snapshot.At = time.Now()
+	snapshot.Comments, snapshot.Records, snapshot.Attendance, snapshot.Marks = nil, nil, nil, nil
+	snapshot.RestoredAt = nil
+
+	for id, st := range s.students {
+		if !commentsInScope(scope, st.GroupID) {
+			continue
+		}
+		snapshot.Comments = append(snapshot.Comments, models.StudentComment{StudentID: id, Comments: st.Comments})
+		st.Comments = ""
+		st.Version++
+		s.students[id] = st
+	}
+	for id, d := range s.data {
+		if !s.disciplineInScope(scope, d.DisciplineID) || s.termClosed(d.TermID) {
+			continue
+		}
+		snapshot.Records = append(snapshot.Records, d)
+		d.Score = 0
+		d.TotalClasses = 0
+		d.AttendedClasses = 0
+		d.Version++
+		s.data[id] = d
+	}
+	for id, m := range s.attendance {
+		if l := s.lessons[m.LessonID]; s.disciplineInScope(scope, l.DisciplineID) && !s.termClosed(l.TermID) {
+			snapshot.Attendance = append(snapshot.Attendance, m)
+			delete(s.attendance, id)
+		}
+	}
+	for id, m := range s.marks {
+		if a := s.assessments[m.AssessmentID]; s.disciplineInScope(scope, a.DisciplineID) && !s.termClosed(a.TermID) {
+			snapshot.Marks = append(snapshot.Marks, m)
+			delete(s.marks, id)
+		}
+	}
+	s.snapshots[snapshot.ID] = *snapshot
+	if audit != nil {
+		s.appendAudit(audit(snapshot))
+	}
+	return nil
+}
+
+// disciplineInScope вызывается под блокировкой.
+func (s *MemoryStore) disciplineInScope(scope models.ResetScope, disciplineID primitive.ObjectID) bool {
+	switch {
+	case scope.DisciplineID != nil:
+		return disciplineID == *scope.DisciplineID
+	case scope.GroupID != nil:
+		return s.disciplines[disciplineID].GroupID == *scope.GroupID
+	}
+	return true
+}
+
+func (s *MemoryStore) GetResetSnapshots() ([]models.ResetSnapshot, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var snapshots []models.ResetSnapshot
+	for _, snap := range s.snapshots {
+		snapshots = append(snapshots, snap)
+	}
+	sortSnapshots(snapshots)
+	return snapshots, nil
+}
+
+func (s *MemoryStore) GetResetSnapshotByID(id primitive.ObjectID) (*models.ResetSnapshot, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	snap, ok := s.snapshots[id]
+	if !ok {
+		return &models.ResetSnapshot{}, ErrNotFound
+	}
+	return &snap, nil
+}
+
+func (s *MemoryStore) RestoreResetSnapshot(id primitive.ObjectID, audit []models.AuditEntry) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	snap, ok := s.snapshots[id]
+	if !ok {
+		return ErrNotFound
+	}
+	if snap.RestoredAt != nil {
+		return ErrConflict
+	}
+
+	for _, c := range snap.Comments {
+		if st, ok := s.students[c.StudentID]; ok {
+			st.Comments = c.Comments
+			st.Version++
+			s.students[st.ID] = st
+		}
+	}
+	for _, saved := range snap.Records {
+		if d, ok := s.data[saved.ID]; ok && !s.termClosed(d.TermID) {
+			d.Score, d.TotalClasses, d.AttendedClasses = saved.Score, saved.TotalClasses, saved.AttendedClasses
+			d.Version++
+			s.data[d.ID] = d
+		}
+	}
+
+	// Отметки возвращаются поверх поставленных после сброса; затем выводимые
+	// поля пересчитываются по всему журналу дисциплины
+	journaled := make(map[termKey]bool)
+	for _, m := range snap.Attendance {
+		lesson, ok := s.lessons[m.LessonID]
+		if !ok || s.termClosed(lesson.TermID) {
+			continue
+		}
+		for mid, cur := range s.attendance {
+			if cur.LessonID == m.LessonID && cur.StudentID == m.StudentID {
+				delete(s.attendance, mid)
+			}
+		}
+		s.attendance[m.ID] = m
+		journaled[termKey{lesson.DisciplineID, lesson.TermID}] = true
+	}
+	graded := make(map[termKey]bool)
+	for _, m := range snap.Marks {
+		assessment, ok := s.assessments[m.AssessmentID]
+		if !ok || s.termClosed(assessment.TermID) {
+			continue
+		}
+		for mid, cur := range s.marks {
+			if cur.AssessmentID == m.AssessmentID && cur.StudentID == m.StudentID {
+				delete(s.marks, mid)
+			}
+		}
+		s.marks[m.ID] = m
+		graded[termKey{assessment.DisciplineID, assessment.TermID}] = true
+	}
+	for k := range journaled {
+		s.recomputeAttendance(k.discipline, k.term)
+	}
+	for k := range graded {
+		s.recomputeScores(k.discipline, k.term)
+	}
+
+	now := time.Now()
+	snap.RestoredAt = &now
+	s.snapshots[id] = snap
+	s.appendAudit(audit)
+	return nil
+}
+
+func (s *MemoryStore) GetLessonsByGroupID(groupID, termID primitive.ObjectID) ([]models.Lesson, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var lessons []models.Lesson
+	for _, l := range s.lessons {
+		if l.GroupID == groupID && l.TermID == termID {
+			lessons = append(lessons, l)
+		}
+	}
+	sortLessons(lessons)
+	return lessons, nil
+}
+
+func (s *MemoryStore) GetLessonByID(id primitive.ObjectID) (*models.Lesson, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	l, ok := s.lessons[id]
+	if !ok {
+		return &models.Lesson{}, ErrNotFound
+	}
+	return &l, nil
+}
+
+func (s *MemoryStore) CreateLesson(lesson *models.Lesson) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if lesson.ID.IsZero() {
+		lesson.ID = primitive.NewObjectID()
+	}
+	s.lessons[lesson.ID] = *lesson
+	return nil
+}
+
+func (s *MemoryStore) DeleteLesson(id primitive.ObjectID, audit []models.AuditEntry) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	lesson, ok := s.lessons[id]
+	if !ok {
+		return ErrNotFound
+	}
+	for markID, m := range s.attendance {
+		if m.LessonID == id {
+			delete(s.attendance, markID)
+		}
+	}
+	delete(s.lessons, id)
+	s.recomputeAttendance(lesson.DisciplineID, lesson.TermID)
+	s.appendAudit(audit)
+	return nil
+}
+
+func (s *MemoryStore) GetAttendanceByLesson(lessonID primitive.ObjectID) ([]models.AttendanceMark, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var marks []models.AttendanceMark
+	for _, m := range s.attendance {
+		if m.LessonID == lessonID {
+			marks = append(marks, m)
+		}
+	}
+	return marks, nil
+}
+
+func (s *MemoryStore) SaveAttendance(lessonID primitive.ObjectID, marks map[primitive.ObjectID]models.AttendanceStatus, audit []models.AuditEntry) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	lesson, ok := s.lessons[lessonID]
+	if !ok {
+		return ErrNotFound
+	}
+	existing := make(map[primitive.ObjectID]primitive.ObjectID)
+	for id, m := range s.attendance {
+		if m.LessonID == lessonID {
+			existing[m.StudentID] = id
+		}
+	}
+	for studentID, status := range marks {
+		id, ok := existing[studentID]
+		if !ok {
+			id = primitive.NewObjectID()
+		}
+		s.attendance[id] = models.AttendanceMark{ID: id, LessonID: lessonID, StudentID: studentID, Status: status}
+	}
+	s.recomputeAttendance(lesson.DisciplineID, lesson.TermID)
+	s.appendAudit(audit)
+	return nil
+}
+
+// recomputeAttendance вызывается под блокировкой.
+func (s *MemoryStore) recomputeAttendance(disciplineID, termID primitive.ObjectID) {
+	var marks []models.AttendanceMark
+	for _, m := range s.attendance {
+		if l, ok := s.lessons[m.LessonID]; ok && l.DisciplineID == disciplineID && l.TermID == termID {
+			marks = append(marks, m)
+		}
+	}
+	counts := tallyAttendance(marks)
+	for id, d := range s.data {
+		if d.DisciplineID != disciplineID || d.TermID != termID {
+			continue
+		}
+		c := counts[d.StudentID]
+		d.TotalClasses = c.total
+		d.AttendedClasses = c.attended
+		s.data[id] = d
+	}
+}
+
+func (s *MemoryStore) GetAssessmentsByDisciplineID(disciplineID, termID primitive.ObjectID) ([]models.Assessment, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.assessmentsOf(disciplineID, termID), nil
+}
+
+func (s *MemoryStore) assessmentsOf(disciplineID, termID primitive.ObjectID) []models.Assessment {
+	var assessments []models.Assessment
+	for _, a := range s.assessments {
+		if a.DisciplineID == disciplineID && a.TermID == termID {
+			assessments = append(assessments, a)
+		}
+	}
+	sortAssessments(assessments)
+	return assessments
+}
+
+func (s *MemoryStore) GetAssessmentByID(id primitive.ObjectID) (*models.Assessment, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	a, ok := s.assessments[id]
+	if !ok {
+		return &models.Assessment{}, ErrNotFound
+	}
+	return &a, nil
+}
+
+func (s *MemoryStore) CreateAssessment(assessment *models.Assessment) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if assessment.ID.IsZero() {
+		assessment.ID = primitive.NewObjectID()
+	}
+	s.assessments[assessment.ID] = *assessment
+	return nil
+}
+
+func (s *MemoryStore) DeleteAssessment(id primitive.ObjectID, audit []models.AuditEntry) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	assessment, ok := s.assessments[id]
+	if !ok {
+		return ErrNotFound
+	}
+	for markID, m := range s.marks {
+		if m.AssessmentID == id {
+			delete(s.marks, markID)
+		}
+	}
+	delete(s.assessments, id)
+	s.recomputeScores(assessment.DisciplineID, assessment.TermID)
+	s.appendAudit(audit)
+	return nil
+}
+
+func (s *MemoryStore) GetMarksByAssessment(assessmentID primitive.ObjectID) ([]models.AssessmentMark, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var marks []models.AssessmentMark
+	for _, m := range s.marks {
+		if m.AssessmentID == assessmentID {
+			marks = append(marks, m)
+		}
+	}
+	return marks, nil
+}
+
+func (s *MemoryStore) GetMarksByStudent(studentID primitive.ObjectID) ([]models.AssessmentMark, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var marks []models.AssessmentMark
+	for _, m := range s.marks {
+		if m.StudentID == studentID {
+			marks = append(marks, m)
+		}
+	}
+	return marks, nil
+}
+
+func (s *MemoryStore) SaveAssessmentMarks(assessmentID primitive.ObjectID, points map[primitive.ObjectID]float64, audit []models.AuditEntry) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	assessment, ok := s.assessments[assessmentID]
+	if !ok {
+		return ErrNotFound
+	}
+	for id, m := range s.marks {
+		if m.AssessmentID == assessmentID {
+			delete(s.marks, id)
+		}
+	}
+	for studentID, p := range points {
+		id := primitive.NewObjectID()
+		s.marks[id] = models.AssessmentMark{ID: id, AssessmentID: assessmentID, StudentID: studentID, Points: p}
+	}
+	s.recomputeScores(assessment.DisciplineID, assessment.TermID)
+	s.appendAudit(audit)
+	return nil
+}
+
+// recomputeScores вызывается под блокировкой.
+func (s *MemoryStore) recomputeScores(disciplineID, termID primitive.ObjectID) {
+	assessments := s.assessmentsOf(disciplineID, termID)
+	if len(assessments) == 0 {
+		return
+	}
+	var marks []models.AssessmentMark
+	for _, m := range s.marks {
+		if a, ok := s.assessments[m.AssessmentID]; ok && a.DisciplineID == disciplineID && a.TermID == termID {
+			marks = append(marks, m)
+		}
+	}
+	scores := scoreAssessments(assessments, marks)
+	for id, d := range s.data {
+		if d.DisciplineID != disciplineID || d.TermID != termID {
+			continue
+		}
+		d.Score = scores[d.StudentID]
+		s.data[id] = d
+	}
+}
+
+func (s *MemoryStore) GetGradingScales() ([]models.GradingScale, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var scales []models.GradingScale
+	for _, sc := range s.scales {
+		scales = append(scales, copyScale(sc))
+	}
+	sort.Slice(scales, func(i, j int) bool { return lessID(scales[i].ID, scales[j].ID) })
+	return scales, nil
+}
+
+func (s *MemoryStore) GetGradingScaleByID(id primitive.ObjectID) (*models.GradingScale, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	sc, ok := s.scales[id]
+	if !ok {
+		return &models.GradingScale{}, ErrNotFound
+	}
+	sc = copyScale(sc)
+	return &sc, nil
+}
+
+func (s *MemoryStore) CreateGradingScale(scale *models.GradingScale) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if scale.ID.IsZero() {
+		scale.ID = primitive.NewObjectID()
+	}
+	s.scales[scale.ID] = copyScale(*scale)
+	return nil
+}
+
+func (s *MemoryStore) DeleteGradingScale(id primitive.ObjectID) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.scales[id]; !ok {
+		return ErrNotFound
+	}
+	for gid, g := range s.groups {
+		if g.GradingScaleID != nil && *g.GradingScaleID == id {
+			g.GradingScaleID = nil
+			s.groups[gid] = g
+		}
+	}
+	for did, d := range s.disciplines {
+		if d.GradingScaleID != nil && *d.GradingScaleID == id {
+			d.GradingScaleID = nil
+			s.disciplines[did] = d
+		}
+	}
+	delete(s.scales, id)
+	return nil
+}
+
+func (s *MemoryStore) SetGroupGradingScale(groupID primitive.ObjectID, scaleID *primitive.ObjectID) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	g, ok := s.groups[groupID]
+	if !ok {
+		return ErrNotFound
+	}
+	g.GradingScaleID = copyID(scaleID)
+	s.groups[groupID] = g
+	return nil
+}
+
+func (s *MemoryStore) SetDisciplineGradingScale(disciplineID primitive.ObjectID, scaleID *primitive.ObjectID) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	d, ok := s.disciplines[disciplineID]
+	if !ok {
+		return ErrNotFound
+	}
+	d.GradingScaleID = copyID(scaleID)
+	s.disciplines[disciplineID] = d
+	return nil
+}
+
+// copyScale и copyID не дают вызывающему коду менять данные хранилища
+// через общий срез или указатель.
+func copyScale(sc models.GradingScale) models.GradingScale {
+	sc.Bands = append([]models.GradeBand(nil), sc.Bands...)
+	return sc
+}
+
+func copyID(id *primitive.ObjectID) *primitive.ObjectID {
+	if id == nil {
+		return nil
+	}
+	c := *id
+	return &c
+}
+
+func (s *MemoryStore) GetUsers() ([]models.User, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var users []models.User
+	for _, u := range s.users {
+		users = append(users, copyUser(u))
+	}
+	sort.Slice(users, func(i, j int) bool { return users[i].Login < users[j].Login })
+	return users, nil
+}
+
+func (s *MemoryStore) GetUserByID(id primitive.ObjectID) (*models.User, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	u, ok := s.users[id]
+	if !ok {
+		return &models.User{}, ErrNotFound
+	}
+	u = copyUser(u)
+	return &u, nil
+}
+
+func (s *MemoryStore) GetUserByLogin(login string) (*models.User, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, u := range s.users {
+		if u.Login == login {
+			u = copyUser(u)
+			return &u, nil
+		}
+	}
+	return &models.User{}, ErrNotFound
+}
+
+// loginTaken вызывается под блокировкой.
+func (s *MemoryStore) loginTaken(login string, except primitive.ObjectID) bool {
+	for id, u := range s.users {
+		if u.Login == login && id != except {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *MemoryStore) CreateUser(user *models.User) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.loginTaken(user.Login, primitive.NilObjectID) {
+		return ErrLoginTaken
+	}
+	if user.ID.IsZero() {
+		user.ID = primitive.NewObjectID()
+	}
+	s.users[user.ID] = copyUser(*user)
+	return nil
+}
+
+func (s *MemoryStore) UpdateUser(user *models.User) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.users[user.ID]; !ok {
+		return ErrNotFound
+	}
+	if s.loginTaken(user.Login, user.ID) {
+		return ErrLoginTaken
+	}
+	s.users[user.ID] = copyUser(*user)
+	return nil
+}
+
+func (s *MemoryStore) DeleteUser(id primitive.ObjectID) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for token, sess := range s.sessions {
+		if sess.UserID == id {
+			delete(s.sessions, token)
+		}
+	}
+	for discID, d := range s.disciplines {
+		if d.TeacherID != nil && *d.TeacherID == id {
+			d.TeacherID = nil
+			s.disciplines[discID] = d
+		}
+	}
+	delete(s.users, id)
+	return nil
+}
+
+func (s *MemoryStore) CreateSession(session *models.Session) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Заодно выметаем просроченные сессии
+	now := time.Now()
+	for token, sess := range s.sessions {
+		if !sess.ExpiresAt.After(now) {
+			delete(s.sessions, token)
+		}
+	}
+	s.sessions[session.Token] = *session
+	return nil
+}
+
+func (s *MemoryStore) GetSession(token string) (*models.Session, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	sess, ok := s.sessions[token]
+	if !ok || !sess.ExpiresAt.After(time.Now()) {
+		return &models.Session{}, ErrNotFound
+	}
+	return &sess, nil
+}
+
+func (s *MemoryStore) DeleteSession(token string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.sessions, token)
+	return nil
+}
+
+// appendAudit вызывается под блокировкой вместе с изменением, о котором
+// пишет журнал.
+func (s *MemoryStore) appendAudit(entries []models.AuditEntry) {
+	for _, e := range entries {
+		if e.ID.IsZero() {
+			e.ID = primitive.NewObjectID()
+		}
+		s.audit = append(s.audit, e)
+	}
+}
+
+func (s *MemoryStore) GetAudit(filter models.AuditFilter) ([]models.AuditEntry, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var entries []models.AuditEntry
+	for i := len(s.audit) - 1; i >= 0; i-- {
+		if filter.Limit > 0 && len(entries) == filter.Limit {
+			break
+		}
+		if filter.Match(s.audit[i]) {
+			entries = append(entries, s.audit[i])
+		}
+	}
+	return entries, nil
+}
+
+func copyUser(u models.User) models.User {
+	u.StudentIDs = append([]primitive.ObjectID(nil), u.StudentIDs...)
+	return u
+}
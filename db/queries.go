@@ -4,24 +4,174 @@ package db
 import (
 	"context"
 	"electronic-diary/models"
-	
+	"errors"
+
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
-func GetGroupIDByName(name string) (primitive.ObjectID, error) {
+// GetGroups возвращает все группы, отсортированные по названию.
+// Архивные группы включаются только при includeArchived.
+func (s *MongoStore) GetGroups(includeArchived bool) ([]models.Group, error) {
 	ctx := context.Background()
+	filter := bson.M{}
+	if !includeArchived {
+		filter["archived"] = bson.M{"$ne": true}
+	}
+	cursor, err := s.groupsCol.Find(ctx, filter, options.Find().SetSort(bson.M{"name": 1}))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var groups []models.Group
+	err = cursor.All(ctx, &groups)
+	return groups, err
+}
+
+func (s *MongoStore) GetGroupByID(id primitive.ObjectID) (*models.Group, error) {
+	var group models.Group
+	err := findOne(s.groupsCol, bson.M{"_id": id}, &group)
+	return &group, err
+}
+
+func (s *MongoStore) GetGroupBySlug(slug string) (*models.Group, error) {
 	var group models.Group
-	err := groupsCol.FindOne(ctx, bson.M{"name": name}).Decode(&group)
+	err := findOne(s.groupsCol, bson.M{"slug": slug}, &group)
+	return &group, err
+}
+
+// slugTaken — занят ли slug какой-либо группой, кроме exceptID.
+func (s *MongoStore) slugTaken(exceptID primitive.ObjectID) func(string) (bool, error) {
+	return func(slug string) (bool, error) {
+		count, err := s.groupsCol.CountDocuments(context.Background(), bson.M{"slug": slug, "_id": bson.M{"$ne": exceptID}})
+		return count > 0, err
+	}
+}
+
+func (s *MongoStore) CreateGroup(name string) (*models.Group, error) {
+	name, err := cleanName(name)
+	if err != nil {
+		return nil, err
+	}
+	slug, err := uniqueSlug(name, s.slugTaken(primitive.NilObjectID))
+	if err != nil {
+		return nil, err
+	}
+
+	group := models.Group{ID: primitive.NewObjectID(), Name: name, Slug: slug}
+	_, err = s.groupsCol.InsertOne(context.Background(), group)
+	if err != nil {
+		return nil, duplicateConflict(err)
+	}
+	return &group, nil
+}
+
+// RenameGroup меняет название группы и пересчитывает её slug.
+func (s *MongoStore) RenameGroup(id primitive.ObjectID, name string) (*models.Group, error) {
+	name, err := cleanName(name)
+	if err != nil {
+		return nil, err
+	}
+	slug, err := uniqueSlug(name, s.slugTaken(id))
+	if err != nil {
+		return nil, err
+	}
+
+	ctx := context.Background()
+	_, err = s.groupsCol.UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$set": bson.M{"name": name, "slug": slug}})
+	if err != nil {
+		return nil, duplicateConflict(err)
+	}
+	return s.GetGroupByID(id)
+}
+
+// SetGroupArchived архивирует группу (или возвращает её из архива).
+// Данные группы при этом не удаляются.
+func (s *MongoStore) SetGroupArchived(id primitive.ObjectID, archived bool) error {
+	ctx := context.Background()
+	_, err := s.groupsCol.UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$set": bson.M{"archived": archived}})
+	return err
+}
+
+// ensureGroupSlugs проставляет slug группам, созданным до появления этого поля.
+func (s *MongoStore) ensureGroupSlugs() error {
+	ctx := context.Background()
+	cursor, err := s.groupsCol.Find(ctx, bson.M{"$or": []bson.M{{"slug": bson.M{"$exists": false}}, {"slug": ""}}})
 	if err != nil {
-		return primitive.NilObjectID, err
+		return err
+	}
+	defer cursor.Close(ctx)
+
+	var groups []models.Group
+	if err := cursor.All(ctx, &groups); err != nil {
+		return err
+	}
+	for _, g := range groups {
+		slug, err := uniqueSlug(g.Name, s.slugTaken(g.ID))
+		if err != nil {
+			return err
+		}
+		if _, err := s.groupsCol.UpdateOne(ctx, bson.M{"_id": g.ID}, bson.M{"$set": bson.M{"slug": slug}}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ensureIndexes создаёт уникальные индексы, которые в SQLite задаёт схема:
+// slug группы и запись студента по дисциплине в периоде. Без них две
+// одновременные вставки проходят проверку и обе сохраняются.
+func (s *MongoStore) ensureIndexes() error {
+	ctx := context.Background()
+	_, err := s.groupsCol.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "slug", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	})
+	if err != nil {
+		return err
+	}
+	_, err = s.studentDisciplineDataCol.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "studentId", Value: 1}, {Key: "disciplineId", Value: 1}, {Key: "termId", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	})
+	return err
+}
+
+// duplicateConflict превращает нарушение уникального индекса в ErrConflict:
+// ту же группу или запись только что сохранил кто-то другой.
+func duplicateConflict(err error) error {
+	if mongo.IsDuplicateKeyError(err) {
+		return ErrConflict
 	}
-	return group.ID, nil
+	return err
 }
 
-func GetStudentsByGroupID(groupID primitive.ObjectID) ([]models.Student, error) {
+// insertMissingData заводит пустые записи. Запись, которую между
+// проверкой и вставкой успел завести параллельный запрос, уже есть —
+// это не ошибка, остальные записи всё равно вставляются.
+func (s *MongoStore) insertMissingData(ctx context.Context, entries []interface{}) error {
+	if len(entries) == 0 {
+		return nil
+	}
+	_, err := s.studentDisciplineDataCol.InsertMany(ctx, entries, options.InsertMany().SetOrdered(false))
+	var bwe mongo.BulkWriteException
+	if !errors.As(err, &bwe) || bwe.WriteConcernError != nil || len(bwe.WriteErrors) == 0 {
+		return err
+	}
+	for _, we := range bwe.WriteErrors {
+		if we.Code != 11000 { // duplicate key
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *MongoStore) GetStudentsByGroupID(groupID primitive.ObjectID) ([]models.Student, error) {
 	ctx := context.Background()
-	cursor, err := studentsCol.Find(ctx, bson.M{"groupId": groupID})
+	cursor, err := s.studentsCol.Find(ctx, bson.M{"groupId": groupID})
 	if err != nil {
 		return nil, err
 	}
@@ -32,16 +182,27 @@ func GetStudentsByGroupID(groupID primitive.ObjectID) ([]models.Student, error)
 	return students, err
 }
 
-func GetStudentByID(id primitive.ObjectID) (*models.Student, error) {
-	ctx := context.Background()
+func (s *MongoStore) GetStudentByID(id primitive.ObjectID) (*models.Student, error) {
 	var student models.Student
-	err := studentsCol.FindOne(ctx, bson.M{"_id": id}).Decode(&student)
+	err := findOne(s.studentsCol, bson.M{"_id": id}, &student)
 	return &student, err
 }
 
-func GetDisciplinesByGroupID(groupID primitive.ObjectID) ([]models.Discipline, error) {
+// GetDisciplinesByGroupID возвращает действующие дисциплины группы в порядке
+// их расположения. Выведенные из программы дисциплины не попадают в список.
+func (s *MongoStore) GetDisciplinesByGroupID(groupID primitive.ObjectID) ([]models.Discipline, error) {
+	return s.findDisciplines(bson.M{"groupId": groupID, "retired": bson.M{"$ne": true}})
+}
+
+// GetAllDisciplinesByGroupID возвращает все дисциплины группы, включая выведенные.
+func (s *MongoStore) GetAllDisciplinesByGroupID(groupID primitive.ObjectID) ([]models.Discipline, error) {
+	return s.findDisciplines(bson.M{"groupId": groupID})
+}
+
+func (s *MongoStore) findDisciplines(filter bson.M) ([]models.Discipline, error) {
 	ctx := context.Background()
-	cursor, err := disciplinesCol.Find(ctx, bson.M{"groupId": groupID})
+	opts := options.Find().SetSort(bson.D{{Key: "position", Value: 1}, {Key: "_id", Value: 1}})
+	cursor, err := s.disciplinesCol.Find(ctx, filter, opts)
 	if err != nil {
 		return nil, err
 	}
@@ -52,58 +213,525 @@ func GetDisciplinesByGroupID(groupID primitive.ObjectID) ([]models.Discipline, e
 	return disciplines, err
 }
 
-func GetStudentDisciplineData(studentID primitive.ObjectID) ([]models.StudentDisciplineData, error) {
+func (s *MongoStore) GetDisciplineByID(id primitive.ObjectID) (*models.Discipline, error) {
+	var discipline models.Discipline
+	err := findOne(s.disciplinesCol, bson.M{"_id": id}, &discipline)
+	return &discipline, err
+}
+
+// CreateDiscipline добавляет дисциплину в конец списка группы и заводит
+// пустые записи StudentDisciplineData для всех студентов группы.
+func (s *MongoStore) CreateDiscipline(groupID primitive.ObjectID, name string) (*models.Discipline, error) {
+	name, err := cleanName(name)
+	if err != nil {
+		return nil, err
+	}
 	ctx := context.Background()
-	cursor, err := studentDisciplineDataCol.Find(ctx, bson.M{"studentId": studentID})
+
+	existing, err := s.GetAllDisciplinesByGroupID(groupID)
 	if err != nil {
 		return nil, err
 	}
-	defer cursor.Close(ctx)
 
-	var data []models.StudentDisciplineData
-	err = cursor.All(ctx, &data)
-	return data, err
+	discipline := models.Discipline{ID: primitive.NewObjectID(), Name: name, GroupID: groupID, Position: nextPosition(existing)}
+	if _, err := s.disciplinesCol.InsertOne(ctx, discipline); err != nil {
+		return nil, err
+	}
+
+	if err := s.syncDisciplineData(discipline.ID, groupID); err != nil {
+		return nil, err
+	}
+	return &discipline, nil
+}
+
+// syncDisciplineData заводит пустые записи по дисциплине тем студентам группы,
+// у которых их ещё нет в открытых периодах.
+func (s *MongoStore) syncDisciplineData(disciplineID, groupID primitive.ObjectID) error {
+	ctx := context.Background()
+
+	students, err := s.GetStudentsByGroupID(groupID)
+	if err != nil {
+		return err
+	}
+	terms, err := s.findTerms(bson.M{"closed": false})
+	if err != nil {
+		return err
+	}
+	for _, term := range terms {
+		existing, err := s.findDisciplineData(ctx, bson.M{"disciplineId": disciplineID, "termId": term.ID})
+		if err != nil {
+			return err
+		}
+
+		have := make(map[primitive.ObjectID]bool, len(existing))
+		for _, d := range existing {
+			have[d.StudentID] = true
+		}
+		var entries []interface{}
+		for _, st := range students {
+			if !have[st.ID] {
+				entries = append(entries, models.StudentDisciplineData{StudentID: st.ID, DisciplineID: disciplineID, TermID: term.ID})
+			}
+		}
+		if err := s.insertMissingData(ctx, entries); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *MongoStore) RenameDiscipline(id primitive.ObjectID, name string) error {
+	name, err := cleanName(name)
+	if err != nil {
+		return err
+	}
+	ctx := context.Background()
+	_, err = s.disciplinesCol.UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$set": bson.M{"name": name}})
+	return err
+}
+
+// MoveDiscipline сдвигает дисциплину на одну позицию вверх (offset = -1)
+// или вниз (offset = 1) среди действующих дисциплин группы.
+func (s *MongoStore) MoveDiscipline(id primitive.ObjectID, offset int) error {
+	discipline, err := s.GetDisciplineByID(id)
+	if err != nil {
+		return err
+	}
+	list, err := s.GetDisciplinesByGroupID(discipline.GroupID)
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	for discID, position := range moveInList(list, id, offset) {
+		if _, err := s.disciplinesCol.UpdateOne(ctx, bson.M{"_id": discID}, bson.M{"$set": bson.M{"position": position}}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SetDisciplineRetired выводит дисциплину из программы (или возвращает её).
+// Записи студентов по дисциплине при выводе сохраняются.
+func (s *MongoStore) SetDisciplineRetired(id primitive.ObjectID, retired bool) error {
+	ctx := context.Background()
+	_, err := s.disciplinesCol.UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$set": bson.M{"retired": retired}})
+	if err != nil || retired {
+		return err
+	}
+
+	// Пока дисциплина была выведена, в группу могли прийти новые студенты.
+	discipline, err := s.GetDisciplineByID(id)
+	if err != nil {
+		return err
+	}
+	return s.syncDisciplineData(discipline.ID, discipline.GroupID)
+}
+
+func (s *MongoStore) SetDisciplineTeacher(id primitive.ObjectID, teacherID *primitive.ObjectID) error {
+	update := bson.M{"$unset": bson.M{"teacherId": ""}}
+	if teacherID != nil {
+		update = bson.M{"$set": bson.M{"teacherId": *teacherID}}
+	}
+	res, err := s.disciplinesCol.UpdateOne(context.Background(), bson.M{"_id": id}, update)
+	if err == nil && res.MatchedCount == 0 {
+		return ErrNotFound
+	}
+	return err
+}
+
+func (s *MongoStore) GetStudentDisciplineData(studentID, termID primitive.ObjectID) ([]models.StudentDisciplineData, error) {
+	return s.findDisciplineData(context.Background(), bson.M{"studentId": studentID, "termId": termID})
+}
+
+func (s *MongoStore) GetDisciplineDataFor(studentID, disciplineID, termID primitive.ObjectID) (*models.StudentDisciplineData, error) {
+	var data models.StudentDisciplineData
+	err := findOne(s.studentDisciplineDataCol, bson.M{"studentId": studentID, "disciplineId": disciplineID, "termId": termID}, &data)
+	return &data, err
+}
+
+func (s *MongoStore) GetDisciplineDataByID(id primitive.ObjectID) (*models.StudentDisciplineData, error) {
+	var data models.StudentDisciplineData
+	err := findOne(s.studentDisciplineDataCol, bson.M{"_id": id}, &data)
+	return &data, err
+}
+
+func (s *MongoStore) InsertDisciplineData(data *models.StudentDisciplineData, audit []models.AuditEntry) error {
+	if data.ID.IsZero() {
+		data.ID = primitive.NewObjectID()
+	}
+	auditNewRows(audit, []models.StudentDisciplineData{*data})
+	return s.inTransaction(nil, func(ctx context.Context) error {
+		if _, err := s.studentDisciplineDataCol.InsertOne(ctx, data); err != nil {
+			return duplicateConflict(err)
+		}
+		return s.appendAudit(ctx, audit)
+	})
+}
+
+// CreateStudent добавляет студента в группу и заводит ему пустые записи
+// StudentDisciplineData по всем дисциплинам группы.
+func (s *MongoStore) CreateStudent(name string, groupID primitive.ObjectID) (*models.Student, error) {
+	name, err := cleanName(name)
+	if err != nil {
+		return nil, err
+	}
+	ctx := context.Background()
+
+	student := models.Student{ID: primitive.NewObjectID(), Name: name, GroupID: groupID}
+	if _, err := s.studentsCol.InsertOne(ctx, student); err != nil {
+		return nil, err
+	}
+	if err := s.syncStudentDisciplineData(student.ID, groupID); err != nil {
+		return nil, err
+	}
+	return &student, nil
 }
 
-func UpdateStudent(studentID primitive.ObjectID, comments string) error {
+func (s *MongoStore) RenameStudent(studentID primitive.ObjectID, name string) error {
+	name, err := cleanName(name)
+	if err != nil {
+		return err
+	}
 	ctx := context.Background()
-	_, err := studentsCol.UpdateOne(ctx, bson.M{"_id": studentID}, bson.M{"$set": bson.M{"comments": comments}})
+	_, err = s.studentsCol.UpdateOne(ctx, bson.M{"_id": studentID}, bson.M{"$set": bson.M{"name": name}, "$inc": bson.M{"version": 1}})
 	return err
 }
 
-func UpdateDisciplineData(dataID primitive.ObjectID, score, total, attended int) error {
+// MoveStudent переводит студента в другую группу: в открытых периодах
+// записи по дисциплинам старой группы удаляются, по дисциплинам новой —
+// создаются. Закрытые периоды хранят прежние записи.
+func (s *MongoStore) MoveStudent(studentID, groupID primitive.ObjectID) error {
+	ctx := context.Background()
+	_, err := s.studentsCol.UpdateOne(ctx, bson.M{"_id": studentID}, bson.M{"$set": bson.M{"groupId": groupID}, "$inc": bson.M{"version": 1}})
+	if err != nil {
+		return err
+	}
+	return s.syncStudentDisciplineData(studentID, groupID)
+}
+
+// DeleteStudent удаляет студента вместе со всеми его записями по дисциплинам
+// и отметками в журнале.
+func (s *MongoStore) DeleteStudent(studentID primitive.ObjectID) error {
 	ctx := context.Background()
-	_, err := studentDisciplineDataCol.UpdateOne(ctx,
-		bson.M{"_id": dataID},
-		bson.M{"$set": bson.M{
-			"score":            score,
-			"totalClasses":     total,
-			"attendedClasses":  attended,
-		}},
-	)
+	if _, err := s.studentDisciplineDataCol.DeleteMany(ctx, bson.M{"studentId": studentID}); err != nil {
+		return err
+	}
+	if _, err := s.attendanceCol.DeleteMany(ctx, bson.M{"studentId": studentID}); err != nil {
+		return err
+	}
+	if _, err := s.assessmentMarksCol.DeleteMany(ctx, bson.M{"studentId": studentID}); err != nil {
+		return err
+	}
+	_, err := s.studentsCol.DeleteOne(ctx, bson.M{"_id": studentID})
 	return err
 }
 
-func ResetDynamicData() error {
+// syncStudentDisciplineData приводит записи студента в открытых периодах
+// в соответствие с дисциплинами группы: недостающие создаёт, чужие удаляет.
+func (s *MongoStore) syncStudentDisciplineData(studentID, groupID primitive.ObjectID) error {
+	terms, err := s.findTerms(bson.M{"closed": false})
+	if err != nil {
+		return err
+	}
+	for _, term := range terms {
+		if err := s.syncStudentTerm(studentID, groupID, term.ID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *MongoStore) syncStudentTerm(studentID, groupID, termID primitive.ObjectID) error {
 	ctx := context.Background()
 
-	// Обнуляем комментарии у всех студентов
-	_, err := studentsCol.UpdateMany(ctx, bson.M{}, bson.M{"$set": bson.M{"comments": ""}})
+	disciplines, err := s.GetAllDisciplinesByGroupID(groupID)
+	if err != nil {
+		return err
+	}
+	existing, err := s.GetStudentDisciplineData(studentID, termID)
+	if err != nil {
+		return err
+	}
+
+	stale, missing := planStudentSync(disciplines, existing)
+	if len(stale) > 0 {
+		if _, err := s.studentDisciplineDataCol.DeleteMany(ctx, bson.M{"_id": bson.M{"$in": stale}}); err != nil {
+			return err
+		}
+	}
+
+	var entries []interface{}
+	for _, discID := range missing {
+		entries = append(entries, models.StudentDisciplineData{StudentID: studentID, DisciplineID: discID, TermID: termID})
+	}
+	return s.insertMissingData(ctx, entries)
+}
+
+// UpdateStudent меняет студента с фильтром по версии вместе с журналом;
+// записи новой группы, как и в MoveStudent, создаются отдельным шагом
+// после него.
+func (s *MongoStore) UpdateStudent(studentID primitive.ObjectID, version int, patch StudentPatch, audit []models.AuditEntry) error {
+	set := bson.M{}
+	if patch.Name != nil {
+		name, err := cleanName(*patch.Name)
+		if err != nil {
+			return err
+		}
+		set["name"] = name
+	}
+	if patch.GroupID != nil {
+		set["groupId"] = *patch.GroupID
+	}
+	if patch.Comments != nil {
+		set["comments"] = *patch.Comments
+	}
+	update := bson.M{"$inc": bson.M{"version": 1}}
+	if len(set) > 0 {
+		update["$set"] = set
+	}
+
+	err := s.inTransaction(nil, func(ctx context.Context) error {
+		res, err := s.studentsCol.UpdateOne(ctx, bson.M{"_id": studentID, "version": version}, update)
+		if err != nil {
+			return err
+		}
+		if res.MatchedCount == 0 {
+			n, err := s.studentsCol.CountDocuments(ctx, bson.M{"_id": studentID})
+			if err != nil {
+				return err
+			}
+			if n == 0 {
+				return ErrNotFound
+			}
+			return ErrConflict
+		}
+		return s.appendAudit(ctx, audit)
+	})
 	if err != nil {
 		return err
 	}
+	if patch.GroupID != nil {
+		return s.syncStudentDisciplineData(studentID, *patch.GroupID)
+	}
+	return nil
+}
+
+// SaveStudentSheet пишет комментарий и записи в одной транзакции.
+// Транзакции MongoDB есть только на наборе реплик; на одиночном сервере
+// версии студента и всех записей сверяются заранее, и при конфликте не
+// пишется ничего. Окно между проверкой и записью там остаётся: полную
+// атомарность даёт только набор реплик.
+func (s *MongoStore) SaveStudentSheet(studentID primitive.ObjectID, version int, comments string, rows []models.StudentDisciplineData, audit []models.AuditEntry) error {
+	rows = append([]models.StudentDisciplineData(nil), rows...)
+	for i := range rows {
+		rows[i].StudentID = studentID
+	}
+	writes, versions, created := dataWrites(rows)
+	auditNewRows(audit, rows)
 
-	// Обнуляем данные по дисциплинам
-	_, err = studentDisciplineDataCol.UpdateMany(ctx, bson.M{}, bson.M{
-		"$set": bson.M{
-			"score":            0,
-			"totalClasses":     0,
-			"attendedClasses":  0,
+	updateStudent := func(ctx context.Context) error {
+		res, err := s.studentsCol.UpdateOne(ctx,
+			bson.M{"_id": studentID, "version": version},
+			bson.M{"$set": bson.M{"comments": comments}, "$inc": bson.M{"version": 1}})
+		if err != nil {
+			return err
+		}
+		if res.MatchedCount == 0 {
+			n, err := s.studentsCol.CountDocuments(ctx, bson.M{"_id": studentID})
+			if err != nil {
+				return err
+			}
+			if n == 0 {
+				return ErrNotFound
+			}
+			return ErrConflict
+		}
+		return nil
+	}
+	checkStudent := func(ctx context.Context) error {
+		var student models.Student
+		err := s.studentsCol.FindOne(ctx, bson.M{"_id": studentID}).Decode(&student)
+		if err == mongo.ErrNoDocuments {
+			return ErrNotFound
+		}
+		if err != nil {
+			return err
+		}
+		if student.Version != version {
+			return ErrConflict
+		}
+		return nil
+	}
+
+	return s.inTransaction(
+		func(ctx context.Context) error {
+			if err := checkStudent(ctx); err != nil {
+				return err
+			}
+			return s.checkDataRows(ctx, versions, created)
 		},
+		func(ctx context.Context) error {
+			if err := updateStudent(ctx); err != nil {
+				return err
+			}
+			if err := s.checkDataRows(ctx, versions, created); err != nil {
+				return err
+			}
+			if err := s.writeDataRows(ctx, writes); err != nil {
+				return err
+			}
+			return s.appendAudit(ctx, audit)
+		})
+}
+
+// SaveDisciplineData сохраняет записи одним целым, как SaveStudentSheet,
+// но без комментария и версии студента.
+func (s *MongoStore) SaveDisciplineData(rows []models.StudentDisciplineData, audit []models.AuditEntry) error {
+	writes, versions, created := dataWrites(rows)
+	if len(writes) == 0 {
+		return nil
+	}
+	auditNewRows(audit, rows)
+	check := func(ctx context.Context) error {
+		return s.checkDataRows(ctx, versions, created)
+	}
+	return s.inTransaction(check, func(ctx context.Context) error {
+		if err := check(ctx); err != nil {
+			return err
+		}
+		if err := s.writeDataRows(ctx, writes); err != nil {
+			return err
+		}
+		return s.appendAudit(ctx, audit)
+	})
+}
+
+// inTransaction выполняет save в транзакции. Транзакции есть только на
+// наборе реплик; на одиночном сервере (о нём предупреждает
+// warnStandalone при запуске) сначала выполняется check — та же проверка
+// версий без записи, — и только потом save без отката. check может быть
+// nil, если проверять заранее нечего.
+func (s *MongoStore) inTransaction(check, save func(context.Context) error) error {
+	ctx := context.Background()
+	session, err := s.client.StartSession()
+	if err != nil {
+		return err
+	}
+	defer session.EndSession(ctx)
+
+	_, err = session.WithTransaction(ctx, func(sc mongo.SessionContext) (interface{}, error) {
+		return nil, save(sc)
 	})
+	if transactionsUnsupported(err) {
+		if check != nil {
+			if err := check(ctx); err != nil {
+				return err
+			}
+		}
+		return save(ctx)
+	}
+	return err
+}
+
+// dataWrites готовит запись строк одним BulkWrite: новые вставляются,
+// существующие обновляются с фильтром по версии. ID и Version строк
+// заполняются на месте. versions (версии существующих строк) и created
+// (ключи новых) нужны для checkDataRows.
+func dataWrites(rows []models.StudentDisciplineData) (writes []mongo.WriteModel, versions map[primitive.ObjectID]int, created []bson.M) {
+	versions = make(map[primitive.ObjectID]int, len(rows))
+	for i := range rows {
+		row := &rows[i]
+		if row.ID.IsZero() {
+			row.ID = primitive.NewObjectID()
+			row.Version = 1
+			created = append(created, bson.M{"studentId": row.StudentID, "disciplineId": row.DisciplineID, "termId": row.TermID})
+			writes = append(writes, mongo.NewInsertOneModel().SetDocument(*row))
+			continue
+		}
+		versions[row.ID] = row.Version
+		writes = append(writes, mongo.NewUpdateOneModel().
+			SetFilter(bson.M{"_id": row.ID, "studentId": row.StudentID, "version": row.Version}).
+			SetUpdate(bson.M{
+				"$set": bson.M{
+					"score":           row.Score,
+					"totalClasses":    row.TotalClasses,
+					"attendedClasses": row.AttendedClasses,
+				},
+				"$inc": bson.M{"version": 1},
+			}))
+		row.Version++
+	}
+	return writes, versions, created
+}
+
+// checkDataRows сверяет версии одним запросом до первой записи: без
+// транзакции устаревшая строка иначе нашлась бы посреди BulkWrite, когда
+// часть записей уже сохранена.
+func (s *MongoStore) checkDataRows(ctx context.Context, versions map[primitive.ObjectID]int, created []bson.M) error {
+	if len(versions) == 0 && len(created) == 0 {
+		return nil
+	}
+	ids := make([]primitive.ObjectID, 0, len(versions))
+	for id := range versions {
+		ids = append(ids, id)
+	}
+	match := append([]bson.M{{"_id": bson.M{"$in": ids}}}, created...)
+	cursor, err := s.studentDisciplineDataCol.Find(ctx, bson.M{"$or": match})
 	if err != nil {
 		return err
 	}
+	var existing []models.StudentDisciplineData
+	if err := cursor.All(ctx, &existing); err != nil {
+		return err
+	}
+	matched := 0
+	for _, d := range existing {
+		// Запись, которой нет среди versions, — уже созданная кем-то новая
+		v, ok := versions[d.ID]
+		if !ok || d.Version != v {
+			return ErrConflict
+		}
+		matched++
+	}
+	if matched < len(versions) {
+		return ErrConflict
+	}
+	return nil
+}
 
+// writeDataRows выполняет подготовленные dataWrites; строка, версия
+// которой успела измениться, или новая строка, которую уже кто-то
+// создал, — ErrConflict.
+func (s *MongoStore) writeDataRows(ctx context.Context, writes []mongo.WriteModel) error {
+	if len(writes) == 0 {
+		return nil
+	}
+	bw, err := s.studentDisciplineDataCol.BulkWrite(ctx, writes)
+	if err != nil {
+		return duplicateConflict(err)
+	}
+	if bw.MatchedCount+bw.InsertedCount < int64(len(writes)) {
+		return ErrConflict
+	}
 	return nil
+}
+
+// transactionsUnsupported — сервер отказал в транзакции (одиночный mongod).
+func transactionsUnsupported(err error) bool {
+	var cmdErr mongo.CommandError
+	return errors.As(err, &cmdErr) && cmdErr.Code == 20 // IllegalOperation
+}
+
+func (s *MongoStore) DeleteDisciplineData(dataID primitive.ObjectID, audit []models.AuditEntry) error {
+	return s.inTransaction(nil, func(ctx context.Context) error {
+		res, err := s.studentDisciplineDataCol.DeleteOne(ctx, bson.M{"_id": dataID})
+		if err != nil {
+			return err
+		}
+		if res.DeletedCount == 0 {
+			return ErrNotFound
+		}
+		return s.appendAudit(ctx, audit)
+	})
 }
\ No newline at end of file
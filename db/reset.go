@@ -0,0 +1,313 @@
+// db/reset.go
+package db
+
+import (
+	"context"
+	"electronic-diary/models"
+	"errors"
+	"log"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// resetFilters — фильтры записей, отметок журнала и баллов за работы,
+// попадающих в область сброса. Закрытые периоды в область не входят.
+func (s *MongoStore) resetFilters(scope models.ResetScope) (data, attendance, marks bson.M, err error) {
+	ctx := context.Background()
+	closed, err := s.closedTermIDs()
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	data = bson.M{"termId": bson.M{"$nin": closed}}
+	switch {
+	case scope.DisciplineID != nil:
+		data["disciplineId"] = *scope.DisciplineID
+	case scope.GroupID != nil:
+		ids, err := s.disciplinesCol.Distinct(ctx, "_id", bson.M{"groupId": *scope.GroupID})
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		data["disciplineId"] = bson.M{"$in": ids}
+	}
+
+	lessonIDs, err := s.lessonsCol.Distinct(ctx, "_id", data)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	assessmentIDs, err := s.assessmentsCol.Distinct(ctx, "_id", data)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	return data, bson.M{"lessonId": bson.M{"$in": lessonIDs}}, bson.M{"assessmentId": bson.M{"$in": assessmentIDs}}, nil
+}
+
+// ResetDynamicData сбрасывает данные одной транзакцией вместе с журналом.
+func (s *MongoStore) ResetDynamicData(snapshot *models.ResetSnapshot, audit func(*models.ResetSnapshot) []models.AuditEntry) error {
+	dataFilter, attendanceFilter, marksFilter, err := s.resetFilters(snapshot.Scope)
+	if err != nil {
+		return err
+	}
+	return s.inTransaction(nil, func(ctx context.Context) error {
+		return s.resetData(ctx, snapshot, dataFilter, attendanceFilter, marksFilter, audit)
+	})
+}
+
+// resetData снимает и обнуляет данные; ctx — контекст транзакции.
+func (s *MongoStore) resetData(ctx context.Context, snapshot *models.ResetSnapshot, dataFilter, attendanceFilter, marksFilter bson.M, audit func(*models.ResetSnapshot) []models.AuditEntry) error {
+	scope := snapshot.Scope
+	var err error
+
+	snapshot.ID = primitive.NewObjectID()
+	snapshot.At = time.Now()
+	snapshot.Comments = nil
+	snapshot.RestoredAt = nil
+
+	// Комментарии обнуляются при сбросе всего дневника или группы
+	var studentFilter bson.M
+	if scope.DisciplineID == nil {
+		studentFilter = bson.M{}
+		if scope.GroupID != nil {
+			studentFilter["groupId"] = *scope.GroupID
+		}
+		students, err := s.findStudents(ctx, studentFilter)
+		if err != nil {
+			return err
+		}
+		for _, st := range students {
+			snapshot.Comments = append(snapshot.Comments, models.StudentComment{StudentID: st.ID, Comments: st.Comments})
+		}
+	}
+	if snapshot.Records, err = s.findDisciplineData(ctx, dataFilter); err != nil {
+		return err
+	}
+	if snapshot.Attendance, err = s.findAttendance(ctx, attendanceFilter); err != nil {
+		return err
+	}
+	if snapshot.Marks, err = s.findAssessmentMarks(ctx, marksFilter); err != nil {
+		return err
+	}
+
+	// Снимок сохраняется до сброса: если сброс оборвётся на середине,
+	// прежние данные всё равно можно будет вернуть
+	if _, err := s.resetSnapshotsCol.InsertOne(ctx, snapshot); err != nil {
+		return err
+	}
+
+	if studentFilter != nil {
+		if _, err := s.studentsCol.UpdateMany(ctx, studentFilter, bson.M{
+			"$set": bson.M{"comments": ""},
+			"$inc": bson.M{"version": 1},
+		}); err != nil {
+			return err
+		}
+	}
+
+	// Обнуляем данные по дисциплинам
+	_, err = s.studentDisciplineDataCol.UpdateMany(ctx, dataFilter, bson.M{
+		"$set": bson.M{
+			"score":           0,
+			"totalClasses":    0,
+			"attendedClasses": 0,
+		},
+		"$inc": bson.M{"version": 1},
+	})
+	if err != nil {
+		return err
+	}
+
+	// Отметки журнала и баллы за работы тоже обнуляются — иначе
+	// счётчики и оценки пересчитаются обратно
+	if _, err = s.attendanceCol.DeleteMany(ctx, attendanceFilter); err != nil {
+		return err
+	}
+	if _, err = s.assessmentMarksCol.DeleteMany(ctx, marksFilter); err != nil {
+		return err
+	}
+	if audit == nil {
+		return nil
+	}
+	return s.appendAudit(ctx, audit(snapshot))
+}
+
+func (s *MongoStore) findStudents(ctx context.Context, filter bson.M) ([]models.Student, error) {
+	cursor, err := s.studentsCol.Find(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var students []models.Student
+	err = cursor.All(ctx, &students)
+	return students, err
+}
+
+func (s *MongoStore) findDisciplineData(ctx context.Context, filter bson.M) ([]models.StudentDisciplineData, error) {
+	cursor, err := s.studentDisciplineDataCol.Find(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var data []models.StudentDisciplineData
+	err = cursor.All(ctx, &data)
+	return data, err
+}
+
+func (s *MongoStore) GetResetSnapshots() ([]models.ResetSnapshot, error) {
+	ctx := context.Background()
+	opts := options.Find().SetSort(bson.D{{Key: "at", Value: -1}, {Key: "_id", Value: -1}})
+	cursor, err := s.resetSnapshotsCol.Find(ctx, bson.M{}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var snapshots []models.ResetSnapshot
+	err = cursor.All(ctx, &snapshots)
+	return snapshots, err
+}
+
+func (s *MongoStore) GetResetSnapshotByID(id primitive.ObjectID) (*models.ResetSnapshot, error) {
+	var snap models.ResetSnapshot
+	err := findOne(s.resetSnapshotsCol, bson.M{"_id": id}, &snap)
+	return &snap, err
+}
+
+// RestoreResetSnapshot возвращает данные снимка одной транзакцией вместе
+// с журналом: если восстановление оборвётся, не останется ни половины
+// данных, ни отметки restoredAt.
+func (s *MongoStore) RestoreResetSnapshot(id primitive.ObjectID, audit []models.AuditEntry) error {
+	check := func(context.Context) error {
+		snap, err := s.GetResetSnapshotByID(id)
+		if err != nil {
+			return err
+		}
+		if snap.RestoredAt != nil {
+			return ErrConflict
+		}
+		return nil
+	}
+	return s.inTransaction(check, func(ctx context.Context) error {
+		// Отметка о восстановлении ставится первой: второе восстановление
+		// того же снимка не пройдёт даже при одновременных запросах
+		var snap models.ResetSnapshot
+		err := s.resetSnapshotsCol.FindOneAndUpdate(ctx,
+			bson.M{"_id": id, "restoredAt": bson.M{"$exists": false}},
+			bson.M{"$set": bson.M{"restoredAt": time.Now()}},
+		).Decode(&snap)
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			if err := check(ctx); err != nil {
+				return err
+			}
+			return ErrConflict
+		}
+		if err != nil {
+			return err
+		}
+		err = s.restoreSnapshot(ctx, &snap)
+		if err == nil {
+			err = s.appendAudit(ctx, audit)
+		}
+		if err != nil && mongo.SessionFromContext(ctx) == nil {
+			// Без транзакции записанное не откатить, но снятая отметка
+			// позволит повторить восстановление: оно только выставляет
+			// значения снимка, и второй проход их не испортит
+			if _, uerr := s.resetSnapshotsCol.UpdateOne(context.Background(), bson.M{"_id": id},
+				bson.M{"$unset": bson.M{"restoredAt": ""}}); uerr != nil {
+				log.Printf("Не удалось снять отметку восстановления снимка %s: %v", id.Hex(), uerr)
+			}
+		}
+		return err
+	})
+}
+
+// restoreSnapshot записывает данные снимка; ctx — контекст транзакции.
+func (s *MongoStore) restoreSnapshot(ctx context.Context, snap *models.ResetSnapshot) error {
+	for _, c := range snap.Comments {
+		_, err := s.studentsCol.UpdateOne(ctx, bson.M{"_id": c.StudentID}, bson.M{
+			"$set": bson.M{"comments": c.Comments},
+			"$inc": bson.M{"version": 1},
+		})
+		if err != nil {
+			return err
+		}
+	}
+	closed, err := s.closedTermIDs()
+	if err != nil {
+		return err
+	}
+	frozen := make(map[interface{}]bool, len(closed))
+	for _, id := range closed {
+		frozen[id] = true
+	}
+	for _, d := range snap.Records {
+		_, err := s.studentDisciplineDataCol.UpdateOne(ctx, bson.M{"_id": d.ID, "termId": bson.M{"$nin": closed}}, bson.M{
+			"$set": bson.M{"score": d.Score, "totalClasses": d.TotalClasses, "attendedClasses": d.AttendedClasses},
+			"$inc": bson.M{"version": 1},
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	// Отметки возвращаются поверх поставленных после сброса (занятия и
+	// работы, удалённые с тех пор, пропускаются); затем выводимые поля
+	// пересчитываются по всему журналу дисциплины
+	journaled := make(map[termKey]bool)
+	for _, m := range snap.Attendance {
+		lesson, err := s.GetLessonByID(m.LessonID)
+		if errors.Is(err, ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if frozen[lesson.TermID] {
+			continue
+		}
+		_, err = s.attendanceCol.UpdateOne(ctx,
+			bson.M{"lessonId": m.LessonID, "studentId": m.StudentID},
+			bson.M{"$set": bson.M{"status": m.Status}},
+			options.Update().SetUpsert(true))
+		if err != nil {
+			return err
+		}
+		journaled[termKey{lesson.DisciplineID, lesson.TermID}] = true
+	}
+	graded := make(map[termKey]bool)
+	for _, m := range snap.Marks {
+		assessment, err := s.GetAssessmentByID(m.AssessmentID)
+		if errors.Is(err, ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if frozen[assessment.TermID] {
+			continue
+		}
+		_, err = s.assessmentMarksCol.UpdateOne(ctx,
+			bson.M{"assessmentId": m.AssessmentID, "studentId": m.StudentID},
+			bson.M{"$set": bson.M{"points": m.Points}},
+			options.Update().SetUpsert(true))
+		if err != nil {
+			return err
+		}
+		graded[termKey{assessment.DisciplineID, assessment.TermID}] = true
+	}
+	for k := range journaled {
+		if err := s.recomputeAttendance(ctx, k.discipline, k.term); err != nil {
+			return err
+		}
+	}
+	for k := range graded {
+		if err := s.recomputeScores(ctx, k.discipline, k.term); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,26 @@
+// db/slug.go
+package db
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Slugify превращает название группы в короткий адрес для URL:
+// "Backend 2025" → "backend-2025". Кириллица сохраняется как есть.
+func Slugify(name string) string {
+	var b strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(strings.TrimSpace(name)) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			b.WriteRune(r)
+			dash = false
+			continue
+		}
+		if !dash && b.Len() > 0 {
+			b.WriteRune('-')
+			dash = true
+		}
+	}
+	return strings.TrimSuffix(b.String(), "-")
+}
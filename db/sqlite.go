@@ -0,0 +1,1896 @@
+// db/sqlite.go
+package db
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"electronic-diary/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	_ "modernc.org/sqlite"
+)
+
+// SQLiteStore — реализация Store поверх файла SQLite для школ без MongoDB.
+// Идентификаторы хранятся как hex-строки ObjectID, поэтому URL и JSON
+// выглядят одинаково при любом хранилище.
+type SQLiteStore struct {
+	db *sql.DB
+}
+
+const sqliteSchema = `
+CREATE TABLE IF NOT EXISTS groups (
+	id       TEXT PRIMARY KEY,
+	name     TEXT NOT NULL,
+	slug     TEXT NOT NULL UNIQUE,
+	archived INTEGER NOT NULL DEFAULT 0
+);
+CREATE TABLE IF NOT EXISTS students (
+	id       TEXT PRIMARY KEY,
+	name     TEXT NOT NULL,
+	group_id TEXT NOT NULL,
+	comments TEXT NOT NULL DEFAULT '',
+	version  INTEGER NOT NULL DEFAULT 0
+);
+CREATE INDEX IF NOT EXISTS students_group ON students(group_id);
+CREATE TABLE IF NOT EXISTS disciplines (
+	id       TEXT PRIMARY KEY,
+	name     TEXT NOT NULL,
+	group_id TEXT NOT NULL,
+	position INTEGER NOT NULL DEFAULT 0,
+	retired  INTEGER NOT NULL DEFAULT 0
+);
+CREATE INDEX IF NOT EXISTS disciplines_group ON disciplines(group_id);
+CREATE TABLE IF NOT EXISTS student_discipline_data (
+	id               TEXT PRIMARY KEY,
+	student_id       TEXT NOT NULL,
+	discipline_id    TEXT NOT NULL,
+	term_id          TEXT NOT NULL DEFAULT '',
+	score            INTEGER NOT NULL DEFAULT 0,
+	total_classes    INTEGER NOT NULL DEFAULT 0,
+	attended_classes INTEGER NOT NULL DEFAULT 0,
+	version          INTEGER NOT NULL DEFAULT 0,
+	UNIQUE (student_id, discipline_id, term_id)
+);
+CREATE TABLE IF NOT EXISTS lessons (
+	id            TEXT PRIMARY KEY,
+	group_id      TEXT NOT NULL,
+	discipline_id TEXT NOT NULL,
+	date          TEXT NOT NULL,
+	topic         TEXT NOT NULL DEFAULT ''
+);
+CREATE INDEX IF NOT EXISTS lessons_group ON lessons(group_id);
+CREATE TABLE IF NOT EXISTS attendance (
+	id         TEXT PRIMARY KEY,
+	lesson_id  TEXT NOT NULL,
+	student_id TEXT NOT NULL,
+	status     TEXT NOT NULL,
+	UNIQUE (lesson_id, student_id)
+);
+CREATE TABLE IF NOT EXISTS assessments (
+	id            TEXT PRIMARY KEY,
+	group_id      TEXT NOT NULL,
+	discipline_id TEXT NOT NULL,
+	name          TEXT NOT NULL,
+	date          TEXT NOT NULL,
+	max_points    REAL NOT NULL,
+	weight        REAL NOT NULL DEFAULT 1
+);
+CREATE INDEX IF NOT EXISTS assessments_discipline ON assessments(discipline_id);
+CREATE TABLE IF NOT EXISTS assessment_marks (
+	id            TEXT PRIMARY KEY,
+	assessment_id TEXT NOT NULL,
+	student_id    TEXT NOT NULL,
+	points        REAL NOT NULL,
+	UNIQUE (assessment_id, student_id)
+);
+CREATE TABLE IF NOT EXISTS users (
+	id            TEXT PRIMARY KEY,
+	login         TEXT NOT NULL UNIQUE,
+	name          TEXT NOT NULL DEFAULT '',
+	role          TEXT NOT NULL,
+	password_hash TEXT NOT NULL,
+	student_ids   TEXT NOT NULL DEFAULT '[]'
+);
+CREATE TABLE IF NOT EXISTS sessions (
+	token      TEXT PRIMARY KEY,
+	user_id    TEXT NOT NULL,
+	expires_at TEXT NOT NULL
+);
+CREATE TABLE IF NOT EXISTS grading_scales (
+	id    TEXT PRIMARY KEY,
+	key   TEXT NOT NULL DEFAULT '',
+	name  TEXT NOT NULL,
+	bands TEXT NOT NULL
+);
+CREATE TABLE IF NOT EXISTS audit_log (
+	id            TEXT PRIMARY KEY,
+	at            TEXT NOT NULL,
+	actor_id      TEXT NOT NULL,
+	actor_name    TEXT NOT NULL,
+	entity        TEXT NOT NULL,
+	entity_id     TEXT NOT NULL,
+	student_id    TEXT,
+	discipline_id TEXT,
+	field         TEXT NOT NULL,
+	old_value     TEXT NOT NULL,
+	new_value     TEXT NOT NULL
+);
+CREATE INDEX IF NOT EXISTS audit_log_at ON audit_log(at);
+CREATE INDEX IF NOT EXISTS audit_log_student ON audit_log(student_id);
+CREATE TABLE IF NOT EXISTS academic_years (
+	id   TEXT PRIMARY KEY,
+	name TEXT NOT NULL
+);
+CREATE TABLE IF NOT EXISTS terms (
+	id         TEXT PRIMARY KEY,
+	year_id    TEXT NOT NULL,
+	name       TEXT NOT NULL,
+	start_date TEXT NOT NULL,
+	end_date   TEXT NOT NULL,
+	closed     INTEGER NOT NULL DEFAULT 0
+);
+CREATE TABLE IF NOT EXISTS reset_snapshots (
+	id            TEXT PRIMARY KEY,
+	at            TEXT NOT NULL,
+	actor_id      TEXT NOT NULL,
+	actor_name    TEXT NOT NULL,
+	group_id      TEXT,
+	discipline_id TEXT,
+	scope_name    TEXT NOT NULL,
+	data          TEXT NOT NULL,
+	restored_at   TEXT
+);
+`
+
+// sqliteColumns — колонки, добавленные после первой версии схемы.
+// В уже существующие базы они доставляются через ALTER TABLE.
+var sqliteColumns = []struct{ table, column, definition string }{
+	{"groups", "grading_scale_id", "TEXT"},
+	{"disciplines", "grading_scale_id", "TEXT"},
+	{"disciplines", "teacher_id", "TEXT"},
+	{"students", "version", "INTEGER NOT NULL DEFAULT 0"},
+	{"student_discipline_data", "version", "INTEGER NOT NULL DEFAULT 0"},
+	{"lessons", "term_id", "TEXT NOT NULL DEFAULT ''"},
+	{"assessments", "term_id", "TEXT NOT NULL DEFAULT ''"},
+}
+
+// sqliteTables — таблицы в порядке удаления при Reset.
+var sqliteTables = []string{"reset_snapshots", "audit_log", "sessions", "users", "grading_scales", "assessment_marks", "assessments", "attendance", "lessons", "student_discipline_data", "terms", "academic_years", "disciplines", "students", "groups"}
+
+// NewSQLiteStore открывает (или создаёт) файл базы path и накатывает схему.
+func NewSQLiteStore(path string) (*SQLiteStore, error) {
+	conn, err := sql.Open("sqlite", path+"?_pragma=busy_timeout(5000)")
+	if err != nil {
+		return nil, fmt.Errorf("не удалось открыть SQLite: %w", err)
+	}
+	// SQLite не любит параллельных писателей — одного соединения достаточно
+	conn.SetMaxOpenConns(1)
+
+	if _, err := conn.Exec(sqliteSchema); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("не удалось создать схему SQLite: %w", err)
+	}
+	if err := addMissingColumns(conn); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("не удалось обновить схему SQLite: %w", err)
+	}
+	if err := addTermToDisciplineData(conn); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("не удалось обновить схему SQLite: %w", err)
+	}
+
+	log.Println("✅ Открыли SQLite:", path)
+	return &SQLiteStore{db: conn}, nil
+}
+
+func addMissingColumns(conn *sql.DB) error {
+	for _, c := range sqliteColumns {
+		var n int
+		err := conn.QueryRow("SELECT COUNT(*) FROM pragma_table_info(?) WHERE name = ?", c.table, c.column).Scan(&n)
+		if err != nil {
+			return err
+		}
+		if n > 0 {
+			continue
+		}
+		if _, err := conn.Exec("ALTER TABLE " + c.table + " ADD COLUMN " + c.column + " " + c.definition); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// addTermToDisciplineData пересобирает student_discipline_data баз,
+// созданных до появления периодов: ALTER TABLE не умеет менять UNIQUE,
+// а запись теперь уникальна в пределах периода. Старые строки получают
+// пустой term_id — их относит к периоду AssignLegacyTerm.
+func addTermToDisciplineData(conn *sql.DB) error {
+	var schema string
+	err := conn.QueryRow("SELECT sql FROM sqlite_master WHERE type = 'table' AND name = 'student_discipline_data'").Scan(&schema)
+	if err != nil || strings.Contains(schema, "term_id") {
+		return err
+	}
+
+	tx, err := conn.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	const columns = "id, student_id, discipline_id, score, total_classes, attended_classes, version"
+	stmts := []string{
+		`CREATE TABLE student_discipline_data_new (
+			id               TEXT PRIMARY KEY,
+			student_id       TEXT NOT NULL,
+			discipline_id    TEXT NOT NULL,
+			term_id          TEXT NOT NULL DEFAULT '',
+			score            INTEGER NOT NULL DEFAULT 0,
+			total_classes    INTEGER NOT NULL DEFAULT 0,
+			attended_classes INTEGER NOT NULL DEFAULT 0,
+			version          INTEGER NOT NULL DEFAULT 0,
+			UNIQUE (student_id, discipline_id, term_id)
+		)`,
+		"INSERT INTO student_discipline_data_new (" + columns + ") SELECT " + columns + " FROM student_discipline_data",
+		"DROP TABLE student_discipline_data",
+		"ALTER TABLE student_discipline_data_new RENAME TO student_discipline_data",
+	}
+	for _, stmt := range stmts {
+		if _, err := tx.Exec(stmt); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+func (s *SQLiteStore) Close() error {
+	return s.db.Close()
+}
+
+func (s *SQLiteStore) Reset() error {
+	for _, table := range sqliteTables {
+		if _, err := s.db.Exec("DELETE FROM " + table); err != nil {
+			return err
+		}
+	}
+	log.Println("Все данные удалены.")
+	return nil
+}
+
+// scanner — общий интерфейс *sql.Row и *sql.Rows.
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// hexID — обёртка для чтения ObjectID из TEXT-колонки.
+type hexID struct{ id *primitive.ObjectID }
+
+func (h hexID) Scan(src interface{}) error {
+	var s string
+	switch v := src.(type) {
+	case string:
+		s = v
+	case []byte:
+		s = string(v)
+	default:
+		return fmt.Errorf("неожиданный тип идентификатора %T", src)
+	}
+	// Пустой term_id — строки, созданные до появления периодов
+	if s == "" {
+		*h.id = primitive.NilObjectID
+		return nil
+	}
+	id, err := primitive.ObjectIDFromHex(s)
+	if err != nil {
+		return err
+	}
+	*h.id = id
+	return nil
+}
+
+// nullHexID — как hexID, но NULL превращается в nil.
+type nullHexID struct{ id **primitive.ObjectID }
+
+func (h nullHexID) Scan(src interface{}) error {
+	if src == nil {
+		*h.id = nil
+		return nil
+	}
+	var id primitive.ObjectID
+	if err := (hexID{&id}).Scan(src); err != nil {
+		return err
+	}
+	*h.id = &id
+	return nil
+}
+
+// nullHex — значение для nullable-колонки с идентификатором.
+func nullHex(id *primitive.ObjectID) interface{} {
+	if id == nil {
+		return nil
+	}
+	return id.Hex()
+}
+
+// notFound превращает sql.ErrNoRows в ErrNotFound.
+func notFound(err error) error {
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrNotFound
+	}
+	return err
+}
+
+const groupColumns = "id, name, slug, archived, grading_scale_id"
+
+func scanGroup(row scanner) (models.Group, error) {
+	var g models.Group
+	err := row.Scan(hexID{&g.ID}, &g.Name, &g.Slug, &g.Archived, nullHexID{&g.GradingScaleID})
+	return g, err
+}
+
+func (s *SQLiteStore) queryGroups(query string, args ...interface{}) ([]models.Group, error) {
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var groups []models.Group
+	for rows.Next() {
+		g, err := scanGroup(rows)
+		if err != nil {
+			return nil, err
+		}
+		groups = append(groups, g)
+	}
+	return groups, rows.Err()
+}
+
+func (s *SQLiteStore) GetGroups(includeArchived bool) ([]models.Group, error) {
+	query := "SELECT " + groupColumns + " FROM groups"
+	if !includeArchived {
+		query += " WHERE archived = 0"
+	}
+	return s.queryGroups(query + " ORDER BY name")
+}
+
+func (s *SQLiteStore) GetGroupByID(id primitive.ObjectID) (*models.Group, error) {
+	g, err := scanGroup(s.db.QueryRow("SELECT "+groupColumns+" FROM groups WHERE id = ?", id.Hex()))
+	return &g, notFound(err)
+}
+
+func (s *SQLiteStore) GetGroupBySlug(slug string) (*models.Group, error) {
+	g, err := scanGroup(s.db.QueryRow("SELECT "+groupColumns+" FROM groups WHERE slug = ?", slug))
+	return &g, notFound(err)
+}
+
+func (s *SQLiteStore) slugTaken(exceptID primitive.ObjectID) func(string) (bool, error) {
+	return func(slug string) (bool, error) {
+		var count int
+		err := s.db.QueryRow("SELECT COUNT(*) FROM groups WHERE slug = ? AND id <> ?", slug, exceptID.Hex()).Scan(&count)
+		return count > 0, err
+	}
+}
+
+func (s *SQLiteStore) CreateGroup(name string) (*models.Group, error) {
+	name, err := cleanName(name)
+	if err != nil {
+		return nil, err
+	}
+	slug, err := uniqueSlug(name, s.slugTaken(primitive.NilObjectID))
+	if err != nil {
+		return nil, err
+	}
+
+	group := models.Group{ID: primitive.NewObjectID(), Name: name, Slug: slug}
+	_, err = s.db.Exec("INSERT INTO groups (id, name, slug) VALUES (?, ?, ?)", group.ID.Hex(), group.Name, group.Slug)
+	if uniqueViolation(err) {
+		return nil, ErrConflict
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &group, nil
+}
+
+func (s *SQLiteStore) RenameGroup(id primitive.ObjectID, name string) (*models.Group, error) {
+	name, err := cleanName(name)
+	if err != nil {
+		return nil, err
+	}
+	slug, err := uniqueSlug(name, s.slugTaken(id))
+	if err != nil {
+		return nil, err
+	}
+	_, err = s.db.Exec("UPDATE groups SET name = ?, slug = ? WHERE id = ?", name, slug, id.Hex())
+	if uniqueViolation(err) {
+		return nil, ErrConflict
+	}
+	if err != nil {
+		return nil, err
+	}
+	return s.GetGroupByID(id)
+}
+
+func (s *SQLiteStore) SetGroupArchived(id primitive.ObjectID, archived bool) error {
+	_, err := s.db.Exec("UPDATE groups SET archived = ? WHERE id = ?", archived, id.Hex())
+	return err
+}
+
+const studentColumns = "id, name, group_id, comments, version"
+
+func scanStudent(row scanner) (models.Student, error) {
+	var st models.Student
+	err := row.Scan(hexID{&st.ID}, &st.Name, hexID{&st.GroupID}, &st.Comments, &st.Version)
+	return st, err
+}
+
+func (s *SQLiteStore) GetStudentsByGroupID(groupID primitive.ObjectID) ([]models.Student, error) {
+	rows, err := s.db.Query("SELECT "+studentColumns+" FROM students WHERE group_id = ? ORDER BY id", groupID.Hex())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var students []models.Student
+	for rows.Next() {
+		st, err := scanStudent(rows)
+		if err != nil {
+			return nil, err
+		}
+		students = append(students, st)
+	}
+	return students, rows.Err()
+}
+
+func (s *SQLiteStore) GetStudentByID(id primitive.ObjectID) (*models.Student, error) {
+	st, err := scanStudent(s.db.QueryRow("SELECT "+studentColumns+" FROM students WHERE id = ?", id.Hex()))
+	return &st, notFound(err)
+}
+
+func (s *SQLiteStore) CreateStudent(name string, groupID primitive.ObjectID) (*models.Student, error) {
+	name, err := cleanName(name)
+	if err != nil {
+		return nil, err
+	}
+
+	student := models.Student{ID: primitive.NewObjectID(), Name: name, GroupID: groupID}
+	err = s.inTx(func(tx *sql.Tx) error {
+		if _, err := tx.Exec("INSERT INTO students (id, name, group_id) VALUES (?, ?, ?)",
+			student.ID.Hex(), student.Name, groupID.Hex()); err != nil {
+			return err
+		}
+		return s.syncStudentDisciplineData(tx, student.ID, groupID)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &student, nil
+}
+
+func (s *SQLiteStore) RenameStudent(studentID primitive.ObjectID, name string) error {
+	name, err := cleanName(name)
+	if err != nil {
+		return err
+	}
+	_, err = s.db.Exec("UPDATE students SET name = ?, version = version + 1 WHERE id = ?", name, studentID.Hex())
+	return err
+}
+
+func (s *SQLiteStore) MoveStudent(studentID, groupID primitive.ObjectID) error {
+	return s.inTx(func(tx *sql.Tx) error {
+		if _, err := tx.Exec("UPDATE students SET group_id = ?, version = version + 1 WHERE id = ?", groupID.Hex(), studentID.Hex()); err != nil {
+			return err
+		}
+		return s.syncStudentDisciplineData(tx, studentID, groupID)
+	})
+}
+
+func (s *SQLiteStore) DeleteStudent(studentID primitive.ObjectID) error {
+	return s.inTx(func(tx *sql.Tx) error {
+		if _, err := tx.Exec("DELETE FROM student_discipline_data WHERE student_id = ?", studentID.Hex()); err != nil {
+			return err
+		}
+		if _, err := tx.Exec("DELETE FROM attendance WHERE student_id = ?", studentID.Hex()); err != nil {
+			return err
+		}
+		if _, err := tx.Exec("DELETE FROM assessment_marks WHERE student_id = ?", studentID.Hex()); err != nil {
+			return err
+		}
+		_, err := tx.Exec("DELETE FROM students WHERE id = ?", studentID.Hex())
+		return err
+	})
+}
+
+// syncStudentDisciplineData приводит записи студента в открытых периодах
+// в соответствие с дисциплинами группы: недостающие создаёт, чужие удаляет.
+func (s *SQLiteStore) syncStudentDisciplineData(tx *sql.Tx, studentID, groupID primitive.ObjectID) error {
+	terms, err := openTermIDs(tx)
+	if err != nil {
+		return err
+	}
+	for _, termID := range terms {
+		if err := syncStudentTermTx(tx, studentID, groupID, termID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func syncStudentTermTx(tx *sql.Tx, studentID, groupID, termID primitive.ObjectID) error {
+	disciplines, err := queryDisciplines(tx, "SELECT "+disciplineColumns+" FROM disciplines WHERE group_id = ?", groupID.Hex())
+	if err != nil {
+		return err
+	}
+	existing, err := queryDisciplineData(tx, "SELECT "+dataColumns+" FROM student_discipline_data WHERE student_id = ? AND term_id = ?",
+		studentID.Hex(), termID.Hex())
+	if err != nil {
+		return err
+	}
+
+	stale, missing := planStudentSync(disciplines, existing)
+	for _, id := range stale {
+		if _, err := tx.Exec("DELETE FROM student_discipline_data WHERE id = ?", id.Hex()); err != nil {
+			return err
+		}
+	}
+	for _, discID := range missing {
+		if _, err := tx.Exec("INSERT INTO student_discipline_data (id, student_id, discipline_id, term_id) VALUES (?, ?, ?, ?)",
+			primitive.NewObjectID().Hex(), studentID.Hex(), discID.Hex(), termID.Hex()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// openTermIDs — идентификаторы открытых периодов.
+func openTermIDs(q querier) ([]primitive.ObjectID, error) {
+	rows, err := q.Query("SELECT id FROM terms WHERE closed = 0")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []primitive.ObjectID
+	for rows.Next() {
+		var id primitive.ObjectID
+		if err := rows.Scan(hexID{&id}); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+func (s *SQLiteStore) UpdateStudent(studentID primitive.ObjectID, version int, patch StudentPatch, audit []models.AuditEntry) error {
+	set, args := "version = version + 1", []interface{}{}
+	if patch.Name != nil {
+		name, err := cleanName(*patch.Name)
+		if err != nil {
+			return err
+		}
+		set, args = set+", name = ?", append(args, name)
+	}
+	if patch.GroupID != nil {
+		set, args = set+", group_id = ?", append(args, patch.GroupID.Hex())
+	}
+	if patch.Comments != nil {
+		set, args = set+", comments = ?", append(args, *patch.Comments)
+	}
+	return s.inTx(func(tx *sql.Tx) error {
+		res, err := tx.Exec("UPDATE students SET "+set+" WHERE id = ? AND version = ?", append(args, studentID.Hex(), version)...)
+		if err != nil {
+			return err
+		}
+		if n, _ := res.RowsAffected(); n == 0 {
+			var exists int
+			if err := tx.QueryRow("SELECT COUNT(*) FROM students WHERE id = ?", studentID.Hex()).Scan(&exists); err != nil {
+				return err
+			}
+			if exists == 0 {
+				return ErrNotFound
+			}
+			return ErrConflict
+		}
+		if patch.GroupID != nil {
+			if err := s.syncStudentDisciplineData(tx, studentID, *patch.GroupID); err != nil {
+				return err
+			}
+		}
+		return appendAuditTx(tx, audit)
+	})
+}
+
+const disciplineColumns = "id, name, group_id, position, retired, grading_scale_id, teacher_id"
+
+// querier — общий интерфейс *sql.DB и *sql.Tx.
+type querier interface {
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+}
+
+func scanDiscipline(row scanner) (models.Discipline, error) {
+	var d models.Discipline
+	err := row.Scan(hexID{&d.ID}, &d.Name, hexID{&d.GroupID}, &d.Position, &d.Retired, nullHexID{&d.GradingScaleID}, nullHexID{&d.TeacherID})
+	return d, err
+}
+
+func queryDisciplines(q querier, query string, args ...interface{}) ([]models.Discipline, error) {
+	rows, err := q.Query(query+" ORDER BY position, id", args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var disciplines []models.Discipline
+	for rows.Next() {
+		d, err := scanDiscipline(rows)
+		if err != nil {
+			return nil, err
+		}
+		disciplines = append(disciplines, d)
+	}
+	return disciplines, rows.Err()
+}
+
+func (s *SQLiteStore) GetDisciplinesByGroupID(groupID primitive.ObjectID) ([]models.Discipline, error) {
+	return queryDisciplines(s.db, "SELECT "+disciplineColumns+" FROM disciplines WHERE group_id = ? AND retired = 0", groupID.Hex())
+}
+
+func (s *SQLiteStore) GetAllDisciplinesByGroupID(groupID primitive.ObjectID) ([]models.Discipline, error) {
+	return queryDisciplines(s.db, "SELECT "+disciplineColumns+" FROM disciplines WHERE group_id = ?", groupID.Hex())
+}
+
+func (s *SQLiteStore) GetDisciplineByID(id primitive.ObjectID) (*models.Discipline, error) {
+	d, err := scanDiscipline(s.db.QueryRow("SELECT "+disciplineColumns+" FROM disciplines WHERE id = ?", id.Hex()))
+	return &d, notFound(err)
+}
+
+func (s *SQLiteStore) CreateDiscipline(groupID primitive.ObjectID, name string) (*models.Discipline, error) {
+	name, err := cleanName(name)
+	if err != nil {
+		return nil, err
+	}
+
+	discipline := models.Discipline{ID: primitive.NewObjectID(), Name: name, GroupID: groupID}
+	err = s.inTx(func(tx *sql.Tx) error {
+		existing, err := queryDisciplines(tx, "SELECT "+disciplineColumns+" FROM disciplines WHERE group_id = ?", groupID.Hex())
+		if err != nil {
+			return err
+		}
+		discipline.Position = nextPosition(existing)
+		if _, err := tx.Exec("INSERT INTO disciplines (id, name, group_id, position) VALUES (?, ?, ?, ?)",
+			discipline.ID.Hex(), discipline.Name, groupID.Hex(), discipline.Position); err != nil {
+			return err
+		}
+		return syncDisciplineDataTx(tx, discipline.ID, groupID)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &discipline, nil
+}
+
+// syncDisciplineDataTx заводит пустые записи по дисциплине тем студентам
+// группы, у которых их ещё нет в открытых периодах.
+func syncDisciplineDataTx(tx *sql.Tx, disciplineID, groupID primitive.ObjectID) error {
+	terms, err := openTermIDs(tx)
+	if err != nil {
+		return err
+	}
+	for _, termID := range terms {
+		rows, err := tx.Query(`SELECT id FROM students WHERE group_id = ? AND id NOT IN
+			(SELECT student_id FROM student_discipline_data WHERE discipline_id = ? AND term_id = ?)`,
+			groupID.Hex(), disciplineID.Hex(), termID.Hex())
+		if err != nil {
+			return err
+		}
+		var missing []string
+		for rows.Next() {
+			var id string
+			if err := rows.Scan(&id); err != nil {
+				rows.Close()
+				return err
+			}
+			missing = append(missing, id)
+		}
+		rows.Close()
+
+		for _, studentID := range missing {
+			if _, err := tx.Exec("INSERT INTO student_discipline_data (id, student_id, discipline_id, term_id) VALUES (?, ?, ?, ?)",
+				primitive.NewObjectID().Hex(), studentID, disciplineID.Hex(), termID.Hex()); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (s *SQLiteStore) RenameDiscipline(id primitive.ObjectID, name string) error {
+	name, err := cleanName(name)
+	if err != nil {
+		return err
+	}
+	_, err = s.db.Exec("UPDATE disciplines SET name = ? WHERE id = ?", name, id.Hex())
+	return err
+}
+
+func (s *SQLiteStore) MoveDiscipline(id primitive.ObjectID, offset int) error {
+	discipline, err := s.GetDisciplineByID(id)
+	if err != nil {
+		return err
+	}
+	return s.inTx(func(tx *sql.Tx) error {
+		list, err := queryDisciplines(tx, "SELECT "+disciplineColumns+" FROM disciplines WHERE group_id = ? AND retired = 0", discipline.GroupID.Hex())
+		if err != nil {
+			return err
+		}
+		for discID, position := range moveInList(list, id, offset) {
+			if _, err := tx.Exec("UPDATE disciplines SET position = ? WHERE id = ?", position, discID.Hex()); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (s *SQLiteStore) SetDisciplineRetired(id primitive.ObjectID, retired bool) error {
+	discipline, err := s.GetDisciplineByID(id)
+	if err != nil {
+		return err
+	}
+	return s.inTx(func(tx *sql.Tx) error {
+		if _, err := tx.Exec("UPDATE disciplines SET retired = ? WHERE id = ?", retired, id.Hex()); err != nil {
+			return err
+		}
+		if retired {
+			return nil
+		}
+		return syncDisciplineDataTx(tx, discipline.ID, discipline.GroupID)
+	})
+}
+
+func (s *SQLiteStore) SetDisciplineTeacher(id primitive.ObjectID, teacherID *primitive.ObjectID) error {
+	res, err := s.db.Exec("UPDATE disciplines SET teacher_id = ? WHERE id = ?", nullHex(teacherID), id.Hex())
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (s *SQLiteStore) GetAcademicYears() ([]models.AcademicYear, error) {
+	rows, err := s.db.Query("SELECT id, name FROM academic_years ORDER BY name")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var years []models.AcademicYear
+	for rows.Next() {
+		var y models.AcademicYear
+		if err := rows.Scan(hexID{&y.ID}, &y.Name); err != nil {
+			return nil, err
+		}
+		years = append(years, y)
+	}
+	return years, rows.Err()
+}
+
+func (s *SQLiteStore) CreateAcademicYear(name string) (*models.AcademicYear, error) {
+	name, err := cleanName(name)
+	if err != nil {
+		return nil, err
+	}
+	year := models.AcademicYear{ID: primitive.NewObjectID(), Name: name}
+	if _, err := s.db.Exec("INSERT INTO academic_years (id, name) VALUES (?, ?)", year.ID.Hex(), year.Name); err != nil {
+		return nil, err
+	}
+	return &year, nil
+}
+
+const termColumns = "id, year_id, name, start_date, end_date, closed"
+
+func scanTerm(row scanner) (models.Term, error) {
+	var t models.Term
+	err := row.Scan(hexID{&t.ID}, hexID{&t.YearID}, &t.Name, timeText{&t.Start}, timeText{&t.End}, &t.Closed)
+	return t, err
+}
+
+func (s *SQLiteStore) GetTerms() ([]models.Term, error) {
+	rows, err := s.db.Query("SELECT " + termColumns + " FROM terms")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var terms []models.Term
+	for rows.Next() {
+		t, err := scanTerm(rows)
+		if err != nil {
+			return nil, err
+		}
+		terms = append(terms, t)
+	}
+	sortTerms(terms)
+	return terms, rows.Err()
+}
+
+func (s *SQLiteStore) GetTermByID(id primitive.ObjectID) (*models.Term, error) {
+	t, err := scanTerm(s.db.QueryRow("SELECT "+termColumns+" FROM terms WHERE id = ?", id.Hex()))
+	return &t, notFound(err)
+}
+
+func (s *SQLiteStore) CreateTerm(term *models.Term) error {
+	name, err := cleanName(term.Name)
+	if err != nil {
+		return err
+	}
+	return s.inTx(func(tx *sql.Tx) error {
+		var n int
+		if err := tx.QueryRow("SELECT COUNT(*) FROM academic_years WHERE id = ?", term.YearID.Hex()).Scan(&n); err != nil {
+			return err
+		}
+		if n == 0 {
+			return ErrNotFound
+		}
+		term.ID = primitive.NewObjectID()
+		term.Name = name
+		_, err := tx.Exec("INSERT INTO terms ("+termColumns+") VALUES (?, ?, ?, ?, ?, ?)",
+			term.ID.Hex(), term.YearID.Hex(), term.Name, term.Start.Format(time.RFC3339Nano), term.End.Format(time.RFC3339Nano), term.Closed)
+		if err != nil {
+			return err
+		}
+
+		students, err := tx.Query("SELECT id, group_id FROM students")
+		if err != nil {
+			return err
+		}
+		var list []models.Student
+		for students.Next() {
+			var st models.Student
+			if err := students.Scan(hexID{&st.ID}, hexID{&st.GroupID}); err != nil {
+				students.Close()
+				return err
+			}
+			list = append(list, st)
+		}
+		students.Close()
+		if err := students.Err(); err != nil {
+			return err
+		}
+		for _, st := range list {
+			if err := syncStudentTermTx(tx, st.ID, st.GroupID, term.ID); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (s *SQLiteStore) SetTermClosed(id primitive.ObjectID, closed bool) error {
+	res, err := s.db.Exec("UPDATE terms SET closed = ? WHERE id = ?", closed, id.Hex())
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (s *SQLiteStore) AssignLegacyTerm(termID primitive.ObjectID) error {
+	return s.inTx(func(tx *sql.Tx) error {
+		// Пустые записи, которые CreateTerm успел завести в периоде, уступают старым
+		_, err := tx.Exec(`DELETE FROM student_discipline_data WHERE term_id = ? AND (student_id, discipline_id) IN
+			(SELECT student_id, discipline_id FROM student_discipline_data WHERE term_id IN ('', ?))`,
+			termID.Hex(), primitive.NilObjectID.Hex())
+		if err != nil {
+			return err
+		}
+		for _, table := range []string{"student_discipline_data", "lessons", "assessments"} {
+			_, err := tx.Exec("UPDATE "+table+" SET term_id = ? WHERE term_id IN ('', ?)", termID.Hex(), primitive.NilObjectID.Hex())
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+const dataColumns = "id, student_id, discipline_id, term_id, score, total_classes, attended_classes, version"
+
+func scanDisciplineData(row scanner) (models.StudentDisciplineData, error) {
+	var d models.StudentDisciplineData
+	err := row.Scan(hexID{&d.ID}, hexID{&d.StudentID}, hexID{&d.DisciplineID}, hexID{&d.TermID}, &d.Score, &d.TotalClasses, &d.AttendedClasses, &d.Version)
+	return d, err
+}
+
+func queryDisciplineData(q querier, query string, args ...interface{}) ([]models.StudentDisciplineData, error) {
+	rows, err := q.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var data []models.StudentDisciplineData
+	for rows.Next() {
+		d, err := scanDisciplineData(rows)
+		if err != nil {
+			return nil, err
+		}
+		data = append(data, d)
+	}
+	return data, rows.Err()
+}
+
+func (s *SQLiteStore) GetStudentDisciplineData(studentID, termID primitive.ObjectID) ([]models.StudentDisciplineData, error) {
+	return queryDisciplineData(s.db, "SELECT "+dataColumns+" FROM student_discipline_data WHERE student_id = ? AND term_id = ? ORDER BY id",
+		studentID.Hex(), termID.Hex())
+}
+
+func (s *SQLiteStore) GetDisciplineDataFor(studentID, disciplineID, termID primitive.ObjectID) (*models.StudentDisciplineData, error) {
+	d, err := scanDisciplineData(s.db.QueryRow("SELECT "+dataColumns+" FROM student_discipline_data WHERE student_id = ? AND discipline_id = ? AND term_id = ?",
+		studentID.Hex(), disciplineID.Hex(), termID.Hex()))
+	return &d, notFound(err)
+}
+
+func (s *SQLiteStore) GetDisciplineDataByID(id primitive.ObjectID) (*models.StudentDisciplineData, error) {
+	d, err := scanDisciplineData(s.db.QueryRow("SELECT "+dataColumns+" FROM student_discipline_data WHERE id = ?", id.Hex()))
+	return &d, notFound(err)
+}
+
+func (s *SQLiteStore) InsertDisciplineData(data *models.StudentDisciplineData, audit []models.AuditEntry) error {
+	if data.ID.IsZero() {
+		data.ID = primitive.NewObjectID()
+	}
+	return s.inTx(func(tx *sql.Tx) error {
+		_, err := tx.Exec("INSERT INTO student_discipline_data ("+dataColumns+") VALUES (?, ?, ?, ?, ?, ?, ?, ?)",
+			data.ID.Hex(), data.StudentID.Hex(), data.DisciplineID.Hex(), data.TermID.Hex(), data.Score, data.TotalClasses, data.AttendedClasses, data.Version)
+		if uniqueViolation(err) {
+			return ErrConflict
+		}
+		if err != nil {
+			return err
+		}
+		auditNewRows(audit, []models.StudentDisciplineData{*data})
+		return appendAuditTx(tx, audit)
+	})
+}
+
+func (s *SQLiteStore) SaveStudentSheet(studentID primitive.ObjectID, version int, comments string, rows []models.StudentDisciplineData, audit []models.AuditEntry) error {
+	return s.inTx(func(tx *sql.Tx) error {
+		res, err := tx.Exec("UPDATE students SET comments = ?, version = version + 1 WHERE id = ? AND version = ?",
+			comments, studentID.Hex(), version)
+		if err != nil {
+			return err
+		}
+		if n, _ := res.RowsAffected(); n == 0 {
+			var exists int
+			if err := tx.QueryRow("SELECT COUNT(*) FROM students WHERE id = ?", studentID.Hex()).Scan(&exists); err != nil {
+				return err
+			}
+			if exists == 0 {
+				return ErrNotFound
+			}
+			return ErrConflict
+		}
+		rows = append([]models.StudentDisciplineData(nil), rows...)
+		for i := range rows {
+			rows[i].StudentID = studentID
+		}
+		if err := saveDataRows(tx, rows); err != nil {
+			return err
+		}
+		auditNewRows(audit, rows)
+		return appendAuditTx(tx, audit)
+	})
+}
+
+func (s *SQLiteStore) SaveDisciplineData(rows []models.StudentDisciplineData, audit []models.AuditEntry) error {
+	return s.inTx(func(tx *sql.Tx) error {
+		if err := saveDataRows(tx, rows); err != nil {
+			return err
+		}
+		auditNewRows(audit, rows)
+		return appendAuditTx(tx, audit)
+	})
+}
+
+// saveDataRows пишет записи в транзакции tx: новые вставляет, остальные
+// обновляет, только если версия не изменилась; иначе ErrConflict. ID и
+// Version строк обновляются на месте.
+func saveDataRows(tx *sql.Tx, rows []models.StudentDisciplineData) error {
+	for i := range rows {
+		row := &rows[i]
+		if row.ID.IsZero() {
+			id := primitive.NewObjectID()
+			// Запись на ту же дисциплину и период уже создана кем-то другим — UNIQUE не пустит
+			res, err := tx.Exec("INSERT OR IGNORE INTO student_discipline_data ("+dataColumns+") VALUES (?, ?, ?, ?, ?, ?, ?, 1)",
+				id.Hex(), row.StudentID.Hex(), row.DisciplineID.Hex(), row.TermID.Hex(), row.Score, row.TotalClasses, row.AttendedClasses)
+			if err != nil {
+				return err
+			}
+			if n, _ := res.RowsAffected(); n == 0 {
+				return ErrConflict
+			}
+			row.ID, row.Version = id, 1
+			continue
+		}
+		res, err := tx.Exec("UPDATE student_discipline_data SET score = ?, total_classes = ?, attended_classes = ?, version = version + 1 WHERE id = ? AND student_id = ? AND version = ?",
+			row.Score, row.TotalClasses, row.AttendedClasses, row.ID.Hex(), row.StudentID.Hex(), row.Version)
+		if err != nil {
+			return err
+		}
+		if n, _ := res.RowsAffected(); n == 0 {
+			return ErrConflict
+		}
+		row.Version++
+	}
+	return nil
+}
+
+func (s *SQLiteStore) DeleteDisciplineData(dataID primitive.ObjectID, audit []models.AuditEntry) error {
+	return s.inTx(func(tx *sql.Tx) error {
+		res, err := tx.Exec("DELETE FROM student_discipline_data WHERE id = ?", dataID.Hex())
+		if err != nil {
+			return err
+		}
+		if n, _ := res.RowsAffected(); n == 0 {
+			return ErrNotFound
+		}
+		return appendAuditTx(tx, audit)
+	})
+}
+
+// resetScopeSQL — условие на колонку дисциплины для области сброса.
+func resetScopeSQL(scope models.ResetScope, column string) (string, []interface{}) {
+	switch {
+	case scope.DisciplineID != nil:
+		return column + " = ?", []interface{}{scope.DisciplineID.Hex()}
+	case scope.GroupID != nil:
+		return column + " IN (SELECT id FROM disciplines WHERE group_id = ?)", []interface{}{scope.GroupID.Hex()}
+	}
+	return "1 = 1", nil
+}
+
+// openTermSQL — условие «строка не из закрытого периода».
+const openTermSQL = "term_id NOT IN (SELECT id FROM terms WHERE closed = 1)"
+
+// snapshotData — содержимое снимка сброса; хранится JSON в колонке data.
+type snapshotData struct {
+	Comments   []models.StudentComment        `json:"comments"`
+	Records    []models.StudentDisciplineData `json:"records"`
+	Attendance []models.AttendanceMark        `json:"attendance"`
+	Marks      []models.AssessmentMark        `json:"marks"`
+}
+
+func (s *SQLiteStore) ResetDynamicData(snapshot *models.ResetSnapshot, audit func(*models.ResetSnapshot) []models.AuditEntry) error {
+	scope := snapshot.Scope
+	discCond, discArgs := resetScopeSQL(scope, "discipline_id")
+	// Закрытые периоды заморожены и сбросом не трогаются
+	discCond += " AND " + openTermSQL
+	dataCond := "WHERE " + discCond
+	attendanceCond := "WHERE lesson_id IN (SELECT id FROM lessons WHERE " + discCond + ")"
+	marksCond := "WHERE assessment_id IN (SELECT id FROM assessments WHERE " + discCond + ")"
+
+	return s.inTx(func(tx *sql.Tx) error {
+		var data snapshotData
+
+		// Комментарии обнуляются при сбросе всего дневника или группы
+		if scope.DisciplineID == nil {
+			studentCond, studentArgs := "", []interface{}(nil)
+			if scope.GroupID != nil {
+				studentCond, studentArgs = " WHERE group_id = ?", []interface{}{scope.GroupID.Hex()}
+			}
+			rows, err := tx.Query("SELECT id, comments FROM students"+studentCond, studentArgs...)
+			if err != nil {
+				return err
+			}
+			for rows.Next() {
+				var c models.StudentComment
+				if err := rows.Scan(hexID{&c.StudentID}, &c.Comments); err != nil {
+					rows.Close()
+					return err
+				}
+				data.Comments = append(data.Comments, c)
+			}
+			rows.Close()
+			if err := rows.Err(); err != nil {
+				return err
+			}
+			if _, err := tx.Exec("UPDATE students SET comments = '', version = version + 1"+studentCond, studentArgs...); err != nil {
+				return err
+			}
+		}
+
+		var err error
+		if data.Records, err = queryDisciplineData(tx, "SELECT "+dataColumns+" FROM student_discipline_data "+dataCond, discArgs...); err != nil {
+			return err
+		}
+		if data.Attendance, err = queryAttendance(tx, "SELECT "+markColumns+" FROM attendance "+attendanceCond, discArgs...); err != nil {
+			return err
+		}
+		if data.Marks, err = queryAssessmentMarks(tx, "SELECT "+assessmentMarkColumns+" FROM assessment_marks "+marksCond, discArgs...); err != nil {
+			return err
+		}
+
+		// Обнуляем данные по дисциплинам
+		if _, err := tx.Exec("UPDATE student_discipline_data SET score = 0, total_classes = 0, attended_classes = 0, version = version + 1 "+dataCond, discArgs...); err != nil {
+			return err
+		}
+		// Отметки журнала и баллы за работы тоже обнуляются — иначе
+		// счётчики и оценки пересчитаются обратно
+		if _, err := tx.Exec("DELETE FROM attendance "+attendanceCond, discArgs...); err != nil {
+			return err
+		}
+		if _, err := tx.Exec("DELETE FROM assessment_marks "+marksCond, discArgs...); err != nil {
+			return err
+		}
+
+		payload, err := json.Marshal(data)
+		if err != nil {
+			return err
+		}
+		snapshot.ID = primitive.NewObjectID()
+		snapshot.At = time.Now()
+		snapshot.Comments, snapshot.Records, snapshot.Attendance, snapshot.Marks = data.Comments, data.Records, data.Attendance, data.Marks
+		snapshot.RestoredAt = nil
+		_, err = tx.Exec(`INSERT INTO reset_snapshots (id, at, actor_id, actor_name, group_id, discipline_id, scope_name, data)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+			snapshot.ID.Hex(), snapshot.At.UTC().Format(sortableTime), snapshot.ActorID.Hex(), snapshot.ActorName,
+			nullHex(scope.GroupID), nullHex(scope.DisciplineID), snapshot.ScopeName, string(payload))
+		if err != nil || audit == nil {
+			return err
+		}
+		return appendAuditTx(tx, audit(snapshot))
+	})
+}
+
+const snapshotColumns = "id, at, actor_id, actor_name, group_id, discipline_id, scope_name, data, restored_at"
+
+func scanSnapshot(row scanner) (models.ResetSnapshot, error) {
+	var snap models.ResetSnapshot
+	var payload string
+	var restoredAt sql.NullString
+	err := row.Scan(hexID{&snap.ID}, timeText{&snap.At}, hexID{&snap.ActorID}, &snap.ActorName,
+		nullHexID{&snap.Scope.GroupID}, nullHexID{&snap.Scope.DisciplineID}, &snap.ScopeName, &payload, &restoredAt)
+	if err != nil {
+		return snap, err
+	}
+	if restoredAt.Valid {
+		t, err := time.Parse(time.RFC3339Nano, restoredAt.String)
+		if err != nil {
+			return snap, err
+		}
+		snap.RestoredAt = &t
+	}
+	var data snapshotData
+	if err := json.Unmarshal([]byte(payload), &data); err != nil {
+		return snap, err
+	}
+	snap.Comments, snap.Records, snap.Attendance, snap.Marks = data.Comments, data.Records, data.Attendance, data.Marks
+	return snap, nil
+}
+
+func (s *SQLiteStore) GetResetSnapshots() ([]models.ResetSnapshot, error) {
+	rows, err := s.db.Query("SELECT " + snapshotColumns + " FROM reset_snapshots ORDER BY at DESC, id DESC")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var snapshots []models.ResetSnapshot
+	for rows.Next() {
+		snap, err := scanSnapshot(rows)
+		if err != nil {
+			return nil, err
+		}
+		snapshots = append(snapshots, snap)
+	}
+	return snapshots, rows.Err()
+}
+
+func (s *SQLiteStore) GetResetSnapshotByID(id primitive.ObjectID) (*models.ResetSnapshot, error) {
+	snap, err := scanSnapshot(s.db.QueryRow("SELECT "+snapshotColumns+" FROM reset_snapshots WHERE id = ?", id.Hex()))
+	if err != nil {
+		return &snap, notFound(err)
+	}
+	return &snap, nil
+}
+
+func (s *SQLiteStore) RestoreResetSnapshot(id primitive.ObjectID, audit []models.AuditEntry) error {
+	snap, err := s.GetResetSnapshotByID(id)
+	if err != nil {
+		return err
+	}
+	return s.inTx(func(tx *sql.Tx) error {
+		// Отметка о восстановлении ставится первой: второе восстановление
+		// того же снимка не пройдёт даже при одновременных запросах
+		res, err := tx.Exec("UPDATE reset_snapshots SET restored_at = ? WHERE id = ? AND restored_at IS NULL",
+			time.Now().UTC().Format(time.RFC3339Nano), id.Hex())
+		if err != nil {
+			return err
+		}
+		if n, _ := res.RowsAffected(); n == 0 {
+			return ErrConflict
+		}
+
+		for _, c := range snap.Comments {
+			if _, err := tx.Exec("UPDATE students SET comments = ?, version = version + 1 WHERE id = ?", c.Comments, c.StudentID.Hex()); err != nil {
+				return err
+			}
+		}
+		for _, d := range snap.Records {
+			_, err := tx.Exec(`UPDATE student_discipline_data SET score = ?, total_classes = ?, attended_classes = ?, version = version + 1
+				WHERE id = ? AND `+openTermSQL, d.Score, d.TotalClasses, d.AttendedClasses, d.ID.Hex())
+			if err != nil {
+				return err
+			}
+		}
+
+		// Отметки возвращаются поверх поставленных после сброса (занятия и
+		// работы, удалённые с тех пор, пропускаются); затем выводимые поля
+		// пересчитываются по всему журналу дисциплины
+		journaled := make(map[termKey]bool)
+		for _, m := range snap.Attendance {
+			var k termKey
+			err := tx.QueryRow("SELECT discipline_id, term_id FROM lessons WHERE id = ? AND "+openTermSQL, m.LessonID.Hex()).
+				Scan(hexID{&k.discipline}, hexID{&k.term})
+			if errors.Is(err, sql.ErrNoRows) {
+				continue
+			}
+			if err != nil {
+				return err
+			}
+			_, err = tx.Exec(`INSERT INTO attendance (`+markColumns+`) VALUES (?, ?, ?, ?)
+				ON CONFLICT (lesson_id, student_id) DO UPDATE SET status = excluded.status`,
+				primitive.NewObjectID().Hex(), m.LessonID.Hex(), m.StudentID.Hex(), string(m.Status))
+			if err != nil {
+				return err
+			}
+			journaled[k] = true
+		}
+		graded := make(map[termKey]bool)
+		for _, m := range snap.Marks {
+			var k termKey
+			err := tx.QueryRow("SELECT discipline_id, term_id FROM assessments WHERE id = ? AND "+openTermSQL, m.AssessmentID.Hex()).
+				Scan(hexID{&k.discipline}, hexID{&k.term})
+			if errors.Is(err, sql.ErrNoRows) {
+				continue
+			}
+			if err != nil {
+				return err
+			}
+			_, err = tx.Exec(`INSERT INTO assessment_marks (`+assessmentMarkColumns+`) VALUES (?, ?, ?, ?)
+				ON CONFLICT (assessment_id, student_id) DO UPDATE SET points = excluded.points`,
+				primitive.NewObjectID().Hex(), m.AssessmentID.Hex(), m.StudentID.Hex(), m.Points)
+			if err != nil {
+				return err
+			}
+			graded[k] = true
+		}
+		for k := range journaled {
+			if err := recomputeAttendanceTx(tx, k.discipline, k.term); err != nil {
+				return err
+			}
+		}
+		for k := range graded {
+			if err := recomputeScoresTx(tx, k.discipline, k.term); err != nil {
+				return err
+			}
+		}
+		return appendAuditTx(tx, audit)
+	})
+}
+
+// timeText — обёртка для чтения времени из TEXT-колонки в формате RFC 3339.
+type timeText struct{ t *time.Time }
+
+func (tt timeText) Scan(src interface{}) error {
+	var s string
+	switch v := src.(type) {
+	case string:
+		s = v
+	case []byte:
+		s = string(v)
+	case time.Time:
+		*tt.t = v
+		return nil
+	default:
+		return fmt.Errorf("неожиданный тип времени %T", src)
+	}
+	t, err := time.Parse(time.RFC3339Nano, s)
+	if err != nil {
+		return err
+	}
+	*tt.t = t
+	return nil
+}
+
+const lessonColumns = "id, group_id, discipline_id, term_id, date, topic"
+
+func scanLesson(row scanner) (models.Lesson, error) {
+	var l models.Lesson
+	err := row.Scan(hexID{&l.ID}, hexID{&l.GroupID}, hexID{&l.DisciplineID}, hexID{&l.TermID}, timeText{&l.Date}, &l.Topic)
+	return l, err
+}
+
+func (s *SQLiteStore) GetLessonsByGroupID(groupID, termID primitive.ObjectID) ([]models.Lesson, error) {
+	rows, err := s.db.Query("SELECT "+lessonColumns+" FROM lessons WHERE group_id = ? AND term_id = ?", groupID.Hex(), termID.Hex())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var lessons []models.Lesson
+	for rows.Next() {
+		l, err := scanLesson(rows)
+		if err != nil {
+			return nil, err
+		}
+		lessons = append(lessons, l)
+	}
+	// Сортируем в Go: строки RFC 3339 с разными поясами сравнивать нельзя
+	sortLessons(lessons)
+	return lessons, rows.Err()
+}
+
+func (s *SQLiteStore) GetLessonByID(id primitive.ObjectID) (*models.Lesson, error) {
+	l, err := scanLesson(s.db.QueryRow("SELECT "+lessonColumns+" FROM lessons WHERE id = ?", id.Hex()))
+	return &l, notFound(err)
+}
+
+func (s *SQLiteStore) CreateLesson(lesson *models.Lesson) error {
+	if lesson.ID.IsZero() {
+		lesson.ID = primitive.NewObjectID()
+	}
+	_, err := s.db.Exec("INSERT INTO lessons ("+lessonColumns+") VALUES (?, ?, ?, ?, ?, ?)",
+		lesson.ID.Hex(), lesson.GroupID.Hex(), lesson.DisciplineID.Hex(), lesson.TermID.Hex(), lesson.Date.Format(time.RFC3339Nano), lesson.Topic)
+	return err
+}
+
+func (s *SQLiteStore) DeleteLesson(id primitive.ObjectID, audit []models.AuditEntry) error {
+	lesson, err := s.GetLessonByID(id)
+	if err != nil {
+		return err
+	}
+	return s.inTx(func(tx *sql.Tx) error {
+		if _, err := tx.Exec("DELETE FROM attendance WHERE lesson_id = ?", id.Hex()); err != nil {
+			return err
+		}
+		if _, err := tx.Exec("DELETE FROM lessons WHERE id = ?", id.Hex()); err != nil {
+			return err
+		}
+		if err := recomputeAttendanceTx(tx, lesson.DisciplineID, lesson.TermID); err != nil {
+			return err
+		}
+		return appendAuditTx(tx, audit)
+	})
+}
+
+const markColumns = "id, lesson_id, student_id, status"
+
+func queryAttendance(q querier, query string, args ...interface{}) ([]models.AttendanceMark, error) {
+	rows, err := q.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var marks []models.AttendanceMark
+	for rows.Next() {
+		var m models.AttendanceMark
+		if err := rows.Scan(hexID{&m.ID}, hexID{&m.LessonID}, hexID{&m.StudentID}, &m.Status); err != nil {
+			return nil, err
+		}
+		marks = append(marks, m)
+	}
+	return marks, rows.Err()
+}
+
+func (s *SQLiteStore) GetAttendanceByLesson(lessonID primitive.ObjectID) ([]models.AttendanceMark, error) {
+	return queryAttendance(s.db, "SELECT "+markColumns+" FROM attendance WHERE lesson_id = ?", lessonID.Hex())
+}
+
+func (s *SQLiteStore) SaveAttendance(lessonID primitive.ObjectID, marks map[primitive.ObjectID]models.AttendanceStatus, audit []models.AuditEntry) error {
+	lesson, err := s.GetLessonByID(lessonID)
+	if err != nil {
+		return err
+	}
+	return s.inTx(func(tx *sql.Tx) error {
+		for studentID, status := range marks {
+			_, err := tx.Exec(`INSERT INTO attendance (`+markColumns+`) VALUES (?, ?, ?, ?)
+				ON CONFLICT (lesson_id, student_id) DO UPDATE SET status = excluded.status`,
+				primitive.NewObjectID().Hex(), lessonID.Hex(), studentID.Hex(), string(status))
+			if err != nil {
+				return err
+			}
+		}
+		if err := recomputeAttendanceTx(tx, lesson.DisciplineID, lesson.TermID); err != nil {
+			return err
+		}
+		return appendAuditTx(tx, audit)
+	})
+}
+
+// recomputeAttendanceTx выводит TotalClasses/AttendedClasses всех студентов
+// дисциплины за период из отметок журнала.
+func recomputeAttendanceTx(tx *sql.Tx, disciplineID, termID primitive.ObjectID) error {
+	marks, err := queryAttendance(tx, `SELECT a.id, a.lesson_id, a.student_id, a.status FROM attendance a
+		JOIN lessons l ON l.id = a.lesson_id WHERE l.discipline_id = ? AND l.term_id = ?`, disciplineID.Hex(), termID.Hex())
+	if err != nil {
+		return err
+	}
+	counts := tallyAttendance(marks)
+
+	rows, err := queryDisciplineData(tx, "SELECT "+dataColumns+" FROM student_discipline_data WHERE discipline_id = ? AND term_id = ?",
+		disciplineID.Hex(), termID.Hex())
+	if err != nil {
+		return err
+	}
+	for _, row := range rows {
+		c := counts[row.StudentID]
+		if row.TotalClasses == c.total && row.AttendedClasses == c.attended {
+			continue
+		}
+		if _, err := tx.Exec("UPDATE student_discipline_data SET total_classes = ?, attended_classes = ? WHERE id = ?",
+			c.total, c.attended, row.ID.Hex()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+const assessmentColumns = "id, group_id, discipline_id, term_id, name, date, max_points, weight"
+
+func queryAssessments(q querier, query string, args ...interface{}) ([]models.Assessment, error) {
+	rows, err := q.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var assessments []models.Assessment
+	for rows.Next() {
+		a, err := scanAssessment(rows)
+		if err != nil {
+			return nil, err
+		}
+		assessments = append(assessments, a)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	sortAssessments(assessments)
+	return assessments, nil
+}
+
+func scanAssessment(row scanner) (models.Assessment, error) {
+	var a models.Assessment
+	err := row.Scan(hexID{&a.ID}, hexID{&a.GroupID}, hexID{&a.DisciplineID}, hexID{&a.TermID}, &a.Name, timeText{&a.Date}, &a.MaxPoints, &a.Weight)
+	return a, err
+}
+
+func (s *SQLiteStore) GetAssessmentsByDisciplineID(disciplineID, termID primitive.ObjectID) ([]models.Assessment, error) {
+	return queryAssessments(s.db, "SELECT "+assessmentColumns+" FROM assessments WHERE discipline_id = ? AND term_id = ?", disciplineID.Hex(), termID.Hex())
+}
+
+func (s *SQLiteStore) GetAssessmentByID(id primitive.ObjectID) (*models.Assessment, error) {
+	a, err := scanAssessment(s.db.QueryRow("SELECT "+assessmentColumns+" FROM assessments WHERE id = ?", id.Hex()))
+	return &a, notFound(err)
+}
+
+func (s *SQLiteStore) CreateAssessment(assessment *models.Assessment) error {
+	if assessment.ID.IsZero() {
+		assessment.ID = primitive.NewObjectID()
+	}
+	_, err := s.db.Exec("INSERT INTO assessments ("+assessmentColumns+") VALUES (?, ?, ?, ?, ?, ?, ?, ?)",
+		assessment.ID.Hex(), assessment.GroupID.Hex(), assessment.DisciplineID.Hex(), assessment.TermID.Hex(), assessment.Name,
+		assessment.Date.Format(time.RFC3339Nano), assessment.MaxPoints, assessment.Weight)
+	return err
+}
+
+func (s *SQLiteStore) DeleteAssessment(id primitive.ObjectID, audit []models.AuditEntry) error {
+	assessment, err := s.GetAssessmentByID(id)
+	if err != nil {
+		return err
+	}
+	return s.inTx(func(tx *sql.Tx) error {
+		if _, err := tx.Exec("DELETE FROM assessment_marks WHERE assessment_id = ?", id.Hex()); err != nil {
+			return err
+		}
+		if _, err := tx.Exec("DELETE FROM assessments WHERE id = ?", id.Hex()); err != nil {
+			return err
+		}
+		if err := recomputeScoresTx(tx, assessment.DisciplineID, assessment.TermID); err != nil {
+			return err
+		}
+		return appendAuditTx(tx, audit)
+	})
+}
+
+const assessmentMarkColumns = "id, assessment_id, student_id, points"
+
+func queryAssessmentMarks(q querier, query string, args ...interface{}) ([]models.AssessmentMark, error) {
+	rows, err := q.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var marks []models.AssessmentMark
+	for rows.Next() {
+		var m models.AssessmentMark
+		if err := rows.Scan(hexID{&m.ID}, hexID{&m.AssessmentID}, hexID{&m.StudentID}, &m.Points); err != nil {
+			return nil, err
+		}
+		marks = append(marks, m)
+	}
+	return marks, rows.Err()
+}
+
+func (s *SQLiteStore) GetMarksByAssessment(assessmentID primitive.ObjectID) ([]models.AssessmentMark, error) {
+	return queryAssessmentMarks(s.db, "SELECT "+assessmentMarkColumns+" FROM assessment_marks WHERE assessment_id = ?", assessmentID.Hex())
+}
+
+func (s *SQLiteStore) GetMarksByStudent(studentID primitive.ObjectID) ([]models.AssessmentMark, error) {
+	return queryAssessmentMarks(s.db, "SELECT "+assessmentMarkColumns+" FROM assessment_marks WHERE student_id = ?", studentID.Hex())
+}
+
+func (s *SQLiteStore) SaveAssessmentMarks(assessmentID primitive.ObjectID, points map[primitive.ObjectID]float64, audit []models.AuditEntry) error {
+	assessment, err := s.GetAssessmentByID(assessmentID)
+	if err != nil {
+		return err
+	}
+	return s.inTx(func(tx *sql.Tx) error {
+		if _, err := tx.Exec("DELETE FROM assessment_marks WHERE assessment_id = ?", assessmentID.Hex()); err != nil {
+			return err
+		}
+		for studentID, p := range points {
+			_, err := tx.Exec("INSERT INTO assessment_marks ("+assessmentMarkColumns+") VALUES (?, ?, ?, ?)",
+				primitive.NewObjectID().Hex(), assessmentID.Hex(), studentID.Hex(), p)
+			if err != nil {
+				return err
+			}
+		}
+		if err := recomputeScoresTx(tx, assessment.DisciplineID, assessment.TermID); err != nil {
+			return err
+		}
+		return appendAuditTx(tx, audit)
+	})
+}
+
+// recomputeScoresTx выводит Score студентов дисциплины за период из баллов
+// за работы. Без работ оценки ведутся вручную и не трогаются.
+func recomputeScoresTx(tx *sql.Tx, disciplineID, termID primitive.ObjectID) error {
+	assessments, err := queryAssessments(tx, "SELECT "+assessmentColumns+" FROM assessments WHERE discipline_id = ? AND term_id = ?",
+		disciplineID.Hex(), termID.Hex())
+	if err != nil || len(assessments) == 0 {
+		return err
+	}
+	marks, err := queryAssessmentMarks(tx, `SELECT m.id, m.assessment_id, m.student_id, m.points FROM assessment_marks m
+		JOIN assessments a ON a.id = m.assessment_id WHERE a.discipline_id = ? AND a.term_id = ?`, disciplineID.Hex(), termID.Hex())
+	if err != nil {
+		return err
+	}
+	scores := scoreAssessments(assessments, marks)
+
+	rows, err := queryDisciplineData(tx, "SELECT "+dataColumns+" FROM student_discipline_data WHERE discipline_id = ? AND term_id = ?",
+		disciplineID.Hex(), termID.Hex())
+	if err != nil {
+		return err
+	}
+	for _, row := range rows {
+		score := scores[row.StudentID]
+		if row.Score == score {
+			continue
+		}
+		if _, err := tx.Exec("UPDATE student_discipline_data SET score = ? WHERE id = ?", score, row.ID.Hex()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+const scaleColumns = "id, key, name, bands"
+
+func scanGradingScale(row scanner) (models.GradingScale, error) {
+	var sc models.GradingScale
+	var bands string
+	if err := row.Scan(hexID{&sc.ID}, &sc.Key, &sc.Name, &bands); err != nil {
+		return sc, err
+	}
+	// Полосы шкалы хранятся JSON-массивом — отдельная таблица ради них не нужна
+	err := json.Unmarshal([]byte(bands), &sc.Bands)
+	return sc, err
+}
+
+func (s *SQLiteStore) GetGradingScales() ([]models.GradingScale, error) {
+	rows, err := s.db.Query("SELECT " + scaleColumns + " FROM grading_scales ORDER BY id")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var scales []models.GradingScale
+	for rows.Next() {
+		sc, err := scanGradingScale(rows)
+		if err != nil {
+			return nil, err
+		}
+		scales = append(scales, sc)
+	}
+	return scales, rows.Err()
+}
+
+func (s *SQLiteStore) GetGradingScaleByID(id primitive.ObjectID) (*models.GradingScale, error) {
+	sc, err := scanGradingScale(s.db.QueryRow("SELECT "+scaleColumns+" FROM grading_scales WHERE id = ?", id.Hex()))
+	return &sc, notFound(err)
+}
+
+func (s *SQLiteStore) CreateGradingScale(scale *models.GradingScale) error {
+	if scale.ID.IsZero() {
+		scale.ID = primitive.NewObjectID()
+	}
+	bands, err := json.Marshal(scale.Bands)
+	if err != nil {
+		return err
+	}
+	_, err = s.db.Exec("INSERT INTO grading_scales ("+scaleColumns+") VALUES (?, ?, ?, ?)",
+		scale.ID.Hex(), scale.Key, scale.Name, string(bands))
+	return err
+}
+
+func (s *SQLiteStore) DeleteGradingScale(id primitive.ObjectID) error {
+	return s.inTx(func(tx *sql.Tx) error {
+		if _, err := tx.Exec("UPDATE groups SET grading_scale_id = NULL WHERE grading_scale_id = ?", id.Hex()); err != nil {
+			return err
+		}
+		if _, err := tx.Exec("UPDATE disciplines SET grading_scale_id = NULL WHERE grading_scale_id = ?", id.Hex()); err != nil {
+			return err
+		}
+		res, err := tx.Exec("DELETE FROM grading_scales WHERE id = ?", id.Hex())
+		if err != nil {
+			return err
+		}
+		if n, _ := res.RowsAffected(); n == 0 {
+			return ErrNotFound
+		}
+		return nil
+	})
+}
+
+func (s *SQLiteStore) SetGroupGradingScale(groupID primitive.ObjectID, scaleID *primitive.ObjectID) error {
+	_, err := s.db.Exec("UPDATE groups SET grading_scale_id = ? WHERE id = ?", nullHex(scaleID), groupID.Hex())
+	return err
+}
+
+func (s *SQLiteStore) SetDisciplineGradingScale(disciplineID primitive.ObjectID, scaleID *primitive.ObjectID) error {
+	_, err := s.db.Exec("UPDATE disciplines SET grading_scale_id = ? WHERE id = ?", nullHex(scaleID), disciplineID.Hex())
+	return err
+}
+
+const userColumns = "id, login, name, role, password_hash, student_ids"
+
+func scanUser(row scanner) (models.User, error) {
+	var u models.User
+	var studentIDs string
+	if err := row.Scan(hexID{&u.ID}, &u.Login, &u.Name, &u.Role, &u.PasswordHash, &studentIDs); err != nil {
+		return u, err
+	}
+	err := json.Unmarshal([]byte(studentIDs), &u.StudentIDs)
+	return u, err
+}
+
+// studentIDsJSON — связанные студенты хранятся JSON-массивом hex-строк.
+func studentIDsJSON(ids []primitive.ObjectID) (string, error) {
+	if ids == nil {
+		ids = []primitive.ObjectID{}
+	}
+	data, err := json.Marshal(ids)
+	return string(data), err
+}
+
+// uniqueViolation — нарушено ограничение UNIQUE.
+func uniqueViolation(err error) bool {
+	return err != nil && strings.Contains(err.Error(), "UNIQUE constraint failed")
+}
+
+func (s *SQLiteStore) GetUsers() ([]models.User, error) {
+	rows, err := s.db.Query("SELECT " + userColumns + " FROM users ORDER BY login")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var users []models.User
+	for rows.Next() {
+		u, err := scanUser(rows)
+		if err != nil {
+			return nil, err
+		}
+		users = append(users, u)
+	}
+	return users, rows.Err()
+}
+
+func (s *SQLiteStore) GetUserByID(id primitive.ObjectID) (*models.User, error) {
+	u, err := scanUser(s.db.QueryRow("SELECT "+userColumns+" FROM users WHERE id = ?", id.Hex()))
+	return &u, notFound(err)
+}
+
+func (s *SQLiteStore) GetUserByLogin(login string) (*models.User, error) {
+	u, err := scanUser(s.db.QueryRow("SELECT "+userColumns+" FROM users WHERE login = ?", login))
+	return &u, notFound(err)
+}
+
+func (s *SQLiteStore) CreateUser(user *models.User) error {
+	if user.ID.IsZero() {
+		user.ID = primitive.NewObjectID()
+	}
+	studentIDs, err := studentIDsJSON(user.StudentIDs)
+	if err != nil {
+		return err
+	}
+	_, err = s.db.Exec("INSERT INTO users ("+userColumns+") VALUES (?, ?, ?, ?, ?, ?)",
+		user.ID.Hex(), user.Login, user.Name, string(user.Role), user.PasswordHash, studentIDs)
+	if uniqueViolation(err) {
+		return ErrLoginTaken
+	}
+	return err
+}
+
+func (s *SQLiteStore) UpdateUser(user *models.User) error {
+	studentIDs, err := studentIDsJSON(user.StudentIDs)
+	if err != nil {
+		return err
+	}
+	res, err := s.db.Exec("UPDATE users SET login = ?, name = ?, role = ?, password_hash = ?, student_ids = ? WHERE id = ?",
+		user.Login, user.Name, string(user.Role), user.PasswordHash, studentIDs, user.ID.Hex())
+	if uniqueViolation(err) {
+		return ErrLoginTaken
+	}
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (s *SQLiteStore) DeleteUser(id primitive.ObjectID) error {
+	return s.inTx(func(tx *sql.Tx) error {
+		if _, err := tx.Exec("DELETE FROM sessions WHERE user_id = ?", id.Hex()); err != nil {
+			return err
+		}
+		if _, err := tx.Exec("UPDATE disciplines SET teacher_id = NULL WHERE teacher_id = ?", id.Hex()); err != nil {
+			return err
+		}
+		_, err := tx.Exec("DELETE FROM users WHERE id = ?", id.Hex())
+		return err
+	})
+}
+
+func (s *SQLiteStore) CreateSession(session *models.Session) error {
+	// Заодно выметаем просроченные сессии
+	if _, err := s.db.Exec("DELETE FROM sessions WHERE expires_at <= ?", time.Now().UTC().Format(time.RFC3339Nano)); err != nil {
+		return err
+	}
+	_, err := s.db.Exec("INSERT INTO sessions (token, user_id, expires_at) VALUES (?, ?, ?)",
+		session.Token, session.UserID.Hex(), session.ExpiresAt.UTC().Format(time.RFC3339Nano))
+	return err
+}
+
+func (s *SQLiteStore) GetSession(token string) (*models.Session, error) {
+	var sess models.Session
+	err := s.db.QueryRow("SELECT token, user_id, expires_at FROM sessions WHERE token = ?", token).
+		Scan(&sess.Token, hexID{&sess.UserID}, timeText{&sess.ExpiresAt})
+	if err != nil {
+		return &sess, notFound(err)
+	}
+	if !sess.ExpiresAt.After(time.Now()) {
+		return &sess, ErrNotFound
+	}
+	return &sess, nil
+}
+
+func (s *SQLiteStore) DeleteSession(token string) error {
+	_, err := s.db.Exec("DELETE FROM sessions WHERE token = ?", token)
+	return err
+}
+
+// sortableTime — RFC 3339 с дробной частью фиксированной длины: так
+// строки времени журнала и снимков сбросов сортируются как сами моменты.
+const sortableTime = "2006-01-02T15:04:05.000000000Z07:00"
+
+// appendAuditTx пишет журнал в транзакции tx — той же, что и изменение.
+func appendAuditTx(tx *sql.Tx, entries []models.AuditEntry) error {
+	for _, e := range entries {
+		if e.ID.IsZero() {
+			e.ID = primitive.NewObjectID()
+		}
+		_, err := tx.Exec(`INSERT INTO audit_log (id, at, actor_id, actor_name, entity, entity_id, student_id, discipline_id, field, old_value, new_value)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			e.ID.Hex(), e.At.UTC().Format(sortableTime), e.ActorID.Hex(), e.ActorName, e.Entity, e.EntityID.Hex(),
+			nullHex(e.StudentID), nullHex(e.DisciplineID), e.Field, e.OldValue, e.NewValue)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *SQLiteStore) GetAudit(filter models.AuditFilter) ([]models.AuditEntry, error) {
+	var where []string
+	var args []interface{}
+	if filter.ActorID != nil {
+		where = append(where, "actor_id = ?")
+		args = append(args, filter.ActorID.Hex())
+	}
+	if filter.StudentID != nil {
+		where = append(where, "student_id = ?")
+		args = append(args, filter.StudentID.Hex())
+	}
+	if filter.DisciplineID != nil {
+		where = append(where, "discipline_id = ?")
+		args = append(args, filter.DisciplineID.Hex())
+	}
+	if filter.Field != "" {
+		where = append(where, "field = ?")
+		args = append(args, filter.Field)
+	}
+	if !filter.From.IsZero() {
+		where = append(where, "at >= ?")
+		args = append(args, filter.From.UTC().Format(sortableTime))
+	}
+	if !filter.To.IsZero() {
+		where = append(where, "at < ?")
+		args = append(args, filter.To.UTC().Format(sortableTime))
+	}
+
+	query := "SELECT id, at, actor_id, actor_name, entity, entity_id, student_id, discipline_id, field, old_value, new_value FROM audit_log"
+	if len(where) > 0 {
+		query += " WHERE " + strings.Join(where, " AND ")
+	}
+	query += " ORDER BY at DESC, rowid DESC"
+	if filter.Limit > 0 {
+		query += fmt.Sprintf(" LIMIT %d", filter.Limit)
+	}
+
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []models.AuditEntry
+	for rows.Next() {
+		var e models.AuditEntry
+		err := rows.Scan(hexID{&e.ID}, timeText{&e.At}, hexID{&e.ActorID}, &e.ActorName, &e.Entity, hexID{&e.EntityID},
+			nullHexID{&e.StudentID}, nullHexID{&e.DisciplineID}, &e.Field, &e.OldValue, &e.NewValue)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, e)
+	}
+	return entries, rows.Err()
+}
+
+// inTx выполняет fn в транзакции и откатывает её при ошибке.
+func (s *SQLiteStore) inTx(fn func(tx *sql.Tx) error) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	if err := fn(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,376 @@
+// db/store.go
+package db
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"electronic-diary/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var (
+	ErrNotFound   = errors.New("запись не найдена")
+	ErrEmptyName  = errors.New("название не может быть пустым")
+	ErrLoginTaken = errors.New("такой логин уже занят")
+	// ErrConflict — запись изменил кто-то другой после того, как её прочитали.
+	ErrConflict = errors.New("запись изменена другим пользователем")
+)
+
+// Store — хранилище дневника. Обработчики работают только через него,
+// поэтому MongoDB можно подменить другой реализацией (например, MemoryStore).
+type Store interface {
+	// Группы
+	GetGroups(includeArchived bool) ([]models.Group, error)
+	GetGroupByID(id primitive.ObjectID) (*models.Group, error)
+	GetGroupBySlug(slug string) (*models.Group, error)
+	CreateGroup(name string) (*models.Group, error)
+	RenameGroup(id primitive.ObjectID, name string) (*models.Group, error)
+	SetGroupArchived(id primitive.ObjectID, archived bool) error
+
+	// Студенты
+	GetStudentsByGroupID(groupID primitive.ObjectID) ([]models.Student, error)
+	GetStudentByID(id primitive.ObjectID) (*models.Student, error)
+	CreateStudent(name string, groupID primitive.ObjectID) (*models.Student, error)
+	RenameStudent(studentID primitive.ObjectID, name string) error
+	MoveStudent(studentID, groupID primitive.ObjectID) error
+	DeleteStudent(studentID primitive.ObjectID) error
+	// UpdateStudent применяет patch одним целым, только если версия
+	// студента равна version; иначе ErrConflict.
+	UpdateStudent(studentID primitive.ObjectID, version int, patch StudentPatch, audit []models.AuditEntry) error
+
+	// Дисциплины
+	GetDisciplinesByGroupID(groupID primitive.ObjectID) ([]models.Discipline, error)
+	GetAllDisciplinesByGroupID(groupID primitive.ObjectID) ([]models.Discipline, error)
+	GetDisciplineByID(id primitive.ObjectID) (*models.Discipline, error)
+	CreateDiscipline(groupID primitive.ObjectID, name string) (*models.Discipline, error)
+	RenameDiscipline(id primitive.ObjectID, name string) error
+	MoveDiscipline(id primitive.ObjectID, offset int) error
+	SetDisciplineRetired(id primitive.ObjectID, retired bool) error
+	// SetDisciplineTeacher назначает преподавателя; nil снимает назначение.
+	SetDisciplineTeacher(id primitive.ObjectID, teacherID *primitive.ObjectID) error
+
+	// Учебные годы и периоды. Записи, занятия и работы относятся к периоду;
+	// CreateTerm заводит пустые записи студентов по дисциплинам их групп.
+	// Пока период открыт, записи в нём поддерживаются как раньше: перевод
+	// студента и новая дисциплина добавляют и убирают записи только в
+	// открытых периодах. Писать в закрытый период не дают обработчики.
+	// AssignLegacyTerm относит к периоду данные, созданные до появления
+	// периодов; пустые записи периода, совпавшие со старыми, удаляются.
+	GetAcademicYears() ([]models.AcademicYear, error)
+	CreateAcademicYear(name string) (*models.AcademicYear, error)
+	GetTerms() ([]models.Term, error)
+	GetTermByID(id primitive.ObjectID) (*models.Term, error)
+	CreateTerm(term *models.Term) error
+	SetTermClosed(id primitive.ObjectID, closed bool) error
+	AssignLegacyTerm(termID primitive.ObjectID) error
+
+	// Успеваемость и посещаемость — в пределах периода
+	GetStudentDisciplineData(studentID, termID primitive.ObjectID) ([]models.StudentDisciplineData, error)
+	GetDisciplineDataFor(studentID, disciplineID, termID primitive.ObjectID) (*models.StudentDisciplineData, error)
+	GetDisciplineDataByID(id primitive.ObjectID) (*models.StudentDisciplineData, error)
+	InsertDisciplineData(data *models.StudentDisciplineData, audit []models.AuditEntry) error
+	DeleteDisciplineData(dataID primitive.ObjectID, audit []models.AuditEntry) error
+	// SaveStudentSheet сохраняет комментарий и записи студента одним целым:
+	// при ошибке не меняется ничего. version и Version записей — версии,
+	// которые видел пользователь; если запись с тех пор изменилась (или
+	// запись без ID уже кем-то создана), возвращается ErrConflict.
+	SaveStudentSheet(studentID primitive.ObjectID, version int, comments string, rows []models.StudentDisciplineData, audit []models.AuditEntry) error
+	// SaveDisciplineData сохраняет записи разных студентов одним целым:
+	// записи без ID создаются, остальные обновляются, только если Version
+	// совпадает с сохранённой; иначе ErrConflict и не меняется ничего.
+	// При успехе ID и Version строк обновляются на месте.
+	SaveDisciplineData(rows []models.StudentDisciplineData, audit []models.AuditEntry) error
+
+	// Сброс данных. ResetDynamicData обнуляет комментарии, счётчики и баллы
+	// в пределах snapshot.Scope и удаляет отметки журнала и баллы за работы
+	// (закрытые периоды не трогаются); версии затронутых студентов и записей
+	// растут, чтобы открытая до сброса форма получила конфликт;
+	// прежние значения хранилище сохраняет в snapshot (заполняя ID, At и
+	// данные), чтобы сброс можно было отменить. RestoreResetSnapshot
+	// возвращает данные снимка поверх текущих, пропуская удалённое с тех пор,
+	// и пересчитывает выводимые поля; повторное восстановление — ErrConflict.
+	// Журнал сброса зависит от снимка, поэтому audit строит его по
+	// заполненному snapshot.
+	ResetDynamicData(snapshot *models.ResetSnapshot, audit func(*models.ResetSnapshot) []models.AuditEntry) error
+	GetResetSnapshots() ([]models.ResetSnapshot, error)
+	GetResetSnapshotByID(id primitive.ObjectID) (*models.ResetSnapshot, error)
+	RestoreResetSnapshot(id primitive.ObjectID, audit []models.AuditEntry) error
+
+	// Журнал посещаемости. SaveAttendance и DeleteLesson пересчитывают
+	// TotalClasses/AttendedClasses студентов по отметкам дисциплины за период.
+	GetLessonsByGroupID(groupID, termID primitive.ObjectID) ([]models.Lesson, error)
+	GetLessonByID(id primitive.ObjectID) (*models.Lesson, error)
+	CreateLesson(lesson *models.Lesson) error
+	DeleteLesson(id primitive.ObjectID, audit []models.AuditEntry) error
+	GetAttendanceByLesson(lessonID primitive.ObjectID) ([]models.AttendanceMark, error)
+	SaveAttendance(lessonID primitive.ObjectID, marks map[primitive.ObjectID]models.AttendanceStatus, audit []models.AuditEntry) error
+
+	// Оцениваемые работы. SaveAssessmentMarks и DeleteAssessment пересчитывают
+	// Score студентов по взвешенным баллам, пока у дисциплины есть работы
+	// в периоде.
+	GetAssessmentsByDisciplineID(disciplineID, termID primitive.ObjectID) ([]models.Assessment, error)
+	GetAssessmentByID(id primitive.ObjectID) (*models.Assessment, error)
+	CreateAssessment(assessment *models.Assessment) error
+	DeleteAssessment(id primitive.ObjectID, audit []models.AuditEntry) error
+	GetMarksByAssessment(assessmentID primitive.ObjectID) ([]models.AssessmentMark, error)
+	GetMarksByStudent(studentID primitive.ObjectID) ([]models.AssessmentMark, error)
+	SaveAssessmentMarks(assessmentID primitive.ObjectID, points map[primitive.ObjectID]float64, audit []models.AuditEntry) error
+
+	// Шкалы оценок. Шкала дисциплины важнее шкалы группы; nil снимает
+	// назначение. DeleteGradingScale снимает шкалу со всех групп и дисциплин.
+	GetGradingScales() ([]models.GradingScale, error)
+	GetGradingScaleByID(id primitive.ObjectID) (*models.GradingScale, error)
+	CreateGradingScale(scale *models.GradingScale) error
+	DeleteGradingScale(id primitive.ObjectID) error
+	SetGroupGradingScale(groupID primitive.ObjectID, scaleID *primitive.ObjectID) error
+	SetDisciplineGradingScale(disciplineID primitive.ObjectID, scaleID *primitive.ObjectID) error
+
+	// Пользователи и сессии. DeleteUser завершает все сессии пользователя
+	// и снимает его с дисциплин, которые он вёл.
+	GetUsers() ([]models.User, error)
+	GetUserByID(id primitive.ObjectID) (*models.User, error)
+	GetUserByLogin(login string) (*models.User, error)
+	CreateUser(user *models.User) error
+	UpdateUser(user *models.User) error
+	DeleteUser(id primitive.ObjectID) error
+	CreateSession(session *models.Session) error
+	GetSession(token string) (*models.Session, error)
+	DeleteSession(token string) error
+
+	// Журнал изменений. Записи только добавляются, и только вместе с
+	// изменением: методы выше с параметром audit пишут его в той же
+	// транзакции, что и данные, и при сбое журнала не сохраняют ничего.
+	// Записи о строках, созданных при сохранении, получают их ID.
+	// GetAudit отдаёт записи от новых к старым.
+	GetAudit(filter models.AuditFilter) ([]models.AuditEntry, error)
+
+	// Reset удаляет все данные хранилища.
+	Reset() error
+	Close() error
+}
+
+// StudentPatch — изменения студента для UpdateStudent; nil — поле не
+// меняется. Смена группы пересоздаёт записи открытых периодов, как
+// MoveStudent.
+type StudentPatch struct {
+	Name     *string
+	GroupID  *primitive.ObjectID
+	Comments *string
+}
+
+// cleanName обрезает пробелы и проверяет, что название не пустое.
+func cleanName(name string) (string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return "", ErrEmptyName
+	}
+	return name, nil
+}
+
+// uniqueSlug подбирает свободный slug для группы, добавляя суффикс -2, -3...
+// taken сообщает, занят ли slug другой группой.
+func uniqueSlug(name string, taken func(slug string) (bool, error)) (string, error) {
+	base := Slugify(name)
+	if base == "" {
+		base = "group"
+	}
+	slug := base
+	for i := 2; ; i++ {
+		busy, err := taken(slug)
+		if err != nil {
+			return "", err
+		}
+		if !busy {
+			return slug, nil
+		}
+		slug = fmt.Sprintf("%s-%d", base, i)
+	}
+}
+
+// auditNewRows проставляет записям журнала о новых строках ID, выданные
+// при сохранении: журнал готовят до записи, когда ID ещё нет.
+func auditNewRows(audit []models.AuditEntry, rows []models.StudentDisciplineData) {
+	for i := range audit {
+		e := &audit[i]
+		if e.Entity != models.AuditRecord || !e.EntityID.IsZero() || e.StudentID == nil || e.DisciplineID == nil {
+			continue
+		}
+		for _, row := range rows {
+			if row.StudentID == *e.StudentID && row.DisciplineID == *e.DisciplineID {
+				e.EntityID = row.ID
+				break
+			}
+		}
+	}
+}
+
+// nextPosition — позиция для новой дисциплины в конце списка группы.
+func nextPosition(disciplines []models.Discipline) int {
+	position := 1
+	for _, d := range disciplines {
+		if d.Position >= position {
+			position = d.Position + 1
+		}
+	}
+	return position
+}
+
+// moveInList переставляет дисциплину id на offset позиций внутри упорядоченного
+// списка и возвращает новые позиции тех дисциплин, у которых они изменились.
+func moveInList(list []models.Discipline, id primitive.ObjectID, offset int) map[primitive.ObjectID]int {
+	from := -1
+	for i, d := range list {
+		if d.ID == id {
+			from = i
+		}
+	}
+	to := from + offset
+	if from < 0 || to < 0 || to >= len(list) {
+		return nil
+	}
+	list[from], list[to] = list[to], list[from]
+
+	changed := make(map[primitive.ObjectID]int)
+	for i, d := range list {
+		if d.Position != i+1 {
+			changed[d.ID] = i + 1
+		}
+	}
+	return changed
+}
+
+// planStudentSync сравнивает записи студента с дисциплинами его группы:
+// stale — записи по чужим дисциплинам, missing — действующие дисциплины
+// группы, по которым записи ещё нет.
+func planStudentSync(disciplines []models.Discipline, existing []models.StudentDisciplineData) (stale, missing []primitive.ObjectID) {
+	wanted := make(map[primitive.ObjectID]bool, len(disciplines))
+	for _, d := range disciplines {
+		wanted[d.ID] = true
+	}
+	have := make(map[primitive.ObjectID]bool, len(existing))
+	for _, d := range existing {
+		have[d.DisciplineID] = true
+		if !wanted[d.DisciplineID] {
+			stale = append(stale, d.ID)
+		}
+	}
+	for _, d := range disciplines {
+		if !have[d.ID] && !d.Retired {
+			missing = append(missing, d.ID)
+		}
+	}
+	return stale, missing
+}
+
+// sortDisciplines упорядочивает дисциплины так же, как это делает MongoStore:
+// по позиции, затем по времени создания.
+func sortDisciplines(list []models.Discipline) {
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].Position != list[j].Position {
+			return list[i].Position < list[j].Position
+		}
+		return lessID(list[i].ID, list[j].ID)
+	})
+}
+
+// lessID — ObjectID упорядочены по времени создания.
+func lessID(a, b primitive.ObjectID) bool {
+	return bytes.Compare(a[:], b[:]) < 0
+}
+
+// attendanceCounts — счётчики посещаемости одного студента.
+type attendanceCounts struct {
+	total, attended int
+}
+
+// tallyAttendance сводит отметки по дисциплине в счётчики по студентам.
+func tallyAttendance(marks []models.AttendanceMark) map[primitive.ObjectID]attendanceCounts {
+	byStudent := make(map[primitive.ObjectID][]models.AttendanceStatus)
+	for _, m := range marks {
+		byStudent[m.StudentID] = append(byStudent[m.StudentID], m.Status)
+	}
+	counts := make(map[primitive.ObjectID]attendanceCounts, len(byStudent))
+	for studentID, statuses := range byStudent {
+		total, attended := models.CountAttendance(statuses)
+		counts[studentID] = attendanceCounts{total: total, attended: attended}
+	}
+	return counts
+}
+
+// sortLessons — сначала свежие занятия.
+func sortLessons(list []models.Lesson) {
+	sort.Slice(list, func(i, j int) bool {
+		if !list[i].Date.Equal(list[j].Date) {
+			return list[i].Date.After(list[j].Date)
+		}
+		return lessID(list[j].ID, list[i].ID)
+	})
+}
+
+// sortAssessments — работы в порядке проведения.
+func sortAssessments(list []models.Assessment) {
+	sort.Slice(list, func(i, j int) bool {
+		if !list[i].Date.Equal(list[j].Date) {
+			return list[i].Date.Before(list[j].Date)
+		}
+		return lessID(list[i].ID, list[j].ID)
+	})
+}
+
+// scoreAssessments считает Score каждого студента по баллам за работы
+// дисциплины. Студенты без единой оценки в результат не попадают.
+func scoreAssessments(assessments []models.Assessment, marks []models.AssessmentMark) map[primitive.ObjectID]int {
+	byStudent := make(map[primitive.ObjectID]map[primitive.ObjectID]float64)
+	for _, m := range marks {
+		if byStudent[m.StudentID] == nil {
+			byStudent[m.StudentID] = make(map[primitive.ObjectID]float64)
+		}
+		byStudent[m.StudentID][m.AssessmentID] = m.Points
+	}
+	scores := make(map[primitive.ObjectID]int, len(byStudent))
+	for studentID, points := range byStudent {
+		if score, ok := models.WeightedScore(assessments, points); ok {
+			scores[studentID] = score
+		}
+	}
+	return scores
+}
+
+// commentsInScope сообщает, обнуляет ли сброс комментарий студента группы
+// groupID: сброс по дисциплине комментарии не трогает.
+func commentsInScope(scope models.ResetScope, groupID primitive.ObjectID) bool {
+	if scope.DisciplineID != nil {
+		return false
+	}
+	return scope.GroupID == nil || *scope.GroupID == groupID
+}
+
+// termKey — дисциплина в пределах периода: выводимые поля записей
+// пересчитываются по такой паре.
+type termKey struct {
+	discipline, term primitive.ObjectID
+}
+
+// sortTerms упорядочивает периоды по дате начала.
+func sortTerms(list []models.Term) {
+	sort.Slice(list, func(i, j int) bool {
+		if !list[i].Start.Equal(list[j].Start) {
+			return list[i].Start.Before(list[j].Start)
+		}
+		return lessID(list[i].ID, list[j].ID)
+	})
+}
+
+// sortSnapshots упорядочивает снимки сбросов от новых к старым.
+func sortSnapshots(list []models.ResetSnapshot) {
+	sort.Slice(list, func(i, j int) bool {
+		if !list[i].At.Equal(list[j].At) {
+			return list[i].At.After(list[j].At)
+		}
+		return lessID(list[j].ID, list[i].ID)
+	})
+}
@@ -0,0 +1,369 @@
+// db/store_test.go
+package db
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"electronic-diary/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Контракт Store: одни и те же проверки для всех реализаций. MongoDB проверяется, только если задан
+// DIARY_TEST_MONGO_URI; база DIARY_TEST_MONGO_DATABASE (по умолчанию
+// electronic_diary_test) очищается перед каждой проверкой.
+
+// storeFactories — реализации Store под тестом; open создаёт пустое хранилище.
+var storeFactories = []struct {
+	name string
+	open func(t *testing.T) Store
+}{
+	{"memory", func(t *testing.T) Store {
+		return NewMemoryStore()
+	}},
+	{"sqlite", func(t *testing.T) Store {
+		s, err := NewSQLiteStore(filepath.Join(t.TempDir(), "diary.db"))
+		if err != nil {
+			t.Fatalf("не удалось открыть SQLite: %v", err)
+		}
+		return s
+	}},
+	{"mongo", func(t *testing.T) Store {
+		uri := os.Getenv("DIARY_TEST_MONGO_URI")
+		if uri == "" {
+			t.Skip("DIARY_TEST_MONGO_URI не задан")
+		}
+		database := os.Getenv("DIARY_TEST_MONGO_DATABASE")
+		if database == "" {
+			database = "electronic_diary_test"
+		}
+		s, err := NewMongoStore(uri, database)
+		if err != nil {
+			t.Skipf("MongoDB недоступна: %v", err)
+		}
+		if err := s.Reset(); err != nil {
+			t.Fatalf("не удалось очистить MongoDB: %v", err)
+		}
+		return s
+	}},
+}
+
+// fixture — группа с дисциплиной и двумя студентами в открытом и
+// закрытом периодах. В закрытом периоде у первого студента 90 баллов.
+type fixture struct {
+	open, closed models.Term
+	discipline   *models.Discipline
+	students     []*models.Student
+}
+
+func newFixture(t *testing.T, s Store) *fixture {
+	t.Helper()
+	year, err := s.CreateAcademicYear("2025/2026")
+	must(t, err)
+	f := &fixture{
+		closed: models.Term{
+			YearID: year.ID,
+			Name:   "Осенний семестр",
+			Start:  time.Date(2025, time.September, 1, 0, 0, 0, 0, time.UTC),
+			End:    time.Date(2026, time.January, 31, 0, 0, 0, 0, time.UTC),
+		},
+		open: models.Term{
+			YearID: year.ID,
+			Name:   "Весенний семестр",
+			Start:  time.Date(2026, time.February, 1, 0, 0, 0, 0, time.UTC),
+			End:    time.Date(2026, time.August, 31, 0, 0, 0, 0, time.UTC),
+		},
+	}
+	must(t, s.CreateTerm(&f.closed))
+	must(t, s.CreateTerm(&f.open))
+
+	group, err := s.CreateGroup("ИС-21")
+	must(t, err)
+	f.discipline, err = s.CreateDiscipline(group.ID, "Математика")
+	must(t, err)
+	for _, name := range []string{"Иванов Иван", "Петров Пётр"} {
+		st, err := s.CreateStudent(name, group.ID)
+		must(t, err)
+		f.students = append(f.students, st)
+	}
+
+	old := f.record(t, s, 0, f.closed.ID)
+	old.Score = 90
+	must(t, s.SaveDisciplineData([]models.StudentDisciplineData{*old}, nil))
+	must(t, s.SetTermClosed(f.closed.ID, true))
+	return f
+}
+
+// record читает запись студента i по дисциплине фикстуры за период.
+func (f *fixture) record(t *testing.T, s Store, i int, termID primitive.ObjectID) *models.StudentDisciplineData {
+	t.Helper()
+	d, err := s.GetDisciplineDataFor(f.students[i].ID, f.discipline.ID, termID)
+	if err != nil {
+		t.Fatalf("запись студента %d: %v", i, err)
+	}
+	return d
+}
+
+// student читает студента i заново.
+func (f *fixture) student(t *testing.T, s Store, i int) *models.Student {
+	t.Helper()
+	st, err := s.GetStudentByID(f.students[i].ID)
+	if err != nil {
+		t.Fatalf("студент %d: %v", i, err)
+	}
+	return st
+}
+
+func must(t *testing.T, err error) {
+	t.Helper()
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestStoreContract(t *testing.T) {
+	tests := []struct {
+		name string
+		run  func(t *testing.T, s Store, f *fixture)
+	}{
+		{"студенты получают записи по дисциплинам группы", func(t *testing.T, s Store, f *fixture) {
+			for i := range f.students {
+				f.record(t, s, i, f.open.ID)
+			}
+			st, err := s.CreateStudent("Сидоров Сидор", f.discipline.GroupID)
+			must(t, err)
+			if _, err := s.GetDisciplineDataFor(st.ID, f.discipline.ID, f.open.ID); err != nil {
+				t.Errorf("запись нового студента: %v", err)
+			}
+			must(t, s.DeleteStudent(st.ID))
+			if _, err := s.GetDisciplineDataFor(st.ID, f.discipline.ID, f.open.ID); !errors.Is(err, ErrNotFound) {
+				t.Errorf("запись удалённого студента: ошибка %v, ждали ErrNotFound", err)
+			}
+		}},
+		{"SaveDisciplineData повышает версию", func(t *testing.T, s Store, f *fixture) {
+			d := f.record(t, s, 0, f.open.ID)
+			version := d.Version
+			d.Score = 70
+			rows := []models.StudentDisciplineData{*d}
+			must(t, s.SaveDisciplineData(rows, nil))
+			if rows[0].Version != version+1 {
+				t.Errorf("версия в строке %d, ждали %d", rows[0].Version, version+1)
+			}
+			if got := f.record(t, s, 0, f.open.ID); got.Score != 70 || got.Version != version+1 {
+				t.Errorf("сохранено %d баллов, версия %d; ждали 70 и %d", got.Score, got.Version, version+1)
+			}
+		}},
+		{"SaveDisciplineData: устаревшая версия — ничего не сохраняется", func(t *testing.T, s Store, f *fixture) {
+			a := f.record(t, s, 0, f.open.ID)
+			b := f.record(t, s, 1, f.open.ID)
+			stale := *a
+			a.Score = 10
+			must(t, s.SaveDisciplineData([]models.StudentDisciplineData{*a}, nil))
+
+			stale.Score = 20
+			b.Score = 30
+			err := s.SaveDisciplineData([]models.StudentDisciplineData{*b, stale}, nil)
+			if !errors.Is(err, ErrConflict) {
+				t.Fatalf("ошибка %v, ждали ErrConflict", err)
+			}
+			if got := f.record(t, s, 0, f.open.ID); got.Score != 10 {
+				t.Errorf("первая запись: %d баллов, ждали 10", got.Score)
+			}
+			if got := f.record(t, s, 1, f.open.ID); got.Score != 0 || got.Version != b.Version {
+				t.Errorf("вторая запись изменилась: %d баллов, версия %d", got.Score, got.Version)
+			}
+		}},
+		{"SaveDisciplineData: запись без ID для занятой пары — конфликт", func(t *testing.T, s Store, f *fixture) {
+			err := s.SaveDisciplineData([]models.StudentDisciplineData{{
+				StudentID:    f.students[0].ID,
+				DisciplineID: f.discipline.ID,
+				TermID:       f.open.ID,
+				Score:        50,
+			}}, nil)
+			if !errors.Is(err, ErrConflict) {
+				t.Fatalf("ошибка %v, ждали ErrConflict", err)
+			}
+			if got := f.record(t, s, 0, f.open.ID); got.Score != 0 {
+				t.Errorf("запись изменилась: %d баллов", got.Score)
+			}
+		}},
+		{"SaveStudentSheet сверяет версию студента", func(t *testing.T, s Store, f *fixture) {
+			version := f.student(t, s, 0).Version
+			d := f.record(t, s, 0, f.open.ID)
+			d.Score = 60
+			must(t, s.SaveStudentSheet(f.students[0].ID, version, "старается", []models.StudentDisciplineData{*d}, nil))
+			st := f.student(t, s, 0)
+			if st.Version != version+1 || st.Comments != "старается" {
+				t.Errorf("версия %d, комментарий %q; ждали %d и «старается»", st.Version, st.Comments, version+1)
+			}
+
+			d = f.record(t, s, 0, f.open.ID)
+			d.Score = 65
+			err := s.SaveStudentSheet(f.students[0].ID, version, "устарело", []models.StudentDisciplineData{*d}, nil)
+			if !errors.Is(err, ErrConflict) {
+				t.Fatalf("ошибка %v, ждали ErrConflict", err)
+			}
+			if got := f.record(t, s, 0, f.open.ID); got.Score != 60 {
+				t.Errorf("запись изменилась при конфликте: %d баллов", got.Score)
+			}
+			if got := f.student(t, s, 0); got.Comments != "старается" {
+				t.Errorf("комментарий изменился при конфликте: %q", got.Comments)
+			}
+		}},
+		{"SaveStudentSheet: устаревшая запись — ничего не сохраняется", func(t *testing.T, s Store, f *fixture) {
+			d := f.record(t, s, 0, f.open.ID)
+			stale := *d
+			d.Score = 40
+			must(t, s.SaveDisciplineData([]models.StudentDisciplineData{*d}, nil))
+
+			version := f.student(t, s, 0).Version
+			stale.Score = 45
+			err := s.SaveStudentSheet(f.students[0].ID, version, "новый", []models.StudentDisciplineData{stale}, nil)
+			if !errors.Is(err, ErrConflict) {
+				t.Fatalf("ошибка %v, ждали ErrConflict", err)
+			}
+			if got := f.student(t, s, 0); got.Comments != "" || got.Version != version {
+				t.Errorf("студент изменился при конфликте: %q, версия %d", got.Comments, got.Version)
+			}
+		}},
+		{"UpdateStudent сверяет версию", func(t *testing.T, s Store, f *fixture) {
+			version := f.student(t, s, 0).Version
+			name, comments := "Иванов Иван Иванович", "переведён"
+			must(t, s.UpdateStudent(f.students[0].ID, version, StudentPatch{Name: &name, Comments: &comments}, nil))
+			st := f.student(t, s, 0)
+			if st.Version != version+1 || st.Name != name || st.Comments != comments {
+				t.Errorf("студент %+v, ждали версию %d", st, version+1)
+			}
+
+			other := "Другое имя"
+			if err := s.UpdateStudent(f.students[0].ID, version, StudentPatch{Name: &other}, nil); !errors.Is(err, ErrConflict) {
+				t.Errorf("устаревшая версия: ошибка %v, ждали ErrConflict", err)
+			}
+			if got := f.student(t, s, 0); got.Name != name {
+				t.Errorf("имя изменилось при конфликте: %q", got.Name)
+			}
+			if err := s.UpdateStudent(primitive.NewObjectID(), 0, StudentPatch{Name: &other}, nil); !errors.Is(err, ErrNotFound) {
+				t.Errorf("нет студента: ошибка %v, ждали ErrNotFound", err)
+			}
+		}},
+		{"сброс повышает версии и отменяется", func(t *testing.T, s Store, f *fixture) {
+			d := f.record(t, s, 0, f.open.ID)
+			d.Score = 80
+			must(t, s.SaveDisciplineData([]models.StudentDisciplineData{*d}, nil))
+			comments := "отличник"
+			must(t, s.UpdateStudent(f.students[0].ID, f.student(t, s, 0).Version, StudentPatch{Comments: &comments}, nil))
+
+			before := f.record(t, s, 0, f.open.ID)
+			beforeStudent := f.student(t, s, 0)
+
+			snapshot := &models.ResetSnapshot{ScopeName: "все группы"}
+			must(t, s.ResetDynamicData(snapshot, nil))
+
+			after := f.record(t, s, 0, f.open.ID)
+			if after.Score != 0 || after.Version <= before.Version {
+				t.Errorf("после сброса %d баллов, версия %d (была %d)", after.Score, after.Version, before.Version)
+			}
+			st := f.student(t, s, 0)
+			if st.Comments != "" || st.Version <= beforeStudent.Version {
+				t.Errorf("после сброса комментарий %q, версия %d (была %d)", st.Comments, st.Version, beforeStudent.Version)
+			}
+
+			// Форма, открытая до сброса, получает конфликт
+			before.Score = 85
+			if err := s.SaveDisciplineData([]models.StudentDisciplineData{*before}, nil); !errors.Is(err, ErrConflict) {
+				t.Errorf("запись до сброса: ошибка %v, ждали ErrConflict", err)
+			}
+			if err := s.UpdateStudent(f.students[0].ID, beforeStudent.Version, StudentPatch{Comments: &comments}, nil); !errors.Is(err, ErrConflict) {
+				t.Errorf("студент до сброса: ошибка %v, ждали ErrConflict", err)
+			}
+
+			must(t, s.RestoreResetSnapshot(snapshot.ID, nil))
+			if got := f.record(t, s, 0, f.open.ID); got.Score != 80 {
+				t.Errorf("после восстановления %d баллов, ждали 80", got.Score)
+			}
+			if got := f.student(t, s, 0); got.Comments != comments {
+				t.Errorf("после восстановления комментарий %q", got.Comments)
+			}
+			if err := s.RestoreResetSnapshot(snapshot.ID, nil); !errors.Is(err, ErrConflict) {
+				t.Errorf("повторное восстановление: ошибка %v, ждали ErrConflict", err)
+			}
+		}},
+		{"закрытый период не трогают сброс и восстановление", func(t *testing.T, s Store, f *fixture) {
+			before := f.record(t, s, 0, f.closed.ID)
+			d := f.record(t, s, 0, f.open.ID)
+			d.Score = 80
+			must(t, s.SaveDisciplineData([]models.StudentDisciplineData{*d}, nil))
+
+			snapshot := &models.ResetSnapshot{ScopeName: "все группы"}
+			must(t, s.ResetDynamicData(snapshot, nil))
+			for _, r := range snapshot.Records {
+				if r.TermID == f.closed.ID {
+					t.Errorf("в снимок попала запись закрытого периода %s", r.ID.Hex())
+				}
+			}
+			if got := f.record(t, s, 0, f.closed.ID); got.Score != 90 || got.Version != before.Version {
+				t.Errorf("сброс изменил закрытый период: %d баллов, версия %d", got.Score, got.Version)
+			}
+
+			// Период, закрытый после сброса, восстановление тоже не трогает
+			must(t, s.SetTermClosed(f.open.ID, true))
+			must(t, s.RestoreResetSnapshot(snapshot.ID, nil))
+			if got := f.record(t, s, 0, f.open.ID); got.Score != 0 {
+				t.Errorf("восстановление вернуло %d баллов в закрытый период", got.Score)
+			}
+			if got := f.record(t, s, 0, f.closed.ID); got.Score != 90 || got.Version != before.Version {
+				t.Errorf("восстановление изменило закрытый период: %d баллов, версия %d", got.Score, got.Version)
+			}
+		}},
+		{"журнал пишется вместе с изменением", func(t *testing.T, s Store, f *fixture) {
+			audit := func(d models.StudentDisciplineData) []models.AuditEntry {
+				return []models.AuditEntry{{At: time.Now(), Entity: models.AuditRecord, EntityID: d.ID,
+					StudentID: &d.StudentID, DisciplineID: &d.DisciplineID, Field: "score"}}
+			}
+			entries := func() []models.AuditEntry {
+				t.Helper()
+				list, err := s.GetAudit(models.AuditFilter{})
+				must(t, err)
+				return list
+			}
+
+			d := f.record(t, s, 0, f.open.ID)
+			stale := *d
+			d.Score = 70
+			must(t, s.SaveDisciplineData([]models.StudentDisciplineData{*d}, audit(*d)))
+			if got := len(entries()); got != 1 {
+				t.Fatalf("после сохранения %d записей журнала, ждали 1", got)
+			}
+			stale.Score = 60
+			if err := s.SaveDisciplineData([]models.StudentDisciplineData{stale}, audit(stale)); !errors.Is(err, ErrConflict) {
+				t.Fatalf("ошибка %v, ждали ErrConflict", err)
+			}
+			if got := len(entries()); got != 1 {
+				t.Errorf("после конфликта %d записей журнала, ждали 1", got)
+			}
+
+			must(t, s.DeleteDisciplineData(d.ID, audit(*d)))
+			created := models.StudentDisciplineData{StudentID: d.StudentID, DisciplineID: d.DisciplineID, TermID: d.TermID, Score: 50}
+			must(t, s.InsertDisciplineData(&created, audit(models.StudentDisciplineData{StudentID: d.StudentID, DisciplineID: d.DisciplineID})))
+			list := entries()
+			if len(list) != 3 || list[0].EntityID != created.ID {
+				t.Errorf("журнал %+v: ждали 3 записи, последняя — о записи %s", list, created.ID.Hex())
+			}
+		}},
+	}
+
+	for _, factory := range storeFactories {
+		t.Run(factory.name, func(t *testing.T) {
+			for _, tt := range tests {
+				t.Run(tt.name, func(t *testing.T) {
+					s := factory.open(t)
+					defer s.Close()
+					tt.run(t, s, newFixture(t, s))
+				})
+			}
+		})
+	}
+}
@@ -0,0 +1,140 @@
+// db/terms.go
+package db
+
+import (
+	"context"
+	"electronic-diary/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// legacyTerm — фильтр данных, созданных до появления периодов.
+var legacyTerm = bson.M{"termId": bson.M{"$in": bson.A{nil, primitive.NilObjectID}}}
+
+func (s *MongoStore) GetAcademicYears() ([]models.AcademicYear, error) {
+	ctx := context.Background()
+	opts := options.Find().SetSort(bson.D{{Key: "name", Value: 1}})
+	cursor, err := s.yearsCol.Find(ctx, bson.M{}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var years []models.AcademicYear
+	err = cursor.All(ctx, &years)
+	return years, err
+}
+
+func (s *MongoStore) CreateAcademicYear(name string) (*models.AcademicYear, error) {
+	name, err := cleanName(name)
+	if err != nil {
+		return nil, err
+	}
+	year := models.AcademicYear{ID: primitive.NewObjectID(), Name: name}
+	if _, err := s.yearsCol.InsertOne(context.Background(), year); err != nil {
+		return nil, err
+	}
+	return &year, nil
+}
+
+func (s *MongoStore) GetTerms() ([]models.Term, error) {
+	return s.findTerms(bson.M{})
+}
+
+func (s *MongoStore) findTerms(filter bson.M) ([]models.Term, error) {
+	ctx := context.Background()
+	cursor, err := s.termsCol.Find(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var terms []models.Term
+	if err := cursor.All(ctx, &terms); err != nil {
+		return nil, err
+	}
+	sortTerms(terms)
+	return terms, nil
+}
+
+// closedTermIDs — идентификаторы закрытых периодов для фильтров $nin.
+func (s *MongoStore) closedTermIDs() ([]interface{}, error) {
+	ids, err := s.termsCol.Distinct(context.Background(), "_id", bson.M{"closed": true})
+	if ids == nil {
+		ids = []interface{}{}
+	}
+	return ids, err
+}
+
+func (s *MongoStore) GetTermByID(id primitive.ObjectID) (*models.Term, error) {
+	var term models.Term
+	err := findOne(s.termsCol, bson.M{"_id": id}, &term)
+	return &term, err
+}
+
+// CreateTerm добавляет период и заводит в нём пустые записи всем студентам
+// по дисциплинам их групп.
+func (s *MongoStore) CreateTerm(term *models.Term) error {
+	name, err := cleanName(term.Name)
+	if err != nil {
+		return err
+	}
+	var year models.AcademicYear
+	if err := findOne(s.yearsCol, bson.M{"_id": term.YearID}, &year); err != nil {
+		return err
+	}
+	term.ID = primitive.NewObjectID()
+	term.Name = name
+	if _, err := s.termsCol.InsertOne(context.Background(), term); err != nil {
+		return err
+	}
+
+	students, err := s.findStudents(context.Background(), bson.M{})
+	if err != nil {
+		return err
+	}
+	for _, st := range students {
+		if err := s.syncStudentTerm(st.ID, st.GroupID, term.ID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *MongoStore) SetTermClosed(id primitive.ObjectID, closed bool) error {
+	res, err := s.termsCol.UpdateOne(context.Background(), bson.M{"_id": id}, bson.M{"$set": bson.M{"closed": closed}})
+	if err == nil && res.MatchedCount == 0 {
+		return ErrNotFound
+	}
+	return err
+}
+
+func (s *MongoStore) AssignLegacyTerm(termID primitive.ObjectID) error {
+	ctx := context.Background()
+
+	// Пустые записи, которые CreateTerm успел завести в периоде, уступают старым
+	legacy, err := s.findDisciplineData(ctx, legacyTerm)
+	if err != nil {
+		return err
+	}
+	for _, d := range legacy {
+		_, err := s.studentDisciplineDataCol.DeleteMany(ctx, bson.M{
+			"studentId": d.StudentID, "disciplineId": d.DisciplineID, "termId": termID,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	set := bson.M{"$set": bson.M{"termId": termID}}
+	if _, err := s.studentDisciplineDataCol.UpdateMany(ctx, legacyTerm, set); err != nil {
+		return err
+	}
+	if _, err := s.lessonsCol.UpdateMany(ctx, legacyTerm, set); err != nil {
+		return err
+	}
+	_, err = s.assessmentsCol.UpdateMany(ctx, legacyTerm, set)
+	return err
+}
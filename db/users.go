@@ -0,0 +1,108 @@
+// db/users.go
+package db
+
+import (
+	"context"
+	"electronic-diary/models"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// ensureUserIndexes — уникальный логин и автоматическое удаление
+// просроченных сессий средствами MongoDB.
+func (s *MongoStore) ensureUserIndexes() error {
+	ctx := context.Background()
+	_, err := s.usersCol.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "login", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	})
+	if err != nil {
+		return err
+	}
+	_, err = s.sessionsCol.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "expiresAt", Value: 1}},
+		Options: options.Index().SetExpireAfterSeconds(0),
+	})
+	return err
+}
+
+func (s *MongoStore) GetUsers() ([]models.User, error) {
+	ctx := context.Background()
+	opts := options.Find().SetSort(bson.D{{Key: "login", Value: 1}})
+	cursor, err := s.usersCol.Find(ctx, bson.M{}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var users []models.User
+	err = cursor.All(ctx, &users)
+	return users, err
+}
+
+func (s *MongoStore) GetUserByID(id primitive.ObjectID) (*models.User, error) {
+	var user models.User
+	err := findOne(s.usersCol, bson.M{"_id": id}, &user)
+	return &user, err
+}
+
+func (s *MongoStore) GetUserByLogin(login string) (*models.User, error) {
+	var user models.User
+	err := findOne(s.usersCol, bson.M{"login": login}, &user)
+	return &user, err
+}
+
+func (s *MongoStore) CreateUser(user *models.User) error {
+	if user.ID.IsZero() {
+		user.ID = primitive.NewObjectID()
+	}
+	_, err := s.usersCol.InsertOne(context.Background(), user)
+	if mongo.IsDuplicateKeyError(err) {
+		return ErrLoginTaken
+	}
+	return err
+}
+
+func (s *MongoStore) UpdateUser(user *models.User) error {
+	res, err := s.usersCol.ReplaceOne(context.Background(), bson.M{"_id": user.ID}, user)
+	if mongo.IsDuplicateKeyError(err) {
+		return ErrLoginTaken
+	}
+	if err == nil && res.MatchedCount == 0 {
+		return ErrNotFound
+	}
+	return err
+}
+
+func (s *MongoStore) DeleteUser(id primitive.ObjectID) error {
+	ctx := context.Background()
+	if _, err := s.sessionsCol.DeleteMany(ctx, bson.M{"userId": id}); err != nil {
+		return err
+	}
+	if _, err := s.disciplinesCol.UpdateMany(ctx, bson.M{"teacherId": id}, bson.M{"$unset": bson.M{"teacherId": ""}}); err != nil {
+		return err
+	}
+	_, err := s.usersCol.DeleteOne(ctx, bson.M{"_id": id})
+	return err
+}
+
+func (s *MongoStore) CreateSession(session *models.Session) error {
+	_, err := s.sessionsCol.InsertOne(context.Background(), session)
+	return err
+}
+
+// GetSession находит действующую сессию; просроченная считается ненайденной.
+func (s *MongoStore) GetSession(token string) (*models.Session, error) {
+	var session models.Session
+	err := findOne(s.sessionsCol, bson.M{"_id": token, "expiresAt": bson.M{"$gt": time.Now()}}, &session)
+	return &session, err
+}
+
+func (s *MongoStore) DeleteSession(token string) error {
+	_, err := s.sessionsCol.DeleteOne(context.Background(), bson.M{"_id": token})
+	return err
+}
@@ -0,0 +1,320 @@
+// handlers/analytics.go
+package handlers
+
+import (
+	"electronic-diary/models"
+	"electronic-diary/studentstats"
+	"html/template"
+	"log"
+	"math"
+	"net/http"
+	"sort"
+
+	"github.com/montanaflynn/stats"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Пороги, ниже которых студент попадает в группу риска.
+const (
+	riskScore      = 50 // средний балл
+	riskAttendance = 70 // посещаемость, %
+)
+
+// gradeCount — сколько студентов получили оценку по дисциплине.
+type gradeCount struct {
+	Band    models.GradeBand
+	Count   int
+	Percent int
+}
+
+// disciplineStats — сводка по дисциплине за период.
+type disciplineStats struct {
+	Discipline models.Discipline
+	Filled     int // записей с данными
+	Mean       float64
+	Median     float64
+	StdDev     float64
+	Attendance float64 // средняя посещаемость студентов, %; -1 — занятий не было
+	Grades     []gradeCount
+}
+
+// studentSummary — итоги студента по всем дисциплинам периода.
+type studentSummary struct {
+	Student    models.Student
+	Rank       int
+	Mean       float64 // средний балл; -1 — данных нет
+	Attendance float64 // посещаемость, %; -1 — занятий не было
+	Weak       []string
+	Reasons    []string
+}
+
+// summarize — среднее, медиана и стандартное отклонение; для пустой
+// выборки — нули.
+func summarize(values []float64) (mean, median, stdDev float64) {
+	if len(values) == 0 {
+		return 0, 0, 0
+	}
+	mean, _ = stats.Mean(values)
+	median, _ = stats.Median(values)
+	stdDev, _ = stats.StandardDeviation(values)
+	return mean, median, stdDev
+}
+
+// groupAnalytics считает сводку группы за период: статистику по
+// дисциплинам, рейтинг студентов и группу риска.
+func (s *Server) groupAnalytics(group *models.Group, termID primitive.ObjectID) ([]disciplineStats, []studentSummary, error) {
+	students, err := s.store.GetStudentsByGroupID(group.ID)
+	if err != nil {
+		return nil, nil, err
+	}
+	disciplines, err := s.store.GetDisciplinesByGroupID(group.ID)
+	if err != nil {
+		return nil, nil, err
+	}
+	scales, err := s.gradingScales()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	scores := make(map[primitive.ObjectID][]float64, len(disciplines))
+	attendance := make(map[primitive.ObjectID][]float64, len(disciplines))
+	grades := make(map[primitive.ObjectID]map[string]int, len(disciplines))
+	byID := make(map[primitive.ObjectID]models.Discipline, len(disciplines))
+	for _, d := range disciplines {
+		byID[d.ID] = d
+		grades[d.ID] = map[string]int{}
+	}
+
+	summaries := make([]studentSummary, 0, len(students))
+	for _, st := range students {
+		records, err := s.store.GetStudentDisciplineData(st.ID, termID)
+		if err != nil {
+			return nil, nil, err
+		}
+		sum := studentSummary{Student: st, Mean: -1, Attendance: -1}
+		var own []models.StudentDisciplineData
+		for _, d := range records {
+			discipline, ok := byID[d.DisciplineID]
+			if !ok || studentstats.Empty(d) {
+				continue
+			}
+			own = append(own, d)
+			scores[d.DisciplineID] = append(scores[d.DisciplineID], float64(d.Score))
+			if d.TotalClasses > 0 {
+				attendance[d.DisciplineID] = append(attendance[d.DisciplineID], float64(d.AttendedClasses)/float64(d.TotalClasses)*100)
+			}
+			grades[d.DisciplineID][resolveScale(scales, group, discipline).Grade(d.Score).Label]++
+			if d.Score < riskScore {
+				sum.Weak = append(sum.Weak, discipline.Name)
+			}
+		}
+		if totals := studentstats.Compute(own); totals.Count > 0 {
+			sum.Mean = totals.GPA
+			if totals.HasAttendance() {
+				sum.Attendance = totals.AttendanceRate()
+			}
+		}
+		sort.Strings(sum.Weak)
+		if sum.Mean >= 0 && sum.Mean < riskScore {
+			sum.Reasons = append(sum.Reasons, "низкий средний балл")
+		}
+		if sum.Attendance >= 0 && sum.Attendance < riskAttendance {
+			sum.Reasons = append(sum.Reasons, "низкая посещаемость")
+		}
+		summaries = append(summaries, sum)
+	}
+
+	// Рейтинг: по среднему баллу, затем по посещаемости; равные делят место
+	sort.SliceStable(summaries, func(i, j int) bool {
+		a, b := summaries[i], summaries[j]
+		if a.Mean != b.Mean {
+			return a.Mean > b.Mean
+		}
+		if a.Attendance != b.Attendance {
+			return a.Attendance > b.Attendance
+		}
+		return a.Student.Name < b.Student.Name
+	})
+	for i := range summaries {
+		summaries[i].Rank = i + 1
+		if i > 0 && summaries[i].Mean == summaries[i-1].Mean && summaries[i].Attendance == summaries[i-1].Attendance {
+			summaries[i].Rank = summaries[i-1].Rank
+		}
+	}
+
+	result := make([]disciplineStats, 0, len(disciplines))
+	for _, d := range disciplines {
+		ds := disciplineStats{Discipline: d, Filled: len(scores[d.ID]), Attendance: -1}
+		ds.Mean, ds.Median, ds.StdDev = summarize(scores[d.ID])
+		if len(attendance[d.ID]) > 0 {
+			ds.Attendance, _ = stats.Mean(attendance[d.ID])
+		}
+		for _, band := range resolveScale(scales, group, d).Bands {
+			gc := gradeCount{Band: band, Count: grades[d.ID][band.Label]}
+			if ds.Filled > 0 {
+				gc.Percent = int(math.Round(float64(gc.Count) / float64(ds.Filled) * 100))
+			}
+			ds.Grades = append(ds.Grades, gc)
+		}
+		result = append(result, ds)
+	}
+	return result, summaries, nil
+}
+
+// analyticsPage — сводка группы за выбранный период.
+func (s *Server) analyticsPage(w http.ResponseWriter, r *http.Request, group *models.Group) {
+	term, err := s.currentTerm(r)
+	if err != nil {
+		log.Printf("Ошибка получения периодов: %v", err)
+		http.Error(w, "Ошибка БД", http.StatusInternalServerError)
+		return
+	}
+	disciplines, students, err := s.groupAnalytics(group, term.ID())
+	if err != nil {
+		log.Printf("Ошибка аналитики группы %s: %v", group.Name, err)
+		http.Error(w, "Ошибка БД", http.StatusInternalServerError)
+		return
+	}
+	var atRisk []studentSummary
+	for _, st := range students {
+		if len(st.Reasons) > 0 {
+			atRisk = append(atRisk, st)
+		}
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	tmpl := `
+<!DOCTYPE html>
+<html>
+<head>
+	<title>Аналитика — {{.Group.Name}}</title>
+	<link rel="stylesheet" href="/static/style.css">
+</head>
+<body>
+	<div class="card wide-card">
+		{{template "userbar" .User}}
+		<h1>Аналитика группы {{.Group.Name}}</h1>
+		{{template "termbar" .Term}}
+		<p class="lesson-meta">Учитываются записи, в которых есть балл или занятия.</p>
+
+		<h2>Группа риска</h2>
+		{{if .AtRisk}}
+		<table>
+			<thead>
+				<tr><th>Студент</th><th>Средний балл</th><th>Посещаемость</th><th>Почему</th></tr>
+			</thead>
+			<tbody>
+			{{range .AtRisk}}
+			<tr class="risk-row">
+				<td><a href="/student/{{.Student.ID.Hex}}">{{.Student.Name}}</a></td>
+				<td>{{number .Mean}}</td>
+				<td>{{percent .Attendance}}</td>
+				<td>
+					{{range $i, $r := .Reasons}}{{if $i}}, {{end}}{{$r}}{{end}}
+					{{with .Weak}}<br><small>слабые дисциплины: {{range $i, $d := .}}{{if $i}}, {{end}}{{$d}}{{end}}</small>{{end}}
+				</td>
+			</tr>
+			{{end}}
+			</tbody>
+		</table>
+		{{else}}
+		<p>Студентов со средним баллом ниже {{.RiskScore}} или посещаемостью ниже {{.RiskAttendance}}% нет.</p>
+		{{end}}
+
+		<h2>Дисциплины</h2>
+		<table>
+			<thead>
+				<tr><th>Дисциплина</th><th>Записей</th><th>Средний балл</th><th>Медиана</th><th>Разброс</th><th>Посещаемость</th><th>Оценки</th></tr>
+			</thead>
+			<tbody>
+			{{range .Disciplines}}
+			<tr>
+				<td>{{.Discipline.Name}}</td>
+				<td>{{.Filled}}</td>
+				{{if .Filled}}
+				<td>{{number .Mean}}</td>
+				<td>{{number .Median}}</td>
+				<td>±{{number .StdDev}}</td>
+				{{else}}
+				<td>—</td><td>—</td><td>—</td>
+				{{end}}
+				<td>{{percent .Attendance}}</td>
+				<td class="grade-dist">
+					{{range .Grades}}
+					<div class="grade-dist-row" title="{{.Count}} студ.">
+						<span class="grade-cell {{.Band.Class}}">{{.Band.Label}}</span>
+						<span class="grade-dist-bar"><span class="{{.Band.Class}}" style="width: {{.Percent}}%"></span></span>
+						<small>{{.Count}}</small>
+					</div>
+					{{end}}
+				</td>
+			</tr>
+			{{end}}
+			</tbody>
+		</table>
+
+		<h2>Рейтинг студентов</h2>
+		<table>
+			<thead>
+				<tr><th>Место</th><th>Студент</th><th>Средний балл</th><th>Посещаемость</th></tr>
+			</thead>
+			<tbody>
+			{{range .Students}}
+			<tr>
+				<td>{{if ge .Mean 0.0}}{{.Rank}}{{else}}—{{end}}</td>
+				<td><a href="/student/{{.Student.ID.Hex}}">{{.Student.Name}}</a></td>
+				<td>{{number .Mean}}</td>
+				<td>{{percent .Attendance}}</td>
+			</tr>
+			{{end}}
+			</tbody>
+		</table>
+
+		<a href="{{groupURL .Group}}" class="back-link">← Назад к группе</a>
+	</div>
+</body>
+</html>`
+
+	funcs := template.FuncMap{
+		"groupURL": groupURL,
+		// number и percent показывают «—» для отрицательных значений:
+		// так помечено отсутствие данных
+		"number": func(v float64) string {
+			if v < 0 {
+				return "—"
+			}
+			return formatPoints(math.Round(v*10) / 10)
+		},
+		"percent": func(v float64) string {
+			if v < 0 {
+				return "—"
+			}
+			return formatPoints(math.Round(v)) + "%"
+		},
+	}
+
+	data := struct {
+		User           *models.User
+		Term           termState
+		Group          *models.Group
+		Disciplines    []disciplineStats
+		Students       []studentSummary
+		AtRisk         []studentSummary
+		RiskScore      int
+		RiskAttendance int
+	}{
+		User:           currentUser(r),
+		Term:           term,
+		Group:          group,
+		Disciplines:    disciplines,
+		Students:       students,
+		AtRisk:         atRisk,
+		RiskScore:      riskScore,
+		RiskAttendance: riskAttendance,
+	}
+
+	t := template.Must(template.New("analytics").Funcs(funcs).Parse(tmpl + userBarTmpl + termBarTmpl))
+	if err := t.Execute(w, data); err != nil {
+		log.Printf("Ошибка шаблона аналитики: %v", err)
+	}
+}
@@ -0,0 +1,288 @@
+// handlers/apiv1.go
+package handlers
+
+import (
+	"context"
+	"electronic-diary/models"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// API v1 — JSON API для мобильного приложения и интеграций.
+//
+// Успешный ответ — {"data": ...}; списки дополнительно несут
+// {"pagination": {"page", "perPage", "total"}}. Ошибка —
+// {"error": {"code", "message", "fields"}}, где fields — ошибки отдельных
+// полей при 422. Вход — та же cookie сессии, что и у страниц (POST /login).
+
+const (
+	defaultPerPage = 20
+	maxPerPage     = 100
+)
+
+type apiError struct {
+	Code    string            `json:"code"`
+	Message string            `json:"message"`
+	Fields  map[string]string `json:"fields,omitempty"`
+}
+
+type pagination struct {
+	Page    int `json:"page"`
+	PerPage int `json:"perPage"`
+	Total   int `json:"total"`
+}
+
+func apiData(w http.ResponseWriter, status int, v interface{}) {
+	writeJSON(w, status, map[string]interface{}{"data": v})
+}
+
+func apiFail(w http.ResponseWriter, status int, code, msg string) {
+	writeJSON(w, status, map[string]apiError{"error": {Code: code, Message: msg}})
+}
+
+// apiInvalid — 422 с ошибками по полям.
+func apiInvalid(w http.ResponseWriter, fields map[string]string) {
+	writeJSON(w, http.StatusUnprocessableEntity, map[string]apiError{"error": {
+		Code:    "validation_failed",
+		Message: "Проверьте поля запроса",
+		Fields:  fields,
+	}})
+}
+
+func apiNotFound(w http.ResponseWriter, msg string) {
+	apiFail(w, http.StatusNotFound, "not_found", msg)
+}
+
+func apiBadRequest(w http.ResponseWriter, err error) {
+	apiFail(w, http.StatusBadRequest, "bad_request", err.Error())
+}
+
+// apiStoreError — ошибка хранилища: ненайденная запись — 404, прочее — 500.
+func apiStoreError(w http.ResponseWriter, err error, notFoundMsg string) {
+	if isNotFound(err) {
+		apiNotFound(w, notFoundMsg)
+		return
+	}
+	log.Printf("Ошибка API: %v", err)
+	apiFail(w, http.StatusInternalServerError, "internal", "Ошибка БД")
+}
+
+func apiMethodNotAllowed(w http.ResponseWriter, allowed ...string) {
+	w.Header().Set("Allow", strings.Join(allowed, ", "))
+	apiFail(w, http.StatusMethodNotAllowed, "method_not_allowed", "Метод не поддерживается")
+}
+
+func apiForbidden(w http.ResponseWriter) {
+	apiFail(w, http.StatusForbidden, "forbidden", "Недостаточно прав")
+}
+
+// apiCreated отвечает 201 с адресом новой записи.
+func apiCreated(w http.ResponseWriter, location string, v interface{}) {
+	w.Header().Set("Location", location)
+	apiData(w, http.StatusCreated, v)
+}
+
+// decodeJSON читает тело запроса; неизвестные поля — ошибка,
+// чтобы опечатка в имени поля не терялась молча.
+func decodeJSON(r *http.Request, dst interface{}) error {
+	if !strings.HasPrefix(r.Header.Get("Content-Type"), "application/json") {
+		return errors.New("ожидается Content-Type: application/json")
+	}
+	dec := json.NewDecoder(r.Body)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(dst); err != nil {
+		if errors.Is(err, io.EOF) {
+			return errors.New("пустое тело запроса")
+		}
+		return fmt.Errorf("некорректный JSON: %v", err)
+	}
+	return nil
+}
+
+// nullableID — поле PATCH, которое задаётся id или сбрасывается null.
+// Set отличает «не передано» от «передан null».
+type nullableID struct {
+	Set bool
+	ID  *primitive.ObjectID
+}
+
+func (n *nullableID) UnmarshalJSON(b []byte) error {
+	n.Set = true
+	if string(b) == "null" {
+		n.ID = nil
+		return nil
+	}
+	var hex string
+	if err := json.Unmarshal(b, &hex); err != nil {
+		return err
+	}
+	if hex == "" {
+		n.ID = nil
+		return nil
+	}
+	id, err := parseObjectID(hex)
+	if err != nil {
+		return fmt.Errorf("некорректный id %q", hex)
+	}
+	n.ID = &id
+	return nil
+}
+
+// readPage разбирает ?page= и ?perPage=.
+func readPage(r *http.Request) (page, perPage int, fields map[string]string) {
+	page, perPage = 1, defaultPerPage
+	fields = map[string]string{}
+	q := r.URL.Query()
+	if v := q.Get("page"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			fields["page"] = "номер страницы — целое число от 1"
+		}
+		page = n
+	}
+	if v := q.Get("perPage"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > maxPerPage {
+			fields["perPage"] = fmt.Sprintf("размер страницы — от 1 до %d", maxPerPage)
+		}
+		perPage = n
+	}
+	return page, perPage, fields
+}
+
+// apiList отдаёт страницу списка items (срез любого типа).
+func apiList(w http.ResponseWriter, r *http.Request, items interface{}) {
+	page, perPage, fields := readPage(r)
+	if len(fields) > 0 {
+		apiInvalid(w, fields)
+		return
+	}
+
+	v := reflect.ValueOf(items)
+	total := v.Len()
+	// Номер страницы ограничиваем до умножения, иначе огромный page
+	// переполняет (page-1)*perPage
+	from := total
+	if page-1 <= total/perPage {
+		from = (page - 1) * perPage
+	}
+	to := from + perPage
+	if to > total {
+		to = total
+	}
+	var data interface{} = []struct{}{}
+	if from < to {
+		data = v.Slice(from, to).Interface()
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"data":       data,
+		"pagination": pagination{Page: page, PerPage: perPage, Total: total},
+	})
+}
+
+// APIv1Handler — маршрутизатор /api/v1/{ресурс}[/{id}]:
+//
+//	groups, students, disciplines, records — GET (список и запись), POST, PATCH, DELETE
+//	openapi.yaml, openapi.json — спецификация (см. openapi.go)
+//
+// Сессию проверяет сам, чтобы и 401 отдавался в формате API.
+func (s *Server) APIv1Handler(w http.ResponseWriter, r *http.Request) {
+	// Спецификация открыта без входа: по ней клиент узнаёт, как войти
+	if strings.HasPrefix(r.URL.Path, "/api/v1/openapi.") {
+		OpenAPIHandler(w, r)
+		return
+	}
+	user := s.sessionUser(r)
+	if user == nil {
+		apiFail(w, http.StatusUnauthorized, "unauthorized", "Требуется вход")
+		return
+	}
+	r = r.WithContext(context.WithValue(r.Context(), userKey{}, user))
+
+	parts := strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/v1"), "/"), "/")
+	if len(parts) > 2 {
+		apiFail(w, http.StatusNotFound, "not_found", "Ресурс не найден")
+		return
+	}
+	var id *primitive.ObjectID
+	if len(parts) == 2 {
+		parsed, err := parseObjectID(parts[1])
+		if err != nil {
+			apiFail(w, http.StatusNotFound, "not_found", "Некорректный id")
+			return
+		}
+		id = &parsed
+	}
+
+	resource, ok := s.v1Resources()[parts[0]]
+	if !ok {
+		apiFail(w, http.StatusNotFound, "not_found", "Ресурс не найден")
+		return
+	}
+	resource(w, r, id)
+}
+
+// v1Resources — ресурсы API v1. По этой таблице тест сверяет openapi.yaml,
+// поэтому новый ресурс сначала описывается в спецификации.
+func (s *Server) v1Resources() map[string]func(http.ResponseWriter, *http.Request, *primitive.ObjectID) {
+	return map[string]func(http.ResponseWriter, *http.Request, *primitive.ObjectID){
+		"groups":      s.v1Groups,
+		"students":    s.v1Students,
+		"disciplines": s.v1Disciplines,
+		"records":     s.v1Records,
+	}
+}
+
+// Методы коллекции и отдельной записи — одинаковые у всех ресурсов.
+var (
+	v1CollectionMethods = []string{http.MethodGet, http.MethodPost}
+	v1ItemMethods       = []string{http.MethodGet, http.MethodPatch, http.MethodDelete}
+)
+
+// v1Route раскладывает запрос по методам коллекции (id == nil) и записи.
+type v1Route struct {
+	list, create         http.HandlerFunc
+	get, update, destroy func(w http.ResponseWriter, r *http.Request, id primitive.ObjectID)
+}
+
+func (rt v1Route) serve(w http.ResponseWriter, r *http.Request, id *primitive.ObjectID) {
+	if id == nil {
+		switch r.Method {
+		case http.MethodGet:
+			rt.list(w, r)
+		case http.MethodPost:
+			rt.create(w, r)
+		default:
+			apiMethodNotAllowed(w, v1CollectionMethods...)
+		}
+		return
+	}
+	switch r.Method {
+	case http.MethodGet:
+		rt.get(w, r, *id)
+	case http.MethodPatch:
+		rt.update(w, r, *id)
+	case http.MethodDelete:
+		rt.destroy(w, r, *id)
+	default:
+		apiMethodNotAllowed(w, v1ItemMethods...)
+	}
+}
+
+// isAdmin и isStaff — проверки ролей для API.
+func isAdmin(r *http.Request) bool {
+	return currentUser(r).Role == models.RoleAdmin
+}
+
+func isStaff(r *http.Request) bool {
+	return currentUser(r).Role.Staff()
+}
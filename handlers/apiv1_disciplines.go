@@ -0,0 +1,168 @@
+// handlers/apiv1_disciplines.go
+package handlers
+
+import (
+	"electronic-diary/db"
+	"electronic-diary/models"
+	"net/http"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// v1Disciplines — /api/v1/disciplines. Читают сотрудники, меняет администратор.
+//
+//	GET    /api/v1/disciplines?group={id}[&retired=1] — дисциплины группы
+//	POST   /api/v1/disciplines                        — {"name", "groupId"}
+//	GET    /api/v1/disciplines/{id}
+//	PATCH  /api/v1/disciplines/{id}                   — {"name", "retired", "teacherId", "gradingScaleId"}
+//	DELETE /api/v1/disciplines/{id}                   — вывести из программы; записи сохраняются
+func (s *Server) v1Disciplines(w http.ResponseWriter, r *http.Request, id *primitive.ObjectID) {
+	if !isStaff(r) || (r.Method != http.MethodGet && !isAdmin(r)) {
+		apiForbidden(w)
+		return
+	}
+	v1Route{
+		list:    s.v1ListDisciplines,
+		create:  s.v1CreateDiscipline,
+		get:     s.v1GetDiscipline,
+		update:  s.v1UpdateDiscipline,
+		destroy: s.v1DeleteDiscipline,
+	}.serve(w, r, id)
+}
+
+func (s *Server) v1ListDisciplines(w http.ResponseWriter, r *http.Request) {
+	groupID, err := parseObjectID(r.URL.Query().Get("group"))
+	if err != nil {
+		apiInvalid(w, map[string]string{"group": "укажите id группы"})
+		return
+	}
+	var disciplines []models.Discipline
+	if r.URL.Query().Get("retired") == "1" {
+		disciplines, err = s.store.GetAllDisciplinesByGroupID(groupID)
+	} else {
+		disciplines, err = s.store.GetDisciplinesByGroupID(groupID)
+	}
+	if err != nil {
+		apiStoreError(w, err, "")
+		return
+	}
+	apiList(w, r, disciplines)
+}
+
+func (s *Server) v1CreateDiscipline(w http.ResponseWriter, r *http.Request) {
+	var in struct {
+		Name    string `json:"name"`
+		GroupID string `json:"groupId"`
+	}
+	if err := decodeJSON(r, &in); err != nil {
+		apiBadRequest(w, err)
+		return
+	}
+
+	fields := map[string]string{}
+	if strings.TrimSpace(in.Name) == "" {
+		fields["name"] = db.ErrEmptyName.Error()
+	}
+	groupID, err := parseObjectID(in.GroupID)
+	if err != nil {
+		fields["groupId"] = "укажите группу"
+	} else if _, err := s.store.GetGroupByID(groupID); err != nil {
+		fields["groupId"] = "группа не найдена"
+	}
+	if len(fields) > 0 {
+		apiInvalid(w, fields)
+		return
+	}
+
+	discipline, err := s.store.CreateDiscipline(groupID, in.Name)
+	if err != nil {
+		apiStoreError(w, err, "")
+		return
+	}
+	apiCreated(w, "/api/v1/disciplines/"+discipline.ID.Hex(), discipline)
+}
+
+func (s *Server) v1GetDiscipline(w http.ResponseWriter, r *http.Request, id primitive.ObjectID) {
+	discipline, err := s.store.GetDisciplineByID(id)
+	if err != nil {
+		apiStoreError(w, err, "Дисциплина не найдена")
+		return
+	}
+	apiData(w, http.StatusOK, discipline)
+}
+
+func (s *Server) v1UpdateDiscipline(w http.ResponseWriter, r *http.Request, id primitive.ObjectID) {
+	if _, err := s.store.GetDisciplineByID(id); err != nil {
+		apiStoreError(w, err, "Дисциплина не найдена")
+		return
+	}
+	var in struct {
+		Name           *string    `json:"name"`
+		Retired        *bool      `json:"retired"`
+		TeacherID      nullableID `json:"teacherId"`
+		GradingScaleID nullableID `json:"gradingScaleId"`
+	}
+	if err := decodeJSON(r, &in); err != nil {
+		apiBadRequest(w, err)
+		return
+	}
+
+	fields := map[string]string{}
+	if in.Name != nil && strings.TrimSpace(*in.Name) == "" {
+		fields["name"] = db.ErrEmptyName.Error()
+	}
+	if in.TeacherID.ID != nil {
+		teacher, err := s.store.GetUserByID(*in.TeacherID.ID)
+		if err != nil || teacher.Role != models.RoleTeacher {
+			fields["teacherId"] = "преподаватель не найден"
+		}
+	}
+	if in.GradingScaleID.ID != nil {
+		if _, err := s.store.GetGradingScaleByID(*in.GradingScaleID.ID); err != nil {
+			fields["gradingScaleId"] = "шкала не найдена"
+		}
+	}
+	if len(fields) > 0 {
+		apiInvalid(w, fields)
+		return
+	}
+
+	if in.Name != nil {
+		if err := s.store.RenameDiscipline(id, *in.Name); err != nil {
+			apiStoreError(w, err, "Дисциплина не найдена")
+			return
+		}
+	}
+	if in.Retired != nil {
+		if err := s.store.SetDisciplineRetired(id, *in.Retired); err != nil {
+			apiStoreError(w, err, "Дисциплина не найдена")
+			return
+		}
+	}
+	if in.TeacherID.Set {
+		if err := s.store.SetDisciplineTeacher(id, in.TeacherID.ID); err != nil {
+			apiStoreError(w, err, "Дисциплина не найдена")
+			return
+		}
+	}
+	if in.GradingScaleID.Set {
+		if err := s.store.SetDisciplineGradingScale(id, in.GradingScaleID.ID); err != nil {
+			apiStoreError(w, err, "Дисциплина не найдена")
+			return
+		}
+	}
+	s.v1GetDiscipline(w, r, id)
+}
+
+func (s *Server) v1DeleteDiscipline(w http.ResponseWriter, r *http.Request, id primitive.ObjectID) {
+	if _, err := s.store.GetDisciplineByID(id); err != nil {
+		apiStoreError(w, err, "Дисциплина не найдена")
+		return
+	}
+	if err := s.store.SetDisciplineRetired(id, true); err != nil {
+		apiStoreError(w, err, "Дисциплина не найдена")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
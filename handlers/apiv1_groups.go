@@ -0,0 +1,144 @@
+// handlers/apiv1_groups.go
+package handlers
+
+import (
+	"electronic-diary/db"
+	"errors"
+	"net/http"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// v1Groups — /api/v1/groups. Читают сотрудники, меняет администратор.
+//
+//	GET    /api/v1/groups[?archived=1] — список (с архивом по запросу)
+//	POST   /api/v1/groups              — {"name"}
+//	GET    /api/v1/groups/{id}
+//	PATCH  /api/v1/groups/{id}         — {"name", "archived", "gradingScaleId"}
+//	DELETE /api/v1/groups/{id}         — отправить в архив; данные сохраняются
+func (s *Server) v1Groups(w http.ResponseWriter, r *http.Request, id *primitive.ObjectID) {
+	if !isStaff(r) || (r.Method != http.MethodGet && !isAdmin(r)) {
+		apiForbidden(w)
+		return
+	}
+	v1Route{
+		list:    s.v1ListGroups,
+		create:  s.v1CreateGroup,
+		get:     s.v1GetGroup,
+		update:  s.v1UpdateGroup,
+		destroy: s.v1DeleteGroup,
+	}.serve(w, r, id)
+}
+
+func (s *Server) v1ListGroups(w http.ResponseWriter, r *http.Request) {
+	groups, err := s.store.GetGroups(r.URL.Query().Get("archived") == "1")
+	if err != nil {
+		apiStoreError(w, err, "")
+		return
+	}
+	apiList(w, r, groups)
+}
+
+func (s *Server) v1CreateGroup(w http.ResponseWriter, r *http.Request) {
+	var in struct {
+		Name string `json:"name"`
+	}
+	if err := decodeJSON(r, &in); err != nil {
+		apiBadRequest(w, err)
+		return
+	}
+	group, err := s.store.CreateGroup(in.Name)
+	if errors.Is(err, db.ErrEmptyName) {
+		apiInvalid(w, map[string]string{"name": err.Error()})
+		return
+	}
+	if errors.Is(err, db.ErrConflict) {
+		apiFail(w, http.StatusConflict, "conflict", groupSlugConflict)
+		return
+	}
+	if err != nil {
+		apiStoreError(w, err, "")
+		return
+	}
+	apiCreated(w, "/api/v1/groups/"+group.ID.Hex(), group)
+}
+
+func (s *Server) v1GetGroup(w http.ResponseWriter, r *http.Request, id primitive.ObjectID) {
+	group, err := s.store.GetGroupByID(id)
+	if err != nil {
+		apiStoreError(w, err, "Группа не найдена")
+		return
+	}
+	apiData(w, http.StatusOK, group)
+}
+
+func (s *Server) v1UpdateGroup(w http.ResponseWriter, r *http.Request, id primitive.ObjectID) {
+	group, err := s.store.GetGroupByID(id)
+	if err != nil {
+		apiStoreError(w, err, "Группа не найдена")
+		return
+	}
+	var in struct {
+		Name           *string    `json:"name"`
+		Archived       *bool      `json:"archived"`
+		GradingScaleID nullableID `json:"gradingScaleId"`
+	}
+	if err := decodeJSON(r, &in); err != nil {
+		apiBadRequest(w, err)
+		return
+	}
+
+	fields := map[string]string{}
+	if in.Name != nil && strings.TrimSpace(*in.Name) == "" {
+		fields["name"] = db.ErrEmptyName.Error()
+	}
+	if in.GradingScaleID.ID != nil {
+		if _, err := s.store.GetGradingScaleByID(*in.GradingScaleID.ID); err != nil {
+			fields["gradingScaleId"] = "шкала не найдена"
+		}
+	}
+	if len(fields) > 0 {
+		apiInvalid(w, fields)
+		return
+	}
+
+	if in.Name != nil {
+		group, err = s.store.RenameGroup(id, *in.Name)
+		if errors.Is(err, db.ErrConflict) {
+			apiFail(w, http.StatusConflict, "conflict", groupSlugConflict)
+			return
+		}
+		if err != nil {
+			apiStoreError(w, err, "Группа не найдена")
+			return
+		}
+	}
+	if in.Archived != nil {
+		if err := s.store.SetGroupArchived(id, *in.Archived); err != nil {
+			apiStoreError(w, err, "Группа не найдена")
+			return
+		}
+		group.Archived = *in.Archived
+	}
+	if in.GradingScaleID.Set {
+		if err := s.store.SetGroupGradingScale(id, in.GradingScaleID.ID); err != nil {
+			apiStoreError(w, err, "Группа не найдена")
+			return
+		}
+		group.GradingScaleID = in.GradingScaleID.ID
+	}
+	apiData(w, http.StatusOK, group)
+}
+
+func (s *Server) v1DeleteGroup(w http.ResponseWriter, r *http.Request, id primitive.ObjectID) {
+	if _, err := s.store.GetGroupByID(id); err != nil {
+		apiStoreError(w, err, "Группа не найдена")
+		return
+	}
+	if err := s.store.SetGroupArchived(id, true); err != nil {
+		apiStoreError(w, err, "Группа не найдена")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,361 @@
+// handlers/apiv1_records.go
+package handlers
+
+import (
+	"electronic-diary/db"
+	"electronic-diary/models"
+	"errors"
+	"net/http"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// v1Records — /api/v1/records, записи студента по дисциплине. Семья видит
+// записи своих студентов; менять можно только по дисциплинам, которые
+// ведёт пользователь (администратор — по любым). Записи относятся к
+// периоду; без term берётся текущий, записи закрытого периода не меняются.
+//
+//	GET    /api/v1/records?student={id} | ?discipline={id} [&term={id}]
+//	POST   /api/v1/records      — {"studentId", "disciplineId", "termId", "score", "totalClasses", "attendedClasses"}
+//	GET    /api/v1/records/{id}
+//	PATCH  /api/v1/records/{id} — {"score", "totalClasses", "attendedClasses", "version"}
+//	DELETE /api/v1/records/{id}
+func (s *Server) v1Records(w http.ResponseWriter, r *http.Request, id *primitive.ObjectID) {
+	if r.Method != http.MethodGet && !isStaff(r) {
+		apiForbidden(w)
+		return
+	}
+	v1Route{
+		list:    s.v1ListRecords,
+		create:  s.v1CreateRecord,
+		get:     s.v1GetRecord,
+		update:  s.v1UpdateRecord,
+		destroy: s.v1DeleteRecord,
+	}.serve(w, r, id)
+}
+
+// recordInput — счётчики записи; nil — поле не передано.
+type recordInput struct {
+	Score           *int `json:"score"`
+	TotalClasses    *int `json:"totalClasses"`
+	AttendedClasses *int `json:"attendedClasses"`
+}
+
+// validate проверяет итоговые значения записи и то, что выводимые поля
+// не задаются вручную: посещаемость ведёт журнал, баллы — работы.
+func (in recordInput) validate(data *models.StudentDisciplineData, journaled, graded bool) map[string]string {
+	fields := map[string]string{}
+	if journaled && (in.TotalClasses != nil || in.AttendedClasses != nil) {
+		fields["attendedClasses"] = "посещаемость считается по журналу"
+	}
+	if graded && in.Score != nil {
+		fields["score"] = "балл считается по работам"
+	}
+	if in.Score != nil {
+		data.Score = *in.Score
+	}
+	if in.TotalClasses != nil {
+		data.TotalClasses = *in.TotalClasses
+	}
+	if in.AttendedClasses != nil {
+		data.AttendedClasses = *in.AttendedClasses
+	}
+	for field, msg := range data.Validate() {
+		if _, ok := fields[field]; !ok {
+			fields[field] = msg
+		}
+	}
+	return fields
+}
+
+// recordDiscipline загружает дисциплину записи и проверяет право её менять.
+func (s *Server) recordDiscipline(w http.ResponseWriter, r *http.Request, id primitive.ObjectID) (*models.Discipline, bool) {
+	discipline, err := s.store.GetDisciplineByID(id)
+	if err != nil {
+		apiStoreError(w, err, "Дисциплина не найдена")
+		return nil, false
+	}
+	if r.Method != http.MethodGet && !canEdit(currentUser(r), discipline) {
+		apiFail(w, http.StatusForbidden, "forbidden", "Дисциплину «"+discipline.Name+"» ведёт другой преподаватель")
+		return nil, false
+	}
+	return discipline, true
+}
+
+// recordTerm загружает период записи и отказывает в изменении закрытого.
+func (s *Server) recordTerm(w http.ResponseWriter, r *http.Request, id primitive.ObjectID) bool {
+	term, err := s.store.GetTermByID(id)
+	if isNotFound(err) {
+		return true
+	}
+	if err != nil {
+		apiStoreError(w, err, "")
+		return false
+	}
+	if r.Method != http.MethodGet && term.Closed {
+		apiFail(w, http.StatusConflict, "term_closed", termClosedMsg)
+		return false
+	}
+	return true
+}
+
+// derivedFields сообщает, выводятся ли посещаемость и балл дисциплины
+// в периоде автоматически.
+func (s *Server) derivedFields(discipline *models.Discipline, termID primitive.ObjectID) (journaled, graded bool, err error) {
+	j, err := s.journaledDisciplines(discipline.GroupID, termID)
+	if err != nil {
+		return false, false, err
+	}
+	g, err := s.gradedDisciplines([]models.Discipline{*discipline}, termID)
+	if err != nil {
+		return false, false, err
+	}
+	return j[discipline.ID], g[discipline.ID], nil
+}
+
+func (s *Server) v1ListRecords(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	var records []models.StudentDisciplineData
+	term, err := s.currentTerm(r)
+	if err != nil {
+		apiStoreError(w, err, "")
+		return
+	}
+	if q.Get("term") != "" && q.Get("term") != term.ID().Hex() {
+		apiInvalid(w, map[string]string{"term": "период не найден"})
+		return
+	}
+
+	switch {
+	case q.Get("student") != "":
+		studentID, err := parseObjectID(q.Get("student"))
+		if err != nil {
+			apiInvalid(w, map[string]string{"student": "некорректный id студента"})
+			return
+		}
+		if !canSeeStudent(r, studentID) {
+			apiForbidden(w)
+			return
+		}
+		if records, err = s.store.GetStudentDisciplineData(studentID, term.ID()); err != nil {
+			apiStoreError(w, err, "")
+			return
+		}
+	case q.Get("discipline") != "":
+		disciplineID, err := parseObjectID(q.Get("discipline"))
+		if err != nil {
+			apiInvalid(w, map[string]string{"discipline": "некорректный id дисциплины"})
+			return
+		}
+		discipline, err := s.store.GetDisciplineByID(disciplineID)
+		if err != nil {
+			apiStoreError(w, err, "Дисциплина не найдена")
+			return
+		}
+		students, err := s.store.GetStudentsByGroupID(discipline.GroupID)
+		if err != nil {
+			apiStoreError(w, err, "")
+			return
+		}
+		for _, st := range students {
+			if !canSeeStudent(r, st.ID) {
+				continue
+			}
+			data, err := s.store.GetDisciplineDataFor(st.ID, discipline.ID, term.ID())
+			if isNotFound(err) {
+				continue
+			}
+			if err != nil {
+				apiStoreError(w, err, "")
+				return
+			}
+			records = append(records, *data)
+		}
+	default:
+		apiInvalid(w, map[string]string{"student": "укажите student или discipline"})
+		return
+	}
+	if records == nil {
+		records = []models.StudentDisciplineData{}
+	}
+	apiList(w, r, records)
+}
+
+func (s *Server) v1CreateRecord(w http.ResponseWriter, r *http.Request) {
+	var in struct {
+		StudentID    string `json:"studentId"`
+		DisciplineID string `json:"disciplineId"`
+		TermID       string `json:"termId"`
+		recordInput
+	}
+	if err := decodeJSON(r, &in); err != nil {
+		apiBadRequest(w, err)
+		return
+	}
+
+	fields := map[string]string{}
+	studentID, err := parseObjectID(in.StudentID)
+	if err != nil {
+		fields["studentId"] = "укажите студента"
+	}
+	disciplineID, err := parseObjectID(in.DisciplineID)
+	if err != nil {
+		fields["disciplineId"] = "укажите дисциплину"
+	}
+	var termID primitive.ObjectID
+	if in.TermID != "" {
+		if termID, err = parseObjectID(in.TermID); err != nil {
+			fields["termId"] = "некорректный id периода"
+		}
+	}
+	if len(fields) > 0 {
+		apiInvalid(w, fields)
+		return
+	}
+
+	student, err := s.store.GetStudentByID(studentID)
+	if err != nil {
+		if isNotFound(err) {
+			apiInvalid(w, map[string]string{"studentId": "студент не найден"})
+			return
+		}
+		apiStoreError(w, err, "")
+		return
+	}
+	discipline, err := s.store.GetDisciplineByID(disciplineID)
+	if err != nil || discipline.GroupID != student.GroupID {
+		if err == nil || isNotFound(err) {
+			apiInvalid(w, map[string]string{"disciplineId": "дисциплина не относится к группе студента"})
+			return
+		}
+		apiStoreError(w, err, "")
+		return
+	}
+	if _, ok := s.recordDiscipline(w, r, discipline.ID); !ok {
+		return
+	}
+	if termID.IsZero() {
+		term, err := s.currentTerm(r)
+		if err != nil {
+			apiStoreError(w, err, "")
+			return
+		}
+		termID = term.ID()
+	} else if _, err := s.store.GetTermByID(termID); err != nil {
+		if isNotFound(err) {
+			apiInvalid(w, map[string]string{"termId": "период не найден"})
+			return
+		}
+		apiStoreError(w, err, "")
+		return
+	}
+	if !s.recordTerm(w, r, termID) {
+		return
+	}
+	if _, err := s.store.GetDisciplineDataFor(studentID, disciplineID, termID); err == nil {
+		apiFail(w, http.StatusConflict, "conflict", "Запись уже есть — измените её через PATCH")
+		return
+	} else if !isNotFound(err) {
+		apiStoreError(w, err, "")
+		return
+	}
+
+	journaled, graded, err := s.derivedFields(discipline, termID)
+	if err != nil {
+		apiStoreError(w, err, "")
+		return
+	}
+	data := models.StudentDisciplineData{StudentID: studentID, DisciplineID: disciplineID, TermID: termID}
+	if fields := in.validate(&data, journaled, graded); len(fields) > 0 {
+		apiInvalid(w, fields)
+		return
+	}
+	err = s.store.InsertDisciplineData(&data, auditBy(r, recordChanges(models.StudentDisciplineData{}, data)))
+	if errors.Is(err, db.ErrConflict) {
+		apiFail(w, http.StatusConflict, "conflict", "Запись уже есть — измените её через PATCH")
+		return
+	}
+	if err != nil {
+		apiStoreError(w, err, "")
+		return
+	}
+	apiCreated(w, "/api/v1/records/"+data.ID.Hex(), data)
+}
+
+func (s *Server) v1GetRecord(w http.ResponseWriter, r *http.Request, id primitive.ObjectID) {
+	data, err := s.store.GetDisciplineDataByID(id)
+	if err != nil {
+		apiStoreError(w, err, "Запись не найдена")
+		return
+	}
+	if !canSeeStudent(r, data.StudentID) {
+		apiForbidden(w)
+		return
+	}
+	apiData(w, http.StatusOK, data)
+}
+
+func (s *Server) v1UpdateRecord(w http.ResponseWriter, r *http.Request, id primitive.ObjectID) {
+	data, err := s.store.GetDisciplineDataByID(id)
+	if err != nil {
+		apiStoreError(w, err, "Запись не найдена")
+		return
+	}
+	discipline, ok := s.recordDiscipline(w, r, data.DisciplineID)
+	if !ok || !s.recordTerm(w, r, data.TermID) {
+		return
+	}
+	var in struct {
+		recordInput
+		Version *int `json:"version"`
+	}
+	if err := decodeJSON(r, &in); err != nil {
+		apiBadRequest(w, err)
+		return
+	}
+	// Версию сверяет хранилище при записи: без version — с той, что
+	// прочитана выше
+	if in.Version != nil {
+		data.Version = *in.Version
+	}
+
+	journaled, graded, err := s.derivedFields(discipline, data.TermID)
+	if err != nil {
+		apiStoreError(w, err, "")
+		return
+	}
+	before := *data
+	if fields := in.validate(data, journaled, graded); len(fields) > 0 {
+		apiInvalid(w, fields)
+		return
+	}
+	rows := []models.StudentDisciplineData{*data}
+	err = s.store.SaveDisciplineData(rows, auditBy(r, recordChanges(before, *data)))
+	if errors.Is(err, db.ErrConflict) {
+		apiFail(w, http.StatusConflict, "conflict", "Запись изменил другой пользователь — загрузите её заново")
+		return
+	}
+	if err != nil {
+		apiStoreError(w, err, "Запись не найдена")
+		return
+	}
+	apiData(w, http.StatusOK, rows[0])
+}
+
+func (s *Server) v1DeleteRecord(w http.ResponseWriter, r *http.Request, id primitive.ObjectID) {
+	data, err := s.store.GetDisciplineDataByID(id)
+	if err != nil {
+		apiStoreError(w, err, "Запись не найдена")
+		return
+	}
+	if _, ok := s.recordDiscipline(w, r, data.DisciplineID); !ok || !s.recordTerm(w, r, data.TermID) {
+		return
+	}
+	deleted := *data
+	deleted.Score, deleted.TotalClasses, deleted.AttendedClasses = 0, 0, 0
+	if err := s.store.DeleteDisciplineData(id, auditBy(r, recordChanges(*data, deleted))); err != nil {
+		apiStoreError(w, err, "Запись не найдена")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,206 @@
+// handlers/apiv1_students.go
+package handlers
+
+import (
+	"electronic-diary/db"
+	"electronic-diary/models"
+	"errors"
+	"net/http"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// v1Students — /api/v1/students. Студент и родитель видят только связанных
+// с ними студентов; комментарий меняют сотрудники, остальное — администратор.
+//
+//	GET    /api/v1/students[?group={id}] — список (без group — все группы)
+//	POST   /api/v1/students              — {"name", "groupId"}
+//	GET    /api/v1/students/{id}
+//	PATCH  /api/v1/students/{id}         — {"name", "groupId", "comments", "version"}
+//	DELETE /api/v1/students/{id}         — удалить вместе с записями
+func (s *Server) v1Students(w http.ResponseWriter, r *http.Request, id *primitive.ObjectID) {
+	switch {
+	case r.Method == http.MethodGet:
+	case r.Method == http.MethodPatch && isStaff(r):
+	case !isAdmin(r):
+		apiForbidden(w)
+		return
+	}
+	v1Route{
+		list:    s.v1ListStudents,
+		create:  s.v1CreateStudent,
+		get:     s.v1GetStudent,
+		update:  s.v1UpdateStudent,
+		destroy: s.v1DeleteStudent,
+	}.serve(w, r, id)
+}
+
+// canSeeStudent — сотрудники видят всех, семья — только своих.
+func canSeeStudent(r *http.Request, studentID primitive.ObjectID) bool {
+	user := currentUser(r)
+	return user.Role.Staff() || user.LinkedTo(studentID)
+}
+
+func (s *Server) v1ListStudents(w http.ResponseWriter, r *http.Request) {
+	user := currentUser(r)
+	var students []models.Student
+
+	switch group := r.URL.Query().Get("group"); {
+	case !user.Role.Staff():
+		for _, id := range user.StudentIDs {
+			if st, err := s.store.GetStudentByID(id); err == nil {
+				students = append(students, *st)
+			}
+		}
+	case group != "":
+		groupID, err := parseObjectID(group)
+		if err != nil {
+			apiInvalid(w, map[string]string{"group": "некорректный id группы"})
+			return
+		}
+		if students, err = s.store.GetStudentsByGroupID(groupID); err != nil {
+			apiStoreError(w, err, "")
+			return
+		}
+	default:
+		list, _, err := s.studentsByGroup()
+		if err != nil {
+			apiStoreError(w, err, "")
+			return
+		}
+		for _, g := range list {
+			students = append(students, g.Students...)
+		}
+	}
+	apiList(w, r, students)
+}
+
+func (s *Server) v1CreateStudent(w http.ResponseWriter, r *http.Request) {
+	var in struct {
+		Name    string `json:"name"`
+		GroupID string `json:"groupId"`
+	}
+	if err := decodeJSON(r, &in); err != nil {
+		apiBadRequest(w, err)
+		return
+	}
+
+	fields := map[string]string{}
+	if strings.TrimSpace(in.Name) == "" {
+		fields["name"] = db.ErrEmptyName.Error()
+	}
+	groupID, err := parseObjectID(in.GroupID)
+	if err != nil {
+		fields["groupId"] = "укажите группу"
+	} else if _, err := s.store.GetGroupByID(groupID); err != nil {
+		fields["groupId"] = "группа не найдена"
+	}
+	if len(fields) > 0 {
+		apiInvalid(w, fields)
+		return
+	}
+
+	student, err := s.store.CreateStudent(in.Name, groupID)
+	if err != nil {
+		apiStoreError(w, err, "")
+		return
+	}
+	apiCreated(w, "/api/v1/students/"+student.ID.Hex(), student)
+}
+
+func (s *Server) v1GetStudent(w http.ResponseWriter, r *http.Request, id primitive.ObjectID) {
+	if !canSeeStudent(r, id) {
+		apiForbidden(w)
+		return
+	}
+	student, err := s.store.GetStudentByID(id)
+	if err != nil {
+		apiStoreError(w, err, "Студент не найден")
+		return
+	}
+	apiData(w, http.StatusOK, student)
+}
+
+func (s *Server) v1UpdateStudent(w http.ResponseWriter, r *http.Request, id primitive.ObjectID) {
+	student, err := s.store.GetStudentByID(id)
+	if err != nil {
+		apiStoreError(w, err, "Студент не найден")
+		return
+	}
+	var in struct {
+		Name     *string `json:"name"`
+		GroupID  *string `json:"groupId"`
+		Comments *string `json:"comments"`
+		Version  *int    `json:"version"`
+	}
+	if err := decodeJSON(r, &in); err != nil {
+		apiBadRequest(w, err)
+		return
+	}
+	if (in.Name != nil || in.GroupID != nil) && !isAdmin(r) {
+		apiForbidden(w)
+		return
+	}
+	fields := map[string]string{}
+	if in.Name != nil && strings.TrimSpace(*in.Name) == "" {
+		fields["name"] = db.ErrEmptyName.Error()
+	}
+	var groupID primitive.ObjectID
+	if in.GroupID != nil {
+		if groupID, err = parseObjectID(*in.GroupID); err != nil {
+			fields["groupId"] = "некорректный id группы"
+		} else if _, err := s.store.GetGroupByID(groupID); err != nil {
+			fields["groupId"] = "группа не найдена"
+		}
+	}
+	if len(fields) > 0 {
+		apiInvalid(w, fields)
+		return
+	}
+
+	// Версию сверяет хранилище в той же записи: без version — с той,
+	// что прочитана выше
+	version := student.Version
+	if in.Version != nil {
+		version = *in.Version
+	}
+	patch := db.StudentPatch{Name: in.Name, Comments: in.Comments}
+	if in.GroupID != nil && groupID != student.GroupID {
+		patch.GroupID = &groupID
+	}
+	if patch.Name != nil || patch.GroupID != nil || patch.Comments != nil {
+		var audit []models.AuditEntry
+		if in.Comments != nil {
+			audit = auditBy(r, commentChange(id, student.Comments, *in.Comments))
+		}
+		err := s.store.UpdateStudent(id, version, patch, audit)
+		if errors.Is(err, db.ErrConflict) {
+			apiFail(w, http.StatusConflict, "conflict", "Студента изменил другой пользователь — загрузите его заново")
+			return
+		}
+		if err != nil {
+			apiStoreError(w, err, "Студент не найден")
+			return
+		}
+	}
+
+	updated, err := s.store.GetStudentByID(id)
+	if err != nil {
+		apiStoreError(w, err, "Студент не найден")
+		return
+	}
+	apiData(w, http.StatusOK, updated)
+}
+
+func (s *Server) v1DeleteStudent(w http.ResponseWriter, r *http.Request, id primitive.ObjectID) {
+	if _, err := s.store.GetStudentByID(id); err != nil {
+		apiStoreError(w, err, "Студент не найден")
+		return
+	}
+	if err := s.store.DeleteStudent(id); err != nil {
+		apiStoreError(w, err, "Студент не найден")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
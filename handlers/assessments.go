@@ -0,0 +1,551 @@
+// handlers/assessments.go
+package handlers
+
+import (
+	"electronic-diary/models"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var assessmentFuncs = template.FuncMap{
+	"groupURL": groupURL,
+	"formatDate": func(t time.Time) string {
+		return t.Format("02.01.2006")
+	},
+	"points": formatPoints,
+}
+
+// formatPoints печатает баллы без лишних нулей: 7, 7.5.
+func formatPoints(p float64) string {
+	return strconv.FormatFloat(p, 'f', -1, 64)
+}
+
+// gradedDisciplines — дисциплины, у которых в периоде есть оцениваемые
+// работы. Их Score выводится из баллов и вручную не редактируется.
+func (s *Server) gradedDisciplines(disciplines []models.Discipline, termID primitive.ObjectID) (map[primitive.ObjectID]bool, error) {
+	graded := make(map[primitive.ObjectID]bool)
+	for _, d := range disciplines {
+		assessments, err := s.store.GetAssessmentsByDisciplineID(d.ID, termID)
+		if err != nil {
+			return nil, err
+		}
+		if len(assessments) > 0 {
+			graded[d.ID] = true
+		}
+	}
+	return graded, nil
+}
+
+// Страница дисциплины — работы и сводная ведомость баллов
+func (s *Server) DisciplineHandler(w http.ResponseWriter, r *http.Request) {
+	disciplineID, err := parseObjectID(strings.TrimPrefix(r.URL.Path, "/discipline/"))
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	discipline, err := s.store.GetDisciplineByID(disciplineID)
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	group, err := s.store.GetGroupByID(discipline.GroupID)
+	if err != nil {
+		http.Error(w, "Группа не найдена", http.StatusInternalServerError)
+		return
+	}
+	students, err := s.store.GetStudentsByGroupID(group.ID)
+	if err != nil {
+		http.Error(w, "Ошибка БД", http.StatusInternalServerError)
+		return
+	}
+	term, err := s.currentTerm(r)
+	if err != nil {
+		log.Printf("Ошибка получения периодов: %v", err)
+		http.Error(w, "Ошибка БД", http.StatusInternalServerError)
+		return
+	}
+	assessments, err := s.store.GetAssessmentsByDisciplineID(discipline.ID, term.ID())
+	if err != nil {
+		log.Printf("Ошибка получения работ: %v", err)
+		http.Error(w, "Ошибка БД", http.StatusInternalServerError)
+		return
+	}
+
+	// Ведомость: студент → работа → баллы
+	sheet := make(map[primitive.ObjectID]map[primitive.ObjectID]string, len(students))
+	for _, st := range students {
+		sheet[st.ID] = make(map[primitive.ObjectID]string)
+	}
+	for _, a := range assessments {
+		marks, err := s.store.GetMarksByAssessment(a.ID)
+		if err != nil {
+			http.Error(w, "Ошибка БД", http.StatusInternalServerError)
+			return
+		}
+		for _, m := range marks {
+			if row, ok := sheet[m.StudentID]; ok {
+				row[a.ID] = formatPoints(m.Points)
+			}
+		}
+	}
+	scores := make(map[primitive.ObjectID]int, len(students))
+	for _, st := range students {
+		if data, err := s.store.GetDisciplineDataFor(st.ID, discipline.ID, term.ID()); err == nil {
+			scores[st.ID] = data.Score
+		}
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	tmpl := `
+<!DOCTYPE html>
+<html>
+<head>
+	<title>{{.Discipline.Name}} — {{.Group.Name}}</title>
+	<link rel="stylesheet" href="/static/style.css">
+</head>
+<body>
+	<div class="card">
+		<h1>{{.Discipline.Name}}</h1>
+		<p class="lesson-meta">{{.Group.Name}} · итоговый балл считается по весам работ</p>
+		{{template "termbar" .Term}}
+
+		<table class="assessment-sheet">
+			<thead>
+				<tr>
+					<th>Студент</th>
+					{{range .Assessments}}
+					<th><a href="/assessment/{{.ID.Hex}}">{{.Name}}</a><br><small>{{formatDate .Date}} · max {{points .MaxPoints}} · вес {{points .Weight}}</small></th>
+					{{end}}
+					<th>Итого</th>
+				</tr>
+			</thead>
+			<tbody>
+			{{range .Students}}
+			{{$row := index $.Sheet .ID}}
+				<tr>
+					<td><a href="/student/{{.ID.Hex}}">{{.Name}}</a></td>
+					{{range $.Assessments}}
+					<td>{{with index $row .ID}}{{.}}{{else}}—{{end}}</td>
+					{{end}}
+					<td><strong>{{index $.Scores .ID}}</strong></td>
+				</tr>
+			{{end}}
+			</tbody>
+		</table>
+
+		{{if .CanEdit}}
+		<h2>Новая работа</h2>
+		<form action="/api/assessments" method="POST" class="inline-form">
+			<input type="hidden" name="disciplineId" value="{{.Discipline.ID.Hex}}">
+			<input type="hidden" name="term" value="{{.Term.ID.Hex}}">
+			<input type="text" name="name" placeholder="Лабораторная №1" required>
+			<input type="date" name="date" value="{{.Today}}" required>
+			<input type="number" name="maxPoints" placeholder="Макс. баллов" min="0.5" step="0.5" required title="Максимум баллов">
+			<input type="number" name="weight" value="1" min="0.1" step="0.1" required title="Вес работы">
+			<input type="submit" value="Добавить">
+		</form>
+		{{end}}
+
+		<a href="{{groupURL .Group}}" class="back-link">← Назад к группе</a>
+	</div>
+</body>
+</html>`
+
+	data := struct {
+		Term        termState
+		Group       *models.Group
+		Discipline  *models.Discipline
+		Students    []models.Student
+		Assessments []models.Assessment
+		Sheet       map[primitive.ObjectID]map[primitive.ObjectID]string
+		Scores      map[primitive.ObjectID]int
+		Today       string
+		CanEdit     bool
+	}{
+		Term:        term,
+		CanEdit:     canEdit(currentUser(r), discipline) && !term.Closed(),
+		Group:       group,
+		Discipline:  discipline,
+		Students:    students,
+		Assessments: assessments,
+		Sheet:       sheet,
+		Scores:      scores,
+		Today:       time.Now().Format(dateLayout),
+	}
+
+	t := template.Must(template.New("discipline").Funcs(assessmentFuncs).Parse(tmpl + termBarTmpl))
+	t.Execute(w, data)
+}
+
+// Страница работы — ввод баллов всей группы
+func (s *Server) AssessmentHandler(w http.ResponseWriter, r *http.Request) {
+	assessmentID, err := parseObjectID(strings.TrimPrefix(r.URL.Path, "/assessment/"))
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	assessment, err := s.store.GetAssessmentByID(assessmentID)
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	discipline, err := s.store.GetDisciplineByID(assessment.DisciplineID)
+	if err != nil {
+		http.Error(w, "Дисциплина не найдена", http.StatusInternalServerError)
+		return
+	}
+	students, err := s.store.GetStudentsByGroupID(assessment.GroupID)
+	if err != nil {
+		http.Error(w, "Ошибка БД", http.StatusInternalServerError)
+		return
+	}
+	marks, err := s.store.GetMarksByAssessment(assessment.ID)
+	if err != nil {
+		http.Error(w, "Ошибка БД", http.StatusInternalServerError)
+		return
+	}
+	points := make(map[primitive.ObjectID]string, len(marks))
+	for _, m := range marks {
+		points[m.StudentID] = formatPoints(m.Points)
+	}
+	term, err := s.store.GetTermByID(assessment.TermID)
+	if err != nil && !isNotFound(err) {
+		http.Error(w, "Ошибка БД", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	tmpl := `
+<!DOCTYPE html>
+<html>
+<head>
+	<title>{{.Assessment.Name}} — {{.Discipline.Name}}</title>
+	<link rel="stylesheet" href="/static/style.css">
+</head>
+<body>
+	<div class="card">
+		<h1>{{.Assessment.Name}}</h1>
+		<p class="lesson-meta">{{.Discipline.Name}} · {{formatDate .Assessment.Date}} · максимум {{points .Assessment.MaxPoints}} · вес {{points .Assessment.Weight}}</p>
+
+		{{if .Term.Closed}}<p class="archived-note">Период «{{.Term.Name}}» закрыт — баллы доступны только для просмотра.</p>
+		{{else if not .CanEdit}}<p class="archived-note">Дисциплину ведёт другой преподаватель — баллы доступны только для просмотра.</p>{{end}}
+		<form method="POST" action="/api/assessments/{{.Assessment.ID.Hex}}/marks">
+		<fieldset class="plain"{{if not .CanEdit}} disabled{{end}}>
+		<table>
+			<thead>
+				<tr>
+					<th>Студент</th>
+					<th>Баллы</th>
+				</tr>
+			</thead>
+			<tbody>
+			{{range .Students}}
+				<tr>
+					<td>{{.Name}}</td>
+					<td><input type="number" name="points_{{.ID.Hex}}" value="{{index $.Points .ID}}" min="0" max="{{points $.Assessment.MaxPoints}}" step="0.5" placeholder="—"></td>
+				</tr>
+			{{end}}
+			</tbody>
+		</table>
+		{{if .CanEdit}}<input type="submit" value="Сохранить баллы">{{end}}
+		</fieldset>
+		</form>
+
+		{{if .CanEdit}}
+		<form method="POST" action="/api/assessments/{{.Assessment.ID.Hex}}/delete" onsubmit="return confirm('Удалить работу вместе с баллами?')">
+			<button type="submit" class="small-btn danger">Удалить работу</button>
+		</form>
+		{{end}}
+
+		<a href="/discipline/{{.Discipline.ID.Hex}}" class="back-link">← Назад к дисциплине</a>
+	</div>
+</body>
+</html>`
+
+	data := struct {
+		Discipline *models.Discipline
+		Assessment *models.Assessment
+		Students   []models.Student
+		Points     map[primitive.ObjectID]string
+		Term       *models.Term
+		CanEdit    bool
+	}{
+		Term:       term,
+		CanEdit:    canEdit(currentUser(r), discipline) && !term.Closed,
+		Discipline: discipline,
+		Assessment: assessment,
+		Students:   students,
+		Points:     points,
+	}
+
+	t := template.Must(template.New("assessment").Funcs(assessmentFuncs).Parse(tmpl))
+	t.Execute(w, data)
+}
+
+// AssessmentsAPIHandler — оцениваемые работы:
+//
+//	GET  /api/assessments?discipline={id}&term={id} — работы дисциплины за период (по умолчанию текущий)
+//	POST /api/assessments                   — создать (disciplineId, name, date, maxPoints, weight, term)
+//	GET  /api/assessments/{id}              — работа с баллами
+//	POST /api/assessments/{id}/marks        — заменить баллы (points_{studentId} или {"marks": {...}})
+//	POST /api/assessments/{id}/delete       — удалить работу
+func (s *Server) AssessmentsAPIHandler(w http.ResponseWriter, r *http.Request) {
+	rest := strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/assessments"), "/")
+
+	if rest == "" {
+		switch r.Method {
+		case http.MethodGet:
+			s.listAssessments(w, r)
+		case http.MethodPost:
+			s.createAssessment(w, r)
+		default:
+			http.NotFound(w, r)
+		}
+		return
+	}
+
+	parts := strings.Split(rest, "/")
+	assessmentID, err := parseObjectID(parts[0])
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	assessment, err := s.store.GetAssessmentByID(assessmentID)
+	if isNotFound(err) {
+		respondError(w, r, http.StatusNotFound, "Работа не найдена")
+		return
+	}
+	if err != nil {
+		log.Printf("Ошибка получения работы: %v", err)
+		respondError(w, r, http.StatusInternalServerError, "Ошибка БД")
+		return
+	}
+	if r.Method == http.MethodPost {
+		discipline, err := s.store.GetDisciplineByID(assessment.DisciplineID)
+		if err != nil {
+			respondError(w, r, http.StatusInternalServerError, "Дисциплина не найдена")
+			return
+		}
+		if !requireTeacher(w, r, discipline) || !s.requireOpenTerm(w, r, assessment.TermID) {
+			return
+		}
+	}
+
+	switch {
+	case len(parts) == 1 && r.Method == http.MethodGet:
+		marks, err := s.store.GetMarksByAssessment(assessment.ID)
+		if err != nil {
+			respondError(w, r, http.StatusInternalServerError, "Ошибка БД")
+			return
+		}
+		if marks == nil {
+			marks = []models.AssessmentMark{}
+		}
+		writeJSON(w, http.StatusOK, map[string]interface{}{"assessment": assessment, "marks": marks})
+	case len(parts) == 2 && parts[1] == "marks" && r.Method == http.MethodPost:
+		s.saveAssessmentMarks(w, r, assessment)
+	case len(parts) == 2 && parts[1] == "delete" && r.Method == http.MethodPost:
+		before, err := s.store.GetMarksByAssessment(assessment.ID)
+		if err != nil {
+			respondError(w, r, http.StatusInternalServerError, "Ошибка БД")
+			return
+		}
+		if err := s.store.DeleteAssessment(assessment.ID, auditBy(r, pointsChanges(assessment, before, nil))); err != nil {
+			log.Printf("Ошибка удаления работы %s: %v", assessment.ID.Hex(), err)
+			respondError(w, r, http.StatusInternalServerError, "Ошибка БД")
+			return
+		}
+		respond(w, r, "/discipline/"+assessment.DisciplineID.Hex(), map[string]string{"status": "deleted"})
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (s *Server) listAssessments(w http.ResponseWriter, r *http.Request) {
+	disciplineID, err := parseObjectID(r.URL.Query().Get("discipline"))
+	if err != nil {
+		respondError(w, r, http.StatusBadRequest, "Укажите дисциплину: ?discipline={id}")
+		return
+	}
+	term, err := s.currentTerm(r)
+	if err != nil {
+		log.Printf("Ошибка получения периодов: %v", err)
+		respondError(w, r, http.StatusInternalServerError, "Ошибка БД")
+		return
+	}
+	assessments, err := s.store.GetAssessmentsByDisciplineID(disciplineID, term.ID())
+	if err != nil {
+		log.Printf("Ошибка получения работ: %v", err)
+		respondError(w, r, http.StatusInternalServerError, "Ошибка БД")
+		return
+	}
+	if assessments == nil {
+		assessments = []models.Assessment{}
+	}
+	writeJSON(w, http.StatusOK, assessments)
+}
+
+func (s *Server) createAssessment(w http.ResponseWriter, r *http.Request) {
+	var in struct {
+		DisciplineID string  `json:"disciplineId"`
+		Name         string  `json:"name"`
+		Date         string  `json:"date"`
+		MaxPoints    float64 `json:"maxPoints"`
+		Weight       float64 `json:"weight"`
+	}
+	if err := readInput(r, &in); err != nil {
+		respondError(w, r, http.StatusBadRequest, "Некорректные данные")
+		return
+	}
+	disciplineID, err := parseObjectID(in.DisciplineID)
+	if err != nil {
+		respondError(w, r, http.StatusBadRequest, "Некорректная дисциплина")
+		return
+	}
+	discipline, err := s.store.GetDisciplineByID(disciplineID)
+	if err != nil {
+		respondError(w, r, http.StatusBadRequest, "Дисциплина не найдена")
+		return
+	}
+	if !requireTeacher(w, r, discipline) {
+		return
+	}
+	name := strings.TrimSpace(in.Name)
+	if name == "" {
+		respondError(w, r, http.StatusBadRequest, "Название не может быть пустым")
+		return
+	}
+	date, err := time.Parse(dateLayout, in.Date)
+	if err != nil {
+		respondError(w, r, http.StatusBadRequest, "Дата должна быть в формате ГГГГ-ММ-ДД")
+		return
+	}
+	// NaN и бесконечность проходят любые сравнения, поэтому отсекаются отдельно
+	if math.IsNaN(in.MaxPoints) || math.IsInf(in.MaxPoints, 0) {
+		respondFieldError(w, r, "maxPoints", "Максимум баллов должен быть числом")
+		return
+	}
+	if math.IsNaN(in.Weight) || math.IsInf(in.Weight, 0) {
+		respondFieldError(w, r, "weight", "Вес должен быть числом")
+		return
+	}
+	if in.MaxPoints <= 0 {
+		respondFieldError(w, r, "maxPoints", "Максимум баллов должен быть больше нуля")
+		return
+	}
+	if in.Weight == 0 {
+		in.Weight = 1
+	}
+	if in.Weight < 0 {
+		respondFieldError(w, r, "weight", "Вес не может быть отрицательным")
+		return
+	}
+	term, err := s.currentTerm(r)
+	if err != nil {
+		log.Printf("Ошибка получения периодов: %v", err)
+		respondError(w, r, http.StatusInternalServerError, "Ошибка БД")
+		return
+	}
+	if term.Closed() {
+		respondError(w, r, http.StatusConflict, termClosedMsg)
+		return
+	}
+
+	assessment := models.Assessment{
+		GroupID:      discipline.GroupID,
+		DisciplineID: discipline.ID,
+		TermID:       term.ID(),
+		Name:         name,
+		Date:         date,
+		MaxPoints:    in.MaxPoints,
+		Weight:       in.Weight,
+	}
+	if err := s.store.CreateAssessment(&assessment); err != nil {
+		log.Printf("Ошибка создания работы: %v", err)
+		respondError(w, r, http.StatusInternalServerError, "Ошибка БД")
+		return
+	}
+	respond(w, r, "/assessment/"+assessment.ID.Hex(), assessment)
+}
+
+func (s *Server) saveAssessmentMarks(w http.ResponseWriter, r *http.Request, assessment *models.Assessment) {
+	raw := make(map[string]float64)
+	// field — имя поля баллов студента в ответе об ошибке: в форме
+	// points_{id}, в JSON marks.{id}
+	field := func(idHex string) string { return "points_" + idHex }
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "application/json") {
+		field = func(idHex string) string { return "marks." + idHex }
+		var in struct {
+			Marks map[string]float64 `json:"marks"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+			respondError(w, r, http.StatusBadRequest, "Некорректные данные")
+			return
+		}
+		raw = in.Marks
+	} else {
+		r.ParseForm()
+		for key := range r.Form {
+			value := strings.TrimSpace(r.FormValue(key))
+			if !strings.HasPrefix(key, "points_") || value == "" {
+				continue
+			}
+			p, err := strconv.ParseFloat(strings.Replace(value, ",", ".", 1), 64)
+			if err != nil {
+				respondFieldError(w, r, key, "Некорректные баллы: "+value)
+				return
+			}
+			if math.IsNaN(p) || math.IsInf(p, 0) {
+				respondFieldError(w, r, key, "Баллы должны быть числом: "+value)
+				return
+			}
+			raw[strings.TrimPrefix(key, "points_")] = p
+		}
+	}
+
+	students, err := s.store.GetStudentsByGroupID(assessment.GroupID)
+	if err != nil {
+		respondError(w, r, http.StatusInternalServerError, "Ошибка БД")
+		return
+	}
+	inGroup := make(map[primitive.ObjectID]bool, len(students))
+	for _, st := range students {
+		inGroup[st.ID] = true
+	}
+
+	points := make(map[primitive.ObjectID]float64, len(raw))
+	for idHex, p := range raw {
+		studentID, err := parseObjectID(idHex)
+		if err != nil || !inGroup[studentID] {
+			respondError(w, r, http.StatusBadRequest, "Студент "+idHex+" не состоит в группе")
+			return
+		}
+		if p < 0 || p > assessment.MaxPoints {
+			respondFieldError(w, r, field(idHex),
+				fmt.Sprintf("Баллы должны быть от 0 до %s", formatPoints(assessment.MaxPoints)))
+			return
+		}
+		points[studentID] = p
+	}
+
+	before, err := s.store.GetMarksByAssessment(assessment.ID)
+	if err != nil {
+		respondError(w, r, http.StatusInternalServerError, "Ошибка БД")
+		return
+	}
+	if err := s.store.SaveAssessmentMarks(assessment.ID, points, auditBy(r, pointsChanges(assessment, before, points))); err != nil {
+		log.Printf("Ошибка сохранения баллов работы %s: %v", assessment.ID.Hex(), err)
+		respondError(w, r, http.StatusInternalServerError, "Ошибка БД")
+		return
+	}
+	respond(w, r, "/assessment/"+assessment.ID.Hex(), map[string]string{"status": "ok"})
+}
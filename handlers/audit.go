@@ -0,0 +1,477 @@
+// handlers/audit.go
+package handlers
+
+import (
+	"electronic-diary/models"
+	"fmt"
+	"html/template"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// auditFields — поля журнала изменений и их подписи на странице.
+var auditFields = []struct{ Key, Title string }{
+	{"comments", "Комментарий"},
+	{"score", "Баллы"},
+	{"totalClasses", "Всего пар"},
+	{"attendedClasses", "Посетил"},
+	{"attendance", "Отметка на занятии"},
+	{"points", "Баллы за работу"},
+	{"reset", "Сброс данных"},
+	{"restore", "Отмена сброса"},
+}
+
+// auditLimit — сколько записей журнала показывать, если не указано иное.
+const auditLimit = 200
+
+// auditBy подписывает записи журнала текущим пользователем и временем.
+// Журнал готовится до сохранения: хранилище пишет его вместе с самим
+// изменением, и если журнал записать не удалось, не сохраняется ничего.
+func auditBy(r *http.Request, entries []models.AuditEntry) []models.AuditEntry {
+	user := currentUser(r)
+	now := time.Now()
+	for i := range entries {
+		entries[i].At = now
+		if user != nil {
+			entries[i].ActorID = user.ID
+			entries[i].ActorName = actorName(user)
+		}
+	}
+	return entries
+}
+
+// actorName — имя пользователя для журнала; без имени — логин.
+func actorName(user *models.User) string {
+	if user.Name == "" {
+		return user.Login
+	}
+	return user.Name
+}
+
+// recordChanges — изменённые поля записи по дисциплине, по записи журнала на поле.
+func recordChanges(before, after models.StudentDisciplineData) []models.AuditEntry {
+	studentID, disciplineID := after.StudentID, after.DisciplineID
+	entry := func(field string, old, new int) models.AuditEntry {
+		return models.AuditEntry{
+			Entity:       models.AuditRecord,
+			EntityID:     after.ID,
+			StudentID:    &studentID,
+			DisciplineID: &disciplineID,
+			Field:        field,
+			OldValue:     strconv.Itoa(old),
+			NewValue:     strconv.Itoa(new),
+		}
+	}
+	var entries []models.AuditEntry
+	if before.Score != after.Score {
+		entries = append(entries, entry("score", before.Score, after.Score))
+	}
+	if before.TotalClasses != after.TotalClasses {
+		entries = append(entries, entry("totalClasses", before.TotalClasses, after.TotalClasses))
+	}
+	if before.AttendedClasses != after.AttendedClasses {
+		entries = append(entries, entry("attendedClasses", before.AttendedClasses, after.AttendedClasses))
+	}
+	return entries
+}
+
+// commentChange — запись журнала об изменении комментария студента.
+func commentChange(studentID primitive.ObjectID, old, new string) []models.AuditEntry {
+	if old == new {
+		return nil
+	}
+	return []models.AuditEntry{{
+		Entity:    models.AuditStudent,
+		EntityID:  studentID,
+		StudentID: &studentID,
+		Field:     "comments",
+		OldValue:  old,
+		NewValue:  new,
+	}}
+}
+
+// attendanceChanges — изменённые отметки занятия, по записи журнала на
+// студента. Значения — «дата: отметка», чтобы запись была понятна без
+// страницы занятия. Пустая отметка в after — удалённая.
+func attendanceChanges(lesson *models.Lesson, before []models.AttendanceMark, after map[primitive.ObjectID]models.AttendanceStatus) []models.AuditEntry {
+	old := make(map[primitive.ObjectID]models.AttendanceStatus, len(before))
+	for _, m := range before {
+		old[m.StudentID] = m.Status
+	}
+	value := func(status models.AttendanceStatus) string {
+		if status == "" {
+			return "—"
+		}
+		return lesson.Date.Format("02.01.2006") + ": " + attendanceLabels[status]
+	}
+	ids := make([]primitive.ObjectID, 0, len(after))
+	for id := range after {
+		ids = append(ids, id)
+	}
+	sortIDs(ids)
+	var entries []models.AuditEntry
+	for _, studentID := range ids {
+		if old[studentID] == after[studentID] {
+			continue
+		}
+		studentID, disciplineID := studentID, lesson.DisciplineID
+		entries = append(entries, models.AuditEntry{
+			Entity:       models.AuditLesson,
+			EntityID:     lesson.ID,
+			StudentID:    &studentID,
+			DisciplineID: &disciplineID,
+			Field:        "attendance",
+			OldValue:     value(old[studentID]),
+			NewValue:     value(after[studentID]),
+		})
+	}
+	return entries
+}
+
+// pointsChanges — изменённые баллы за работу, по записи журнала на
+// студента. SaveAssessmentMarks заменяет все баллы работы, поэтому
+// пропавший из after балл — удалённый.
+func pointsChanges(assessment *models.Assessment, before []models.AssessmentMark, after map[primitive.ObjectID]float64) []models.AuditEntry {
+	old := make(map[primitive.ObjectID]float64, len(before))
+	for _, m := range before {
+		old[m.StudentID] = m.Points
+	}
+	value := func(points map[primitive.ObjectID]float64, id primitive.ObjectID) string {
+		p, ok := points[id]
+		if !ok {
+			return "—"
+		}
+		return assessment.Name + ": " + formatPoints(p)
+	}
+	ids := make([]primitive.ObjectID, 0, len(old)+len(after))
+	for id := range old {
+		ids = append(ids, id)
+	}
+	for id := range after {
+		if _, ok := old[id]; !ok {
+			ids = append(ids, id)
+		}
+	}
+	sortIDs(ids)
+	var entries []models.AuditEntry
+	for _, studentID := range ids {
+		oldValue, newValue := value(old, studentID), value(after, studentID)
+		if oldValue == newValue {
+			continue
+		}
+		studentID, disciplineID := studentID, assessment.DisciplineID
+		entries = append(entries, models.AuditEntry{
+			Entity:       models.AuditWork,
+			EntityID:     assessment.ID,
+			StudentID:    &studentID,
+			DisciplineID: &disciplineID,
+			Field:        "points",
+			OldValue:     oldValue,
+			NewValue:     newValue,
+		})
+	}
+	return entries
+}
+
+// sortIDs упорядочивает id, чтобы записи журнала шли в одном порядке.
+func sortIDs(ids []primitive.ObjectID) {
+	sort.Slice(ids, func(i, j int) bool { return ids[i].Hex() < ids[j].Hex() })
+}
+
+// clearedAttendance — отметки занятия после его удаления: у каждого, кто
+// был отмечен, отметка пропадает.
+func clearedAttendance(before []models.AttendanceMark) map[primitive.ObjectID]models.AttendanceStatus {
+	cleared := make(map[primitive.ObjectID]models.AttendanceStatus, len(before))
+	for _, m := range before {
+		cleared[m.StudentID] = ""
+	}
+	return cleared
+}
+
+// sheetChanges — чем сохраняемая форма студента отличается от
+// прочитанного перед сохранением. ID новых записей проставит хранилище.
+func sheetChanges(student *models.Student, comments string, before, after []models.StudentDisciplineData) []models.AuditEntry {
+	entries := commentChange(student.ID, student.Comments, comments)
+	for i := range after {
+		entries = append(entries, recordChanges(before[i], after[i])...)
+	}
+	return entries
+}
+
+// resetChanges — журнал сброса данных: каждое обнулённое поле и итоговая
+// запись об удалении отметок журнала и баллов за работы.
+func resetChanges(snap *models.ResetSnapshot) []models.AuditEntry {
+	var entries []models.AuditEntry
+	for _, c := range snap.Comments {
+		entries = append(entries, commentChange(c.StudentID, c.Comments, "")...)
+	}
+	for _, d := range snap.Records {
+		cleared := d
+		cleared.Score, cleared.TotalClasses, cleared.AttendedClasses = 0, 0, 0
+		entries = append(entries, recordChanges(d, cleared)...)
+	}
+	entries = append(entries, models.AuditEntry{
+		Entity:       models.AuditDiary,
+		EntityID:     snap.ID,
+		DisciplineID: snap.Scope.DisciplineID,
+		Field:        "reset",
+		OldValue:     snap.ScopeName,
+		NewValue: fmt.Sprintf("обнулено комментариев: %d, записей: %d; удалено отметок посещаемости: %d, баллов за работы: %d",
+			len(snap.Comments), len(snap.Records), len(snap.Attendance), len(snap.Marks)),
+	})
+	return entries
+}
+
+// sheetState — текущие комментарии и записи студентов из снимка сброса,
+// которые вернёт восстановление: удалённые с тех пор и записи закрытых
+// периодов пропускаются.
+func (s *Server) sheetState(snap *models.ResetSnapshot) (map[primitive.ObjectID]string, map[primitive.ObjectID]models.StudentDisciplineData) {
+	comments := make(map[primitive.ObjectID]string, len(snap.Comments))
+	for _, c := range snap.Comments {
+		if st, err := s.store.GetStudentByID(c.StudentID); err == nil {
+			comments[st.ID] = st.Comments
+		}
+	}
+	closed := make(map[primitive.ObjectID]bool)
+	records := make(map[primitive.ObjectID]models.StudentDisciplineData, len(snap.Records))
+	for _, d := range snap.Records {
+		cur, err := s.store.GetDisciplineDataByID(d.ID)
+		if err != nil {
+			continue
+		}
+		frozen, seen := closed[cur.TermID]
+		if !seen {
+			term, err := s.store.GetTermByID(cur.TermID)
+			frozen = err == nil && term.Closed
+			closed[cur.TermID] = frozen
+		}
+		if !frozen {
+			records[cur.ID] = *cur
+		}
+	}
+	return comments, records
+}
+
+// restoreChanges — что изменит восстановление снимка: before — текущее
+// состояние, полученное sheetState, после — значения снимка. Поля,
+// выводимые из журнала занятий и баллов за работы, записываются по
+// снимку, без последующего пересчёта.
+func restoreChanges(snap *models.ResetSnapshot, beforeComments map[primitive.ObjectID]string, beforeRecords map[primitive.ObjectID]models.StudentDisciplineData) []models.AuditEntry {
+	var entries []models.AuditEntry
+	for _, c := range snap.Comments {
+		if old, ok := beforeComments[c.StudentID]; ok {
+			entries = append(entries, commentChange(c.StudentID, old, c.Comments)...)
+		}
+	}
+	for _, d := range snap.Records {
+		old, ok := beforeRecords[d.ID]
+		if !ok {
+			continue
+		}
+		restored := old
+		restored.Score, restored.TotalClasses, restored.AttendedClasses = d.Score, d.TotalClasses, d.AttendedClasses
+		entries = append(entries, recordChanges(old, restored)...)
+	}
+	entries = append(entries, models.AuditEntry{
+		Entity:       models.AuditDiary,
+		EntityID:     snap.ID,
+		DisciplineID: snap.Scope.DisciplineID,
+		Field:        "restore",
+		OldValue:     snap.ScopeName,
+		NewValue:     "восстановлен сброс от " + snap.At.Local().Format("02.01.2006 15:04"),
+	})
+	return entries
+}
+
+// auditFilter разбирает фильтр журнала из параметров запроса; даты —
+// дни в формате 2006-01-02, «по» включительно.
+func auditFilter(r *http.Request) models.AuditFilter {
+	q := r.URL.Query()
+	filter := models.AuditFilter{Field: q.Get("field"), Limit: auditLimit}
+	optID := func(name string) *primitive.ObjectID {
+		if id, err := parseObjectID(q.Get(name)); err == nil {
+			return &id
+		}
+		return nil
+	}
+	filter.ActorID = optID("actor")
+	filter.StudentID = optID("student")
+	filter.DisciplineID = optID("discipline")
+	if from, err := time.ParseInLocation("2006-01-02", q.Get("from"), time.Local); err == nil {
+		filter.From = from
+	}
+	if to, err := time.ParseInLocation("2006-01-02", q.Get("to"), time.Local); err == nil {
+		filter.To = to.AddDate(0, 0, 1)
+	}
+	if n, err := strconv.Atoi(q.Get("limit")); err == nil && n > 0 {
+		filter.Limit = min(n, 1000)
+	}
+	return filter
+}
+
+// Страница журнала изменений
+func (s *Server) AuditHandler(w http.ResponseWriter, r *http.Request) {
+	filter := auditFilter(r)
+	entries, err := s.store.GetAudit(filter)
+	if err != nil {
+		log.Printf("Ошибка чтения журнала изменений: %v", err)
+		http.Error(w, "Ошибка БД", http.StatusInternalServerError)
+		return
+	}
+	if wantsJSON(r) {
+		if entries == nil {
+			entries = []models.AuditEntry{}
+		}
+		writeJSON(w, http.StatusOK, entries)
+		return
+	}
+
+	users, err := s.store.GetUsers()
+	if err != nil {
+		log.Printf("Ошибка получения пользователей: %v", err)
+		http.Error(w, "Ошибка БД", http.StatusInternalServerError)
+		return
+	}
+	groups, names, err := s.studentsByGroup()
+	if err != nil {
+		log.Printf("Ошибка получения студентов: %v", err)
+		http.Error(w, "Ошибка БД", http.StatusInternalServerError)
+		return
+	}
+	type groupDisciplines struct {
+		Group       models.Group
+		Disciplines []models.Discipline
+	}
+	var disciplines []groupDisciplines
+	disciplineNames := make(map[primitive.ObjectID]string)
+	for _, g := range groups {
+		list, err := s.store.GetAllDisciplinesByGroupID(g.Group.ID)
+		if err != nil {
+			log.Printf("Ошибка получения дисциплин: %v", err)
+			http.Error(w, "Ошибка БД", http.StatusInternalServerError)
+			return
+		}
+		for _, d := range list {
+			disciplineNames[d.ID] = d.Name
+		}
+		disciplines = append(disciplines, groupDisciplines{Group: g.Group, Disciplines: list})
+	}
+	fieldTitles := make(map[string]string, len(auditFields))
+	for _, f := range auditFields {
+		fieldTitles[f.Key] = f.Title
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	tmpl := `
+<!DOCTYPE html>
+<html>
+<head>
+	<title>Журнал изменений</title>
+	<link rel="stylesheet" href="/static/style.css">
+</head>
+<body>
+	<div class="card">
+		{{template "userbar" .User}}
+		<h1>Журнал изменений</h1>
+
+		<form method="GET" action="/audit" class="audit-filter">
+			<select name="actor">
+				<option value="">Все пользователи</option>
+				{{range .Users}}<option value="{{.ID.Hex}}"{{if eq $.Query.actor .ID.Hex}} selected{{end}}>{{or .Name .Login}}</option>{{end}}
+			</select>
+			<select name="student">
+				<option value="">Все студенты</option>
+				{{range .Groups}}
+				<optgroup label="{{.Group.Name}}">
+				{{range .Students}}<option value="{{.ID.Hex}}"{{if eq $.Query.student .ID.Hex}} selected{{end}}>{{.Name}}</option>{{end}}
+				</optgroup>
+				{{end}}
+			</select>
+			<select name="discipline">
+				<option value="">Все дисциплины</option>
+				{{range .Disciplines}}
+				<optgroup label="{{.Group.Name}}">
+				{{range .Disciplines}}<option value="{{.ID.Hex}}"{{if eq $.Query.discipline .ID.Hex}} selected{{end}}>{{.Name}}</option>{{end}}
+				</optgroup>
+				{{end}}
+			</select>
+			<select name="field">
+				<option value="">Все поля</option>
+				{{range .Fields}}<option value="{{.Key}}"{{if eq $.Query.field .Key}} selected{{end}}>{{.Title}}</option>{{end}}
+			</select>
+			<label>с <input type="date" name="from" value="{{.Query.from}}"></label>
+			<label>по <input type="date" name="to" value="{{.Query.to}}"></label>
+			<input type="submit" value="Показать">
+			<a href="/audit">Сбросить</a>
+		</form>
+
+		{{if .Entries}}
+		<table class="audit-table">
+			<thead>
+				<tr><th>Время</th><th>Кто</th><th>Студент</th><th>Дисциплина</th><th>Поле</th><th>Было</th><th>Стало</th></tr>
+			</thead>
+			<tbody>
+			{{range .Entries}}
+			<tr>
+				<td>{{.At.Local.Format "02.01.2006 15:04:05"}}</td>
+				<td>{{.ActorName}}</td>
+				<td>{{with .StudentID}}{{or (studentName .) "удалён"}}{{else}}—{{end}}</td>
+				<td>{{with .DisciplineID}}{{or (disciplineName .) "удалена"}}{{else}}—{{end}}</td>
+				<td>{{or (index $.FieldTitles .Field) .Field}}</td>
+				<td class="audit-old">{{.OldValue}}</td>
+				<td class="audit-new">{{.NewValue}}</td>
+			</tr>
+			{{end}}
+			</tbody>
+		</table>
+		{{if eq (len .Entries) .Limit}}<p class="hint">Показаны последние {{.Limit}} изменений — уточните фильтр, чтобы увидеть более ранние.</p>{{end}}
+		{{else}}
+		<p>Изменений не найдено.</p>
+		{{end}}
+
+		<a href="/" class="back-link">← Назад</a>
+	</div>
+</body>
+</html>`
+
+	query := make(map[string]string)
+	for key := range r.URL.Query() {
+		query[key] = strings.TrimSpace(r.URL.Query().Get(key))
+	}
+	data := struct {
+		User        *models.User
+		Query       map[string]string
+		Users       []models.User
+		Groups      []groupStudents
+		Disciplines []groupDisciplines
+		Fields      []struct{ Key, Title string }
+		Entries     []models.AuditEntry
+		Limit       int
+		FieldTitles map[string]string
+	}{
+		User:        currentUser(r),
+		Query:       query,
+		Users:       users,
+		Groups:      groups,
+		Disciplines: disciplines,
+		Fields:      auditFields,
+		Entries:     entries,
+		Limit:       filter.Limit,
+		FieldTitles: fieldTitles,
+	}
+
+	funcs := template.FuncMap{
+		"studentName":    func(id *primitive.ObjectID) string { return names[*id] },
+		"disciplineName": func(id *primitive.ObjectID) string { return disciplineNames[*id] },
+	}
+	t := template.Must(template.New("audit").Funcs(funcs).Parse(tmpl + userBarTmpl))
+	if err := t.Execute(w, data); err != nil {
+		log.Printf("Ошибка шаблона журнала: %v", err)
+	}
+}
@@ -0,0 +1,245 @@
+// handlers/auth.go
+package handlers
+
+import (
+	"context"
+	"crypto/rand"
+	"electronic-diary/models"
+	"encoding/hex"
+	"html/template"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	sessionCookie = "diary_session"
+	sessionTTL    = 7 * 24 * time.Hour
+)
+
+// Access — кто может читать страницу (GET/HEAD) и кто может её изменять
+// (остальные методы). Пустой список — любой вошедший пользователь.
+type Access struct {
+	Read, Write []models.Role
+}
+
+var (
+	staffRoles = []models.Role{models.RoleAdmin, models.RoleTeacher}
+	adminRoles = []models.Role{models.RoleAdmin}
+
+	// AnyUser — достаточно войти; остальное проверяет сам обработчик.
+	AnyUser = Access{}
+	// StaffOnly — администраторы и преподаватели.
+	StaffOnly = Access{Read: staffRoles, Write: staffRoles}
+	// AdminWrites — преподаватели только смотрят, меняет администратор.
+	AdminWrites = Access{Read: staffRoles, Write: adminRoles}
+	// AdminOnly — только администратор.
+	AdminOnly = Access{Read: adminRoles, Write: adminRoles}
+)
+
+type userKey struct{}
+
+// currentUser — пользователь запроса; его кладёт в контекст Protect.
+func currentUser(r *http.Request) *models.User {
+	user, _ := r.Context().Value(userKey{}).(*models.User)
+	return user
+}
+
+func hasRole(user *models.User, roles []models.Role) bool {
+	if len(roles) == 0 {
+		return true
+	}
+	for _, role := range roles {
+		if user.Role == role {
+			return true
+		}
+	}
+	return false
+}
+
+// sessionUser находит пользователя по cookie сессии.
+func (s *Server) sessionUser(r *http.Request) *models.User {
+	cookie, err := r.Cookie(sessionCookie)
+	if err != nil {
+		return nil
+	}
+	session, err := s.store.GetSession(cookie.Value)
+	if err != nil {
+		return nil
+	}
+	user, err := s.store.GetUserByID(session.UserID)
+	if err != nil {
+		return nil
+	}
+	return user
+}
+
+// Protect пускает к обработчику только вошедших пользователей с подходящей
+// ролью. Браузер без сессии отправляется на страницу входа.
+func (s *Server) Protect(access Access, h http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user := s.sessionUser(r)
+		if user == nil {
+			if wantsJSON(r) || r.Method != http.MethodGet {
+				respondError(w, r, http.StatusUnauthorized, "Требуется вход")
+				return
+			}
+			http.Redirect(w, r, "/login?next="+template.URLQueryEscaper(r.URL.RequestURI()), http.StatusSeeOther)
+			return
+		}
+
+		roles := access.Write
+		if r.Method == http.MethodGet || r.Method == http.MethodHead {
+			roles = access.Read
+		}
+		if !hasRole(user, roles) {
+			respondError(w, r, http.StatusForbidden, "Недостаточно прав")
+			return
+		}
+
+		h(w, r.WithContext(context.WithValue(r.Context(), userKey{}, user)))
+	}
+}
+
+// safeNext — куда вернуться после входа; только пути этого сайта.
+func safeNext(next string) string {
+	if !strings.HasPrefix(next, "/") || strings.HasPrefix(next, "//") || strings.HasPrefix(next, "/\\") {
+		return "/"
+	}
+	return next
+}
+
+// LoginHandler — форма входа и проверка пароля
+func (s *Server) LoginHandler(w http.ResponseWriter, r *http.Request) {
+	next := safeNext(r.FormValue("next"))
+
+	switch r.Method {
+	case http.MethodGet:
+		s.loginPage(w, next, "")
+	case http.MethodPost:
+		var in struct {
+			Login    string `json:"login"`
+			Password string `json:"password"`
+		}
+		if err := readInput(r, &in); err != nil {
+			respondError(w, r, http.StatusBadRequest, "Некорректные данные")
+			return
+		}
+
+		user, err := s.store.GetUserByLogin(strings.TrimSpace(in.Login))
+		if err != nil || !user.CheckPassword(in.Password) {
+			if wantsJSON(r) {
+				respondError(w, r, http.StatusUnauthorized, "Неверный логин или пароль")
+				return
+			}
+			w.WriteHeader(http.StatusUnauthorized)
+			s.loginPage(w, next, "Неверный логин или пароль")
+			return
+		}
+
+		buf := make([]byte, 32)
+		if _, err := rand.Read(buf); err != nil {
+			respondError(w, r, http.StatusInternalServerError, "Не удалось создать сессию")
+			return
+		}
+		session := models.Session{
+			Token:     hex.EncodeToString(buf),
+			UserID:    user.ID,
+			ExpiresAt: time.Now().Add(sessionTTL),
+		}
+		if err := s.store.CreateSession(&session); err != nil {
+			log.Printf("Ошибка создания сессии: %v", err)
+			respondError(w, r, http.StatusInternalServerError, "Ошибка БД")
+			return
+		}
+
+		// SameSite=Lax: браузер не пришлёт cookie с POST-формы чужого сайта
+		http.SetCookie(w, &http.Cookie{
+			Name:     sessionCookie,
+			Value:    session.Token,
+			Path:     "/",
+			Expires:  session.ExpiresAt,
+			HttpOnly: true,
+			SameSite: http.SameSiteLaxMode,
+		})
+		respond(w, r, next, user)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (s *Server) loginPage(w http.ResponseWriter, next, errMsg string) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	tmpl := `
+<!DOCTYPE html>
+<html>
+<head>
+	<title>Вход — Электронный дневник</title>
+	<link rel="stylesheet" href="/static/style.css">
+</head>
+<body>
+	<div class="card login-card">
+		<h1>Вход</h1>
+		{{if .Error}}<p class="form-error">{{.Error}}</p>{{end}}
+		<form method="POST" action="/login">
+			<input type="hidden" name="next" value="{{.Next}}">
+			<label for="login">Логин:</label>
+			<input type="text" name="login" id="login" required autofocus>
+			<label for="password">Пароль:</label>
+			<input type="password" name="password" id="password" required>
+			<input type="submit" value="Войти">
+		</form>
+	</div>
+</body>
+</html>`
+
+	data := struct {
+		Next  string
+		Error string
+	}{next, errMsg}
+
+	t := template.Must(template.New("login").Parse(tmpl))
+	t.Execute(w, data)
+}
+
+// LogoutHandler завершает сессию
+func (s *Server) LogoutHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.NotFound(w, r)
+		return
+	}
+	if cookie, err := r.Cookie(sessionCookie); err == nil {
+		if err := s.store.DeleteSession(cookie.Value); err != nil {
+			log.Printf("Ошибка удаления сессии: %v", err)
+		}
+	}
+	http.SetCookie(w, &http.Cookie{
+		Name:     sessionCookie,
+		Value:    "",
+		Path:     "/",
+		MaxAge:   -1,
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+	respond(w, r, "/login", map[string]string{"status": "ok"})
+}
+
+// userBarTmpl — строка с именем пользователя и кнопкой выхода.
+// Страницы дописывают её к своему шаблону и вызывают {{template "userbar" .User}}.
+const userBarTmpl = `
+{{define "userbar"}}{{if .}}
+<div class="user-bar">
+	<span>{{.Name}} <small>{{.Role.Title}}</small></span>
+	{{if eq .Role "admin"}}
+	<a href="/users">Пользователи</a>
+	<a href="/scales">Шкалы</a>
+	<a href="/terms">Периоды</a>
+	<a href="/audit">Журнал изменений</a>
+	{{end}}
+	{{if .Role.Staff}}<a href="/api/docs">API</a>{{end}}
+	<form action="/logout" method="POST">
+		<button type="submit" class="small-btn">Выйти</button>
+	</form>
+</div>
+{{end}}{{end}}`
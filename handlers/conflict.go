@@ -0,0 +1,193 @@
+// handlers/conflict.go
+package handlers
+
+import (
+	"electronic-diary/models"
+	"html/template"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// conflictField — поле, которое пользователь отправил, и то, что
+// сейчас сохранено. При расхождении пользователь выбирает значение.
+type conflictField struct {
+	Name   string // имя поля формы
+	Label  string
+	Mine   string
+	Theirs string
+}
+
+type conflictRow struct {
+	Discipline string
+	VersionKey string // имя поля версии записи
+	Version    string // пусто — записи нет
+	// ScoreKey — скрытое поле балла для дисциплин с работами: по полям
+	// score_ обработчик находит строки формы, а сам балл не меняется
+	ScoreKey string
+	Fields   []conflictField
+}
+
+// conflictPage показывает, чем отправленная форма студента расходится с
+// тем, что успел сохранить другой пользователь, и даёт собрать итог.
+// Форма слияния несёт свежие версии, так что повторная отправка пройдёт,
+// если за это время никто снова не изменил данные.
+func (s *Server) conflictPage(w http.ResponseWriter, r *http.Request, studentID, termID primitive.ObjectID, form url.Values) {
+	student, err := s.store.GetStudentByID(studentID)
+	if err != nil {
+		http.Error(w, "Студент удалён, пока вы редактировали страницу", http.StatusNotFound)
+		return
+	}
+	disciplines, err := s.store.GetDisciplinesByGroupID(student.GroupID)
+	if err != nil {
+		http.Error(w, "Ошибка дисциплин", http.StatusInternalServerError)
+		return
+	}
+	records, err := s.store.GetStudentDisciplineData(studentID, termID)
+	if err != nil {
+		http.Error(w, "Ошибка данных", http.StatusInternalServerError)
+		return
+	}
+	journaled, err := s.journaledDisciplines(student.GroupID, termID)
+	if err != nil {
+		http.Error(w, "Ошибка журнала", http.StatusInternalServerError)
+		return
+	}
+	graded, err := s.gradedDisciplines(disciplines, termID)
+	if err != nil {
+		http.Error(w, "Ошибка работ", http.StatusInternalServerError)
+		return
+	}
+
+	current := make(map[primitive.ObjectID]models.StudentDisciplineData, len(records))
+	for _, d := range records {
+		current[d.DisciplineID] = d
+	}
+
+	// Пустое поле формы означает ноль — так его и сохраняет обработчик
+	mine := func(name string) string {
+		if v := strings.TrimSpace(form.Get(name)); v != "" {
+			return v
+		}
+		return "0"
+	}
+
+	var rows []conflictRow
+	for _, d := range disciplines {
+		hex := d.ID.Hex()
+		if _, ok := form["score_"+hex]; !ok {
+			continue
+		}
+		data, exists := current[d.ID]
+		row := conflictRow{Discipline: d.Name, VersionKey: "version_" + hex}
+		if exists {
+			row.Version = strconv.Itoa(data.Version)
+		}
+		if graded[d.ID] {
+			row.ScoreKey = "score_" + hex
+		} else {
+			row.Fields = append(row.Fields, conflictField{"score_" + hex, "Баллы", mine("score_" + hex), strconv.Itoa(data.Score)})
+		}
+		if !journaled[d.ID] {
+			row.Fields = append(row.Fields,
+				conflictField{"total_" + hex, "Всего пар", mine("total_" + hex), strconv.Itoa(data.TotalClasses)},
+				conflictField{"attended_" + hex, "Посетил", mine("attended_" + hex), strconv.Itoa(data.AttendedClasses)},
+			)
+		}
+		rows = append(rows, row)
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	tmpl := `
+<!DOCTYPE html>
+<html>
+<head>
+	<title>Конфликт изменений — {{.Student.Name}}</title>
+	<link rel="stylesheet" href="/static/style.css">
+</head>
+<body>
+	<div class="card">
+		{{template "userbar" .User}}
+		<h1>{{.Student.Name}}: конфликт изменений</h1>
+		<p class="form-error">Пока вы редактировали страницу, данные студента изменил другой пользователь. Ничего из вашей формы не сохранено.</p>
+		<p>Выберите для каждого расхождения, какое значение оставить, и сохраните ещё раз.</p>
+
+		<form method="POST" action="/api/student/{{.Student.ID.Hex}}">
+			<input type="hidden" name="version" value="{{.Student.Version}}">
+			<input type="hidden" name="term" value="{{.TermID.Hex}}">
+
+			<h2>Комментарий</h2>
+			{{if eq .MyComments .Student.Comments}}
+			<input type="hidden" name="comments" value="{{.MyComments}}">
+			<p>{{if .MyComments}}{{.MyComments}}{{else}}<em>пусто</em>{{end}}</p>
+			{{else}}
+			<div class="conflict-theirs">
+				<small>Сейчас сохранено:</small>
+				<p>{{if .Student.Comments}}{{.Student.Comments}}{{else}}<em>пусто</em>{{end}}</p>
+			</div>
+			<label for="comments">Итоговый комментарий (сейчас — ваш вариант, объедините при необходимости):</label>
+			<textarea name="comments" id="comments">{{.MyComments}}</textarea>
+			{{end}}
+
+			<h2>Дисциплины</h2>
+			<table class="conflict-table">
+				<thead>
+					<tr><th>Дисциплина</th><th>Поле</th><th>Ваше значение</th><th>Сейчас сохранено</th></tr>
+				</thead>
+				<tbody>
+				{{range .Rows}}
+				{{$row := .}}
+				{{range $i, $f := .Fields}}
+				<tr{{if ne .Mine .Theirs}} class="conflict-diff"{{end}}>
+					<td>
+						{{if not $i}}
+						{{$row.Discipline}}
+						{{with $row.Version}}<input type="hidden" name="{{$row.VersionKey}}" value="{{.}}">{{end}}
+						{{with $row.ScoreKey}}<input type="hidden" name="{{.}}" value="0">{{end}}
+						{{end}}
+					</td>
+					<td>{{.Label}}</td>
+					{{if eq .Mine .Theirs}}
+					<td colspan="2">{{.Mine}}<input type="hidden" name="{{.Name}}" value="{{.Mine}}"></td>
+					{{else}}
+					<td><label><input type="radio" name="{{.Name}}" value="{{.Mine}}" checked> {{.Mine}}</label></td>
+					<td><label><input type="radio" name="{{.Name}}" value="{{.Theirs}}"> {{.Theirs}}</label></td>
+					{{end}}
+				</tr>
+				{{end}}
+				{{end}}
+				</tbody>
+			</table>
+
+			<input type="submit" value="Сохранить выбранное">
+		</form>
+
+		<a href="/student/{{.Student.ID.Hex}}" class="back-link">← Отбросить мои изменения</a>
+	</div>
+</body>
+</html>`
+
+	data := struct {
+		User       *models.User
+		Student    *models.Student
+		TermID     primitive.ObjectID
+		MyComments string
+		Rows       []conflictRow
+	}{
+		User:       currentUser(r),
+		Student:    student,
+		TermID:     termID,
+		MyComments: form.Get("comments"),
+		Rows:       rows,
+	}
+
+	t := template.Must(template.New("conflict").Parse(tmpl + userBarTmpl))
+	w.WriteHeader(http.StatusConflict)
+	if err := t.Execute(w, data); err != nil {
+		log.Printf("Ошибка шаблона конфликта: %v", err)
+	}
+}
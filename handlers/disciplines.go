@@ -0,0 +1,272 @@
+// handlers/disciplines.go
+package handlers
+
+import (
+	"electronic-diary/db"
+	"electronic-diary/models"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// DisciplinesAPIHandler — управление дисциплинами группы:
+//
+//	GET  /api/disciplines?group={id}[&retired=1] — дисциплины группы
+//	POST /api/disciplines                        — добавить (name, groupId)
+//	POST /api/disciplines/{id}/rename            — переименовать (name)
+//	POST /api/disciplines/{id}/move              — сдвинуть (direction: up|down)
+//	POST /api/disciplines/{id}/retire            — вывести из программы
+//	POST /api/disciplines/{id}/restore           — вернуть в программу
+//	POST /api/disciplines/{id}/scale             — своя шкала оценок (scaleId, пусто — шкала группы)
+//	POST /api/disciplines/{id}/teacher           — назначить преподавателя (teacherId, пусто — снять)
+func (s *Server) DisciplinesAPIHandler(w http.ResponseWriter, r *http.Request) {
+	rest := strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/disciplines"), "/")
+
+	if rest == "" {
+		switch r.Method {
+		case http.MethodGet:
+			s.listDisciplines(w, r)
+		case http.MethodPost:
+			s.createDiscipline(w, r)
+		default:
+			http.NotFound(w, r)
+		}
+		return
+	}
+
+	parts := strings.Split(rest, "/")
+	if len(parts) != 2 || r.Method != http.MethodPost {
+		http.NotFound(w, r)
+		return
+	}
+	disciplineID, err := parseObjectID(parts[0])
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	discipline, err := s.store.GetDisciplineByID(disciplineID)
+	if isNotFound(err) {
+		respondError(w, r, http.StatusNotFound, "Дисциплина не найдена")
+		return
+	}
+	if err != nil {
+		log.Printf("Ошибка получения дисциплины: %v", err)
+		respondError(w, r, http.StatusInternalServerError, "Ошибка БД")
+		return
+	}
+
+	switch parts[1] {
+	case "rename":
+		s.renameDiscipline(w, r, discipline)
+	case "move":
+		s.moveDiscipline(w, r, discipline)
+	case "retire":
+		s.setDisciplineRetired(w, r, discipline, true)
+	case "restore":
+		s.setDisciplineRetired(w, r, discipline, false)
+	case "scale":
+		s.setDisciplineScale(w, r, discipline)
+	case "teacher":
+		s.setDisciplineTeacher(w, r, discipline)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// canEdit — администратор меняет записи любой дисциплины, преподаватель —
+// только тех, что ведёт сам. Студенты и родители не меняют ничего.
+func canEdit(user *models.User, d *models.Discipline) bool {
+	switch user.Role {
+	case models.RoleAdmin:
+		return true
+	case models.RoleTeacher:
+		return d.TeacherID != nil && *d.TeacherID == user.ID
+	}
+	return false
+}
+
+// editableDisciplines — дисциплины, записи которых пользователь может менять.
+func editableDisciplines(user *models.User, disciplines []models.Discipline) map[primitive.ObjectID]bool {
+	editable := make(map[primitive.ObjectID]bool, len(disciplines))
+	for i := range disciplines {
+		editable[disciplines[i].ID] = canEdit(user, &disciplines[i])
+	}
+	return editable
+}
+
+// requireTeacher отвечает 403, если пользователь не ведёт дисциплину.
+func requireTeacher(w http.ResponseWriter, r *http.Request, d *models.Discipline) bool {
+	if canEdit(currentUser(r), d) {
+		return true
+	}
+	respondError(w, r, http.StatusForbidden, "Дисциплину «"+d.Name+"» ведёт другой преподаватель")
+	return false
+}
+
+func (s *Server) setDisciplineTeacher(w http.ResponseWriter, r *http.Request, discipline *models.Discipline) {
+	var in struct {
+		TeacherID string `json:"teacherId"`
+	}
+	if err := readInput(r, &in); err != nil {
+		respondError(w, r, http.StatusBadRequest, "Некорректные данные")
+		return
+	}
+
+	var teacherID *primitive.ObjectID
+	if in.TeacherID != "" {
+		id, err := parseObjectID(in.TeacherID)
+		if err != nil {
+			respondError(w, r, http.StatusBadRequest, "Некорректный преподаватель")
+			return
+		}
+		teacher, err := s.store.GetUserByID(id)
+		if err != nil || teacher.Role != models.RoleTeacher {
+			respondError(w, r, http.StatusBadRequest, "Преподаватель не найден")
+			return
+		}
+		teacherID = &id
+	}
+
+	if err := s.store.SetDisciplineTeacher(discipline.ID, teacherID); err != nil {
+		log.Printf("Ошибка назначения преподавателя дисциплине %s: %v", discipline.ID.Hex(), err)
+		respondError(w, r, http.StatusInternalServerError, "Ошибка БД")
+		return
+	}
+	discipline.TeacherID = teacherID
+	respond(w, r, s.disciplineGroupURL(discipline), discipline)
+}
+
+// disciplineGroupURL — страница группы, к которой относится дисциплина.
+func (s *Server) disciplineGroupURL(d *models.Discipline) string {
+	group, err := s.store.GetGroupByID(d.GroupID)
+	if err != nil {
+		return "/"
+	}
+	return groupURL(group)
+}
+
+func (s *Server) listDisciplines(w http.ResponseWriter, r *http.Request) {
+	groupID, err := parseObjectID(r.URL.Query().Get("group"))
+	if err != nil {
+		respondError(w, r, http.StatusBadRequest, "Укажите группу: ?group={id}")
+		return
+	}
+
+	var disciplines []models.Discipline
+	if r.URL.Query().Get("retired") == "1" {
+		disciplines, err = s.store.GetAllDisciplinesByGroupID(groupID)
+	} else {
+		disciplines, err = s.store.GetDisciplinesByGroupID(groupID)
+	}
+	if err != nil {
+		log.Printf("Ошибка получения дисциплин: %v", err)
+		respondError(w, r, http.StatusInternalServerError, "Ошибка БД")
+		return
+	}
+	if disciplines == nil {
+		disciplines = []models.Discipline{}
+	}
+	writeJSON(w, http.StatusOK, disciplines)
+}
+
+func (s *Server) createDiscipline(w http.ResponseWriter, r *http.Request) {
+	var in struct {
+		Name    string `json:"name"`
+		GroupID string `json:"groupId"`
+	}
+	if err := readInput(r, &in); err != nil {
+		respondError(w, r, http.StatusBadRequest, "Некорректные данные")
+		return
+	}
+	groupID, err := parseObjectID(in.GroupID)
+	if err != nil {
+		respondError(w, r, http.StatusBadRequest, "Некорректная группа")
+		return
+	}
+	group, err := s.store.GetGroupByID(groupID)
+	if err != nil {
+		respondError(w, r, http.StatusBadRequest, "Группа не найдена")
+		return
+	}
+
+	discipline, err := s.store.CreateDiscipline(group.ID, in.Name)
+	if errors.Is(err, db.ErrEmptyName) {
+		respondError(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err != nil {
+		log.Printf("Ошибка создания дисциплины: %v", err)
+		respondError(w, r, http.StatusInternalServerError, "Ошибка БД")
+		return
+	}
+	respond(w, r, groupURL(group), discipline)
+}
+
+func (s *Server) renameDiscipline(w http.ResponseWriter, r *http.Request, discipline *models.Discipline) {
+	var in struct {
+		Name string `json:"name"`
+	}
+	if err := readInput(r, &in); err != nil {
+		respondError(w, r, http.StatusBadRequest, "Некорректные данные")
+		return
+	}
+
+	err := s.store.RenameDiscipline(discipline.ID, in.Name)
+	if errors.Is(err, db.ErrEmptyName) {
+		respondError(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err != nil {
+		log.Printf("Ошибка переименования дисциплины %s: %v", discipline.ID.Hex(), err)
+		respondError(w, r, http.StatusInternalServerError, "Ошибка БД")
+		return
+	}
+	discipline.Name = strings.TrimSpace(in.Name)
+	respond(w, r, s.disciplineGroupURL(discipline), discipline)
+}
+
+func (s *Server) moveDiscipline(w http.ResponseWriter, r *http.Request, discipline *models.Discipline) {
+	var in struct {
+		Direction string `json:"direction"`
+	}
+	if err := readInput(r, &in); err != nil {
+		respondError(w, r, http.StatusBadRequest, "Некорректные данные")
+		return
+	}
+
+	var offset int
+	switch in.Direction {
+	case "up":
+		offset = -1
+	case "down":
+		offset = 1
+	default:
+		respondError(w, r, http.StatusBadRequest, "direction должен быть up или down")
+		return
+	}
+
+	if err := s.store.MoveDiscipline(discipline.ID, offset); err != nil {
+		log.Printf("Ошибка перемещения дисциплины %s: %v", discipline.ID.Hex(), err)
+		respondError(w, r, http.StatusInternalServerError, "Ошибка БД")
+		return
+	}
+	moved, err := s.store.GetDisciplineByID(discipline.ID)
+	if err != nil {
+		respondError(w, r, http.StatusInternalServerError, "Ошибка БД")
+		return
+	}
+	respond(w, r, s.disciplineGroupURL(moved), moved)
+}
+
+func (s *Server) setDisciplineRetired(w http.ResponseWriter, r *http.Request, discipline *models.Discipline, retired bool) {
+	if err := s.store.SetDisciplineRetired(discipline.ID, retired); err != nil {
+		log.Printf("Ошибка изменения статуса дисциплины %s: %v", discipline.ID.Hex(), err)
+		respondError(w, r, http.StatusInternalServerError, "Ошибка БД")
+		return
+	}
+	discipline.Retired = retired
+	respond(w, r, s.disciplineGroupURL(discipline), discipline)
+}
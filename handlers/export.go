@@ -0,0 +1,161 @@
+// handlers/export.go
+package handlers
+
+import (
+	"bytes"
+	"electronic-diary/models"
+	"electronic-diary/xlsx"
+	"encoding/csv"
+	"fmt"
+	"log"
+	"math"
+	"mime"
+	"net/http"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Колонки ведомости для каждой дисциплины: «Дисциплина: балл» и т.д.
+// По этим же заголовкам импорт узнаёт дисциплину и поле.
+var gradebookFields = []string{"балл", "оценка", "посетил", "всего пар", "посещаемость %"}
+
+// gradebook собирает ведомость группы за период: строка заголовков,
+// затем по строке на студента с колонками gradebookFields по каждой
+// действующей дисциплине.
+func (s *Server) gradebook(group *models.Group, termID primitive.ObjectID) ([][]interface{}, error) {
+	students, err := s.store.GetStudentsByGroupID(group.ID)
+	if err != nil {
+		return nil, err
+	}
+	disciplines, err := s.store.GetDisciplinesByGroupID(group.ID)
+	if err != nil {
+		return nil, err
+	}
+	scales, err := s.gradingScales()
+	if err != nil {
+		return nil, err
+	}
+
+	header := []interface{}{"Студент"}
+	for _, d := range disciplines {
+		for _, f := range gradebookFields {
+			header = append(header, d.Name+": "+f)
+		}
+	}
+	rows := [][]interface{}{header}
+
+	for _, st := range students {
+		records, err := s.store.GetStudentDisciplineData(st.ID, termID)
+		if err != nil {
+			return nil, err
+		}
+		byDiscipline := make(map[primitive.ObjectID]models.StudentDisciplineData, len(records))
+		for _, d := range records {
+			byDiscipline[d.DisciplineID] = d
+		}
+
+		row := []interface{}{st.Name}
+		for _, d := range disciplines {
+			data := byDiscipline[d.ID]
+			percent := 0
+			if data.TotalClasses > 0 {
+				percent = int(math.Round(float64(data.AttendedClasses) / float64(data.TotalClasses) * 100))
+			}
+			row = append(row,
+				data.Score,
+				resolveScale(scales, group, d).Grade(data.Score).Label,
+				data.AttendedClasses,
+				data.TotalClasses,
+				percent,
+			)
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+// exportGradebook — GET /group/{id}/export.csv и /group/{id}/export.xlsx:
+// ведомость группы за выбранный период. CSV — в UTF-8 с BOM и точкой
+// с запятой, как его ожидает Excel с русскими настройками.
+func (s *Server) exportGradebook(w http.ResponseWriter, r *http.Request, group *models.Group, format string) {
+	if r.Method != http.MethodGet {
+		http.NotFound(w, r)
+		return
+	}
+	term, err := s.currentTerm(r)
+	if err != nil {
+		log.Printf("Ошибка получения периодов: %v", err)
+		http.Error(w, "Ошибка БД", http.StatusInternalServerError)
+		return
+	}
+	rows, err := s.gradebook(group, term.ID())
+	if err != nil {
+		log.Printf("Ошибка выгрузки ведомости группы %s: %v", group.Name, err)
+		http.Error(w, "Ошибка БД", http.StatusInternalServerError)
+		return
+	}
+
+	name := "Ведомость " + group.Name
+	if term.Current != nil {
+		name += ", " + term.Current.Name
+	}
+
+	var buf bytes.Buffer
+	var contentType string
+	switch format {
+	case "csv":
+		contentType = "text/csv; charset=utf-8"
+		buf.WriteString("\ufeff")
+		cw := csv.NewWriter(&buf)
+		cw.Comma = ';'
+		for _, row := range rows {
+			record := make([]string, len(row))
+			for i, v := range row {
+				record[i] = csvCell(v)
+			}
+			cw.Write(record)
+		}
+		cw.Flush()
+		err = cw.Error()
+	case "xlsx":
+		contentType = "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+		err = xlsx.Write(&buf, group.Name, rows)
+	}
+	if err != nil {
+		log.Printf("Ошибка выгрузки ведомости группы %s: %v", group.Name, err)
+		http.Error(w, "Ошибка выгрузки", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": name + "." + format}))
+	w.Write(buf.Bytes())
+}
+
+// csvFormulaStart — с этих символов Excel начинает формулу, когда
+// открывает CSV.
+const csvFormulaStart = "=+-@\t\r"
+
+// csvCell — значение ячейки CSV. Текст, который Excel принял бы за
+// формулу (имя «=HYPERLINK(...)», «-Физика»), получает в начале апостроф
+// и остаётся текстом; числа пишутся как есть.
+func csvCell(v interface{}) string {
+	text, ok := v.(string)
+	if !ok {
+		return fmt.Sprint(v)
+	}
+	if text != "" && strings.ContainsRune(csvFormulaStart, rune(text[0])) {
+		return "'" + text
+	}
+	return text
+}
+
+// csvUncell снимает апостроф, который добавил csvCell, чтобы выгрузку
+// можно было загрузить обратно.
+func csvUncell(text string) string {
+	if len(text) > 1 && text[0] == '\'' && strings.ContainsRune(csvFormulaStart, rune(text[1])) {
+		return text[1:]
+	}
+	return text
+}
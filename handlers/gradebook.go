@@ -0,0 +1,476 @@
+// handlers/gradebook.go
+package handlers
+
+import (
+	"electronic-diary/db"
+	"electronic-diary/models"
+	"errors"
+	"html/template"
+	"log"
+	"net/http"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// gridCell — запись студента по дисциплине в сетке ведомости.
+type gridCell struct {
+	Data      models.StudentDisciplineData
+	Exists    bool
+	Grade     models.GradeBand
+	Editable  bool
+	Journaled bool
+	Graded    bool
+}
+
+type gridRow struct {
+	Student models.Student
+	Cells   []gridCell
+}
+
+// gridPage — сетка ведомости группы за период: студенты по строкам,
+// дисциплины по колонкам. Баллы и посещаемость правятся прямо в сетке
+// и сохраняются одним запросом в /api/gradebook.
+func (s *Server) gridPage(w http.ResponseWriter, r *http.Request, group *models.Group) {
+	students, err := s.store.GetStudentsByGroupID(group.ID)
+	if err != nil {
+		log.Printf("Ошибка получения студентов: %v", err)
+		http.Error(w, "Ошибка БД", http.StatusInternalServerError)
+		return
+	}
+	disciplines, err := s.store.GetDisciplinesByGroupID(group.ID)
+	if err != nil {
+		http.Error(w, "Ошибка дисциплин", http.StatusInternalServerError)
+		return
+	}
+	term, err := s.currentTerm(r)
+	if err != nil {
+		log.Printf("Ошибка получения периодов: %v", err)
+		http.Error(w, "Ошибка БД", http.StatusInternalServerError)
+		return
+	}
+	journaled, err := s.journaledDisciplines(group.ID, term.ID())
+	if err != nil {
+		http.Error(w, "Ошибка журнала", http.StatusInternalServerError)
+		return
+	}
+	graded, err := s.gradedDisciplines(disciplines, term.ID())
+	if err != nil {
+		http.Error(w, "Ошибка работ", http.StatusInternalServerError)
+		return
+	}
+	scales, err := s.gradingScales()
+	if err != nil {
+		http.Error(w, "Ошибка шкал оценок", http.StatusInternalServerError)
+		return
+	}
+
+	user := currentUser(r)
+	editable := editableDisciplines(user, disciplines)
+	gradeTables := make(map[string][]models.GradeBand)
+	discScales := make([]string, len(disciplines))
+	for i, d := range disciplines {
+		scale := resolveScale(scales, group, d)
+		gradeTables[scale.ID.Hex()] = scale.Table()
+		discScales[i] = scale.ID.Hex()
+	}
+
+	rows := make([]gridRow, 0, len(students))
+	for _, st := range students {
+		records, err := s.store.GetStudentDisciplineData(st.ID, term.ID())
+		if err != nil {
+			http.Error(w, "Ошибка данных", http.StatusInternalServerError)
+			return
+		}
+		byDiscipline := make(map[primitive.ObjectID]models.StudentDisciplineData, len(records))
+		for _, d := range records {
+			byDiscipline[d.DisciplineID] = d
+		}
+		row := gridRow{Student: st}
+		for _, d := range disciplines {
+			data, exists := byDiscipline[d.ID]
+			row.Cells = append(row.Cells, gridCell{
+				Data:      data,
+				Exists:    exists,
+				Grade:     resolveScale(scales, group, d).Grade(data.Score),
+				Editable:  editable[d.ID] && !term.Closed(),
+				Journaled: journaled[d.ID],
+				Graded:    graded[d.ID],
+			})
+		}
+		rows = append(rows, row)
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	tmpl := `
+<!DOCTYPE html>
+<html>
+<head>
+	<title>Ведомость {{.Group.Name}}</title>
+	<link rel="stylesheet" href="/static/style.css">
+</head>
+<body>
+	<div class="card wide-card">
+		{{template "userbar" .User}}
+		<h1>Ведомость группы {{.Group.Name}}</h1>
+		{{template "termbar" .Term}}
+		<p class="lesson-meta">Стрелки и Enter — переход между ячейками, Ctrl+S — сохранить. Серые ячейки считаются по журналу или работам либо относятся к чужой дисциплине.</p>
+
+		{{if and .Students .Disciplines}}
+		<div class="grid-wrap">
+		<table id="grid" class="grid-table">
+			<thead>
+				<tr>
+					<th rowspan="2">Студент</th>
+					{{range .Disciplines}}<th colspan="3">{{.Name}}</th>{{end}}
+				</tr>
+				<tr>
+					{{range .Disciplines}}<th><small>балл</small></th><th><small>был</small></th><th><small>всего</small></th>{{end}}
+				</tr>
+			</thead>
+			<tbody>
+			{{range $row := .Rows}}
+			<tr>
+				<td class="grid-name"><a href="/student/{{$row.Student.ID.Hex}}">{{$row.Student.Name}}</a></td>
+				{{range $c, $cell := $row.Cells}}
+				{{$disc := index $.Disciplines $c}}
+				<td class="grid-cell" data-student="{{$row.Student.ID.Hex}}" data-disc="{{$disc.ID.Hex}}" data-scale="{{index $.DiscScales $c}}"{{if $cell.Exists}} data-version="{{$cell.Data.Version}}"{{end}}>
+					<input type="number" data-field="score" value="{{$cell.Data.Score}}" min="0" max="100"{{if or (not $cell.Editable) $cell.Graded}} readonly{{end}}>
+					<span class="grid-grade {{$cell.Grade.Class}}">{{$cell.Grade.Label}}</span>
+				</td>
+				<td class="grid-cell" data-student="{{$row.Student.ID.Hex}}" data-disc="{{$disc.ID.Hex}}">
+					<input type="number" data-field="attendedClasses" value="{{$cell.Data.AttendedClasses}}" min="0"{{if or (not $cell.Editable) $cell.Journaled}} readonly{{end}}>
+				</td>
+				<td class="grid-cell" data-student="{{$row.Student.ID.Hex}}" data-disc="{{$disc.ID.Hex}}">
+					<input type="number" data-field="totalClasses" value="{{$cell.Data.TotalClasses}}" min="0"{{if or (not $cell.Editable) $cell.Journaled}} readonly{{end}}>
+				</td>
+				{{end}}
+			</tr>
+			{{end}}
+			</tbody>
+		</table>
+		</div>
+		<p id="grid-status" class="grid-status"></p>
+		<button type="button" id="grid-save" disabled>Сохранить</button>
+		{{else}}
+		<p>В группе пока нет студентов или дисциплин.</p>
+		{{end}}
+
+		<a href="{{groupURL .Group}}" class="back-link">← Назад к группе</a>
+	</div>
+
+	<script>
+		const gradeTables = {{.GradeTables}};
+		const termID = {{.Term.ID.Hex}};
+		const grid = document.getElementById('grid');
+		const saveBtn = document.getElementById('grid-save');
+		const statusLine = document.getElementById('grid-status');
+		// Изменённые записи: "studentId:disciplineId" → true
+		const dirty = new Map();
+
+		function key(td) { return td.dataset.student + ':' + td.dataset.disc; }
+		function cellsOf(k) { return grid.querySelectorAll('td[data-student="' + k.split(':')[0] + '"][data-disc="' + k.split(':')[1] + '"]'); }
+
+		function updateGrade(k) {
+			const first = cellsOf(k)[0];
+			const score = parseInt(first.querySelector('input').value) || 0;
+			const table = gradeTables[first.dataset.scale];
+			const grade = table[Math.min(Math.max(score, 0), 100)];
+			const label = first.querySelector('.grid-grade');
+			label.textContent = grade.label;
+			label.className = 'grid-grade ' + grade.class;
+		}
+
+		if (grid) {
+			grid.addEventListener('input', function(e) {
+				const td = e.target.closest('td');
+				dirty.set(key(td), true);
+				e.target.classList.remove('error');
+				e.target.classList.add('dirty');
+				updateGrade(key(td));
+				saveBtn.disabled = false;
+				statusLine.textContent = 'Есть несохранённые изменения';
+			});
+
+			// Навигация: стрелки и Enter переходят к соседней ячейке
+			grid.addEventListener('keydown', function(e) {
+				if (e.target.tagName !== 'INPUT') return;
+				const td = e.target.closest('td');
+				const tr = td.parentElement;
+				let row = tr.rowIndex, col = td.cellIndex;
+				switch (e.key) {
+				case 'ArrowUp': row--; break;
+				case 'ArrowDown': case 'Enter': row++; break;
+				case 'ArrowLeft': col--; break;
+				case 'ArrowRight': col++; break;
+				default: return;
+				}
+				const next = grid.rows[row] && grid.rows[row].cells[col];
+				const input = next && next.querySelector('input');
+				if (input) {
+					e.preventDefault();
+					input.focus();
+					input.select();
+				}
+			});
+		}
+
+		function save() {
+			if (!dirty.size) return;
+			const records = [];
+			dirty.forEach(function(_, k) {
+				const tds = cellsOf(k);
+				const rec = {studentId: tds[0].dataset.student, disciplineId: tds[0].dataset.disc};
+				if (tds[0].dataset.version !== undefined) rec.version = parseInt(tds[0].dataset.version);
+				tds.forEach(function(td) {
+					const input = td.querySelector('input');
+					if (!input.readOnly) rec[input.dataset.field] = parseInt(input.value) || 0;
+				});
+				records.push(rec);
+			});
+			saveBtn.disabled = true;
+			statusLine.textContent = 'Сохраняем…';
+			fetch('/api/gradebook?term=' + termID, {
+				method: 'POST',
+				headers: {'Content-Type': 'application/json'},
+				body: JSON.stringify({records: records})
+			}).then(function(resp) {
+				return resp.json().then(function(body) { return {ok: resp.ok, status: resp.status, body: body}; });
+			}).then(function(res) {
+				if (res.ok) {
+					res.body.data.forEach(function(d) {
+						const k = d.studentId + ':' + d.disciplineId;
+						const tds = cellsOf(k);
+						tds[0].dataset.version = d.version;
+						tds.forEach(function(td) { td.querySelector('input').classList.remove('dirty'); });
+						dirty.delete(k);
+					});
+					statusLine.textContent = 'Сохранено записей: ' + res.body.data.length;
+					return;
+				}
+				saveBtn.disabled = false;
+				const err = res.body.error || {};
+				statusLine.textContent = err.message || 'Не удалось сохранить';
+				Object.keys(err.fields || {}).forEach(function(name) {
+					const parts = name.split(':');
+					cellsOf(parts[0] + ':' + parts[1]).forEach(function(td) {
+						const input = td.querySelector('input');
+						if (input.dataset.field === parts[2] || parts[2] === 'record') {
+							input.classList.add('error');
+							input.title = err.fields[name];
+						}
+					});
+				});
+			}).catch(function() {
+				saveBtn.disabled = false;
+				statusLine.textContent = 'Нет связи с сервером — изменения не сохранены';
+			});
+		}
+
+		if (saveBtn) saveBtn.addEventListener('click', save);
+		document.addEventListener('keydown', function(e) {
+			if ((e.ctrlKey || e.metaKey) && e.key === 's') {
+				e.preventDefault();
+				save();
+			}
+		});
+		window.addEventListener('beforeunload', function(e) {
+			if (dirty.size) e.preventDefault();
+		});
+	</script>
+</body>
+</html>`
+
+	data := struct {
+		User        *models.User
+		Term        termState
+		Group       *models.Group
+		Students    []models.Student
+		Disciplines []models.Discipline
+		DiscScales  []string
+		Rows        []gridRow
+		GradeTables map[string][]models.GradeBand
+	}{
+		User:        user,
+		Term:        term,
+		Group:       group,
+		Students:    students,
+		Disciplines: disciplines,
+		DiscScales:  discScales,
+		Rows:        rows,
+		GradeTables: gradeTables,
+	}
+
+	t := template.Must(template.New("grid").Funcs(template.FuncMap{"groupURL": groupURL}).Parse(tmpl + userBarTmpl + termBarTmpl))
+	if err := t.Execute(w, data); err != nil {
+		log.Printf("Ошибка шаблона ведомости: %v", err)
+	}
+}
+
+// gradebookRecord — запись в пакетном сохранении сетки.
+type gradebookRecord struct {
+	StudentID    string `json:"studentId"`
+	DisciplineID string `json:"disciplineId"`
+	Version      *int   `json:"version"`
+	recordInput
+}
+
+// GradebookAPIHandler — POST /api/gradebook?term={id}: пакетное
+// сохранение сетки ведомости.
+//
+//	{"records": [{"studentId", "disciplineId", "version", "score", "totalClasses", "attendedClasses"}]}
+//
+// Каждая запись проверяется так же, как PATCH /api/v1/records/{id}:
+// права на дисциплину, версия, выводимые поля и допустимые значения.
+// Проверяются все записи до первой записи в хранилище: если ошибка есть
+// хоть в одной, не меняется ни одна, а ответ перечисляет ошибки с ключами
+// «студент:дисциплина:поле». Одна ячейка дважды в пакете — тоже ошибка.
+// Сохраняется пакет одним SaveDisciplineData: если запись успели
+// изменить после проверки, не сохраняется ничего (409).
+func (s *Server) GradebookAPIHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.NotFound(w, r)
+		return
+	}
+	var in struct {
+		Records []gradebookRecord `json:"records"`
+	}
+	if err := decodeJSON(r, &in); err != nil {
+		apiBadRequest(w, err)
+		return
+	}
+	term, err := s.currentTerm(r)
+	if err != nil {
+		apiStoreError(w, err, "")
+		return
+	}
+	if term.Closed() {
+		apiFail(w, http.StatusConflict, "term_closed", termClosedMsg)
+		return
+	}
+
+	type derived struct{ journaled, graded bool }
+	disciplines := map[primitive.ObjectID]*models.Discipline{}
+	derivedBy := map[primitive.ObjectID]derived{}
+	students := map[primitive.ObjectID]*models.Student{}
+
+	fields := map[string]string{}
+	conflicts := 0
+	seen := map[[2]primitive.ObjectID]bool{}
+	var before, after []models.StudentDisciplineData
+	for _, rec := range in.Records {
+		prefix := rec.StudentID + ":" + rec.DisciplineID + ":"
+		studentID, errS := parseObjectID(rec.StudentID)
+		disciplineID, errD := parseObjectID(rec.DisciplineID)
+		if errS != nil || errD != nil {
+			fields[prefix+"record"] = "некорректный id студента или дисциплины"
+			continue
+		}
+		// Две правки одной ячейки в пакете — какая из них верная, не понять
+		key := [2]primitive.ObjectID{studentID, disciplineID}
+		if seen[key] {
+			fields[prefix+"record"] = "запись повторяется в запросе"
+			continue
+		}
+		seen[key] = true
+
+		discipline, ok := disciplines[disciplineID]
+		if !ok {
+			if discipline, err = s.store.GetDisciplineByID(disciplineID); err != nil {
+				if isNotFound(err) {
+					fields[prefix+"record"] = "дисциплина не найдена"
+					continue
+				}
+				apiStoreError(w, err, "")
+				return
+			}
+			if !canEdit(currentUser(r), discipline) {
+				apiFail(w, http.StatusForbidden, "forbidden", "Дисциплину «"+discipline.Name+"» ведёт другой преподаватель")
+				return
+			}
+			j, g, err := s.derivedFields(discipline, term.ID())
+			if err != nil {
+				apiStoreError(w, err, "")
+				return
+			}
+			disciplines[disciplineID], derivedBy[disciplineID] = discipline, derived{j, g}
+		}
+		student, ok := students[studentID]
+		if !ok {
+			if student, err = s.store.GetStudentByID(studentID); err != nil {
+				if isNotFound(err) {
+					fields[prefix+"record"] = "студент не найден"
+					continue
+				}
+				apiStoreError(w, err, "")
+				return
+			}
+			students[studentID] = student
+		}
+		if student.GroupID != discipline.GroupID {
+			fields[prefix+"record"] = "дисциплина не относится к группе студента"
+			continue
+		}
+
+		data, err := s.store.GetDisciplineDataFor(studentID, disciplineID, term.ID())
+		if isNotFound(err) {
+			data = &models.StudentDisciplineData{StudentID: studentID, DisciplineID: disciplineID, TermID: term.ID()}
+		} else if err != nil {
+			apiStoreError(w, err, "")
+			return
+		}
+		if rec.Version != nil && (data.ID.IsZero() || *rec.Version != data.Version) {
+			fields[prefix+"record"] = "запись изменил другой пользователь"
+			conflicts++
+			continue
+		}
+		old := *data
+		d := derivedBy[disciplineID]
+		for field, msg := range rec.validate(data, d.journaled, d.graded) {
+			fields[prefix+field] = msg
+		}
+		before, after = append(before, old), append(after, *data)
+	}
+
+	if len(fields) > 0 {
+		if conflicts == len(fields) {
+			writeJSON(w, http.StatusConflict, map[string]apiError{"error": {
+				Code:    "conflict",
+				Message: "Часть записей изменил другой пользователь — обновите страницу",
+				Fields:  fields,
+			}})
+			return
+		}
+		apiInvalid(w, fields)
+		return
+	}
+
+	// Пишутся новые и изменённые записи, все сразу: хранилище сверяет
+	// версии, прочитанные выше, и при любом расхождении не сохраняет ничего
+	var changed []models.StudentDisciplineData
+	var changedAt []int
+	var entries []models.AuditEntry
+	for i, data := range after {
+		if data.ID.IsZero() || data.Score != before[i].Score || data.TotalClasses != before[i].TotalClasses || data.AttendedClasses != before[i].AttendedClasses {
+			changed = append(changed, data)
+			changedAt = append(changedAt, i)
+			entries = append(entries, recordChanges(before[i], data)...)
+		}
+	}
+	if err := s.store.SaveDisciplineData(changed, auditBy(r, entries)); err != nil {
+		if errors.Is(err, db.ErrConflict) {
+			apiFail(w, http.StatusConflict, "conflict", "Часть записей изменил другой пользователь — обновите страницу")
+			return
+		}
+		log.Printf("Ошибка сохранения ведомости: %v", err)
+		apiFail(w, http.StatusInternalServerError, "internal", "Ошибка БД — ничего не сохранено")
+		return
+	}
+	for j, i := range changedAt {
+		after[i] = changed[j]
+	}
+
+	if after == nil {
+		after = []models.StudentDisciplineData{}
+	}
+	apiData(w, http.StatusOK, after)
+}
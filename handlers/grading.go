@@ -0,0 +1,279 @@
+// handlers/grading.go
+package handlers
+
+import (
+	"electronic-diary/models"
+	"fmt"
+	"html/template"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// gradingScales загружает все шкалы; по ним resolveScale подбирает
+// шкалу дисциплины без лишних запросов к хранилищу.
+func (s *Server) gradingScales() (map[primitive.ObjectID]models.GradingScale, error) {
+	list, err := s.store.GetGradingScales()
+	if err != nil {
+		return nil, err
+	}
+	scales := make(map[primitive.ObjectID]models.GradingScale, len(list))
+	for _, sc := range list {
+		scales[sc.ID] = sc
+	}
+	return scales, nil
+}
+
+// resolveScale — шкала дисциплины, иначе шкала группы, иначе пятибалльная.
+func resolveScale(scales map[primitive.ObjectID]models.GradingScale, group *models.Group, d models.Discipline) models.GradingScale {
+	if d.GradingScaleID != nil {
+		if sc, ok := scales[*d.GradingScaleID]; ok {
+			return sc
+		}
+	}
+	if group != nil && group.GradingScaleID != nil {
+		if sc, ok := scales[*group.GradingScaleID]; ok {
+			return sc
+		}
+	}
+	for _, sc := range scales {
+		if sc.Key == "five-point" {
+			return sc
+		}
+	}
+	return models.FivePointScale()
+}
+
+// readScaleChoice читает scaleId из запроса: пустое значение снимает
+// назначение, иначе шкала должна существовать.
+func (s *Server) readScaleChoice(r *http.Request) (*primitive.ObjectID, error) {
+	var in struct {
+		ScaleID string `json:"scaleId"`
+	}
+	if err := readInput(r, &in); err != nil {
+		return nil, fmt.Errorf("некорректные данные")
+	}
+	if in.ScaleID == "" {
+		return nil, nil
+	}
+	id, err := parseObjectID(in.ScaleID)
+	if err != nil {
+		return nil, fmt.Errorf("некорректная шкала")
+	}
+	if _, err := s.store.GetGradingScaleByID(id); err != nil {
+		return nil, fmt.Errorf("шкала не найдена")
+	}
+	return &id, nil
+}
+
+func (s *Server) setGroupScale(w http.ResponseWriter, r *http.Request, group *models.Group) {
+	scaleID, err := s.readScaleChoice(r)
+	if err != nil {
+		respondError(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err := s.store.SetGroupGradingScale(group.ID, scaleID); err != nil {
+		log.Printf("Ошибка назначения шкалы группе %s: %v", group.ID.Hex(), err)
+		respondError(w, r, http.StatusInternalServerError, "Ошибка БД")
+		return
+	}
+	group.GradingScaleID = scaleID
+	respond(w, r, groupURL(group), group)
+}
+
+func (s *Server) setDisciplineScale(w http.ResponseWriter, r *http.Request, discipline *models.Discipline) {
+	scaleID, err := s.readScaleChoice(r)
+	if err != nil {
+		respondError(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err := s.store.SetDisciplineGradingScale(discipline.ID, scaleID); err != nil {
+		log.Printf("Ошибка назначения шкалы дисциплине %s: %v", discipline.ID.Hex(), err)
+		respondError(w, r, http.StatusInternalServerError, "Ошибка БД")
+		return
+	}
+	discipline.GradingScaleID = scaleID
+	respond(w, r, s.disciplineGroupURL(discipline), discipline)
+}
+
+// parseBands разбирает полосы шкалы из текста формы: по строке
+// на оценку, «порог оценка», например «80 5» или «0 Незачёт».
+func parseBands(text string) ([]models.GradeBand, error) {
+	var bands []models.GradeBand
+	for n, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		fields := strings.Fields(line)
+		min, err := strconv.Atoi(fields[0])
+		if err != nil || len(fields) < 2 {
+			return nil, fmt.Errorf("строка %d: ожидается «порог оценка»", n+1)
+		}
+		bands = append(bands, models.GradeBand{Min: min, Label: strings.Join(fields[1:], " ")})
+	}
+	return bands, nil
+}
+
+// Страница шкал оценок
+func (s *Server) GradingScalesHandler(w http.ResponseWriter, r *http.Request) {
+	scales, err := s.store.GetGradingScales()
+	if err != nil {
+		log.Printf("Ошибка получения шкал: %v", err)
+		http.Error(w, "Ошибка БД", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	tmpl := `
+<!DOCTYPE html>
+<html>
+<head>
+	<title>Шкалы оценок</title>
+	<link rel="stylesheet" href="/static/style.css">
+</head>
+<body>
+	<div class="card">
+		<h1>Шкалы оценок</h1>
+
+		{{range .}}
+		<div class="scale-row">
+			<h3>{{.Name}}</h3>
+			<div class="scale-bands">
+			{{range .Bands}}
+				<span class="{{.Class}}">{{.Label}} <small>от {{.Min}}</small></span>
+			{{end}}
+			</div>
+			{{if not .Key}}
+			<form action="/api/grading-scales/{{.ID.Hex}}/delete" method="POST" onsubmit="return confirm('Удалить шкалу? Группы и дисциплины с ней вернутся к шкале по умолчанию.')">
+				<button type="submit" class="small-btn danger">Удалить</button>
+			</form>
+			{{end}}
+		</div>
+		{{end}}
+
+		<h2>Новая шкала</h2>
+		<form action="/api/grading-scales" method="POST">
+			<input type="text" name="name" placeholder="Название шкалы" required>
+			<textarea name="bands" rows="5" placeholder="По строке на оценку: порог и оценка, например&#10;85 Отлично&#10;70 Хорошо&#10;50 Удовлетворительно&#10;0 Неудовлетворительно" required></textarea>
+			<input type="submit" value="Создать шкалу">
+		</form>
+
+		<a href="/" class="back-link">← Назад</a>
+	</div>
+</body>
+</html>`
+
+	t := template.Must(template.New("scales").Parse(tmpl))
+	t.Execute(w, scales)
+}
+
+// GradingScalesAPIHandler — шкалы оценок:
+//
+//	GET  /api/grading-scales                    — все шкалы
+//	POST /api/grading-scales                    — создать (name, bands)
+//	GET  /api/grading-scales/{id}               — шкала
+//	GET  /api/grading-scales/{id}/grade?score=N — оценка за балл
+//	POST /api/grading-scales/{id}/delete        — удалить (кроме встроенных)
+func (s *Server) GradingScalesAPIHandler(w http.ResponseWriter, r *http.Request) {
+	rest := strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/grading-scales"), "/")
+
+	if rest == "" {
+		switch r.Method {
+		case http.MethodGet:
+			scales, err := s.store.GetGradingScales()
+			if err != nil {
+				log.Printf("Ошибка получения шкал: %v", err)
+				respondError(w, r, http.StatusInternalServerError, "Ошибка БД")
+				return
+			}
+			if scales == nil {
+				scales = []models.GradingScale{}
+			}
+			writeJSON(w, http.StatusOK, scales)
+		case http.MethodPost:
+			s.createGradingScale(w, r)
+		default:
+			http.NotFound(w, r)
+		}
+		return
+	}
+
+	parts := strings.Split(rest, "/")
+	scaleID, err := parseObjectID(parts[0])
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	scale, err := s.store.GetGradingScaleByID(scaleID)
+	if isNotFound(err) {
+		respondError(w, r, http.StatusNotFound, "Шкала не найдена")
+		return
+	}
+	if err != nil {
+		log.Printf("Ошибка получения шкалы: %v", err)
+		respondError(w, r, http.StatusInternalServerError, "Ошибка БД")
+		return
+	}
+
+	switch {
+	case len(parts) == 1 && r.Method == http.MethodGet:
+		writeJSON(w, http.StatusOK, scale)
+	case len(parts) == 2 && parts[1] == "grade" && r.Method == http.MethodGet:
+		score, err := strconv.Atoi(r.URL.Query().Get("score"))
+		if err != nil || score < 0 || score > 100 {
+			respondError(w, r, http.StatusBadRequest, "Балл должен быть от 0 до 100")
+			return
+		}
+		writeJSON(w, http.StatusOK, scale.Grade(score))
+	case len(parts) == 2 && parts[1] == "delete" && r.Method == http.MethodPost:
+		if scale.Key != "" {
+			respondError(w, r, http.StatusBadRequest, "Встроенную шкалу удалить нельзя")
+			return
+		}
+		if err := s.store.DeleteGradingScale(scale.ID); err != nil {
+			log.Printf("Ошибка удаления шкалы %s: %v", scale.ID.Hex(), err)
+			respondError(w, r, http.StatusInternalServerError, "Ошибка БД")
+			return
+		}
+		respond(w, r, "/scales", map[string]string{"status": "deleted"})
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (s *Server) createGradingScale(w http.ResponseWriter, r *http.Request) {
+	var scale models.GradingScale
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "application/json") {
+		var in struct {
+			Name  string             `json:"name"`
+			Bands []models.GradeBand `json:"bands"`
+		}
+		if err := readInput(r, &in); err != nil {
+			respondError(w, r, http.StatusBadRequest, "Некорректные данные")
+			return
+		}
+		scale = models.GradingScale{Name: in.Name, Bands: in.Bands}
+	} else {
+		bands, err := parseBands(r.FormValue("bands"))
+		if err != nil {
+			respondError(w, r, http.StatusBadRequest, err.Error())
+			return
+		}
+		scale = models.GradingScale{Name: r.FormValue("name"), Bands: bands}
+	}
+
+	if err := scale.Validate(); err != nil {
+		respondError(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err := s.store.CreateGradingScale(&scale); err != nil {
+		log.Printf("Ошибка создания шкалы: %v", err)
+		respondError(w, r, http.StatusInternalServerError, "Ошибка БД")
+		return
+	}
+	respond(w, r, "/scales", scale)
+}
@@ -0,0 +1,162 @@
+// handlers/groups.go
+package handlers
+
+import (
+	"electronic-diary/db"
+	"electronic-diary/models"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+)
+
+// lookupGroup находит группу по ObjectID или по slug.
+func (s *Server) lookupGroup(key string) (*models.Group, error) {
+	if id, err := parseObjectID(key); err == nil {
+		return s.store.GetGroupByID(id)
+	}
+	return s.store.GetGroupBySlug(key)
+}
+
+// groupSlugConflict — две группы с похожими названиями сохраняли
+// одновременно, и адрес достался другой.
+const groupSlugConflict = "Группу с таким адресом только что сохранил другой пользователь — попробуйте ещё раз"
+
+// groupURL — адрес страницы группы.
+func groupURL(g *models.Group) string {
+	if g.Slug != "" {
+		return "/group/" + g.Slug
+	}
+	return "/group/" + g.ID.Hex()
+}
+
+// GroupsAPIHandler — управление группами:
+//
+//	GET  /api/groups              — список групп (?archived=1 — вместе с архивом)
+//	POST /api/groups              — создать группу
+//	POST /api/groups/{id}/rename  — переименовать
+//	POST /api/groups/{id}/archive — отправить в архив
+//	POST /api/groups/{id}/restore — вернуть из архива
+//	POST /api/groups/{id}/scale   — назначить шкалу оценок (scaleId, пусто — по умолчанию)
+func (s *Server) GroupsAPIHandler(w http.ResponseWriter, r *http.Request) {
+	rest := strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/groups"), "/")
+
+	if rest == "" {
+		switch r.Method {
+		case http.MethodGet:
+			groups, err := s.store.GetGroups(r.URL.Query().Get("archived") == "1")
+			if err != nil {
+				log.Printf("Ошибка получения групп: %v", err)
+				respondError(w, r, http.StatusInternalServerError, "Ошибка БД")
+				return
+			}
+			writeJSON(w, http.StatusOK, groups)
+		case http.MethodPost:
+			s.createGroup(w, r)
+		default:
+			http.NotFound(w, r)
+		}
+		return
+	}
+
+	parts := strings.Split(rest, "/")
+	if len(parts) != 2 || r.Method != http.MethodPost {
+		http.NotFound(w, r)
+		return
+	}
+	groupID, err := parseObjectID(parts[0])
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	group, err := s.store.GetGroupByID(groupID)
+	if err != nil {
+		respondError(w, r, http.StatusNotFound, "Группа не найдена")
+		return
+	}
+
+	switch parts[1] {
+	case "rename":
+		s.renameGroup(w, r, group)
+	case "archive":
+		s.setGroupArchived(w, r, group, true)
+	case "restore":
+		s.setGroupArchived(w, r, group, false)
+	case "scale":
+		s.setGroupScale(w, r, group)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (s *Server) createGroup(w http.ResponseWriter, r *http.Request) {
+	var in struct {
+		Name string `json:"name"`
+	}
+	if err := readInput(r, &in); err != nil {
+		respondError(w, r, http.StatusBadRequest, "Некорректные данные")
+		return
+	}
+
+	group, err := s.store.CreateGroup(in.Name)
+	if errors.Is(err, db.ErrEmptyName) {
+		respondError(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+	if errors.Is(err, db.ErrConflict) {
+		respondError(w, r, http.StatusConflict, groupSlugConflict)
+		return
+	}
+	if err != nil {
+		log.Printf("Ошибка создания группы: %v", err)
+		respondError(w, r, http.StatusInternalServerError, "Ошибка БД")
+		return
+	}
+	respond(w, r, groupURL(group), group)
+}
+
+func (s *Server) renameGroup(w http.ResponseWriter, r *http.Request, group *models.Group) {
+	var in struct {
+		Name string `json:"name"`
+	}
+	if err := readInput(r, &in); err != nil {
+		respondError(w, r, http.StatusBadRequest, "Некорректные данные")
+		return
+	}
+
+	renamed, err := s.store.RenameGroup(group.ID, in.Name)
+	if errors.Is(err, db.ErrEmptyName) {
+		respondError(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+	if errors.Is(err, db.ErrConflict) {
+		respondError(w, r, http.StatusConflict, groupSlugConflict)
+		return
+	}
+	if err != nil {
+		log.Printf("Ошибка переименования группы %s: %v", group.ID.Hex(), err)
+		respondError(w, r, http.StatusInternalServerError, "Ошибка БД")
+		return
+	}
+	respond(w, r, groupURL(renamed), renamed)
+}
+
+func (s *Server) setGroupArchived(w http.ResponseWriter, r *http.Request, group *models.Group, archived bool) {
+	if err := s.store.SetGroupArchived(group.ID, archived); err != nil {
+		log.Printf("Ошибка архивации группы %s: %v", group.ID.Hex(), err)
+		respondError(w, r, http.StatusInternalServerError, "Ошибка БД")
+		return
+	}
+	group.Archived = archived
+
+	redirect := "/"
+	if !archived {
+		redirect = groupURL(group)
+	}
+	respond(w, r, redirect, group)
+}
+
+// isNotFound — документ не найден в хранилище.
+func isNotFound(err error) bool {
+	return errors.Is(err, db.ErrNotFound)
+}
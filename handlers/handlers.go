@@ -2,21 +2,30 @@
 package handlers
 
 import (
-	"context"
 	"electronic-diary/db"
 	"electronic-diary/models"
-	"encoding/json"
+	"electronic-diary/studentstats"
+	"errors"
 	"fmt"
 	"html/template"
 	"log"
+	"math"
 	"net/http"
+	"net/url"
 	"strconv"
 	"strings"
-	"go.mongodb.org/mongo-driver/mongo"
-	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
+// Server — HTTP-обработчики дневника поверх выбранного хранилища.
+type Server struct {
+	store db.Store
+}
+
+func NewServer(store db.Store) *Server {
+	return &Server{store: store}
+}
+
 // Утилита: преобразовать строку в ObjectID
 func parseObjectID(s string) (primitive.ObjectID, error) {
 	if !primitive.IsValidObjectID(s) {
@@ -25,12 +34,36 @@ func parseObjectID(s string) (primitive.ObjectID, error) {
 	return primitive.ObjectIDFromHex(s)
 }
 
-// Главная страница
-func HomeHandler(w http.ResponseWriter, r *http.Request) {
+// Главная страница — список групп
+func (s *Server) HomeHandler(w http.ResponseWriter, r *http.Request) {
 	if r.URL.Path != "/" {
 		http.NotFound(w, r)
 		return
 	}
+
+	// Студенты и родители работают в портале
+	user := currentUser(r)
+	if !user.Role.Staff() {
+		http.Redirect(w, r, "/portal", http.StatusSeeOther)
+		return
+	}
+
+	groups, err := s.store.GetGroups(true)
+	if err != nil {
+		log.Printf("Ошибка получения групп: %v", err)
+		http.Error(w, "Ошибка БД", http.StatusInternalServerError)
+		return
+	}
+
+	var active, archived []models.Group
+	for _, g := range groups {
+		if g.Archived {
+			archived = append(archived, g)
+		} else {
+			active = append(active, g)
+		}
+	}
+
 	w.Header().Set("Content-Type", "text/html; charset=utf-8")
 	tmpl := `
 <!DOCTYPE html>
@@ -41,41 +74,111 @@ func HomeHandler(w http.ResponseWriter, r *http.Request) {
 </head>
 <body>
 	<div class="card">
+		{{template "userbar" .User}}
 		<div class="home-header">
 			<h1>Электронный дневник</h1>
 		</div>
 		<div class="home-buttons">
-			<a href="/group/Backend" class="main-group-btn"><button>Backend</button></a>
-			<a href="/group/Frontend" class="main-group-btn"><button>Frontend</button></a>
+		{{range .Groups}}
+			<a href="{{groupURL .}}" class="main-group-btn"><button>{{.Name}}</button></a>
+		{{else}}
+			<p>Групп пока нет{{if .IsAdmin}} — создайте первую{{end}}.</p>
+		{{end}}
 		</div>
+		{{if .IsAdmin}}
+		<form action="/api/groups" method="POST" class="inline-form">
+			<input type="text" name="name" placeholder="Название новой группы" required>
+			<input type="submit" value="Создать группу">
+		</form>
+		{{end}}
+		{{if .Archived}}
+		<details class="archived-groups">
+			<summary>Архив ({{len .Archived}})</summary>
+			{{range .Archived}}
+			<div class="archived-group">
+				<a href="{{groupURL .}}">{{.Name}}</a>
+				{{if $.IsAdmin}}
+				<form action="/api/groups/{{.ID.Hex}}/restore" method="POST">
+					<button type="submit" class="small-btn">Вернуть</button>
+				</form>
+				{{end}}
+			</div>
+			{{end}}
+		</details>
+		{{end}}
+		{{if .IsAdmin}}
 		<div class="home-reset">
-			<form action="/api/reset-dynamic" method="POST" onsubmit="return confirm('Обнулить все баллы, посещаемость и комментарии? Это нельзя отменить!')">
+			<form action="/api/reset-dynamic" method="POST" onsubmit="return confirm('Обнулить все баллы, посещаемость и комментарии? Перед сбросом сохранится снимок — сброс можно будет отменить.')">
 				<button type="submit" class="reset-btn">Сбросить данные</button>
 			</form>
+			<a href="/resets" class="journal-link">История сбросов →</a>
 		</div>
+		{{end}}
 	</div>
 </body>
 </html>`
-	w.Write([]byte(tmpl))
+
+	data := struct {
+		User     *models.User
+		IsAdmin  bool
+		Groups   []models.Group
+		Archived []models.Group
+	}{
+		User:     user,
+		IsAdmin:  user.Role == models.RoleAdmin,
+		Groups:   active,
+		Archived: archived,
+	}
+
+	t := template.Must(template.New("home").Funcs(template.FuncMap{"groupURL": groupURL}).Parse(tmpl + userBarTmpl))
+	t.Execute(w, data)
 }
 
 // Страница группы — показывает студентов
-func GroupHandler(w http.ResponseWriter, r *http.Request) {
-	groupName := strings.TrimPrefix(r.URL.Path, "/group/")
-	if groupName != "Backend" && groupName != "Frontend" {
+func (s *Server) GroupHandler(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, "/group/"), "/"), "/")
+	group, err := s.lookupGroup(parts[0])
+	if isNotFound(err) {
+		http.Error(w, "Группа не найдена", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Printf("Ошибка получения группы: %v", err)
+		http.Error(w, "Ошибка БД", http.StatusInternalServerError)
+		return
+	}
+
+	if len(parts) == 2 && parts[1] == "journal" {
+		s.journalPage(w, r, group)
+		return
+	}
+	if len(parts) == 2 && parts[1] == "grid" {
+		s.gridPage(w, r, group)
+		return
+	}
+	if len(parts) == 2 && parts[1] == "analytics" {
+		s.analyticsPage(w, r, group)
+		return
+	}
+	if len(parts) == 2 && (parts[1] == "export.csv" || parts[1] == "export.xlsx") {
+		s.exportGradebook(w, r, group, strings.TrimPrefix(parts[1], "export."))
+		return
+	}
+	if len(parts) > 1 {
 		http.NotFound(w, r)
 		return
 	}
 
-	groupID, err := db.GetGroupIDByName(groupName)
+	students, err := s.store.GetStudentsByGroupID(group.ID)
 	if err != nil {
-		http.Error(w, "Группа не найдена", http.StatusNotFound)
+		log.Printf("Ошибка получения студентов: %v", err)
+		http.Error(w, "Ошибка БД", http.StatusInternalServerError)
 		return
 	}
 
-	students, err := db.GetStudentsByGroupID(groupID)
+	disciplines, err := s.store.GetAllDisciplinesByGroupID(group.ID)
 	if err != nil {
-		log.Printf("Ошибка получения студентов: %v", err)
+		log.Printf("Ошибка получения дисциплин: %v", err)
 		http.Error(w, "Ошибка БД", http.StatusInternalServerError)
 		return
 	}
@@ -85,36 +188,233 @@ func GroupHandler(w http.ResponseWriter, r *http.Request) {
 <!DOCTYPE html>
 <html>
 <head>
-	<title>Группа {{.GroupName}}</title>
+	<title>Группа {{.Group.Name}}</title>
 	<link rel="stylesheet" href="/static/style.css">
 </head>
 <body>
 	<div class="card">
-		<h1>Группа: {{.GroupName}}</h1>
+		{{template "userbar" .User}}
+		<h1>Группа: {{.Group.Name}}</h1>
+		{{template "termbar" .Term}}
+		{{if .Group.Archived}}<p class="archived-note">Группа находится в архиве.</p>{{end}}
 		<div class="group-student-list">
 		{{range .Students}}
 			<a href="/student/{{.ID.Hex}}">{{.Name}}</a>
 		{{end}}
 		</div>
+
+		{{if .IsAdmin}}
+		<form action="/api/students" method="POST" class="inline-form">
+			<input type="hidden" name="groupId" value="{{.Group.ID.Hex}}">
+			<input type="text" name="name" placeholder="Фамилия Имя" required>
+			<input type="submit" value="Добавить студента">
+		</form>
+		{{end}}
+
+		<a href="{{groupURL .Group}}/journal" class="journal-link">Журнал посещаемости →</a>
+		<a href="{{groupURL .Group}}/grid" class="journal-link">Ведомость группы →</a>
+		<a href="{{groupURL .Group}}/analytics" class="journal-link">Аналитика группы →</a>
+		<p class="export-links">Ведомость за период «{{with .Term.Current}}{{.Name}}{{end}}»:
+			<a href="{{groupURL .Group}}/export.xlsx">Excel</a> ·
+			<a href="{{groupURL .Group}}/export.csv">CSV</a>
+		</p>
+		{{if .IsAdmin}}<a href="/import/{{.Group.ID.Hex}}" class="journal-link">Импорт из CSV или Excel →</a>{{end}}
+
+		<h2>Дисциплины</h2>
+		<div class="discipline-list">
+		{{range $i, $d := .Disciplines}}{{if not $d.Retired}}
+			<div class="discipline-row">
+				{{if not $.IsAdmin}}
+				<span class="discipline-name">{{$d.Name}}{{with $d.TeacherID}} <small>{{index $.TeacherNames (deref .)}}</small>{{end}}</span>
+				<a href="/discipline/{{$d.ID.Hex}}" class="btn small-btn">Работы</a>
+				{{else}}
+				<form action="/api/disciplines/{{$d.ID.Hex}}/rename" method="POST" class="inline-form">
+					<input type="text" name="name" value="{{$d.Name}}" required>
+					<button type="submit" class="small-btn">Переименовать</button>
+				</form>
+				<a href="/discipline/{{$d.ID.Hex}}" class="btn small-btn">Работы</a>
+				<form action="/api/disciplines/{{$d.ID.Hex}}/move" method="POST">
+					<input type="hidden" name="direction" value="up">
+					<button type="submit" class="small-btn" title="Выше">↑</button>
+				</form>
+				<form action="/api/disciplines/{{$d.ID.Hex}}/move" method="POST">
+					<input type="hidden" name="direction" value="down">
+					<button type="submit" class="small-btn" title="Ниже">↓</button>
+				</form>
+				<form action="/api/disciplines/{{$d.ID.Hex}}/retire" method="POST" onsubmit="return confirm('Вывести дисциплину из программы? Данные студентов сохранятся.')">
+					<button type="submit" class="small-btn danger">Вывести</button>
+				</form>
+				{{end}}
+			</div>
+		{{end}}{{end}}
+		</div>
+		{{if .IsAdmin}}
+		<form action="/api/disciplines" method="POST" class="inline-form">
+			<input type="hidden" name="groupId" value="{{.Group.ID.Hex}}">
+			<input type="text" name="name" placeholder="Название дисциплины" required>
+			<input type="submit" value="Добавить дисциплину">
+		</form>
+
+		<details class="group-manage">
+			<summary>Преподаватели</summary>
+			{{range $d := .Disciplines}}{{if not $d.Retired}}
+			<form action="/api/disciplines/{{$d.ID.Hex}}/teacher" method="POST">
+				<label>{{$d.Name}}:</label>
+				<select name="teacherId" onchange="this.form.submit()">
+					<option value="">Не назначен</option>
+				{{range $.Teachers}}
+					<option value="{{.ID.Hex}}"{{if and $d.TeacherID (eq .ID (deref $d.TeacherID))}} selected{{end}}>{{.Name}}</option>
+				{{end}}
+				</select>
+			</form>
+			{{end}}{{end}}
+			{{if not .Teachers}}<p>Преподавателей пока нет — создайте их на странице <a href="/users">пользователей</a>.</p>{{end}}
+		</details>
+
+		<details class="group-manage">
+			<summary>Шкалы оценок</summary>
+			<form action="/api/groups/{{.Group.ID.Hex}}/scale" method="POST">
+				<label>Шкала группы:</label>
+				<select name="scaleId" onchange="this.form.submit()">
+					<option value="">По умолчанию (пятибалльная)</option>
+				{{range .Scales}}
+					<option value="{{.ID.Hex}}"{{if and $.Group.GradingScaleID (eq .ID (deref $.Group.GradingScaleID))}} selected{{end}}>{{.Name}}</option>
+				{{end}}
+				</select>
+			</form>
+			{{range $d := .Disciplines}}{{if not $d.Retired}}
+			<form action="/api/disciplines/{{$d.ID.Hex}}/scale" method="POST">
+				<label>{{$d.Name}}:</label>
+				<select name="scaleId" onchange="this.form.submit()">
+					<option value="">Как у группы</option>
+				{{range $.Scales}}
+					<option value="{{.ID.Hex}}"{{if and $d.GradingScaleID (eq .ID (deref $d.GradingScaleID))}} selected{{end}}>{{.Name}}</option>
+				{{end}}
+				</select>
+			</form>
+			{{end}}{{end}}
+			<a href="/scales" class="journal-link">Настроить шкалы →</a>
+		</details>
+		<details class="group-manage">
+			<summary>Сброс данных</summary>
+			<form action="/api/reset-dynamic" method="POST" onsubmit="return confirm('Обнулить баллы и посещаемость (а для всей группы — и комментарии)? Перед сбросом сохранится снимок — сброс можно будет отменить.')">
+				<input type="hidden" name="groupId" value="{{.Group.ID.Hex}}">
+				<select name="disciplineId">
+					<option value="">Вся группа</option>
+				{{range .Disciplines}}
+					<option value="{{.ID.Hex}}">{{.Name}}{{if .Retired}} (выведена){{end}}</option>
+				{{end}}
+				</select>
+				<button type="submit" class="small-btn danger">Сбросить</button>
+			</form>
+			<a href="/resets" class="journal-link">История сбросов →</a>
+		</details>
+		{{if .HasRetired}}
+		<details class="archived-groups">
+			<summary>Выведенные дисциплины</summary>
+			{{range .Disciplines}}{{if .Retired}}
+			<div class="archived-group">
+				<span>{{.Name}}</span>
+				<form action="/api/disciplines/{{.ID.Hex}}/restore" method="POST">
+					<button type="submit" class="small-btn">Вернуть</button>
+				</form>
+			</div>
+			{{end}}{{end}}
+		</details>
+		{{end}}
+
+		<div class="group-manage">
+			<form action="/api/groups/{{.Group.ID.Hex}}/rename" method="POST" class="inline-form">
+				<input type="text" name="name" value="{{.Group.Name}}" required>
+				<input type="submit" value="Переименовать">
+			</form>
+			{{if .Group.Archived}}
+			<form action="/api/groups/{{.Group.ID.Hex}}/restore" method="POST">
+				<button type="submit" class="small-btn">Вернуть из архива</button>
+			</form>
+			{{else}}
+			<form action="/api/groups/{{.Group.ID.Hex}}/archive" method="POST" onsubmit="return confirm('Отправить группу в архив?')">
+				<button type="submit" class="small-btn danger">В архив</button>
+			</form>
+			{{end}}
+		</div>
+		{{end}}
 		<a href="/" class="back-link">← Назад</a>
 	</div>
 </body>
 </html>`
 
+	scales, err := s.store.GetGradingScales()
+	if err != nil {
+		log.Printf("Ошибка получения шкал: %v", err)
+		http.Error(w, "Ошибка БД", http.StatusInternalServerError)
+		return
+	}
+
+	users, err := s.store.GetUsers()
+	if err != nil {
+		log.Printf("Ошибка получения пользователей: %v", err)
+		http.Error(w, "Ошибка БД", http.StatusInternalServerError)
+		return
+	}
+	var teachers []models.User
+	teacherNames := make(map[primitive.ObjectID]string)
+	for _, u := range users {
+		if u.Role == models.RoleTeacher {
+			teachers = append(teachers, u)
+			teacherNames[u.ID] = u.Name
+		}
+	}
+
+	term, err := s.currentTerm(r)
+	if err != nil {
+		log.Printf("Ошибка получения периодов: %v", err)
+		http.Error(w, "Ошибка БД", http.StatusInternalServerError)
+		return
+	}
+
+	hasRetired := false
+	for _, d := range disciplines {
+		if d.Retired {
+			hasRetired = true
+		}
+	}
+
+	user := currentUser(r)
 	data := struct {
-		GroupName string
-		Students  []models.Student
+		User         *models.User
+		IsAdmin      bool
+		Term         termState
+		Group        *models.Group
+		Students     []models.Student
+		Disciplines  []models.Discipline
+		HasRetired   bool
+		Scales       []models.GradingScale
+		Teachers     []models.User
+		TeacherNames map[primitive.ObjectID]string
 	}{
-		GroupName: groupName,
-		Students:  students,
+		User:         user,
+		IsAdmin:      user.Role == models.RoleAdmin,
+		Term:         term,
+		Group:        group,
+		Students:     students,
+		Disciplines:  disciplines,
+		HasRetired:   hasRetired,
+		Scales:       scales,
+		Teachers:     teachers,
+		TeacherNames: teacherNames,
 	}
 
-	t := template.Must(template.New("group").Parse(tmpl))
+	funcs := template.FuncMap{
+		"groupURL": groupURL,
+		"deref":    func(id *primitive.ObjectID) primitive.ObjectID { return *id },
+	}
+	t := template.Must(template.New("group").Funcs(funcs).Parse(tmpl + userBarTmpl + termBarTmpl))
 	t.Execute(w, data)
 }
 
 // Страница студента — покажем детали
-func StudentHandler(w http.ResponseWriter, r *http.Request) {
+func (s *Server) StudentHandler(w http.ResponseWriter, r *http.Request) {
 	idStr := strings.TrimPrefix(r.URL.Path, "/student/")
 	studentID, err := parseObjectID(idStr)
 	if err != nil {
@@ -122,72 +422,81 @@ func StudentHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	student, err := db.GetStudentByID(studentID)
+	student, err := s.store.GetStudentByID(studentID)
 	if err != nil {
 		http.NotFound(w, r)
 		return
 	}
+	s.studentPage(w, r, student, nil)
+}
 
+// studentForm — отправленная форма студента, которую страница показывает
+// снова вместе с ошибками, если сохранить её нельзя.
+type studentForm struct {
+	Values url.Values
+	Errors map[string]string // имя поля формы → сообщение
+	// Failure — почему не удалось сохранить корректную форму; Status — код ответа
+	Failure string
+	Status  int
+}
+
+// studentPage рисует страницу студента; form != nil — повторный показ
+// формы с введёнными значениями и ошибками (ответ 422 или form.Status).
+func (s *Server) studentPage(w http.ResponseWriter, r *http.Request, student *models.Student, form *studentForm) {
+	studentID := student.ID
+	user := currentUser(r)
 	groupID := student.GroupID
-	disciplines, err := db.GetDisciplinesByGroupID(groupID)
+	disciplines, err := s.store.GetDisciplinesByGroupID(groupID)
 	if err != nil {
 		http.Error(w, "Ошибка дисциплин", http.StatusInternalServerError)
 		return
 	}
 
-	disciplineData, err := db.GetStudentDisciplineData(studentID)
+	term, err := s.currentTerm(r)
+	if err != nil {
+		log.Printf("Ошибка получения периодов: %v", err)
+		http.Error(w, "Ошибка БД", http.StatusInternalServerError)
+		return
+	}
+	disciplineData, err := s.store.GetStudentDisciplineData(studentID, term.ID())
 	if err != nil {
 		http.Error(w, "Ошибка данных", http.StatusInternalServerError)
 		return
 	}
 
+	// Записи по выведенным дисциплинам хранятся, но на странице не показываются
+	active := make(map[primitive.ObjectID]bool, len(disciplines))
+	for _, d := range disciplines {
+		active[d.ID] = true
+	}
+
 	dataMap := make(map[primitive.ObjectID]models.StudentDisciplineData)
 	for _, d := range disciplineData {
-		dataMap[d.DisciplineID] = d
+		if active[d.DisciplineID] {
+			dataMap[d.DisciplineID] = d
+		}
 	}
 
-	// Подготовим данные для статистики
-	var bestScore, worstScore *models.StudentDisciplineData
-	var bestAttendance, worstAttendance *models.StudentDisciplineData
-	var allData []models.StudentDisciplineData
-	for _, d := range dataMap {
-		allData = append(allData, d)
+	journaled, err := s.journaledDisciplines(groupID, term.ID())
+	if err != nil {
+		http.Error(w, "Ошибка журнала", http.StatusInternalServerError)
+		return
+	}
+	graded, err := s.gradedDisciplines(disciplines, term.ID())
+	if err != nil {
+		http.Error(w, "Ошибка работ", http.StatusInternalServerError)
+		return
 	}
 
-	if len(allData) > 0 {
-		bestScore = &allData[0]
-		worstScore = &allData[0]
-		bestAttendance = &allData[0]
-		worstAttendance = &allData[0]
-
-		for _, d := range allData {
-			if d.Score > bestScore.Score {
-				bestScore = &d
-			}
-			if d.Score < worstScore.Score {
-				worstScore = &d
-			}
-			attPerc := 0.0
-			if d.TotalClasses > 0 {
-				attPerc = float64(d.AttendedClasses) / float64(d.TotalClasses) * 100
-			}
-			bestAttPerc := 0.0
-			if bestAttendance.TotalClasses > 0 {
-				bestAttPerc = float64(bestAttendance.AttendedClasses) / float64(bestAttendance.TotalClasses) * 100
-			}
-			worstAttPerc := 0.0
-			if worstAttendance.TotalClasses > 0 {
-				worstAttPerc = float64(worstAttendance.AttendedClasses) / float64(worstAttendance.TotalClasses) * 100
-			}
-
-			if attPerc > bestAttPerc {
-				bestAttendance = &d
-			}
-			if attPerc < worstAttPerc {
-				worstAttendance = &d
-			}
+	// Статистика — по записям в порядке дисциплин, чтобы при равенстве
+	// результат не зависел от обхода карты
+	records := make([]models.StudentDisciplineData, 0, len(dataMap))
+	for _, d := range disciplines {
+		if data, ok := dataMap[d.ID]; ok {
+			records = append(records, data)
 		}
 	}
+	summary := studentstats.Compute(records)
 
 	w.Header().Set("Content-Type", "text/html; charset=utf-8")
 	tmpl := `
@@ -199,9 +508,15 @@ func StudentHandler(w http.ResponseWriter, r *http.Request) {
 </head>
 <body>
 	<div class="card">
+		{{template "userbar" .User}}
 		<h1>{{.Student.Name}}</h1>
+		{{template "termbar" .Term}}
+		{{if .Errors}}<p class="form-error">Исправьте выделенные поля — изменения не сохранены.</p>{{end}}
+		{{with .Failure}}<p class="form-error">{{.}}</p>{{end}}
 
 		<form id="save-form" method="POST" action="/api/student/{{.Student.ID.Hex}}">
+		<input type="hidden" name="version" value="{{.Version}}">
+		<input type="hidden" name="term" value="{{.Term.ID.Hex}}">
 	<div class="comment-area">
 		<label for="comments">Комментарий:</label>
 		<textarea name="comments" id="comments" placeholder="Введите комментарий...">{{.Student.Comments}}</textarea>
@@ -220,13 +535,40 @@ func StudentHandler(w http.ResponseWriter, r *http.Request) {
 			</tr>
 		</thead>
 		<tbody>
-		{{range .Disciplines}}
+		{{range $disc := .Disciplines}}
 		{{$data := index $.DataMap .ID}}
-		<tr data-disc-id="{{.ID.Hex}}">
-			<td>{{.Name}}</td>
-			<td><input type="number" name="score_{{.ID.Hex}}" class="score-input" data-disc="{{.ID.Hex}}" value="{{if ne $data.Score 0}}{{$data.Score}}{{end}}" placeholder="0" min="0" max="100"></td>
-			<td><input type="number" name="total_{{.ID.Hex}}" class="total-input" data-disc="{{.ID.Hex}}" value="{{if ne $data.TotalClasses 0}}{{$data.TotalClasses}}{{end}}" placeholder="0" min="0"></td>
-			<td><input type="number" name="attended_{{.ID.Hex}}" class="attended-input" data-disc="{{.ID.Hex}}" value="{{if ne $data.AttendedClasses 0}}{{$data.AttendedClasses}}{{end}}" placeholder="0" min="0"></td>
+		<tr data-disc-id="{{.ID.Hex}}" data-scale="{{(index $.Scales .ID).ID.Hex}}"{{if not (index $.Editable .ID)}} class="readonly-row" title="{{if $.Term.Closed}}Период закрыт{{else}}Дисциплину ведёт другой преподаватель{{end}}"{{end}}>
+			<td>
+				{{.Name}}
+				{{if index $.Editable .ID}}{{with rowVersion .ID}}<input type="hidden" name="version_{{$disc.ID.Hex}}" value="{{.}}">{{end}}{{end}}
+			</td>
+			{{if not (index $.Editable .ID)}}
+			<td><input type="number" class="score-input" data-disc="{{.ID.Hex}}" value="{{$data.Score}}" readonly></td>
+			<td><input type="number" class="total-input" data-disc="{{.ID.Hex}}" value="{{$data.TotalClasses}}" readonly></td>
+			<td><input type="number" class="attended-input" data-disc="{{.ID.Hex}}" value="{{$data.AttendedClasses}}" readonly></td>
+			{{else}}
+			{{if index $.Graded .ID}}
+			<td><input type="number" name="score_{{.ID.Hex}}" class="score-input" data-disc="{{.ID.Hex}}" value="{{$data.Score}}" readonly title="Считается по работам"></td>
+			{{else}}
+			<td>
+				<input type="number" name="score_{{.ID.Hex}}" class="score-input{{if fieldError "score" .ID}} error{{end}}" data-disc="{{.ID.Hex}}" value="{{value "score" .ID $data.Score}}" placeholder="0" min="0" max="100">
+				{{with fieldError "score" .ID}}<small class="field-error">{{.}}</small>{{end}}
+			</td>
+			{{end}}
+			{{if index $.Journaled .ID}}
+			<td><input type="number" class="total-input" data-disc="{{.ID.Hex}}" value="{{$data.TotalClasses}}" readonly title="Считается по журналу"></td>
+			<td><input type="number" class="attended-input" data-disc="{{.ID.Hex}}" value="{{$data.AttendedClasses}}" readonly title="Считается по журналу"></td>
+			{{else}}
+			<td>
+				<input type="number" name="total_{{.ID.Hex}}" class="total-input{{if fieldError "total" .ID}} error{{end}}" data-disc="{{.ID.Hex}}" value="{{value "total" .ID $data.TotalClasses}}" placeholder="0" min="0">
+				{{with fieldError "total" .ID}}<small class="field-error">{{.}}</small>{{end}}
+			</td>
+			<td>
+				<input type="number" name="attended_{{.ID.Hex}}" class="attended-input{{if fieldError "attended" .ID}} error{{end}}" data-disc="{{.ID.Hex}}" value="{{value "attended" .ID $data.AttendedClasses}}" placeholder="0" min="0">
+				{{with fieldError "attended" .ID}}<small class="field-error">{{.}}</small>{{end}}
+			</td>
+			{{end}}
+			{{end}}
 			<td class="perc-cell">
 				{{if gt $data.TotalClasses 0}}
 					{{printf "%.0f" (div (mul $data.AttendedClasses 100) $data.TotalClasses)}}
@@ -234,7 +576,8 @@ func StudentHandler(w http.ResponseWriter, r *http.Request) {
 					0
 				{{end}}%
 			</td>
-			<td class="grade-cell {{gradeClass $data.Score}}">{{scoreToGrade $data.Score}}</td>
+			{{$grade := grade (index $.Scales .ID) $data.Score}}
+			<td class="grade-cell {{$grade.Class}}">{{$grade.Label}}</td>
 		</tr>
 		{{end}}
 		</tbody>
@@ -242,33 +585,53 @@ func StudentHandler(w http.ResponseWriter, r *http.Request) {
 
 	<div class="statistics">
 		<h3>Статистика</h3>
-		{{if $.BestScore}}
-		<div class="stat-item">Лучший предмет: <strong>{{getDiscName $.Disciplines $.BestScore.DisciplineID}} ({{$.BestScore.Score}} баллов)</strong></div>
-		<div class="stat-item">Слабый предмет: <strong>{{getDiscName $.Disciplines $.WorstScore.DisciplineID}} ({{$.WorstScore.Score}} баллов)</strong></div>
-		<div class="stat-item">Лучшая посещаемость: <strong>{{getDiscName $.Disciplines $.BestAttendance.DisciplineID}} ({{calcPerc $.BestAttendance.AttendedClasses $.BestAttendance.TotalClasses}}%)</strong></div>
-		<div class="stat-item">Худшая посещаемость: <strong>{{getDiscName $.Disciplines $.WorstAttendance.DisciplineID}} ({{calcPerc $.WorstAttendance.AttendedClasses $.WorstAttendance.TotalClasses}}%)</strong></div>
+		{{with .Summary}}{{if .Count}}
+		<div class="stat-item">Средний балл: <strong>{{formatPoints (round1 .GPA)}}</strong></div>
+		{{if .HasAttendance}}<div class="stat-item">Посещаемость: <strong>{{calcPerc .AttendedClasses .TotalClasses}}% ({{.AttendedClasses}} из {{.TotalClasses}})</strong></div>{{end}}
+		<div class="stat-item">Лучший предмет: <strong>{{discNames $.Disciplines .BestScore}} ({{(index .BestScore 0).Score}} баллов)</strong></div>
+		<div class="stat-item">Слабый предмет: <strong>{{discNames $.Disciplines .WorstScore}} ({{(index .WorstScore 0).Score}} баллов)</strong></div>
+		{{if .HasAttendance}}
+		{{with index .BestAttendance 0}}<div class="stat-item">Лучшая посещаемость: <strong>{{discNames $.Disciplines $.Summary.BestAttendance}} ({{calcPerc .AttendedClasses .TotalClasses}}%)</strong></div>{{end}}
+		{{with index .WorstAttendance 0}}<div class="stat-item">Худшая посещаемость: <strong>{{discNames $.Disciplines $.Summary.WorstAttendance}} ({{calcPerc .AttendedClasses .TotalClasses}}%)</strong></div>{{end}}
+		{{end}}
+		{{end}}
 		{{end}}
 	</div>
 
 	<input type="submit" value="Сохранить">
 </form>
 
-<a href="/group/{{.GroupName}}" class="back-link">← Назад к группе</a>
+{{if .IsAdmin}}
+<details class="student-manage">
+	<summary>Управление студентом</summary>
+	<form action="/api/students/{{.Student.ID.Hex}}/update" method="POST">
+		<label for="student-name">Имя:</label>
+		<input type="text" name="name" id="student-name" value="{{.Student.Name}}" required>
+		<label for="student-group">Группа:</label>
+		<select name="groupId" id="student-group">
+		{{range .Groups}}
+			<option value="{{.ID.Hex}}"{{if eq .ID $.Student.GroupID}} selected{{end}}>{{.Name}}</option>
+		{{end}}
+		</select>
+		<input type="submit" value="Сохранить изменения">
+	</form>
+	<form action="/api/students/{{.Student.ID.Hex}}/delete" method="POST" onsubmit="return confirm('Удалить студента вместе со всеми его данными?')">
+		<button type="submit" class="small-btn danger">Удалить студента</button>
+	</form>
+</details>
+{{end}}
+
+<a href="/portal/{{.Student.ID.Hex}}" class="journal-link">Как видит студент →</a>
+<a href="{{groupURL .Group}}" class="back-link">← Назад к группе</a>
 	</div>
 
 	<script>
-		function scoreToGrade(score) {
-			score = parseInt(score);
-			if (score >= 80) return 5;
-			if (score >= 60) return 4;
-			if (score >= 40) return 3;
-			if (score >= 20) return 2;
-			return 1;
-		}
+		// Оценки за баллы 0–100 по шкалам дисциплин считает сервер
+		const gradeTables = {{.GradeTables}};
 
-		function gradeClass(score) {
-			const grade = scoreToGrade(score);
-			return 'grade-' + grade;
+		function gradeFor(row, score) {
+			const table = gradeTables[row.dataset.scale];
+			return table[Math.min(Math.max(score, 0), 100)];
 		}
 
 		function updateRow(discId) {
@@ -295,8 +658,9 @@ func StudentHandler(w http.ResponseWriter, r *http.Request) {
 			// Обновляем % и оценку
 			let perc = total > 0 ? Math.round((attended / total) * 100) : 0;
 			percCell.textContent = perc + '%';
-			gradeCell.textContent = scoreToGrade(score);
-			gradeCell.className = 'grade-cell ' + gradeClass(score);
+			const grade = gradeFor(row, score);
+			gradeCell.textContent = grade.label;
+			gradeCell.className = 'grade-cell ' + grade.class;
 
 			return isValid;
 		}
@@ -311,9 +675,6 @@ func StudentHandler(w http.ResponseWriter, r *http.Request) {
 
 		// Форма сохранения
 		document.getElementById('save-form').addEventListener('submit', function(e) {
-			const comment = document.getElementById('comments').value;
-			document.getElementById('hidden-comments').value = comment;
-
 			// Проверка валидации
 			let allValid = true;
 			document.querySelectorAll('.score-input, .attended-input, .total-input').forEach(input => {
@@ -340,23 +701,8 @@ func StudentHandler(w http.ResponseWriter, r *http.Request) {
 		"mul": func(a, b int) int {
 			return a * b
 		},
-		"scoreToGrade": func(score int) int {
-			switch {
-			case score >= 80: return 5
-			case score >= 60: return 4
-			case score >= 40: return 3
-			case score >= 20: return 2
-			default: return 1
-			}
-		},
-		"gradeClass": func(score int) string {
-			switch {
-			case score >= 80: return "grade-5"
-			case score >= 60: return "grade-4"
-			case score >= 40: return "grade-3"
-			case score >= 20: return "grade-2"
-			default: return "grade-1"
-			}
+		"grade": func(scale models.GradingScale, score int) models.GradeBand {
+			return scale.Grade(score)
 		},
 		"calcPerc": func(attended, total int) int {
 			if total == 0 {
@@ -364,51 +710,154 @@ func StudentHandler(w http.ResponseWriter, r *http.Request) {
 			}
 			return int(float64(attended) / float64(total) * 100)
 		},
-		"getDiscName": func(disciplines []models.Discipline, id primitive.ObjectID) string {
-			for _, d := range disciplines {
-				if d.ID == id {
-					return d.Name
+		"groupURL": groupURL,
+		// value — значение поля строки: введённое пользователем, если форма
+		// показывается повторно, иначе сохранённое (ноль — пустое поле)
+		"value": func(field string, id primitive.ObjectID, stored int) string {
+			if form != nil {
+				if v, ok := form.Values[field+"_"+id.Hex()]; ok {
+					return v[0]
 				}
 			}
-			return "—"
+			if stored == 0 {
+				return ""
+			}
+			return strconv.Itoa(stored)
 		},
+		// rowVersion — версия записи, которую видел пользователь; пусто,
+		// если записи ещё нет
+		"rowVersion": func(id primitive.ObjectID) string {
+			if form != nil {
+				return form.Values.Get("version_" + id.Hex())
+			}
+			if d, ok := dataMap[id]; ok {
+				return strconv.Itoa(d.Version)
+			}
+			return ""
+		},
+		"fieldError": func(field string, id primitive.ObjectID) string {
+			if form == nil {
+				return ""
+			}
+			return form.Errors[field+"_"+id.Hex()]
+		},
+		// discNames — названия дисциплин записей через запятую
+		"discNames": func(disciplines []models.Discipline, records []models.StudentDisciplineData) string {
+			names := make([]string, 0, len(records))
+			for _, d := range records {
+				name := "—"
+				for _, disc := range disciplines {
+					if disc.ID == d.DisciplineID {
+						name = disc.Name
+						break
+					}
+				}
+				names = append(names, name)
+			}
+			return strings.Join(names, ", ")
+		},
+		"formatPoints": formatPoints,
+		"round1": func(v float64) float64 {
+			return math.Round(v*10) / 10
+		},
+	}
+
+	t := template.Must(template.New("student").Funcs(funcMap).Parse(tmpl + userBarTmpl + termBarTmpl))
+
+	group, err := s.store.GetGroupByID(groupID)
+	if err != nil {
+		http.Error(w, "Группа не найдена", http.StatusInternalServerError)
+		return
 	}
 
-	t := template.Must(template.New("student").Funcs(funcMap).Parse(tmpl))
+	scales, err := s.gradingScales()
+	if err != nil {
+		http.Error(w, "Ошибка шкал оценок", http.StatusInternalServerError)
+		return
+	}
+	discScales := make(map[primitive.ObjectID]models.GradingScale, len(disciplines))
+	gradeTables := make(map[string][]models.GradeBand)
+	for _, d := range disciplines {
+		scale := resolveScale(scales, group, d)
+		discScales[d.ID] = scale
+		gradeTables[scale.ID.Hex()] = scale.Table()
+	}
 
-	groupName := "Backend"
-	if groupID == (func() primitive.ObjectID {
-		id, _ := db.GetGroupIDByName("Frontend")
-		return id
-	}()) {
-		groupName = "Frontend"
+	groups, err := s.store.GetGroups(false)
+	if err != nil {
+		http.Error(w, "Ошибка БД", http.StatusInternalServerError)
+		return
+	}
+
+	status := http.StatusOK
+	version := strconv.Itoa(student.Version)
+	var formErrors map[string]string
+	var failure string
+	if form != nil {
+		if v, ok := form.Values["version"]; ok {
+			version = v[0]
+		}
+		status = http.StatusUnprocessableEntity
+		if form.Status != 0 {
+			status = form.Status
+		}
+		formErrors, failure = form.Errors, form.Failure
+		shown := *student
+		shown.Comments = form.Values.Get("comments")
+		student = &shown
+	}
+
+	// В закрытом периоде записи только для просмотра; комментарий
+	// к периоду не привязан и по-прежнему сохраняется
+	editable := editableDisciplines(user, disciplines)
+	if term.Closed() {
+		editable = map[primitive.ObjectID]bool{}
 	}
 
 	data := struct {
-		Student           *models.Student
-		Disciplines       []models.Discipline
-		DataMap           map[primitive.ObjectID]models.StudentDisciplineData
-		GroupName         string
-		BestScore         *models.StudentDisciplineData
-		WorstScore        *models.StudentDisciplineData
-		BestAttendance    *models.StudentDisciplineData
-		WorstAttendance   *models.StudentDisciplineData
+		User        *models.User
+		IsAdmin     bool
+		Term        termState
+		Errors      map[string]string
+		Failure     string
+		Version     string
+		Student     *models.Student
+		Disciplines []models.Discipline
+		DataMap     map[primitive.ObjectID]models.StudentDisciplineData
+		Journaled   map[primitive.ObjectID]bool
+		Graded      map[primitive.ObjectID]bool
+		Editable    map[primitive.ObjectID]bool
+		Scales      map[primitive.ObjectID]models.GradingScale
+		GradeTables map[string][]models.GradeBand
+		Group       *models.Group
+		Groups      []models.Group
+		Summary     studentstats.Summary
 	}{
-		Student:           student,
-		Disciplines:       disciplines,
-		DataMap:           dataMap,
-		GroupName:         groupName,
-		BestScore:         bestScore,
-		WorstScore:        worstScore,
-		BestAttendance:    bestAttendance,
-		WorstAttendance:   worstAttendance,
+		User:        user,
+		IsAdmin:     user.Role == models.RoleAdmin,
+		Term:        term,
+		Errors:      formErrors,
+		Failure:     failure,
+		Version:     version,
+		Student:     student,
+		Disciplines: disciplines,
+		DataMap:     dataMap,
+		Journaled:   journaled,
+		Graded:      graded,
+		Editable:    editable,
+		Scales:      discScales,
+		GradeTables: gradeTables,
+		Group:       group,
+		Groups:      groups,
+		Summary:     summary,
 	}
 
+	w.WriteHeader(status)
 	t.Execute(w, data)
 }
 
 // Обработка сохранения
-func UpdateStudentHandler(w http.ResponseWriter, r *http.Request) {
+func (s *Server) UpdateStudentHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Method != "POST" {
 		http.NotFound(w, r)
 		return
@@ -421,91 +870,196 @@ func UpdateStudentHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Обновляем комментарий
-	comments := r.FormValue("comments")
-	_ = db.UpdateStudent(studentID, comments)
+	student, err := s.store.GetStudentByID(studentID)
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	term, err := s.currentTerm(r)
+	if err != nil {
+		log.Printf("Ошибка получения периодов: %v", err)
+		http.Error(w, "Ошибка БД", http.StatusInternalServerError)
+		return
+	}
+	journaled, err := s.journaledDisciplines(student.GroupID, term.ID())
+	if err != nil {
+		http.Error(w, "Ошибка журнала", http.StatusInternalServerError)
+		return
+	}
+	disciplines, err := s.store.GetDisciplinesByGroupID(student.GroupID)
+	if err != nil {
+		http.Error(w, "Ошибка дисциплин", http.StatusInternalServerError)
+		return
+	}
+	graded, err := s.gradedDisciplines(disciplines, term.ID())
+	if err != nil {
+		http.Error(w, "Ошибка работ", http.StatusInternalServerError)
+		return
+	}
 
+	// Записи меняются только по дисциплинам группы, которые ведёт
+	// пользователь; чужую строку отклоняем до того, как что-то сохранить
 	r.ParseForm()
-	ctx := context.Background()
-
-	for key, values := range r.Form {
-		if strings.HasPrefix(key, "score_") {
-			discIDHex := strings.TrimPrefix(key, "score_")
-			discID, err := parseObjectID(discIDHex)
-			if err != nil {
-				log.Printf("Некорректный ID дисциплины: %s", discIDHex)
-				continue
-			}
-
-			// Считываем score
-			scoreStr := values[0]
-				if scoreStr == "" { scoreStr = "0" }
-				score, _ := strconv.Atoi(scoreStr)
-
-			// Считываем total — НЕ из values[0]!
-			totalStr := r.FormValue("total_" + discIDHex)
-				if totalStr == "" { totalStr = "0" }
-				total, _ := strconv.Atoi(totalStr)
-
-			// Считываем attended — НЕ из values[0]!
-			attendedStr := r.FormValue("attended_" + discIDHex)
-				if attendedStr == "" { attendedStr = "0" }
-				attended, _ := strconv.Atoi(attendedStr)
-
-			// Пытаемся найти существующую запись
-			var data models.StudentDisciplineData
-			err = db.StudentDisciplineDataCol().FindOne(ctx, bson.M{
-				"studentId":    studentID,
-				"disciplineId": discID,
-			}).Decode(&data)
-
-			if err == nil {
-				// Запись найдена — обновляем
-				_ = db.UpdateDisciplineData(data.ID, score, total, attended)
-			} else if err == mongo.ErrNoDocuments {
-				// Записи нет — создаём новую
-				newData := models.StudentDisciplineData{
-					StudentID:       studentID,
-					DisciplineID:    discID,
-					Score:           score,
-					TotalClasses:    total,
-					AttendedClasses: attended,
-				}
-				_, insertErr := db.StudentDisciplineDataCol().InsertOne(ctx, newData)
-				if insertErr != nil {
-					log.Printf("Ошибка при создании записи для студента %s, дисциплины %s: %v", idStr, discIDHex, insertErr)
-				}
-			} else {
-				// Другая ошибка (например, БД недоступна)
-				log.Printf("Ошибка при поиске записи: %v", err)
-			}
+	byID := make(map[primitive.ObjectID]*models.Discipline, len(disciplines))
+	for i := range disciplines {
+		byID[disciplines[i].ID] = &disciplines[i]
+	}
+	for key := range r.Form {
+		if !strings.HasPrefix(key, "score_") {
+			continue
+		}
+		discID, err := parseObjectID(strings.TrimPrefix(key, "score_"))
+		if err != nil {
+			continue
+		}
+		discipline, ok := byID[discID]
+		if !ok {
+			http.Error(w, "Дисциплина не относится к группе студента", http.StatusBadRequest)
+			return
+		}
+		if term.Closed() {
+			respondError(w, r, http.StatusConflict, termClosedMsg)
+			return
+		}
+		if !canEdit(currentUser(r), discipline) {
+			http.Error(w, "Дисциплину «"+discipline.Name+"» ведёт другой преподаватель", http.StatusForbidden)
+			return
 		}
 	}
 
-	// Перенаправляем на страницу студента — данные загрузятся свежие из БД
-	http.Redirect(w, r, "/student/"+idStr, http.StatusSeeOther)
-}	
+	// Сначала разбираем и проверяем все строки, потом сохраняем:
+	// ошибка в любой строке не меняет ничего
+	form := &studentForm{Values: r.Form, Errors: map[string]string{}}
+	var rows, before []models.StudentDisciplineData
 
+	// Страница присылает версии, которые видел пользователь; клиент без
+	// версий (старые интеграции) перезаписывает данные как раньше
+	_, checkVersions := r.Form["version"]
+	version := student.Version
+	if checkVersions {
+		version = form.versionValue("version")
+	}
+	stale := false
+	for key := range r.Form {
+		if !strings.HasPrefix(key, "score_") {
+			continue
+		}
+		discIDHex := strings.TrimPrefix(key, "score_")
+		discID, err := parseObjectID(discIDHex)
+		if err != nil {
+			log.Printf("Некорректный ID дисциплины: %s", discIDHex)
+			continue
+		}
 
-func ResetDynamicHandler(w http.ResponseWriter, r *http.Request) {
-	if r.Method != "POST" {
-		http.NotFound(w, r)
+		data, err := s.store.GetDisciplineDataFor(studentID, discID, term.ID())
+		if isNotFound(err) {
+			data = &models.StudentDisciplineData{StudentID: studentID, DisciplineID: discID, TermID: term.ID()}
+		} else if err != nil {
+			log.Printf("Ошибка при поиске записи: %v", err)
+			http.Error(w, "Ошибка БД", http.StatusInternalServerError)
+			return
+		}
+
+		// Запись появилась или исчезла с тех пор, как открыли страницу
+		_, seen := r.Form["version_"+discIDHex]
+		if checkVersions && seen == data.ID.IsZero() {
+			stale = true
+		}
+
+		// Посещаемость дисциплин с журналом и баллы дисциплин с работами
+		// выводятся автоматически, поэтому их оставляем как есть
+		row := *data
+		if checkVersions && seen {
+			row.Version = form.versionValue("version_" + discIDHex)
+		}
+		if !graded[discID] {
+			row.Score = form.intValue("score_" + discIDHex)
+		}
+		if !journaled[discID] {
+			row.TotalClasses = form.intValue("total_" + discIDHex)
+			row.AttendedClasses = form.intValue("attended_" + discIDHex)
+		}
+		for field, msg := range row.Validate() {
+			name := rowFormFields[field] + "_" + discIDHex
+			if _, ok := form.Errors[name]; !ok {
+				form.Errors[name] = msg
+			}
+		}
+		rows = append(rows, row)
+		before = append(before, *data)
+	}
+
+	if len(form.Errors) > 0 {
+		if wantsJSON(r) {
+			apiInvalid(w, form.Errors)
+			return
+		}
+		s.studentPage(w, r, student, form)
 		return
 	}
 
-	err := db.ResetDynamicData()
+	// Комментарий и все записи сохраняются одним целым; при сбое форма
+	// возвращается пользователю с введёнными значениями
+	err = db.ErrConflict
+	if !stale {
+		audit := auditBy(r, sheetChanges(student, r.FormValue("comments"), before, rows))
+		err = s.store.SaveStudentSheet(studentID, version, r.FormValue("comments"), rows, audit)
+	}
+	if errors.Is(err, db.ErrConflict) {
+		if wantsJSON(r) {
+			respondError(w, r, http.StatusConflict, "Данные студента изменил другой пользователь — загрузите их заново")
+			return
+		}
+		s.conflictPage(w, r, studentID, term.ID(), r.Form)
+		return
+	}
 	if err != nil {
-		log.Printf("Ошибка сброса: %v", err)
-		http.Error(w, "Ошибка при сбросе", http.StatusInternalServerError)
+		form.Status, form.Failure = http.StatusInternalServerError, "Не удалось сохранить изменения — попробуйте ещё раз"
+		if isNotFound(err) {
+			form.Status, form.Failure = http.StatusNotFound, "Студент удалён, пока вы редактировали страницу"
+		} else {
+			log.Printf("Ошибка сохранения студента %s: %v", idStr, err)
+		}
+		if wantsJSON(r) {
+			respondError(w, r, form.Status, form.Failure)
+			return
+		}
+		s.studentPage(w, r, student, form)
 		return
 	}
 
-	// Возвращаем JSON-ответ (для JS) или редирект
-	if r.Header.Get("Accept") == "application/json" {
-		w.Header().Set("Content-Type", "application/json")
-		json.NewEncoder(w).Encode(map[string]string{"status": "ok"})
-	} else {
-		http.Redirect(w, r, "/", http.StatusSeeOther)
+	// Перенаправляем на страницу студента — данные загрузятся свежие из БД
+	respond(w, r, "/student/"+idStr, map[string]string{"status": "ok"})
+}
+
+// rowFormFields — поля записи и префиксы имён полей формы студента.
+var rowFormFields = map[string]string{
+	"score":           "score",
+	"totalClasses":    "total",
+	"attendedClasses": "attended",
+}
+
+// versionValue читает версию, которую видел пользователь; испорченное
+// значение не совпадёт ни с одной версией и приведёт к экрану конфликта.
+func (f *studentForm) versionValue(name string) int {
+	v, err := strconv.Atoi(f.Values.Get(name))
+	if err != nil {
+		return -1
+	}
+	return v
+}
+
+// intValue читает целое из поля формы; пустое поле — ноль,
+// нечисловое значение отмечается ошибкой поля.
+func (f *studentForm) intValue(name string) int {
+	raw := strings.TrimSpace(f.Values.Get(name))
+	if raw == "" {
+		return 0
+	}
+	n, err := strconv.Atoi(raw)
+	if err != nil {
+		f.Errors[name] = "введите целое число"
+		return 0
 	}
-	
+	return n
 }
\ No newline at end of file
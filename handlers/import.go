@@ -0,0 +1,594 @@
+// handlers/import.go
+package handlers
+
+import (
+	"bytes"
+	"electronic-diary/db"
+	"electronic-diary/models"
+	"electronic-diary/xlsx"
+	"encoding/base64"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"html/template"
+	"io"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// maxImportSize — предел размера загружаемого файла.
+const maxImportSize = 5 << 20
+
+// importFields — колонки ведомости, которые импорт переносит в записи.
+// Оценка и процент посещаемости выводятся из них и при импорте
+// пропускаются.
+var importFields = map[string]string{
+	"балл":      "score",
+	"посетил":   "attended",
+	"всего пар": "total",
+}
+
+// importColumn — колонка файла «Дисциплина: поле».
+type importColumn struct {
+	index      int
+	discipline string // ключ nameKey
+	field      string // score, total или attended
+}
+
+// importValues — значения записи из строки файла; nil — ячейка пуста
+// и значение не меняется.
+type importValues struct {
+	Score, Total, Attended *int
+}
+
+// importChange — изменение поля записи, которое внесёт импорт.
+type importChange struct {
+	Discipline string `json:"discipline"`
+	Field      string `json:"field"`
+	Old        int    `json:"old"`
+	New        int    `json:"new"`
+}
+
+// importRow — строка файла и что с ней сделает импорт.
+type importRow struct {
+	Line    int            `json:"line"`
+	Name    string         `json:"name"`
+	Status  string         `json:"status"` // new, changed, unchanged или error
+	Changes []importChange `json:"changes,omitempty"`
+	Errors  []string       `json:"errors,omitempty"`
+
+	student *models.Student // nil — студент будет создан
+	values  map[string]importValues
+}
+
+// importPlan — разбор файла: новые дисциплины и строки. Пробный прогон
+// показывает план, применение выполняет строки без ошибок. План строится
+// от текущих данных, поэтому повторный импорт того же файла ничего не
+// меняет.
+type importPlan struct {
+	NewDisciplines []string    `json:"newDisciplines"`
+	Warnings       []string    `json:"warnings,omitempty"`
+	Rows           []importRow `json:"rows"`
+
+	disciplines map[string]*models.Discipline // по nameKey; nil — будет создана
+	names       map[string]string             // nameKey → название из файла
+	order       []string                      // ключи дисциплин в порядке колонок
+}
+
+// Count — число строк со статусом status.
+func (p *importPlan) Count(status string) int {
+	n := 0
+	for _, row := range p.Rows {
+		if row.Status == status {
+			n++
+		}
+	}
+	return n
+}
+
+// nameKey — имя для сравнения: без лишних пробелов, регистра и «ё».
+func nameKey(name string) string {
+	return strings.ReplaceAll(strings.ToLower(strings.Join(strings.Fields(name), " ")), "ё", "е")
+}
+
+// readTable читает загруженный файл: XLSX узнаётся по сигнатуре ZIP,
+// остальное разбирается как CSV с разделителем «;», «,» или табуляцией;
+// апострофы, которыми выгрузка защищает текст от формул, снимаются.
+func readTable(data []byte) ([][]string, error) {
+	if bytes.HasPrefix(data, []byte("PK\x03\x04")) {
+		return xlsx.Read(bytes.NewReader(data), int64(len(data)))
+	}
+	data = bytes.TrimPrefix(data, []byte("\ufeff"))
+	first := data
+	if i := bytes.IndexByte(data, '\n'); i >= 0 {
+		first = data[:i]
+	}
+	cr := csv.NewReader(bytes.NewReader(data))
+	cr.Comma = ';'
+	for _, sep := range []rune{',', '\t'} {
+		if bytes.Count(first, []byte(string(sep))) > bytes.Count(first, []byte(string(cr.Comma))) {
+			cr.Comma = sep
+		}
+	}
+	cr.FieldsPerRecord = -1
+	cr.LazyQuotes = true
+	table, err := cr.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	for _, row := range table {
+		for i := range row {
+			row[i] = csvUncell(row[i])
+		}
+	}
+	return table, nil
+}
+
+// parseCount разбирает целое значение ячейки; Excel пишет целые и как «61.0».
+func parseCount(cell string) (int, error) {
+	v, err := strconv.ParseFloat(strings.Replace(cell, ",", ".", 1), 64)
+	if err != nil || v != math.Trunc(v) || math.Abs(v) > 1e6 {
+		return 0, errors.New("нужно целое число")
+	}
+	return int(v), nil
+}
+
+// planImport сопоставляет файл с группой: дисциплины по названию из
+// заголовков, студентов по имени, значения — с записями периода term.
+func (s *Server) planImport(group *models.Group, term termState, table [][]string) (*importPlan, error) {
+	if len(table) == 0 {
+		return nil, errors.New("файл пуст")
+	}
+	existing, err := s.store.GetAllDisciplinesByGroupID(group.ID)
+	if err != nil {
+		return nil, err
+	}
+	students, err := s.store.GetStudentsByGroupID(group.ID)
+	if err != nil {
+		return nil, err
+	}
+	journaled, err := s.journaledDisciplines(group.ID, term.ID())
+	if err != nil {
+		return nil, err
+	}
+	graded, err := s.gradedDisciplines(existing, term.ID())
+	if err != nil {
+		return nil, err
+	}
+
+	plan := &importPlan{disciplines: map[string]*models.Discipline{}, names: map[string]string{}}
+	byName := make(map[string]*models.Discipline, len(existing))
+	for i := range existing {
+		byName[nameKey(existing[i].Name)] = &existing[i]
+	}
+
+	// Заголовки: колонка «Студент» и колонки «Дисциплина: поле»
+	nameCol := -1
+	var columns []importColumn
+	for i, title := range table[0] {
+		title = strings.TrimSpace(title)
+		switch nameKey(title) {
+		case "студент", "фио", "имя":
+			if nameCol < 0 {
+				nameCol = i
+			}
+			continue
+		case "":
+			continue
+		}
+		sep := strings.LastIndex(title, ":")
+		if sep < 0 {
+			plan.Warnings = append(plan.Warnings, "Колонка «"+title+"» не распознана и пропущена")
+			continue
+		}
+		discName := strings.Join(strings.Fields(title[:sep]), " ")
+		field, ok := importFields[nameKey(title[sep+1:])]
+		if !ok || discName == "" {
+			continue
+		}
+		key := nameKey(discName)
+		if d, ok := byName[key]; ok && d.Retired {
+			plan.Warnings = append(plan.Warnings, "Дисциплина «"+d.Name+"» выведена из программы — колонка «"+title+"» пропущена")
+			continue
+		}
+		if _, seen := plan.names[key]; !seen {
+			plan.names[key] = discName
+			plan.order = append(plan.order, key)
+			plan.disciplines[key] = byName[key]
+			if byName[key] == nil {
+				plan.NewDisciplines = append(plan.NewDisciplines, discName)
+			}
+		}
+		columns = append(columns, importColumn{index: i, discipline: key, field: field})
+	}
+	if nameCol < 0 {
+		return nil, errors.New("нет колонки «Студент» с именами")
+	}
+
+	byStudent := make(map[string][]*models.Student, len(students))
+	for i := range students {
+		key := nameKey(students[i].Name)
+		byStudent[key] = append(byStudent[key], &students[i])
+	}
+	seenAt := map[string]int{}
+
+	for i, cells := range table[1:] {
+		line := i + 2
+		cell := func(col int) string {
+			if col < len(cells) {
+				return strings.TrimSpace(cells[col])
+			}
+			return ""
+		}
+		blank := true
+		for j := range cells {
+			if cell(j) != "" {
+				blank = false
+			}
+		}
+		if blank {
+			continue
+		}
+
+		row := importRow{Line: line, Name: strings.Join(strings.Fields(cell(nameCol)), " "), values: map[string]importValues{}}
+		key := nameKey(row.Name)
+		switch {
+		case key == "":
+			row.Errors = append(row.Errors, "не указано имя студента")
+		case seenAt[key] != 0:
+			row.Errors = append(row.Errors, fmt.Sprintf("студент уже встречается в строке %d", seenAt[key]))
+		case len(byStudent[key]) > 1:
+			row.Errors = append(row.Errors, "в группе несколько студентов с таким именем — заполните их вручную")
+		case len(byStudent[key]) == 1:
+			row.student = byStudent[key][0]
+		}
+		if key != "" && seenAt[key] == 0 {
+			seenAt[key] = line
+		}
+
+		for _, col := range columns {
+			raw := cell(col.index)
+			if raw == "" {
+				continue
+			}
+			v, err := parseCount(raw)
+			if err != nil {
+				row.Errors = append(row.Errors, "«"+table[0][col.index]+"»: "+err.Error())
+				continue
+			}
+			vals := row.values[col.discipline]
+			switch col.field {
+			case "score":
+				vals.Score = &v
+			case "total":
+				vals.Total = &v
+			case "attended":
+				vals.Attended = &v
+			}
+			row.values[col.discipline] = vals
+		}
+
+		for _, key := range plan.order {
+			vals, ok := row.values[key]
+			if !ok {
+				continue
+			}
+			name := plan.names[key]
+			current := &models.StudentDisciplineData{}
+			d := plan.disciplines[key]
+			if d != nil && row.student != nil {
+				current, err = s.store.GetDisciplineDataFor(row.student.ID, d.ID, term.ID())
+				if isNotFound(err) {
+					current = &models.StudentDisciplineData{}
+				} else if err != nil {
+					return nil, err
+				}
+			}
+			next := vals.apply(*current)
+			if d != nil && graded[d.ID] && next.Score != current.Score {
+				row.Errors = append(row.Errors, "«"+name+"»: балл считается по работам")
+			}
+			if d != nil && journaled[d.ID] && (next.TotalClasses != current.TotalClasses || next.AttendedClasses != current.AttendedClasses) {
+				row.Errors = append(row.Errors, "«"+name+"»: посещаемость считается по журналу")
+			}
+			for _, msg := range next.Validate() {
+				row.Errors = append(row.Errors, "«"+name+"»: "+msg)
+			}
+			row.Changes = append(row.Changes, recordDiff(name, *current, next)...)
+		}
+		if len(row.Changes) > 0 && term.Closed() {
+			row.Errors = append(row.Errors, termClosedMsg)
+		}
+
+		switch {
+		case len(row.Errors) > 0:
+			row.Status = "error"
+		case row.student == nil:
+			row.Status = "new"
+		case len(row.Changes) > 0:
+			row.Status = "changed"
+		default:
+			row.Status = "unchanged"
+		}
+		plan.Rows = append(plan.Rows, row)
+	}
+	return plan, nil
+}
+
+// apply — запись data с заполненными значениями из файла.
+func (v importValues) apply(data models.StudentDisciplineData) models.StudentDisciplineData {
+	if v.Score != nil {
+		data.Score = *v.Score
+	}
+	if v.Total != nil {
+		data.TotalClasses = *v.Total
+	}
+	if v.Attended != nil {
+		data.AttendedClasses = *v.Attended
+	}
+	return data
+}
+
+// recordDiff — изменённые поля записи дисциплины name.
+func recordDiff(name string, before, after models.StudentDisciplineData) []importChange {
+	var changes []importChange
+	if before.Score != after.Score {
+		changes = append(changes, importChange{name, "балл", before.Score, after.Score})
+	}
+	if before.TotalClasses != after.TotalClasses {
+		changes = append(changes, importChange{name, "всего пар", before.TotalClasses, after.TotalClasses})
+	}
+	if before.AttendedClasses != after.AttendedClasses {
+		changes = append(changes, importChange{name, "посетил", before.AttendedClasses, after.AttendedClasses})
+	}
+	return changes
+}
+
+// applyImport выполняет план: создаёт дисциплины и студентов, затем
+// сохраняет изменённые записи каждого студента одним SaveStudentSheet.
+// Строки с ошибками пропускаются; сбой строки записывается в её ошибки.
+func (s *Server) applyImport(r *http.Request, group *models.Group, term termState, plan *importPlan) error {
+	for _, key := range plan.order {
+		if plan.disciplines[key] != nil {
+			continue
+		}
+		created, err := s.store.CreateDiscipline(group.ID, plan.names[key])
+		if err != nil {
+			return err
+		}
+		plan.disciplines[key] = created
+	}
+
+	for i := range plan.Rows {
+		row := &plan.Rows[i]
+		if row.Status == "error" || row.Status == "unchanged" {
+			continue
+		}
+		student := row.student
+		if student == nil {
+			created, err := s.store.CreateStudent(row.Name, group.ID)
+			if err != nil {
+				return err
+			}
+			student = created
+		} else if fresh, err := s.store.GetStudentByID(student.ID); err == nil {
+			student = fresh
+		}
+
+		var before, rows []models.StudentDisciplineData
+		for _, key := range plan.order {
+			vals, ok := row.values[key]
+			if !ok {
+				continue
+			}
+			d := plan.disciplines[key]
+			data, err := s.store.GetDisciplineDataFor(student.ID, d.ID, term.ID())
+			if isNotFound(err) {
+				data = &models.StudentDisciplineData{StudentID: student.ID, DisciplineID: d.ID, TermID: term.ID()}
+			} else if err != nil {
+				return err
+			}
+			next := vals.apply(*data)
+			if next != *data {
+				before = append(before, *data)
+				rows = append(rows, next)
+			}
+		}
+		if len(rows) == 0 {
+			continue
+		}
+		audit := auditBy(r, sheetChanges(student, student.Comments, before, rows))
+		err := s.store.SaveStudentSheet(student.ID, student.Version, student.Comments, rows, audit)
+		if errors.Is(err, db.ErrConflict) {
+			row.Status, row.Errors = "error", []string{"данные студента изменились во время импорта — запустите импорт ещё раз"}
+			continue
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ImportHandler — импорт студентов, дисциплин и записей группы из CSV
+// или XLSX в формате выгрузки ведомости:
+//
+//	GET  /import/{group} — форма загрузки
+//	POST /import/{group} — пробный прогон (file) или применение (data, apply=1)
+//
+// Данные пробного прогона форма подтверждения несёт обратно в поле data,
+// так что применяется ровно тот файл, который показан в предпросмотре.
+func (s *Server) ImportHandler(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxImportSize*2)
+	group, err := s.lookupGroup(strings.Trim(strings.TrimPrefix(r.URL.Path, "/import/"), "/"))
+	if isNotFound(err) {
+		http.Error(w, "Группа не найдена", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Printf("Ошибка получения группы: %v", err)
+		http.Error(w, "Ошибка БД", http.StatusInternalServerError)
+		return
+	}
+	term, err := s.currentTerm(r)
+	if err != nil {
+		log.Printf("Ошибка получения периодов: %v", err)
+		http.Error(w, "Ошибка БД", http.StatusInternalServerError)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		s.importPage(w, r, group, term, nil, "", "")
+		return
+	case http.MethodPost:
+	default:
+		http.NotFound(w, r)
+		return
+	}
+
+	var data []byte
+	if encoded := r.FormValue("data"); encoded != "" {
+		data, err = base64.StdEncoding.DecodeString(encoded)
+	} else if file, _, ferr := r.FormFile("file"); ferr == nil {
+		defer file.Close()
+		data, err = io.ReadAll(io.LimitReader(file, maxImportSize+1))
+	} else {
+		respondError(w, r, http.StatusBadRequest, "Выберите файл CSV или XLSX")
+		return
+	}
+	if err == nil && len(data) > maxImportSize {
+		err = errors.New("файл больше 5 МБ")
+	}
+	var table [][]string
+	if err == nil {
+		table, err = readTable(data)
+	}
+	var plan *importPlan
+	if err == nil {
+		plan, err = s.planImport(group, term, table)
+	}
+	if err != nil {
+		respondError(w, r, http.StatusBadRequest, "Не удалось прочитать файл: "+err.Error())
+		return
+	}
+
+	apply := r.FormValue("apply") == "1"
+	if apply {
+		if err := s.applyImport(r, group, term, plan); err != nil {
+			log.Printf("Ошибка импорта в группу %s: %v", group.Name, err)
+			respondError(w, r, http.StatusInternalServerError, "Импорт прерван из-за ошибки БД — проверьте данные и запустите его ещё раз")
+			return
+		}
+	}
+	if wantsJSON(r) {
+		writeJSON(w, http.StatusOK, map[string]interface{}{"applied": apply, "plan": plan})
+		return
+	}
+	status := "preview"
+	if apply {
+		status = "done"
+	}
+	s.importPage(w, r, group, term, plan, base64.StdEncoding.EncodeToString(data), status)
+}
+
+// importPage рисует форму загрузки и, если plan != nil, предпросмотр
+// (status "preview") или итог импорта ("done").
+func (s *Server) importPage(w http.ResponseWriter, r *http.Request, group *models.Group, term termState, plan *importPlan, encoded, status string) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	tmpl := `
+<!DOCTYPE html>
+<html>
+<head>
+	<title>Импорт — {{.Group.Name}}</title>
+	<link rel="stylesheet" href="/static/style.css">
+</head>
+<body>
+	<div class="card">
+		{{template "userbar" .User}}
+		<h1>Импорт в группу {{.Group.Name}}</h1>
+		{{template "termbar" .Term}}
+
+		{{if .Plan}}
+		{{if eq .Status "done"}}
+		<p>Импорт выполнен: создано студентов — {{.Plan.Count "new"}}, обновлено — {{.Plan.Count "changed"}}, без изменений — {{.Plan.Count "unchanged"}}{{with .Plan.NewDisciplines}}, новых дисциплин — {{len .}}{{end}}.</p>
+		{{if .Plan.Count "error"}}<p class="form-error">Строк пропущено из-за ошибок: {{.Plan.Count "error"}}.</p>{{end}}
+		{{else}}
+		<p>Пробный прогон — ничего не сохранено. Новых студентов: {{.Plan.Count "new"}}, с изменениями: {{.Plan.Count "changed"}}, без изменений: {{.Plan.Count "unchanged"}}, с ошибками: {{.Plan.Count "error"}}.</p>
+		{{end}}
+		{{with .Plan.NewDisciplines}}<p>Новые дисциплины: {{range $i, $d := .}}{{if $i}}, {{end}}«{{$d}}»{{end}}.</p>{{end}}
+		{{range .Plan.Warnings}}<p class="archived-note">{{.}}</p>{{end}}
+
+		<table class="import-table">
+			<thead>
+				<tr><th>Строка</th><th>Студент</th><th>Результат</th><th>Изменения</th></tr>
+			</thead>
+			<tbody>
+			{{range .Plan.Rows}}
+			<tr class="import-{{.Status}}">
+				<td>{{.Line}}</td>
+				<td>{{.Name}}</td>
+				<td>{{index $.Statuses .Status}}</td>
+				<td>
+					{{range .Errors}}<div class="field-error">{{.}}</div>{{end}}
+					{{range .Changes}}<div><small>{{.Discipline}}, {{.Field}}: {{.Old}} → {{.New}}</small></div>{{end}}
+				</td>
+			</tr>
+			{{end}}
+			</tbody>
+		</table>
+
+		{{if eq .Status "preview"}}
+		<form method="POST" action="/import/{{.Group.ID.Hex}}">
+			<input type="hidden" name="data" value="{{.Data}}">
+			<input type="hidden" name="term" value="{{.Term.ID.Hex}}">
+			<input type="hidden" name="apply" value="1">
+			<input type="submit" value="Импортировать{{if .Plan.Count "error"}} без строк с ошибками{{end}}">
+		</form>
+		{{end}}
+		{{end}}
+
+		<h2>{{if .Plan}}Другой файл{{else}}Файл{{end}}</h2>
+		<p>CSV или XLSX в формате выгрузки ведомости: колонка «Студент» и колонки «Дисциплина: балл», «Дисциплина: посетил», «Дисциплина: всего пар». Студенты узнаются по имени, дисциплины — по названию; недостающие будут созданы. Пустая ячейка оставляет значение как есть. Сначала файл проверяется без сохранения.</p>
+		<form method="POST" action="/import/{{.Group.ID.Hex}}" enctype="multipart/form-data" class="inline-form">
+			<input type="hidden" name="term" value="{{.Term.ID.Hex}}">
+			<input type="file" name="file" accept=".csv,.xlsx" required>
+			<input type="submit" value="Проверить">
+		</form>
+
+		<a href="{{groupURL .Group}}" class="back-link">← Назад к группе</a>
+	</div>
+</body>
+</html>`
+
+	data := struct {
+		User     *models.User
+		Term     termState
+		Group    *models.Group
+		Plan     *importPlan
+		Data     string
+		Status   string
+		Statuses map[string]string
+	}{
+		User:   currentUser(r),
+		Term:   term,
+		Group:  group,
+		Plan:   plan,
+		Data:   encoded,
+		Status: status,
+		Statuses: map[string]string{
+			"new":       "новый студент",
+			"changed":   "изменения",
+			"unchanged": "без изменений",
+			"error":     "ошибка",
+		},
+	}
+
+	t := template.Must(template.New("import").Funcs(template.FuncMap{"groupURL": groupURL}).Parse(tmpl + userBarTmpl + termBarTmpl))
+	if err := t.Execute(w, data); err != nil {
+		log.Printf("Ошибка шаблона импорта: %v", err)
+	}
+}
@@ -0,0 +1,109 @@
+// handlers/input.go
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// wantsJSON — клиент ждёт JSON-ответ вместо редиректа на HTML-страницу.
+func wantsJSON(r *http.Request) bool {
+	return strings.Contains(r.Header.Get("Accept"), "application/json") ||
+		strings.HasPrefix(r.Header.Get("Content-Type"), "application/json")
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// respond завершает изменяющий запрос: JSON-клиент получает payload,
+// браузер — редирект на страницу redirect.
+func respond(w http.ResponseWriter, r *http.Request, redirect string, payload interface{}) {
+	if wantsJSON(r) {
+		writeJSON(w, http.StatusOK, payload)
+		return
+	}
+	http.Redirect(w, r, redirect, http.StatusSeeOther)
+}
+
+// respondError отдаёт ошибку в формате, который понимает клиент.
+func respondError(w http.ResponseWriter, r *http.Request, status int, msg string) {
+	if wantsJSON(r) {
+		writeJSON(w, status, map[string]string{"error": msg})
+		return
+	}
+	http.Error(w, msg, status)
+}
+
+// respondFieldError — 422 с ошибкой в конкретном поле запроса.
+func respondFieldError(w http.ResponseWriter, r *http.Request, field, msg string) {
+	if wantsJSON(r) {
+		writeJSON(w, http.StatusUnprocessableEntity, map[string]interface{}{
+			"error":  msg,
+			"fields": map[string]string{field: msg},
+		})
+		return
+	}
+	http.Error(w, msg, http.StatusUnprocessableEntity)
+}
+
+// readInput заполняет структуру dst из JSON-тела или из полей формы.
+// Для форм используются имена из json-тегов; поддерживаются поля
+// string, int, float64, bool и []string (несколько значений поля).
+func readInput(r *http.Request, dst interface{}) error {
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "application/json") {
+		return json.NewDecoder(r.Body).Decode(dst)
+	}
+	if err := r.ParseForm(); err != nil {
+		return err
+	}
+
+	v := reflect.ValueOf(dst).Elem()
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		name := strings.Split(t.Field(i).Tag.Get("json"), ",")[0]
+		if name == "" || name == "-" {
+			continue
+		}
+		if _, ok := r.Form[name]; !ok {
+			continue
+		}
+		raw := strings.TrimSpace(r.FormValue(name))
+		field := v.Field(i)
+		switch field.Kind() {
+		case reflect.Slice:
+			if field.Type().Elem().Kind() != reflect.String {
+				continue
+			}
+			var values []string
+			for _, value := range r.Form[name] {
+				if value = strings.TrimSpace(value); value != "" {
+					values = append(values, value)
+				}
+			}
+			field.Set(reflect.ValueOf(values))
+		case reflect.String:
+			field.SetString(raw)
+		case reflect.Int:
+			n, err := strconv.Atoi(raw)
+			if err != nil {
+				return err
+			}
+			field.SetInt(int64(n))
+		case reflect.Float64:
+			f, err := strconv.ParseFloat(strings.Replace(raw, ",", ".", 1), 64)
+			if err != nil {
+				return err
+			}
+			field.SetFloat(f)
+		case reflect.Bool:
+			field.SetBool(raw == "on" || raw == "true" || raw == "1")
+		}
+	}
+	return nil
+}
@@ -0,0 +1,530 @@
+// handlers/journal.go
+package handlers
+
+import (
+	"electronic-diary/models"
+	"encoding/json"
+	"html/template"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const dateLayout = "2006-01-02"
+
+var attendanceLabels = map[models.AttendanceStatus]string{
+	models.AttendancePresent: "Был",
+	models.AttendanceLate:    "Опоздал",
+	models.AttendanceAbsent:  "Не был",
+	models.AttendanceExcused: "Уваж. причина",
+}
+
+var journalFuncs = template.FuncMap{
+	"groupURL": groupURL,
+	"formatDate": func(t time.Time) string {
+		return t.Format("02.01.2006")
+	},
+	"attendanceLabel": func(s models.AttendanceStatus) string {
+		return attendanceLabels[s]
+	},
+	"getDiscName": func(disciplines []models.Discipline, id primitive.ObjectID) string {
+		for _, d := range disciplines {
+			if d.ID == id {
+				return d.Name
+			}
+		}
+		return "—"
+	},
+}
+
+// journaledDisciplines — дисциплины группы, по которым в периоде ведётся
+// журнал. Для них посещаемость выводится из отметок и вручную не
+// редактируется.
+func (s *Server) journaledDisciplines(groupID, termID primitive.ObjectID) (map[primitive.ObjectID]bool, error) {
+	lessons, err := s.store.GetLessonsByGroupID(groupID, termID)
+	if err != nil {
+		return nil, err
+	}
+	journaled := make(map[primitive.ObjectID]bool)
+	for _, l := range lessons {
+		journaled[l.DisciplineID] = true
+	}
+	return journaled, nil
+}
+
+// Журнал группы — список занятий и форма нового занятия
+func (s *Server) journalPage(w http.ResponseWriter, r *http.Request, group *models.Group) {
+	disciplines, err := s.store.GetAllDisciplinesByGroupID(group.ID)
+	if err != nil {
+		http.Error(w, "Ошибка дисциплин", http.StatusInternalServerError)
+		return
+	}
+	term, err := s.currentTerm(r)
+	if err != nil {
+		log.Printf("Ошибка получения периодов: %v", err)
+		http.Error(w, "Ошибка БД", http.StatusInternalServerError)
+		return
+	}
+	lessons, err := s.store.GetLessonsByGroupID(group.ID, term.ID())
+	if err != nil {
+		log.Printf("Ошибка получения занятий: %v", err)
+		http.Error(w, "Ошибка БД", http.StatusInternalServerError)
+		return
+	}
+
+	filter := r.URL.Query().Get("discipline")
+	if filter != "" {
+		var filtered []models.Lesson
+		for _, l := range lessons {
+			if l.DisciplineID.Hex() == filter {
+				filtered = append(filtered, l)
+			}
+		}
+		lessons = filtered
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	tmpl := `
+<!DOCTYPE html>
+<html>
+<head>
+	<title>Журнал — {{.Group.Name}}</title>
+	<link rel="stylesheet" href="/static/style.css">
+</head>
+<body>
+	<div class="card">
+		<h1>Журнал посещаемости: {{.Group.Name}}</h1>
+		{{template "termbar" .Term}}
+
+		{{if .CanCreate}}
+		<form action="/api/lessons" method="POST" class="inline-form">
+			<input type="hidden" name="term" value="{{.Term.ID.Hex}}">
+			<select name="disciplineId" required>
+			{{range .Disciplines}}{{if and (not .Retired) (index $.Editable .ID)}}
+				<option value="{{.ID.Hex}}"{{if eq .ID.Hex $.Filter}} selected{{end}}>{{.Name}}</option>
+			{{end}}{{end}}
+			</select>
+			<input type="date" name="date" value="{{.Today}}" required>
+			<input type="text" name="topic" placeholder="Тема занятия">
+			<input type="submit" value="Провести занятие">
+		</form>
+		{{end}}
+
+		<form method="GET" class="inline-form">
+			<select name="discipline" onchange="this.form.submit()">
+				<option value="">Все дисциплины</option>
+			{{range .Disciplines}}
+				<option value="{{.ID.Hex}}"{{if eq .ID.Hex $.Filter}} selected{{end}}>{{.Name}}</option>
+			{{end}}
+			</select>
+		</form>
+
+		<table>
+			<thead>
+				<tr>
+					<th>Дата</th>
+					<th>Дисциплина</th>
+					<th>Тема</th>
+				</tr>
+			</thead>
+			<tbody>
+			{{range .Lessons}}
+				<tr>
+					<td><a href="/lesson/{{.ID.Hex}}">{{formatDate .Date}}</a></td>
+					<td>{{getDiscName $.Disciplines .DisciplineID}}</td>
+					<td>{{.Topic}}</td>
+				</tr>
+			{{else}}
+				<tr><td colspan="3">Занятий пока нет.</td></tr>
+			{{end}}
+			</tbody>
+		</table>
+
+		<a href="{{groupURL .Group}}" class="back-link">← Назад к группе</a>
+	</div>
+</body>
+</html>`
+
+	editable := editableDisciplines(currentUser(r), disciplines)
+	canCreate := false
+	for _, d := range disciplines {
+		canCreate = canCreate || (editable[d.ID] && !d.Retired)
+	}
+	canCreate = canCreate && !term.Closed()
+
+	data := struct {
+		Term        termState
+		Group       *models.Group
+		Disciplines []models.Discipline
+		Editable    map[primitive.ObjectID]bool
+		CanCreate   bool
+		Lessons     []models.Lesson
+		Filter      string
+		Today       string
+	}{
+		Term:        term,
+		Group:       group,
+		Disciplines: disciplines,
+		Editable:    editable,
+		CanCreate:   canCreate,
+		Lessons:     lessons,
+		Filter:      filter,
+		Today:       time.Now().Format(dateLayout),
+	}
+
+	t := template.Must(template.New("journal").Funcs(journalFuncs).Parse(tmpl + termBarTmpl))
+	t.Execute(w, data)
+}
+
+// Страница занятия — отметки посещаемости всей группы
+func (s *Server) LessonHandler(w http.ResponseWriter, r *http.Request) {
+	lessonID, err := parseObjectID(strings.TrimPrefix(r.URL.Path, "/lesson/"))
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	lesson, err := s.store.GetLessonByID(lessonID)
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	group, err := s.store.GetGroupByID(lesson.GroupID)
+	if err != nil {
+		http.Error(w, "Группа не найдена", http.StatusInternalServerError)
+		return
+	}
+	discipline, err := s.store.GetDisciplineByID(lesson.DisciplineID)
+	if err != nil {
+		http.Error(w, "Дисциплина не найдена", http.StatusInternalServerError)
+		return
+	}
+	students, err := s.store.GetStudentsByGroupID(group.ID)
+	if err != nil {
+		http.Error(w, "Ошибка БД", http.StatusInternalServerError)
+		return
+	}
+	marks, err := s.store.GetAttendanceByLesson(lesson.ID)
+	if err != nil {
+		http.Error(w, "Ошибка БД", http.StatusInternalServerError)
+		return
+	}
+	term, err := s.store.GetTermByID(lesson.TermID)
+	if err != nil && !isNotFound(err) {
+		http.Error(w, "Ошибка БД", http.StatusInternalServerError)
+		return
+	}
+
+	// Неотмеченных студентов по умолчанию считаем присутствующими
+	statuses := make(map[primitive.ObjectID]models.AttendanceStatus, len(students))
+	for _, st := range students {
+		statuses[st.ID] = models.AttendancePresent
+	}
+	for _, m := range marks {
+		statuses[m.StudentID] = m.Status
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	tmpl := `
+<!DOCTYPE html>
+<html>
+<head>
+	<title>{{.Discipline.Name}} — {{formatDate .Lesson.Date}}</title>
+	<link rel="stylesheet" href="/static/style.css">
+</head>
+<body>
+	<div class="card">
+		<h1>{{.Discipline.Name}}</h1>
+		<p class="lesson-meta">{{.Group.Name}} · {{formatDate .Lesson.Date}}{{if .Lesson.Topic}} · {{.Lesson.Topic}}{{end}}</p>
+
+		{{if .Closed}}<p class="archived-note">Период «{{.Term.Name}}» закрыт — отметки доступны только для просмотра.</p>
+		{{else if not .CanEdit}}<p class="archived-note">Дисциплину ведёт другой преподаватель — отметки доступны только для просмотра.</p>{{end}}
+		<form method="POST" action="/api/lessons/{{.Lesson.ID.Hex}}/attendance">
+		<fieldset class="plain"{{if not .CanEdit}} disabled{{end}}>
+		<table class="attendance-table">
+			<thead>
+				<tr>
+					<th>Студент</th>
+					{{range $.Statuses}}<th>{{attendanceLabel .}}</th>{{end}}
+				</tr>
+			</thead>
+			<tbody>
+			{{range .Students}}
+			{{$current := index $.Marks .ID}}
+			{{$studentID := .ID.Hex}}
+				<tr>
+					<td>{{.Name}}</td>
+					{{range $.Statuses}}
+					<td><input type="radio" name="status_{{$studentID}}" value="{{.}}"{{if eq . $current}} checked{{end}}></td>
+					{{end}}
+				</tr>
+			{{end}}
+			</tbody>
+		</table>
+		{{if .CanEdit}}<input type="submit" value="Сохранить отметки">{{end}}
+		</fieldset>
+		</form>
+
+		{{if .CanEdit}}
+		<form method="POST" action="/api/lessons/{{.Lesson.ID.Hex}}/delete" onsubmit="return confirm('Удалить занятие вместе с отметками?')">
+			<button type="submit" class="small-btn danger">Удалить занятие</button>
+		</form>
+		{{end}}
+
+		<a href="{{groupURL .Group}}/journal" class="back-link">← Назад к журналу</a>
+	</div>
+</body>
+</html>`
+
+	data := struct {
+		Group      *models.Group
+		Discipline *models.Discipline
+		Lesson     *models.Lesson
+		Students   []models.Student
+		Marks      map[primitive.ObjectID]models.AttendanceStatus
+		Statuses   []models.AttendanceStatus
+		Term       *models.Term
+		Closed     bool
+		CanEdit    bool
+	}{
+		Term:       term,
+		Closed:     term.Closed,
+		CanEdit:    canEdit(currentUser(r), discipline) && !term.Closed,
+		Group:      group,
+		Discipline: discipline,
+		Lesson:     lesson,
+		Students:   students,
+		Marks:      statuses,
+		Statuses:   models.AttendanceStatuses,
+	}
+
+	t := template.Must(template.New("lesson").Funcs(journalFuncs).Parse(tmpl))
+	t.Execute(w, data)
+}
+
+// LessonsAPIHandler — журнал посещаемости:
+//
+//	GET  /api/lessons?group={id}&term={id}  — занятия группы за период (по умолчанию текущий)
+//	POST /api/lessons                       — провести занятие (disciplineId, date, topic, term)
+//	GET  /api/lessons/{id}                  — занятие с отметками
+//	POST /api/lessons/{id}/attendance       — сохранить отметки (status_{studentId} или {"marks": {...}})
+//	POST /api/lessons/{id}/delete           — удалить занятие
+func (s *Server) LessonsAPIHandler(w http.ResponseWriter, r *http.Request) {
+	rest := strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/lessons"), "/")
+
+	if rest == "" {
+		switch r.Method {
+		case http.MethodGet:
+			s.listLessons(w, r)
+		case http.MethodPost:
+			s.createLesson(w, r)
+		default:
+			http.NotFound(w, r)
+		}
+		return
+	}
+
+	parts := strings.Split(rest, "/")
+	lessonID, err := parseObjectID(parts[0])
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	lesson, err := s.store.GetLessonByID(lessonID)
+	if isNotFound(err) {
+		respondError(w, r, http.StatusNotFound, "Занятие не найдено")
+		return
+	}
+	if err != nil {
+		log.Printf("Ошибка получения занятия: %v", err)
+		respondError(w, r, http.StatusInternalServerError, "Ошибка БД")
+		return
+	}
+	if r.Method == http.MethodPost {
+		discipline, err := s.store.GetDisciplineByID(lesson.DisciplineID)
+		if err != nil {
+			respondError(w, r, http.StatusInternalServerError, "Дисциплина не найдена")
+			return
+		}
+		if !requireTeacher(w, r, discipline) || !s.requireOpenTerm(w, r, lesson.TermID) {
+			return
+		}
+	}
+
+	switch {
+	case len(parts) == 1 && r.Method == http.MethodGet:
+		marks, err := s.store.GetAttendanceByLesson(lesson.ID)
+		if err != nil {
+			respondError(w, r, http.StatusInternalServerError, "Ошибка БД")
+			return
+		}
+		if marks == nil {
+			marks = []models.AttendanceMark{}
+		}
+		writeJSON(w, http.StatusOK, map[string]interface{}{"lesson": lesson, "marks": marks})
+	case len(parts) == 2 && parts[1] == "attendance" && r.Method == http.MethodPost:
+		s.saveAttendance(w, r, lesson)
+	case len(parts) == 2 && parts[1] == "delete" && r.Method == http.MethodPost:
+		s.deleteLesson(w, r, lesson)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (s *Server) listLessons(w http.ResponseWriter, r *http.Request) {
+	groupID, err := parseObjectID(r.URL.Query().Get("group"))
+	if err != nil {
+		respondError(w, r, http.StatusBadRequest, "Укажите группу: ?group={id}")
+		return
+	}
+	term, err := s.currentTerm(r)
+	if err != nil {
+		log.Printf("Ошибка получения периодов: %v", err)
+		respondError(w, r, http.StatusInternalServerError, "Ошибка БД")
+		return
+	}
+	lessons, err := s.store.GetLessonsByGroupID(groupID, term.ID())
+	if err != nil {
+		log.Printf("Ошибка получения занятий: %v", err)
+		respondError(w, r, http.StatusInternalServerError, "Ошибка БД")
+		return
+	}
+	if lessons == nil {
+		lessons = []models.Lesson{}
+	}
+	writeJSON(w, http.StatusOK, lessons)
+}
+
+func (s *Server) createLesson(w http.ResponseWriter, r *http.Request) {
+	var in struct {
+		DisciplineID string `json:"disciplineId"`
+		Date         string `json:"date"`
+		Topic        string `json:"topic"`
+	}
+	if err := readInput(r, &in); err != nil {
+		respondError(w, r, http.StatusBadRequest, "Некорректные данные")
+		return
+	}
+	disciplineID, err := parseObjectID(in.DisciplineID)
+	if err != nil {
+		respondError(w, r, http.StatusBadRequest, "Некорректная дисциплина")
+		return
+	}
+	discipline, err := s.store.GetDisciplineByID(disciplineID)
+	if err != nil {
+		respondError(w, r, http.StatusBadRequest, "Дисциплина не найдена")
+		return
+	}
+	if !requireTeacher(w, r, discipline) {
+		return
+	}
+	date, err := time.Parse(dateLayout, in.Date)
+	if err != nil {
+		respondError(w, r, http.StatusBadRequest, "Дата должна быть в формате ГГГГ-ММ-ДД")
+		return
+	}
+	term, err := s.currentTerm(r)
+	if err != nil {
+		log.Printf("Ошибка получения периодов: %v", err)
+		respondError(w, r, http.StatusInternalServerError, "Ошибка БД")
+		return
+	}
+	if term.Closed() {
+		respondError(w, r, http.StatusConflict, termClosedMsg)
+		return
+	}
+
+	lesson := models.Lesson{
+		GroupID:      discipline.GroupID,
+		DisciplineID: discipline.ID,
+		TermID:       term.ID(),
+		Date:         date,
+		Topic:        strings.TrimSpace(in.Topic),
+	}
+	if err := s.store.CreateLesson(&lesson); err != nil {
+		log.Printf("Ошибка создания занятия: %v", err)
+		respondError(w, r, http.StatusInternalServerError, "Ошибка БД")
+		return
+	}
+	respond(w, r, "/lesson/"+lesson.ID.Hex(), lesson)
+}
+
+func (s *Server) saveAttendance(w http.ResponseWriter, r *http.Request, lesson *models.Lesson) {
+	raw := make(map[string]string)
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "application/json") {
+		var in struct {
+			Marks map[string]string `json:"marks"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+			respondError(w, r, http.StatusBadRequest, "Некорректные данные")
+			return
+		}
+		raw = in.Marks
+	} else {
+		r.ParseForm()
+		for key := range r.Form {
+			if strings.HasPrefix(key, "status_") {
+				raw[strings.TrimPrefix(key, "status_")] = r.FormValue(key)
+			}
+		}
+	}
+
+	students, err := s.store.GetStudentsByGroupID(lesson.GroupID)
+	if err != nil {
+		respondError(w, r, http.StatusInternalServerError, "Ошибка БД")
+		return
+	}
+	inGroup := make(map[primitive.ObjectID]bool, len(students))
+	for _, st := range students {
+		inGroup[st.ID] = true
+	}
+
+	marks := make(map[primitive.ObjectID]models.AttendanceStatus, len(raw))
+	for idHex, value := range raw {
+		studentID, err := parseObjectID(idHex)
+		if err != nil || !inGroup[studentID] {
+			respondError(w, r, http.StatusBadRequest, "Студент "+idHex+" не состоит в группе")
+			return
+		}
+		status := models.AttendanceStatus(value)
+		if !status.Valid() {
+			respondError(w, r, http.StatusBadRequest, "Неизвестная отметка: "+value)
+			return
+		}
+		marks[studentID] = status
+	}
+
+	before, err := s.store.GetAttendanceByLesson(lesson.ID)
+	if err != nil {
+		respondError(w, r, http.StatusInternalServerError, "Ошибка БД")
+		return
+	}
+	if err := s.store.SaveAttendance(lesson.ID, marks, auditBy(r, attendanceChanges(lesson, before, marks))); err != nil {
+		log.Printf("Ошибка сохранения отметок занятия %s: %v", lesson.ID.Hex(), err)
+		respondError(w, r, http.StatusInternalServerError, "Ошибка БД")
+		return
+	}
+	respond(w, r, "/lesson/"+lesson.ID.Hex(), map[string]string{"status": "ok"})
+}
+
+func (s *Server) deleteLesson(w http.ResponseWriter, r *http.Request, lesson *models.Lesson) {
+	before, err := s.store.GetAttendanceByLesson(lesson.ID)
+	if err != nil {
+		respondError(w, r, http.StatusInternalServerError, "Ошибка БД")
+		return
+	}
+	audit := auditBy(r, attendanceChanges(lesson, before, clearedAttendance(before)))
+	if err := s.store.DeleteLesson(lesson.ID, audit); err != nil {
+		log.Printf("Ошибка удаления занятия %s: %v", lesson.ID.Hex(), err)
+		respondError(w, r, http.StatusInternalServerError, "Ошибка БД")
+		return
+	}
+
+	redirect := "/"
+	if group, err := s.store.GetGroupByID(lesson.GroupID); err == nil {
+		redirect = groupURL(group) + "/journal"
+	}
+	respond(w, r, redirect, map[string]string{"status": "deleted"})
+}
@@ -0,0 +1,324 @@
+// handlers/openapi.go
+package handlers
+
+import (
+	_ "embed"
+	"html/template"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// openAPISpec — контракт API v1. Его сверяет с маршрутами и моделями
+// openapi_test.go, так что правка API без правки спецификации не пройдёт тесты.
+//
+//go:embed openapi.yaml
+var openAPISpec []byte
+
+// openAPIDoc — часть спецификации, которую показывает страница документации.
+type openAPIDoc struct {
+	Info struct {
+		Title       string `yaml:"title"`
+		Version     string `yaml:"version"`
+		Description string `yaml:"description"`
+	} `yaml:"info"`
+	Paths      map[string]map[string]openAPIOperation `yaml:"paths"`
+	Components struct {
+		Responses map[string]openAPIResponse `yaml:"responses"`
+		Schemas   map[string]openAPISchema   `yaml:"schemas"`
+	} `yaml:"components"`
+}
+
+type openAPIOperation struct {
+	Summary     string         `yaml:"summary"`
+	Description string         `yaml:"description"`
+	Parameters  []openAPIParam `yaml:"parameters"`
+	RequestBody *struct {
+		Content map[string]struct {
+			Schema openAPISchema `yaml:"schema"`
+		} `yaml:"content"`
+	} `yaml:"requestBody"`
+	Responses map[string]openAPIResponse `yaml:"responses"`
+}
+
+type openAPIParam struct {
+	Name        string        `yaml:"name"`
+	In          string        `yaml:"in"`
+	Required    bool          `yaml:"required"`
+	Description string        `yaml:"description"`
+	Schema      openAPISchema `yaml:"schema"`
+}
+
+type openAPIResponse struct {
+	Ref         string `yaml:"$ref"`
+	Description string `yaml:"description"`
+}
+
+type openAPISchema struct {
+	Ref         string                   `yaml:"$ref"`
+	Type        string                   `yaml:"type"`
+	Description string                   `yaml:"description"`
+	Nullable    bool                     `yaml:"nullable"`
+	Required    []string                 `yaml:"required"`
+	Properties  map[string]openAPISchema `yaml:"properties"`
+	Items       *openAPISchema           `yaml:"items"`
+}
+
+func loadOpenAPI() (*openAPIDoc, error) {
+	var doc openAPIDoc
+	if err := yaml.Unmarshal(openAPISpec, &doc); err != nil {
+		return nil, err
+	}
+	return &doc, nil
+}
+
+// refName — имя компонента из ссылки "#/components/schemas/Group".
+func refName(ref string) string {
+	return ref[strings.LastIndex(ref, "/")+1:]
+}
+
+// typeName — тип поля для таблицы: Group, []Student, string | null.
+func (sc openAPISchema) typeName() string {
+	var name string
+	switch {
+	case sc.Ref != "":
+		name = refName(sc.Ref)
+	case sc.Type == "array" && sc.Items != nil:
+		name = "[]" + sc.Items.typeName()
+	default:
+		name = sc.Type
+	}
+	if sc.Nullable {
+		name += " | null"
+	}
+	return name
+}
+
+// OpenAPIHandler отдаёт спецификацию: /api/v1/openapi.yaml как есть,
+// /api/v1/openapi.json — то же в JSON для инструментов, которым нужен он.
+func OpenAPIHandler(w http.ResponseWriter, r *http.Request) {
+	switch strings.TrimPrefix(r.URL.Path, "/api/v1/") {
+	case "openapi.yaml":
+		w.Header().Set("Content-Type", "application/yaml; charset=utf-8")
+		w.Write(openAPISpec)
+	case "openapi.json":
+		var spec map[string]interface{}
+		if err := yaml.Unmarshal(openAPISpec, &spec); err != nil {
+			log.Printf("Ошибка разбора openapi.yaml: %v", err)
+			apiFail(w, http.StatusInternalServerError, "internal", "Ошибка спецификации")
+			return
+		}
+		writeJSON(w, http.StatusOK, spec)
+	default:
+		apiFail(w, http.StatusNotFound, "not_found", "Ресурс не найден")
+	}
+}
+
+// Порядок методов на странице документации.
+var docMethods = []string{"get", "post", "patch", "delete"}
+
+type docEndpoint struct {
+	Method      string
+	Path        string
+	Summary     string
+	Description string
+	Params      []docField
+	Body        string
+	Responses   []docResponse
+}
+
+type docResponse struct {
+	Code        string
+	Description string
+}
+
+// docField — поле схемы или параметр метода (In — где передаётся параметр).
+type docField struct {
+	Name        string
+	In          string
+	Type        string
+	Required    bool
+	Description string
+}
+
+type docSchema struct {
+	Name        string
+	Description string
+	Fields      []docField
+}
+
+// APIDocsHandler — страница документации API, собранная из openapi.yaml.
+func APIDocsHandler(w http.ResponseWriter, r *http.Request) {
+	doc, err := loadOpenAPI()
+	if err != nil {
+		log.Printf("Ошибка разбора openapi.yaml: %v", err)
+		http.Error(w, "Ошибка спецификации", http.StatusInternalServerError)
+		return
+	}
+
+	paths := make([]string, 0, len(doc.Paths))
+	for p := range doc.Paths {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+
+	var endpoints []docEndpoint
+	for _, p := range paths {
+		for _, m := range docMethods {
+			op, ok := doc.Paths[p][m]
+			if !ok {
+				continue
+			}
+			e := docEndpoint{
+				Method:      strings.ToUpper(m),
+				Path:        "/api/v1" + p,
+				Summary:     op.Summary,
+				Description: op.Description,
+			}
+			for _, p := range op.Parameters {
+				e.Params = append(e.Params, docField{
+					Name:        p.Name,
+					In:          p.In,
+					Type:        p.Schema.typeName(),
+					Required:    p.Required,
+					Description: p.Description,
+				})
+			}
+			if op.RequestBody != nil {
+				for _, c := range op.RequestBody.Content {
+					e.Body = c.Schema.typeName()
+				}
+			}
+			codes := make([]string, 0, len(op.Responses))
+			for code := range op.Responses {
+				codes = append(codes, code)
+			}
+			sort.Strings(codes)
+			for _, code := range codes {
+				resp := op.Responses[code]
+				if resp.Ref != "" {
+					resp = doc.Components.Responses[refName(resp.Ref)]
+				}
+				e.Responses = append(e.Responses, docResponse{Code: code, Description: resp.Description})
+			}
+			endpoints = append(endpoints, e)
+		}
+	}
+
+	names := make([]string, 0, len(doc.Components.Schemas))
+	for name := range doc.Components.Schemas {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var schemas []docSchema
+	for _, name := range names {
+		sc := doc.Components.Schemas[name]
+		if sc.Type != "object" {
+			continue
+		}
+		required := make(map[string]bool, len(sc.Required))
+		for _, f := range sc.Required {
+			required[f] = true
+		}
+		ds := docSchema{Name: name, Description: sc.Description}
+		for field, prop := range sc.Properties {
+			ds.Fields = append(ds.Fields, docField{
+				Name:        field,
+				Type:        prop.typeName(),
+				Required:    required[field],
+				Description: prop.Description,
+			})
+		}
+		sort.Slice(ds.Fields, func(i, j int) bool { return ds.Fields[i].Name < ds.Fields[j].Name })
+		schemas = append(schemas, ds)
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	tmpl := `
+<!DOCTYPE html>
+<html>
+<head>
+	<title>{{.Info.Title}}</title>
+	<link rel="stylesheet" href="/static/style.css">
+</head>
+<body>
+	<div class="card">
+		<h1>{{.Info.Title}} <small>{{.Info.Version}}</small></h1>
+		<pre class="api-intro">{{.Info.Description}}</pre>
+		<p>
+			Спецификация: <a href="/api/v1/openapi.yaml">openapi.yaml</a>,
+			<a href="/api/v1/openapi.json">openapi.json</a>
+		</p>
+
+		<h2>Методы</h2>
+		{{range .Endpoints}}
+		<details class="api-endpoint">
+			<summary><span class="api-method api-{{.Method}}">{{.Method}}</span> <code>{{.Path}}</code> — {{.Summary}}</summary>
+			{{with .Description}}<p>{{.}}</p>{{end}}
+			{{if .Params}}
+			<table>
+				<thead><tr><th>Параметр</th><th>Где</th><th>Тип</th><th>Описание</th></tr></thead>
+				<tbody>
+				{{range .Params}}
+				<tr>
+					<td><code>{{.Name}}</code>{{if .Required}} *{{end}}</td>
+					<td>{{.In}}</td>
+					<td>{{.Type}}</td>
+					<td>{{.Description}}</td>
+				</tr>
+				{{end}}
+				</tbody>
+			</table>
+			{{end}}
+			{{with .Body}}<p>Тело запроса: <a href="#schema-{{.}}">{{.}}</a></p>{{end}}
+			<ul>
+			{{range .Responses}}
+				<li><code>{{.Code}}</code> — {{.Description}}</li>
+			{{end}}
+			</ul>
+		</details>
+		{{end}}
+
+		<h2>Схемы</h2>
+		{{range .Schemas}}
+		<h3 id="schema-{{.Name}}">{{.Name}}</h3>
+		{{with .Description}}<p>{{.}}</p>{{end}}
+		<table>
+			<thead><tr><th>Поле</th><th>Тип</th><th>Описание</th></tr></thead>
+			<tbody>
+			{{range .Fields}}
+			<tr>
+				<td><code>{{.Name}}</code>{{if .Required}} *{{end}}</td>
+				<td>{{.Type}}</td>
+				<td>{{.Description}}</td>
+			</tr>
+			{{end}}
+			</tbody>
+		</table>
+		{{end}}
+
+		<p class="archived-note">* — обязательное поле</p>
+		<a href="/" class="back-link">← Назад</a>
+	</div>
+</body>
+</html>`
+
+	data := struct {
+		Info      interface{}
+		Endpoints []docEndpoint
+		Schemas   []docSchema
+	}{
+		Info:      doc.Info,
+		Endpoints: endpoints,
+		Schemas:   schemas,
+	}
+
+	t := template.Must(template.New("api-docs").Parse(tmpl))
+	if err := t.Execute(w, data); err != nil {
+		log.Printf("Ошибка шаблона документации API: %v", err)
+	}
+}
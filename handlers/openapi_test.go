@@ -0,0 +1,200 @@
+// handlers/openapi_test.go
+package handlers
+
+import (
+	"context"
+	"electronic-diary/db"
+	"electronic-diary/models"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"gopkg.in/yaml.v3"
+)
+
+// TestOpenAPIRoutes — каждый ресурс API v1 описан в спецификации теми же
+// методами, что он обслуживает, и в спецификации нет лишних путей.
+// Методы не берутся из таблиц, а выясняются запросами к обработчикам.
+func TestOpenAPIRoutes(t *testing.T) {
+	doc, err := loadOpenAPI()
+	if err != nil {
+		t.Fatalf("openapi.yaml не разбирается: %v", err)
+	}
+
+	s := NewServer(db.NewMemoryStore())
+	id := primitive.NewObjectID()
+	want := map[string][]string{}
+	for resource, handler := range s.v1Resources() {
+		want["/"+resource] = servedMethods(t, "/"+resource, handler, nil)
+		want["/"+resource+"/{id}"] = servedMethods(t, "/"+resource+"/{id}", handler, &id)
+	}
+
+	for path, methods := range want {
+		ops, ok := doc.Paths[path]
+		if !ok {
+			t.Errorf("путь %s не описан в openapi.yaml", path)
+			continue
+		}
+		var got []string
+		for m := range ops {
+			got = append(got, strings.ToUpper(m))
+		}
+		if !sameSet(got, methods) {
+			t.Errorf("%s: в спецификации методы %v, обслуживаются %v", path, got, methods)
+		}
+	}
+	for path := range doc.Paths {
+		if _, ok := want[path]; !ok {
+			t.Errorf("путь %s описан в openapi.yaml, но не обслуживается", path)
+		}
+	}
+}
+
+// TestOpenAPISchemas — схемы моделей перечисляют ровно их JSON-поля.
+func TestOpenAPISchemas(t *testing.T) {
+	doc, err := loadOpenAPI()
+	if err != nil {
+		t.Fatalf("openapi.yaml не разбирается: %v", err)
+	}
+
+	tests := []struct {
+		schema string
+		model  interface{}
+	}{
+		{"Group", models.Group{}},
+		{"Student", models.Student{}},
+		{"Discipline", models.Discipline{}},
+		{"StudentDisciplineData", models.StudentDisciplineData{}},
+		{"Pagination", pagination{}},
+	}
+	for _, tt := range tests {
+		sc, ok := doc.Components.Schemas[tt.schema]
+		if !ok {
+			t.Errorf("схема %s не описана", tt.schema)
+			continue
+		}
+		var props []string
+		for name := range sc.Properties {
+			props = append(props, name)
+		}
+		fields := jsonFields(reflect.TypeOf(tt.model))
+		if !sameSet(props, fields) {
+			t.Errorf("схема %s: поля %v, у модели %v", tt.schema, props, fields)
+		}
+		for _, name := range sc.Required {
+			if _, ok := sc.Properties[name]; !ok {
+				t.Errorf("схема %s: обязательное поле %s не описано", tt.schema, name)
+			}
+		}
+	}
+}
+
+// TestOpenAPIRefs — все ссылки $ref ведут на существующие компоненты.
+func TestOpenAPIRefs(t *testing.T) {
+	var spec map[string]interface{}
+	if err := yaml.Unmarshal(openAPISpec, &spec); err != nil {
+		t.Fatalf("openapi.yaml не разбирается: %v", err)
+	}
+
+	var walk func(v interface{})
+	walk = func(v interface{}) {
+		switch v := v.(type) {
+		case map[string]interface{}:
+			if ref, ok := v["$ref"].(string); ok && !resolves(spec, ref) {
+				t.Errorf("ссылка %s никуда не ведёт", ref)
+			}
+			for _, child := range v {
+				walk(child)
+			}
+		case []interface{}:
+			for _, child := range v {
+				walk(child)
+			}
+		}
+	}
+	walk(spec)
+}
+
+func TestAPIDocsPage(t *testing.T) {
+	rec := httptest.NewRecorder()
+	APIDocsHandler(rec, httptest.NewRequest(http.MethodGet, "/api/docs", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("страница документации: код %d", rec.Code)
+	}
+	for _, want := range []string{"/api/v1/records/{id}", "StudentDisciplineData"} {
+		if !strings.Contains(rec.Body.String(), want) {
+			t.Errorf("на странице документации нет %q", want)
+		}
+	}
+}
+
+// servedMethods отправляет обработчику ресурса запрос каждым методом от
+// имени администратора и возвращает методы, на которые он не ответил 405.
+// Заголовок Allow ответа 405 должен перечислять те же методы.
+func servedMethods(t *testing.T, path string, handler func(http.ResponseWriter, *http.Request, *primitive.ObjectID), id *primitive.ObjectID) []string {
+	admin := &models.User{Login: "admin", Role: models.RoleAdmin}
+	var served []string
+	var allow string
+	for _, method := range []string{
+		http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut,
+		http.MethodPatch, http.MethodDelete, http.MethodOptions,
+	} {
+		r := httptest.NewRequest(method, "/api/v1"+path, strings.NewReader("{}"))
+		r.Header.Set("Content-Type", "application/json")
+		r = r.WithContext(context.WithValue(r.Context(), userKey{}, admin))
+		rec := httptest.NewRecorder()
+		handler(rec, r, id)
+		if rec.Code == http.StatusMethodNotAllowed {
+			allow = rec.Header().Get("Allow")
+			continue
+		}
+		served = append(served, method)
+	}
+	if got := strings.Split(allow, ", "); !sameSet(got, served) {
+		t.Errorf("%s: Allow %q, обслуживаются %v", path, allow, served)
+	}
+	return served
+}
+
+// jsonFields — имена полей структуры в JSON.
+func jsonFields(t reflect.Type) []string {
+	var names []string
+	for i := 0; i < t.NumField(); i++ {
+		name := strings.Split(t.Field(i).Tag.Get("json"), ",")[0]
+		if name == "" || name == "-" {
+			continue
+		}
+		names = append(names, name)
+	}
+	return names
+}
+
+func sameSet(a, b []string) bool {
+	a = append([]string(nil), a...)
+	b = append([]string(nil), b...)
+	sort.Strings(a)
+	sort.Strings(b)
+	return reflect.DeepEqual(a, b)
+}
+
+// resolves проверяет локальную ссылку вида "#/components/schemas/Group".
+func resolves(spec map[string]interface{}, ref string) bool {
+	if !strings.HasPrefix(ref, "#/") {
+		return false
+	}
+	var node interface{} = spec
+	for _, key := range strings.Split(strings.TrimPrefix(ref, "#/"), "/") {
+		m, ok := node.(map[string]interface{})
+		if !ok {
+			return false
+		}
+		if node, ok = m[key]; !ok {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,425 @@
+// handlers/portal.go
+package handlers
+
+import (
+	"electronic-diary/models"
+	"fmt"
+	"html/template"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// portalRow — строка дисциплины в портале.
+type portalRow struct {
+	Discipline models.Discipline
+	Teacher    string
+	Data       models.StudentDisciplineData
+	Grade      models.GradeBand
+	Results    []portalResult
+	Sparkline  string
+	Trend      string
+}
+
+// portalResult — результат работы в процентах от максимума.
+type portalResult struct {
+	Assessment models.Assessment
+	Discipline string
+	Points     float64
+	Percent    int
+}
+
+// portalMonth — посещаемость за месяц по всем дисциплинам с журналом.
+type portalMonth struct {
+	Month    time.Time
+	Total    int
+	Attended int
+}
+
+func (m portalMonth) Percent() int {
+	if m.Total == 0 {
+		return 0
+	}
+	return m.Attended * 100 / m.Total
+}
+
+var monthNames = [...]string{"январь", "февраль", "март", "апрель", "май", "июнь",
+	"июль", "август", "сентябрь", "октябрь", "ноябрь", "декабрь"}
+
+// trendArrow сравнивает последний результат с предыдущим.
+func trendArrow(results []portalResult) string {
+	if len(results) < 2 {
+		return ""
+	}
+	last, prev := results[len(results)-1].Percent, results[len(results)-2].Percent
+	switch {
+	case last > prev:
+		return "↑"
+	case last < prev:
+		return "↓"
+	}
+	return "→"
+}
+
+// sparkline — точки ломаной для SVG 100×30 по процентам результатов.
+func sparkline(results []portalResult) string {
+	if len(results) < 2 {
+		return ""
+	}
+	points := make([]string, len(results))
+	step := 100.0 / float64(len(results)-1)
+	for i, res := range results {
+		points[i] = fmt.Sprintf("%.1f,%.1f", float64(i)*step, 30-float64(res.Percent)*0.3)
+	}
+	return strings.Join(points, " ")
+}
+
+// PortalHandler — портал студента и родителя, только просмотр:
+//
+//	/portal      — связанные студенты (единственного открываем сразу)
+//	/portal/{id} — баллы, оценки, посещаемость, комментарий и динамика
+//
+// Студент и родитель видят только связанных с ними студентов; сотрудники —
+// любого, чтобы видеть страницу так же, как её видит семья.
+func (s *Server) PortalHandler(w http.ResponseWriter, r *http.Request) {
+	user := currentUser(r)
+	rest := strings.Trim(strings.TrimPrefix(r.URL.Path, "/portal"), "/")
+	if rest == "" {
+		if user.Role.Staff() {
+			http.Redirect(w, r, "/", http.StatusSeeOther)
+			return
+		}
+		s.portalIndex(w, r, user)
+		return
+	}
+
+	studentID, err := parseObjectID(rest)
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	if !user.Role.Staff() && !user.LinkedTo(studentID) {
+		http.Error(w, "Недостаточно прав", http.StatusForbidden)
+		return
+	}
+	student, err := s.store.GetStudentByID(studentID)
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	s.portalPage(w, r, user, student)
+}
+
+func (s *Server) portalIndex(w http.ResponseWriter, r *http.Request, user *models.User) {
+	if len(user.StudentIDs) == 1 {
+		http.Redirect(w, r, "/portal/"+user.StudentIDs[0].Hex(), http.StatusSeeOther)
+		return
+	}
+
+	var students []models.Student
+	for _, id := range user.StudentIDs {
+		student, err := s.store.GetStudentByID(id)
+		if isNotFound(err) {
+			continue
+		}
+		if err != nil {
+			log.Printf("Ошибка получения студента: %v", err)
+			http.Error(w, "Ошибка БД", http.StatusInternalServerError)
+			return
+		}
+		students = append(students, *student)
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	tmpl := `
+<!DOCTYPE html>
+<html>
+<head>
+	<title>Электронный дневник</title>
+	<link rel="stylesheet" href="/static/style.css">
+</head>
+<body>
+	<div class="card">
+		{{template "userbar" .User}}
+		<div class="home-header">
+			<h1>Электронный дневник</h1>
+		</div>
+		<div class="home-buttons">
+		{{range .Students}}
+			<a href="/portal/{{.ID.Hex}}" class="main-group-btn"><button>{{.Name}}</button></a>
+		{{else}}
+			<p>С вашей учётной записью пока не связан ни один студент — обратитесь к администратору.</p>
+		{{end}}
+		</div>
+	</div>
+</body>
+</html>`
+
+	data := struct {
+		User     *models.User
+		Students []models.Student
+	}{user, students}
+
+	t := template.Must(template.New("portal-index").Parse(tmpl + userBarTmpl))
+	t.Execute(w, data)
+}
+
+func (s *Server) portalPage(w http.ResponseWriter, r *http.Request, user *models.User, student *models.Student) {
+	group, err := s.store.GetGroupByID(student.GroupID)
+	if err != nil {
+		http.Error(w, "Группа не найдена", http.StatusInternalServerError)
+		return
+	}
+	disciplines, err := s.store.GetDisciplinesByGroupID(group.ID)
+	if err != nil {
+		http.Error(w, "Ошибка дисциплин", http.StatusInternalServerError)
+		return
+	}
+	term, err := s.currentTerm(r)
+	if err != nil {
+		log.Printf("Ошибка получения периодов: %v", err)
+		http.Error(w, "Ошибка БД", http.StatusInternalServerError)
+		return
+	}
+	records, err := s.store.GetStudentDisciplineData(student.ID, term.ID())
+	if err != nil {
+		http.Error(w, "Ошибка данных", http.StatusInternalServerError)
+		return
+	}
+	scales, err := s.gradingScales()
+	if err != nil {
+		http.Error(w, "Ошибка шкал оценок", http.StatusInternalServerError)
+		return
+	}
+	users, err := s.store.GetUsers()
+	if err != nil {
+		http.Error(w, "Ошибка БД", http.StatusInternalServerError)
+		return
+	}
+	marks, err := s.store.GetMarksByStudent(student.ID)
+	if err != nil {
+		http.Error(w, "Ошибка БД", http.StatusInternalServerError)
+		return
+	}
+
+	teachers := make(map[primitive.ObjectID]string)
+	for _, u := range users {
+		teachers[u.ID] = u.Name
+	}
+	byDiscipline := make(map[primitive.ObjectID]models.StudentDisciplineData, len(records))
+	for _, d := range records {
+		byDiscipline[d.DisciplineID] = d
+	}
+	points := make(map[primitive.ObjectID]float64, len(marks))
+	for _, m := range marks {
+		points[m.AssessmentID] = m.Points
+	}
+
+	var rows []portalRow
+	var recent []portalResult
+	for _, d := range disciplines {
+		row := portalRow{Discipline: d, Data: byDiscipline[d.ID]}
+		if d.TeacherID != nil {
+			row.Teacher = teachers[*d.TeacherID]
+		}
+		row.Grade = resolveScale(scales, group, d).Grade(row.Data.Score)
+
+		assessments, err := s.store.GetAssessmentsByDisciplineID(d.ID, term.ID())
+		if err != nil {
+			http.Error(w, "Ошибка работ", http.StatusInternalServerError)
+			return
+		}
+		for _, a := range assessments {
+			p, ok := points[a.ID]
+			if !ok || a.MaxPoints <= 0 {
+				continue
+			}
+			res := portalResult{Assessment: a, Discipline: d.Name, Points: p, Percent: int(p / a.MaxPoints * 100)}
+			row.Results = append(row.Results, res)
+			recent = append(recent, res)
+		}
+		row.Sparkline = sparkline(row.Results)
+		row.Trend = trendArrow(row.Results)
+		rows = append(rows, row)
+	}
+	sort.SliceStable(recent, func(i, j int) bool { return recent[i].Assessment.Date.After(recent[j].Assessment.Date) })
+	if len(recent) > 10 {
+		recent = recent[:10]
+	}
+
+	months, err := s.monthlyAttendance(student, disciplines, term.ID())
+	if err != nil {
+		http.Error(w, "Ошибка журнала", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	tmpl := `
+<!DOCTYPE html>
+<html>
+<head>
+	<title>{{.Student.Name}} — дневник</title>
+	<link rel="stylesheet" href="/static/style.css">
+</head>
+<body>
+	<div class="card portal">
+		{{template "userbar" .User}}
+		<h1>{{.Student.Name}}</h1>
+		{{template "termbar" .Term}}
+		<p class="lesson-meta">Группа {{.Group.Name}}</p>
+
+		{{if .Student.Comments}}
+		<div class="portal-comment">
+			<h3>Комментарий преподавателя</h3>
+			<p>{{.Student.Comments}}</p>
+		</div>
+		{{end}}
+
+		<h2>Успеваемость</h2>
+		<table>
+			<thead>
+				<tr>
+					<th>Дисциплина</th>
+					<th>Балл</th>
+					<th>Оценка</th>
+					<th>Посещаемость</th>
+					<th>Динамика</th>
+				</tr>
+			</thead>
+			<tbody>
+			{{range .Rows}}
+				<tr>
+					<td>{{.Discipline.Name}}{{if .Teacher}}<br><small class="portal-teacher">{{.Teacher}}</small>{{end}}</td>
+					<td>{{.Data.Score}}</td>
+					<td class="grade-cell {{.Grade.Class}}">{{.Grade.Label}}</td>
+					<td>{{if .Data.TotalClasses}}{{.Data.AttendedClasses}} из {{.Data.TotalClasses}} ({{percent .Data.AttendedClasses .Data.TotalClasses}}%){{else}}—{{end}}</td>
+					<td>
+						{{if .Sparkline}}
+						<svg class="sparkline" viewBox="0 0 100 30" preserveAspectRatio="none"><polyline points="{{.Sparkline}}"/></svg>
+						<span class="trend">{{.Trend}}</span>
+						{{else}}—{{end}}
+					</td>
+				</tr>
+			{{end}}
+			</tbody>
+		</table>
+
+		{{if .Months}}
+		<h2>Посещаемость по месяцам</h2>
+		<table class="portal-months">
+			<tbody>
+			{{range .Months}}
+				<tr>
+					<td>{{monthName .Month}}</td>
+					<td class="portal-bar"><span style="width: {{.Percent}}%"></span></td>
+					<td>{{.Attended}} из {{.Total}} ({{.Percent}}%)</td>
+				</tr>
+			{{end}}
+			</tbody>
+		</table>
+		{{end}}
+
+		{{if .Recent}}
+		<h2>Последние работы</h2>
+		<table>
+			<tbody>
+			{{range .Recent}}
+				<tr>
+					<td>{{formatDate .Assessment.Date}}</td>
+					<td>{{.Discipline}}</td>
+					<td>{{.Assessment.Name}}</td>
+					<td>{{points .Points}} из {{points .Assessment.MaxPoints}} ({{.Percent}}%)</td>
+				</tr>
+			{{end}}
+			</tbody>
+		</table>
+		{{end}}
+
+		{{if .User.Role.Staff}}
+		<a href="/student/{{.Student.ID.Hex}}" class="back-link">← К странице студента</a>
+		{{else if gt (len .User.StudentIDs) 1}}
+		<a href="/portal" class="back-link">← Все студенты</a>
+		{{end}}
+	</div>
+</body>
+</html>`
+
+	funcs := template.FuncMap{
+		"formatDate": func(t time.Time) string { return t.Format("02.01.2006") },
+		"points":     formatPoints,
+		"percent": func(part, total int) int {
+			if total == 0 {
+				return 0
+			}
+			return part * 100 / total
+		},
+		"monthName": func(t time.Time) string {
+			return fmt.Sprintf("%s %d", monthNames[t.Month()-1], t.Year())
+		},
+	}
+
+	data := struct {
+		User    *models.User
+		Term    termState
+		Student *models.Student
+		Group   *models.Group
+		Rows    []portalRow
+		Months  []portalMonth
+		Recent  []portalResult
+	}{
+		User:    user,
+		Term:    term,
+		Student: student,
+		Group:   group,
+		Rows:    rows,
+		Months:  months,
+		Recent:  recent,
+	}
+
+	t := template.Must(template.New("portal").Funcs(funcs).Parse(tmpl + userBarTmpl + termBarTmpl))
+	t.Execute(w, data)
+}
+
+// monthlyAttendance сводит отметки журнала студента за период по месяцам —
+// по тем же правилам, что и счётчики в StudentDisciplineData.
+func (s *Server) monthlyAttendance(student *models.Student, disciplines []models.Discipline, termID primitive.ObjectID) ([]portalMonth, error) {
+	active := make(map[primitive.ObjectID]bool, len(disciplines))
+	for _, d := range disciplines {
+		active[d.ID] = true
+	}
+	lessons, err := s.store.GetLessonsByGroupID(student.GroupID, termID)
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make(map[time.Time][]models.AttendanceStatus)
+	for _, l := range lessons {
+		if !active[l.DisciplineID] {
+			continue
+		}
+		marks, err := s.store.GetAttendanceByLesson(l.ID)
+		if err != nil {
+			return nil, err
+		}
+		month := time.Date(l.Date.Year(), l.Date.Month(), 1, 0, 0, 0, 0, time.UTC)
+		for _, m := range marks {
+			if m.StudentID == student.ID {
+				statuses[month] = append(statuses[month], m.Status)
+			}
+		}
+	}
+
+	var months []portalMonth
+	for month, list := range statuses {
+		total, attended := models.CountAttendance(list)
+		if total > 0 {
+			months = append(months, portalMonth{Month: month, Total: total, Attended: attended})
+		}
+	}
+	sort.Slice(months, func(i, j int) bool { return months[i].Month.Before(months[j].Month) })
+	return months, nil
+}
@@ -0,0 +1,174 @@
+// handlers/resets.go
+package handlers
+
+import (
+	"electronic-diary/db"
+	"electronic-diary/models"
+	"errors"
+	"html/template"
+	"log"
+	"net/http"
+	"strings"
+)
+
+// ResetDynamicHandler обнуляет данные всего дневника или, если переданы
+// groupId либо disciplineId, одной группы или дисциплины. Перед сбросом
+// хранилище сохраняет снимок, из которого сброс отменяется на /resets.
+func (s *Server) ResetDynamicHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		http.NotFound(w, r)
+		return
+	}
+
+	snapshot := &models.ResetSnapshot{ScopeName: "Весь дневник"}
+	switch {
+	case r.FormValue("disciplineId") != "":
+		disciplineID, err := parseObjectID(r.FormValue("disciplineId"))
+		if err != nil {
+			respondError(w, r, http.StatusBadRequest, "Некорректный ID дисциплины")
+			return
+		}
+		discipline, err := s.store.GetDisciplineByID(disciplineID)
+		if err != nil {
+			respondError(w, r, http.StatusNotFound, "Дисциплина не найдена")
+			return
+		}
+		snapshot.Scope.DisciplineID = &discipline.ID
+		snapshot.ScopeName = "Дисциплина «" + discipline.Name + "»"
+		if group, err := s.store.GetGroupByID(discipline.GroupID); err == nil {
+			snapshot.ScopeName += ", группа «" + group.Name + "»"
+		}
+	case r.FormValue("groupId") != "":
+		groupID, err := parseObjectID(r.FormValue("groupId"))
+		if err != nil {
+			respondError(w, r, http.StatusBadRequest, "Некорректный ID группы")
+			return
+		}
+		group, err := s.store.GetGroupByID(groupID)
+		if err != nil {
+			respondError(w, r, http.StatusNotFound, "Группа не найдена")
+			return
+		}
+		snapshot.Scope.GroupID = &group.ID
+		snapshot.ScopeName = "Группа «" + group.Name + "»"
+	}
+	if user := currentUser(r); user != nil {
+		snapshot.ActorID, snapshot.ActorName = user.ID, actorName(user)
+	}
+
+	audit := func(snap *models.ResetSnapshot) []models.AuditEntry {
+		return auditBy(r, resetChanges(snap))
+	}
+	if err := s.store.ResetDynamicData(snapshot, audit); err != nil {
+		log.Printf("Ошибка сброса (%s): %v", snapshot.ScopeName, err)
+		respondError(w, r, http.StatusInternalServerError, "Ошибка при сбросе")
+		return
+	}
+	respond(w, r, "/resets", map[string]string{"status": "ok", "snapshotId": snapshot.ID.Hex()})
+}
+
+// ResetsAPIHandler — POST /api/resets/{id}/restore: отмена сброса.
+func (s *Server) ResetsAPIHandler(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/resets"), "/"), "/")
+	if len(parts) != 2 || parts[1] != "restore" || r.Method != http.MethodPost {
+		http.NotFound(w, r)
+		return
+	}
+	id, err := parseObjectID(parts[0])
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	snap, err := s.store.GetResetSnapshotByID(id)
+	if err != nil {
+		respondError(w, r, http.StatusNotFound, "Снимок сброса не найден")
+		return
+	}
+
+	comments, records := s.sheetState(snap)
+	err = s.store.RestoreResetSnapshot(id, auditBy(r, restoreChanges(snap, comments, records)))
+	if errors.Is(err, db.ErrConflict) {
+		respondError(w, r, http.StatusConflict, "Этот сброс уже отменён")
+		return
+	}
+	if err != nil {
+		log.Printf("Ошибка отмены сброса %s: %v", id.Hex(), err)
+		respondError(w, r, http.StatusInternalServerError, "Не удалось отменить сброс")
+		return
+	}
+	respond(w, r, "/resets", map[string]string{"status": "ok"})
+}
+
+// Страница истории сбросов
+func (s *Server) ResetsHandler(w http.ResponseWriter, r *http.Request) {
+	snapshots, err := s.store.GetResetSnapshots()
+	if err != nil {
+		log.Printf("Ошибка получения снимков сбросов: %v", err)
+		http.Error(w, "Ошибка БД", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	tmpl := `
+<!DOCTYPE html>
+<html>
+<head>
+	<title>Сбросы данных</title>
+	<link rel="stylesheet" href="/static/style.css">
+</head>
+<body>
+	<div class="card">
+		{{template "userbar" .User}}
+		<h1>Сбросы данных</h1>
+		<p>Перед каждым сбросом сохраняется снимок данных. Отмена возвращает комментарии, баллы, посещаемость, отметки журнала и баллы за работы из снимка — поверх всего, что введено после сброса.</p>
+
+		{{if .Snapshots}}
+		<table>
+			<thead>
+				<tr><th>Когда</th><th>Кто</th><th>Что сброшено</th><th>Данные</th><th></th></tr>
+			</thead>
+			<tbody>
+			{{range .Snapshots}}
+			<tr>
+				<td>{{.At.Local.Format "02.01.2006 15:04"}}</td>
+				<td>{{.ActorName}}</td>
+				<td>{{.ScopeName}}</td>
+				<td>
+					<small>комментариев: {{len .Comments}}, записей: {{len .Records}},
+					отметок: {{len .Attendance}}, баллов за работы: {{len .Marks}}</small>
+				</td>
+				<td>
+					{{if .RestoredAt}}
+					<small>отменён {{.RestoredAt.Local.Format "02.01.2006 15:04"}}</small>
+					{{else}}
+					<form action="/api/resets/{{.ID.Hex}}/restore" method="POST" onsubmit="return confirm('Вернуть данные из снимка? Всё, что введено после сброса в этой области, будет перезаписано.')">
+						<button type="submit" class="small-btn">Отменить сброс</button>
+					</form>
+					{{end}}
+				</td>
+			</tr>
+			{{end}}
+			</tbody>
+		</table>
+		{{else}}
+		<p>Сбросов ещё не было.</p>
+		{{end}}
+
+		<a href="/" class="back-link">← Назад</a>
+	</div>
+</body>
+</html>`
+
+	data := struct {
+		User      *models.User
+		Snapshots []models.ResetSnapshot
+	}{
+		User:      currentUser(r),
+		Snapshots: snapshots,
+	}
+
+	t := template.Must(template.New("resets").Parse(tmpl + userBarTmpl))
+	if err := t.Execute(w, data); err != nil {
+		log.Printf("Ошибка шаблона сбросов: %v", err)
+	}
+}
@@ -0,0 +1,170 @@
+// handlers/students.go
+package handlers
+
+import (
+	"electronic-diary/db"
+	"electronic-diary/models"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+)
+
+// StudentsAPIHandler — управление студентами:
+//
+//	GET  /api/students?group={id}   — студенты группы
+//	POST /api/students              — добавить студента (name, groupId)
+//	GET  /api/students/{id}         — данные студента
+//	POST /api/students/{id}/update  — изменить имя и/или группу (name, groupId)
+//	POST /api/students/{id}/delete  — удалить студента
+func (s *Server) StudentsAPIHandler(w http.ResponseWriter, r *http.Request) {
+	rest := strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/students"), "/")
+
+	if rest == "" {
+		switch r.Method {
+		case http.MethodGet:
+			s.listStudents(w, r)
+		case http.MethodPost:
+			s.createStudent(w, r)
+		default:
+			http.NotFound(w, r)
+		}
+		return
+	}
+
+	parts := strings.Split(rest, "/")
+	studentID, err := parseObjectID(parts[0])
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	student, err := s.store.GetStudentByID(studentID)
+	if isNotFound(err) {
+		respondError(w, r, http.StatusNotFound, "Студент не найден")
+		return
+	}
+	if err != nil {
+		log.Printf("Ошибка получения студента: %v", err)
+		respondError(w, r, http.StatusInternalServerError, "Ошибка БД")
+		return
+	}
+
+	switch {
+	case len(parts) == 1 && r.Method == http.MethodGet:
+		writeJSON(w, http.StatusOK, student)
+	case len(parts) == 2 && parts[1] == "update" && r.Method == http.MethodPost:
+		s.updateStudent(w, r, student)
+	case len(parts) == 2 && parts[1] == "delete" && r.Method == http.MethodPost:
+		s.deleteStudent(w, r, student)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (s *Server) listStudents(w http.ResponseWriter, r *http.Request) {
+	groupID, err := parseObjectID(r.URL.Query().Get("group"))
+	if err != nil {
+		respondError(w, r, http.StatusBadRequest, "Укажите группу: ?group={id}")
+		return
+	}
+	students, err := s.store.GetStudentsByGroupID(groupID)
+	if err != nil {
+		log.Printf("Ошибка получения студентов: %v", err)
+		respondError(w, r, http.StatusInternalServerError, "Ошибка БД")
+		return
+	}
+	if students == nil {
+		students = []models.Student{}
+	}
+	writeJSON(w, http.StatusOK, students)
+}
+
+func (s *Server) createStudent(w http.ResponseWriter, r *http.Request) {
+	var in struct {
+		Name    string `json:"name"`
+		GroupID string `json:"groupId"`
+	}
+	if err := readInput(r, &in); err != nil {
+		respondError(w, r, http.StatusBadRequest, "Некорректные данные")
+		return
+	}
+	groupID, err := parseObjectID(in.GroupID)
+	if err != nil {
+		respondError(w, r, http.StatusBadRequest, "Некорректная группа")
+		return
+	}
+	group, err := s.store.GetGroupByID(groupID)
+	if err != nil {
+		respondError(w, r, http.StatusBadRequest, "Группа не найдена")
+		return
+	}
+
+	student, err := s.store.CreateStudent(in.Name, group.ID)
+	if errors.Is(err, db.ErrEmptyName) {
+		respondError(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err != nil {
+		log.Printf("Ошибка создания студента: %v", err)
+		respondError(w, r, http.StatusInternalServerError, "Ошибка БД")
+		return
+	}
+	respond(w, r, groupURL(group), student)
+}
+
+func (s *Server) updateStudent(w http.ResponseWriter, r *http.Request, student *models.Student) {
+	var in struct {
+		Name    string `json:"name"`
+		GroupID string `json:"groupId"`
+	}
+	if err := readInput(r, &in); err != nil {
+		respondError(w, r, http.StatusBadRequest, "Некорректные данные")
+		return
+	}
+
+	if in.Name != "" && in.Name != student.Name {
+		if err := s.store.RenameStudent(student.ID, in.Name); err != nil {
+			log.Printf("Ошибка переименования студента %s: %v", student.ID.Hex(), err)
+			respondError(w, r, http.StatusInternalServerError, "Ошибка БД")
+			return
+		}
+	}
+
+	if in.GroupID != "" && in.GroupID != student.GroupID.Hex() {
+		groupID, err := parseObjectID(in.GroupID)
+		if err != nil {
+			respondError(w, r, http.StatusBadRequest, "Некорректная группа")
+			return
+		}
+		if _, err := s.store.GetGroupByID(groupID); err != nil {
+			respondError(w, r, http.StatusBadRequest, "Группа не найдена")
+			return
+		}
+		if err := s.store.MoveStudent(student.ID, groupID); err != nil {
+			log.Printf("Ошибка перевода студента %s: %v", student.ID.Hex(), err)
+			respondError(w, r, http.StatusInternalServerError, "Ошибка БД")
+			return
+		}
+	}
+
+	updated, err := s.store.GetStudentByID(student.ID)
+	if err != nil {
+		respondError(w, r, http.StatusInternalServerError, "Ошибка БД")
+		return
+	}
+	respond(w, r, "/student/"+student.ID.Hex(), updated)
+}
+
+func (s *Server) deleteStudent(w http.ResponseWriter, r *http.Request, student *models.Student) {
+	if err := s.store.DeleteStudent(student.ID); err != nil {
+		log.Printf("Ошибка удаления студента %s: %v", student.ID.Hex(), err)
+		respondError(w, r, http.StatusInternalServerError, "Ошибка БД")
+		return
+	}
+
+	redirect := "/"
+	if group, err := s.store.GetGroupByID(student.GroupID); err == nil {
+		redirect = groupURL(group)
+	}
+	respond(w, r, redirect, map[string]string{"status": "deleted"})
+}
@@ -0,0 +1,391 @@
+// handlers/terms.go
+package handlers
+
+import (
+	"electronic-diary/db"
+	"electronic-diary/models"
+	"errors"
+	"html/template"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// termCookie — cookie с периодом, выбранным в переключателе.
+const termCookie = "diary_term"
+
+// termClosedMsg — ответ на попытку изменить данные закрытого периода.
+const termClosedMsg = "Период закрыт — его оценки и посещаемость заморожены"
+
+// termState — выбранный период и все периоды для переключателя.
+type termState struct {
+	Current *models.Term
+	Terms   []models.Term
+	Years   map[primitive.ObjectID]string
+}
+
+// ID — выбранный период; нулевой, если периодов нет.
+func (t termState) ID() primitive.ObjectID {
+	if t.Current == nil {
+		return primitive.NilObjectID
+	}
+	return t.Current.ID
+}
+
+// Closed — выбранный период закрыт: данные в нём только для просмотра.
+func (t termState) Closed() bool {
+	return t.Current != nil && t.Current.Closed
+}
+
+// currentTerm — период запроса: поле или параметр term, затем cookie
+// переключателя, иначе текущий по календарю (models.CurrentTerm).
+func (s *Server) currentTerm(r *http.Request) (termState, error) {
+	terms, err := s.store.GetTerms()
+	if err != nil {
+		return termState{}, err
+	}
+	years, err := s.store.GetAcademicYears()
+	if err != nil {
+		return termState{}, err
+	}
+	state := termState{Terms: terms, Years: make(map[primitive.ObjectID]string, len(years))}
+	for _, y := range years {
+		state.Years[y.ID] = y.Name
+	}
+
+	chosen := r.FormValue("term")
+	if chosen == "" {
+		if c, err := r.Cookie(termCookie); err == nil {
+			chosen = c.Value
+		}
+	}
+	for i := range terms {
+		if terms[i].ID.Hex() == chosen {
+			state.Current = &terms[i]
+			return state, nil
+		}
+	}
+	state.Current = models.CurrentTerm(terms, time.Now())
+	return state, nil
+}
+
+// termBarTmpl — переключатель периода. Страницы дописывают его к шаблону
+// и вызывают {{template "termbar" .Term}}.
+const termBarTmpl = `
+{{define "termbar"}}{{if .Terms}}
+<form action="/api/term" method="POST" class="term-bar">
+	<label for="term-select">Период:</label>
+	<select name="term" id="term-select" onchange="this.form.submit()">
+	{{range .Terms}}
+		<option value="{{.ID.Hex}}"{{if eq .ID $.ID}} selected{{end}}>{{index $.Years .YearID}}, {{.Name}}{{if .Closed}} (закрыт){{end}}</option>
+	{{end}}
+	</select>
+	<noscript><button type="submit" class="small-btn">Выбрать</button></noscript>
+	{{if .Closed}}<span class="term-closed">Период закрыт — данные только для просмотра</span>{{end}}
+</form>
+{{end}}{{end}}`
+
+// SelectTermHandler — POST /api/term: запоминает выбранный период
+// и возвращает на страницу, с которой пришли.
+func (s *Server) SelectTermHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.NotFound(w, r)
+		return
+	}
+	termID, err := parseObjectID(r.FormValue("term"))
+	if err != nil {
+		respondError(w, r, http.StatusBadRequest, "Некорректный ID периода")
+		return
+	}
+	term, err := s.store.GetTermByID(termID)
+	if isNotFound(err) {
+		respondError(w, r, http.StatusNotFound, "Период не найден")
+		return
+	}
+	if err != nil {
+		log.Printf("Ошибка получения периода: %v", err)
+		respondError(w, r, http.StatusInternalServerError, "Ошибка БД")
+		return
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     termCookie,
+		Value:    term.ID.Hex(),
+		Path:     "/",
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+	back := "/"
+	if ref, err := url.Parse(r.Referer()); err == nil && ref.Host == r.Host {
+		back = safeNext(ref.RequestURI())
+	}
+	respond(w, r, back, term)
+}
+
+// Страница учебных годов и периодов
+func (s *Server) TermsHandler(w http.ResponseWriter, r *http.Request) {
+	state, err := s.currentTerm(r)
+	if err != nil {
+		log.Printf("Ошибка получения периодов: %v", err)
+		http.Error(w, "Ошибка БД", http.StatusInternalServerError)
+		return
+	}
+	years, err := s.store.GetAcademicYears()
+	if err != nil {
+		log.Printf("Ошибка получения учебных годов: %v", err)
+		http.Error(w, "Ошибка БД", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	tmpl := `
+<!DOCTYPE html>
+<html>
+<head>
+	<title>Учебные периоды</title>
+	<link rel="stylesheet" href="/static/style.css">
+</head>
+<body>
+	<div class="card">
+		{{template "userbar" .User}}
+		<h1>Учебные периоды</h1>
+		<p>Оценки, посещаемость, занятия и работы относятся к периоду. Закрытый период заморожен: его данные можно смотреть, но не менять.</p>
+
+		{{if .Term.Terms}}
+		<table>
+			<thead>
+				<tr><th>Учебный год</th><th>Период</th><th>Даты</th><th>Состояние</th>{{if .IsAdmin}}<th></th>{{end}}</tr>
+			</thead>
+			<tbody>
+			{{range .Term.Terms}}
+			<tr>
+				<td>{{index $.Term.Years .YearID}}</td>
+				<td>{{.Name}}</td>
+				<td>{{.Start.Format "02.01.2006"}} – {{.End.Format "02.01.2006"}}</td>
+				<td>{{if .Closed}}закрыт{{else}}открыт{{end}}</td>
+				{{if $.IsAdmin}}
+				<td>
+					{{if .Closed}}
+					<form action="/api/terms/{{.ID.Hex}}/reopen" method="POST" onsubmit="return confirm('Открыть период? Его оценки снова можно будет менять.')">
+						<button type="submit" class="small-btn">Открыть</button>
+					</form>
+					{{else}}
+					<form action="/api/terms/{{.ID.Hex}}/close" method="POST" onsubmit="return confirm('Закрыть период? Оценки и посещаемость в нём будут заморожены.')">
+						<button type="submit" class="small-btn danger">Закрыть</button>
+					</form>
+					{{end}}
+				</td>
+				{{end}}
+			</tr>
+			{{end}}
+			</tbody>
+		</table>
+		{{else}}
+		<p>Периодов пока нет.</p>
+		{{end}}
+
+		{{if .IsAdmin}}
+		<h2>Новый период</h2>
+		{{if .Years}}
+		<form action="/api/terms" method="POST" class="inline-form">
+			<select name="yearId">
+			{{range .Years}}
+				<option value="{{.ID.Hex}}">{{.Name}}</option>
+			{{end}}
+			</select>
+			<input type="text" name="name" placeholder="Название, например «Весенний семестр»" required>
+			<input type="date" name="start" required>
+			<input type="date" name="end" required>
+			<input type="submit" value="Создать период">
+		</form>
+		{{end}}
+
+		<h2>Новый учебный год</h2>
+		<form action="/api/years" method="POST" class="inline-form">
+			<input type="text" name="name" placeholder="Например, 2026/2027" required>
+			<input type="submit" value="Создать год">
+		</form>
+		{{end}}
+
+		<a href="/" class="back-link">← Назад</a>
+	</div>
+</body>
+</html>`
+
+	user := currentUser(r)
+	data := struct {
+		User    *models.User
+		IsAdmin bool
+		Term    termState
+		Years   []models.AcademicYear
+	}{
+		User:    user,
+		IsAdmin: user.Role == models.RoleAdmin,
+		Term:    state,
+		Years:   years,
+	}
+
+	t := template.Must(template.New("terms").Parse(tmpl + userBarTmpl))
+	if err := t.Execute(w, data); err != nil {
+		log.Printf("Ошибка шаблона периодов: %v", err)
+	}
+}
+
+// YearsAPIHandler — учебные годы:
+//
+//	GET  /api/years — все годы
+//	POST /api/years — создать (name)
+func (s *Server) YearsAPIHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		years, err := s.store.GetAcademicYears()
+		if err != nil {
+			log.Printf("Ошибка получения учебных годов: %v", err)
+			respondError(w, r, http.StatusInternalServerError, "Ошибка БД")
+			return
+		}
+		if years == nil {
+			years = []models.AcademicYear{}
+		}
+		writeJSON(w, http.StatusOK, years)
+	case http.MethodPost:
+		var in struct {
+			Name string `json:"name"`
+		}
+		if err := readInput(r, &in); err != nil {
+			respondError(w, r, http.StatusBadRequest, "Некорректные данные")
+			return
+		}
+		year, err := s.store.CreateAcademicYear(in.Name)
+		if errors.Is(err, db.ErrEmptyName) {
+			respondError(w, r, http.StatusBadRequest, err.Error())
+			return
+		}
+		if err != nil {
+			log.Printf("Ошибка создания учебного года: %v", err)
+			respondError(w, r, http.StatusInternalServerError, "Ошибка БД")
+			return
+		}
+		respond(w, r, "/terms", year)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// TermsAPIHandler — учебные периоды:
+//
+//	GET  /api/terms              — все периоды
+//	POST /api/terms              — создать (yearId, name, start, end в формате ГГГГ-ММ-ДД)
+//	POST /api/terms/{id}/close   — закрыть: оценки периода замораживаются
+//	POST /api/terms/{id}/reopen  — открыть снова
+func (s *Server) TermsAPIHandler(w http.ResponseWriter, r *http.Request) {
+	rest := strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/terms"), "/")
+
+	if rest == "" {
+		switch r.Method {
+		case http.MethodGet:
+			terms, err := s.store.GetTerms()
+			if err != nil {
+				log.Printf("Ошибка получения периодов: %v", err)
+				respondError(w, r, http.StatusInternalServerError, "Ошибка БД")
+				return
+			}
+			if terms == nil {
+				terms = []models.Term{}
+			}
+			writeJSON(w, http.StatusOK, terms)
+		case http.MethodPost:
+			s.createTerm(w, r)
+		default:
+			http.NotFound(w, r)
+		}
+		return
+	}
+
+	parts := strings.Split(rest, "/")
+	if len(parts) != 2 || r.Method != http.MethodPost || (parts[1] != "close" && parts[1] != "reopen") {
+		http.NotFound(w, r)
+		return
+	}
+	termID, err := parseObjectID(parts[0])
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	err = s.store.SetTermClosed(termID, parts[1] == "close")
+	if isNotFound(err) {
+		respondError(w, r, http.StatusNotFound, "Период не найден")
+		return
+	}
+	if err != nil {
+		log.Printf("Ошибка изменения периода %s: %v", termID.Hex(), err)
+		respondError(w, r, http.StatusInternalServerError, "Ошибка БД")
+		return
+	}
+	respond(w, r, "/terms", map[string]string{"status": "ok"})
+}
+
+func (s *Server) createTerm(w http.ResponseWriter, r *http.Request) {
+	var in struct {
+		YearID string `json:"yearId"`
+		Name   string `json:"name"`
+		Start  string `json:"start"`
+		End    string `json:"end"`
+	}
+	if err := readInput(r, &in); err != nil {
+		respondError(w, r, http.StatusBadRequest, "Некорректные данные")
+		return
+	}
+	yearID, err := parseObjectID(in.YearID)
+	if err != nil {
+		respondError(w, r, http.StatusBadRequest, "Некорректный ID учебного года")
+		return
+	}
+	start, errStart := time.Parse("2006-01-02", in.Start)
+	end, errEnd := time.Parse("2006-01-02", in.End)
+	if errStart != nil || errEnd != nil {
+		respondError(w, r, http.StatusBadRequest, "Даты периода — в формате ГГГГ-ММ-ДД")
+		return
+	}
+	if end.Before(start) {
+		respondError(w, r, http.StatusBadRequest, "Период не может закончиться раньше, чем начался")
+		return
+	}
+
+	term := models.Term{YearID: yearID, Name: in.Name, Start: start, End: end}
+	err = s.store.CreateTerm(&term)
+	if errors.Is(err, db.ErrEmptyName) {
+		respondError(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+	if isNotFound(err) {
+		respondError(w, r, http.StatusNotFound, "Учебный год не найден")
+		return
+	}
+	if err != nil {
+		log.Printf("Ошибка создания периода: %v", err)
+		respondError(w, r, http.StatusInternalServerError, "Ошибка БД")
+		return
+	}
+	respond(w, r, "/terms", term)
+}
+
+// requireOpenTerm пропускает изменение, только если период termID открыт;
+// иначе отвечает 409.
+func (s *Server) requireOpenTerm(w http.ResponseWriter, r *http.Request, termID primitive.ObjectID) bool {
+	term, err := s.store.GetTermByID(termID)
+	if err != nil && !isNotFound(err) {
+		log.Printf("Ошибка получения периода: %v", err)
+		respondError(w, r, http.StatusInternalServerError, "Ошибка БД")
+		return false
+	}
+	if err == nil && term.Closed {
+		respondError(w, r, http.StatusConflict, termClosedMsg)
+		return false
+	}
+	return true
+}
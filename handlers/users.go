@@ -0,0 +1,356 @@
+// handlers/users.go
+package handlers
+
+import (
+	"electronic-diary/db"
+	"electronic-diary/models"
+	"errors"
+	"fmt"
+	"html/template"
+	"log"
+	"net/http"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const minPasswordLength = 6
+
+// userInput — поля учётной записи из формы или JSON.
+type userInput struct {
+	Login      string   `json:"login"`
+	Name       string   `json:"name"`
+	Role       string   `json:"role"`
+	Password   string   `json:"password"`
+	StudentIDs []string `json:"studentIds"`
+}
+
+// apply переносит проверенные поля в учётную запись. Пароль меняется,
+// только если он передан; при создании он обязателен.
+func (s *Server) apply(in userInput, user *models.User, creating bool) error {
+	user.Name = strings.TrimSpace(in.Name)
+	user.Role = models.Role(in.Role)
+	if creating {
+		user.Login = strings.TrimSpace(in.Login)
+		if user.Login == "" {
+			return errors.New("логин не может быть пустым")
+		}
+	}
+	if user.Name == "" {
+		return errors.New("имя не может быть пустым")
+	}
+	if !user.Role.Valid() {
+		return fmt.Errorf("неизвестная роль %q", in.Role)
+	}
+
+	// Студенты привязываются только к студентам и родителям
+	user.StudentIDs = nil
+	if !user.Role.Staff() {
+		for _, hexID := range in.StudentIDs {
+			id, err := parseObjectID(hexID)
+			if err != nil {
+				return fmt.Errorf("некорректный студент %q", hexID)
+			}
+			if _, err := s.store.GetStudentByID(id); err != nil {
+				return fmt.Errorf("студент %s не найден", hexID)
+			}
+			if !user.LinkedTo(id) {
+				user.StudentIDs = append(user.StudentIDs, id)
+			}
+		}
+		if user.Role == models.RoleStudent && len(user.StudentIDs) > 1 {
+			return errors.New("студента можно связать только с одной записью")
+		}
+	}
+
+	if in.Password != "" || creating {
+		if len([]rune(in.Password)) < minPasswordLength {
+			return fmt.Errorf("пароль должен быть не короче %d символов", minPasswordLength)
+		}
+		if err := user.SetPassword(in.Password); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// UsersAPIHandler — учётные записи (только администратор):
+//
+//	GET  /api/users             — список пользователей
+//	POST /api/users             — создать (login, name, role, password, studentIds)
+//	POST /api/users/{id}/update — изменить имя, роль, связи; пароль — если передан
+//	POST /api/users/{id}/delete — удалить пользователя
+func (s *Server) UsersAPIHandler(w http.ResponseWriter, r *http.Request) {
+	rest := strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/users"), "/")
+
+	if rest == "" {
+		switch r.Method {
+		case http.MethodGet:
+			users, err := s.store.GetUsers()
+			if err != nil {
+				log.Printf("Ошибка получения пользователей: %v", err)
+				respondError(w, r, http.StatusInternalServerError, "Ошибка БД")
+				return
+			}
+			if users == nil {
+				users = []models.User{}
+			}
+			writeJSON(w, http.StatusOK, users)
+		case http.MethodPost:
+			s.createUser(w, r)
+		default:
+			http.NotFound(w, r)
+		}
+		return
+	}
+
+	parts := strings.Split(rest, "/")
+	if len(parts) != 2 || r.Method != http.MethodPost {
+		http.NotFound(w, r)
+		return
+	}
+	userID, err := parseObjectID(parts[0])
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	user, err := s.store.GetUserByID(userID)
+	if isNotFound(err) {
+		respondError(w, r, http.StatusNotFound, "Пользователь не найден")
+		return
+	}
+	if err != nil {
+		log.Printf("Ошибка получения пользователя: %v", err)
+		respondError(w, r, http.StatusInternalServerError, "Ошибка БД")
+		return
+	}
+
+	switch parts[1] {
+	case "update":
+		s.updateUser(w, r, user)
+	case "delete":
+		s.deleteUser(w, r, user)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (s *Server) createUser(w http.ResponseWriter, r *http.Request) {
+	var in userInput
+	if err := readInput(r, &in); err != nil {
+		respondError(w, r, http.StatusBadRequest, "Некорректные данные")
+		return
+	}
+
+	var user models.User
+	if err := s.apply(in, &user, true); err != nil {
+		respondError(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+	err := s.store.CreateUser(&user)
+	if errors.Is(err, db.ErrLoginTaken) {
+		respondError(w, r, http.StatusConflict, err.Error())
+		return
+	}
+	if err != nil {
+		log.Printf("Ошибка создания пользователя: %v", err)
+		respondError(w, r, http.StatusInternalServerError, "Ошибка БД")
+		return
+	}
+	respond(w, r, "/users", user)
+}
+
+func (s *Server) updateUser(w http.ResponseWriter, r *http.Request, user *models.User) {
+	// Непереданные поля остаются прежними
+	in := userInput{Name: user.Name, Role: string(user.Role)}
+	for _, id := range user.StudentIDs {
+		in.StudentIDs = append(in.StudentIDs, id.Hex())
+	}
+	if err := readInput(r, &in); err != nil {
+		respondError(w, r, http.StatusBadRequest, "Некорректные данные")
+		return
+	}
+
+	if user.ID == currentUser(r).ID && models.Role(in.Role) != models.RoleAdmin {
+		respondError(w, r, http.StatusBadRequest, "Нельзя снять с себя роль администратора")
+		return
+	}
+	if err := s.apply(in, user, false); err != nil {
+		respondError(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err := s.store.UpdateUser(user); err != nil {
+		log.Printf("Ошибка изменения пользователя %s: %v", user.ID.Hex(), err)
+		respondError(w, r, http.StatusInternalServerError, "Ошибка БД")
+		return
+	}
+	respond(w, r, "/users", user)
+}
+
+func (s *Server) deleteUser(w http.ResponseWriter, r *http.Request, user *models.User) {
+	if user.ID == currentUser(r).ID {
+		respondError(w, r, http.StatusBadRequest, "Нельзя удалить собственную учётную запись")
+		return
+	}
+	if err := s.store.DeleteUser(user.ID); err != nil {
+		log.Printf("Ошибка удаления пользователя %s: %v", user.ID.Hex(), err)
+		respondError(w, r, http.StatusInternalServerError, "Ошибка БД")
+		return
+	}
+	respond(w, r, "/users", map[string]string{"status": "deleted"})
+}
+
+// groupStudents — студенты, разложенные по группам, для выбора связей.
+type groupStudents struct {
+	Group    models.Group
+	Students []models.Student
+}
+
+func (s *Server) studentsByGroup() ([]groupStudents, map[primitive.ObjectID]string, error) {
+	groups, err := s.store.GetGroups(true)
+	if err != nil {
+		return nil, nil, err
+	}
+	var list []groupStudents
+	names := make(map[primitive.ObjectID]string)
+	for _, g := range groups {
+		students, err := s.store.GetStudentsByGroupID(g.ID)
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, st := range students {
+			names[st.ID] = st.Name
+		}
+		list = append(list, groupStudents{Group: g, Students: students})
+	}
+	return list, names, nil
+}
+
+// Страница пользователей
+func (s *Server) UsersHandler(w http.ResponseWriter, r *http.Request) {
+	users, err := s.store.GetUsers()
+	if err != nil {
+		log.Printf("Ошибка получения пользователей: %v", err)
+		http.Error(w, "Ошибка БД", http.StatusInternalServerError)
+		return
+	}
+	groups, names, err := s.studentsByGroup()
+	if err != nil {
+		log.Printf("Ошибка получения студентов: %v", err)
+		http.Error(w, "Ошибка БД", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	tmpl := `
+<!DOCTYPE html>
+<html>
+<head>
+	<title>Пользователи</title>
+	<link rel="stylesheet" href="/static/style.css">
+</head>
+<body>
+	<div class="card">
+		<h1>Пользователи</h1>
+
+		<table>
+			<thead>
+				<tr><th>Логин</th><th>Имя</th><th>Роль</th><th>Студенты</th><th></th></tr>
+			</thead>
+			<tbody>
+			{{range .Users}}
+			<tr>
+				<td>{{.Login}}</td>
+				<td>{{.Name}}</td>
+				<td>{{.Role.Title}}</td>
+				<td>{{range $i, $id := .StudentIDs}}{{if $i}}, {{end}}{{index $.Names $id}}{{end}}</td>
+				<td>
+					<details class="user-edit">
+						<summary>Изменить</summary>
+						<form action="/api/users/{{.ID.Hex}}/update" method="POST">
+							<input type="text" name="name" value="{{.Name}}" required>
+							{{template "role" .Role}}
+							{{template "students" (link .)}}
+							<input type="password" name="password" placeholder="Новый пароль (не менять — оставить пустым)" autocomplete="new-password">
+							<input type="submit" value="Сохранить">
+						</form>
+						{{if ne .ID $.Self}}
+						<form action="/api/users/{{.ID.Hex}}/delete" method="POST" onsubmit="return confirm('Удалить пользователя?')">
+							<button type="submit" class="small-btn danger">Удалить</button>
+						</form>
+						{{end}}
+					</details>
+				</td>
+			</tr>
+			{{end}}
+			</tbody>
+		</table>
+
+		<h2>Новый пользователь</h2>
+		<form action="/api/users" method="POST">
+			<input type="text" name="login" placeholder="Логин" required>
+			<input type="text" name="name" placeholder="Имя" required>
+			{{template "role" "teacher"}}
+			{{template "students" (link nil)}}
+			<input type="password" name="password" placeholder="Пароль (не короче 6 символов)" minlength="6" required autocomplete="new-password">
+			<input type="submit" value="Создать пользователя">
+		</form>
+
+		<a href="/" class="back-link">← Назад</a>
+	</div>
+</body>
+</html>
+{{define "role"}}
+<select name="role">
+{{$current := .}}
+{{range roles}}
+	<option value="{{.}}"{{if eq . $current}} selected{{end}}>{{.Title}}</option>
+{{end}}
+</select>
+{{end}}
+{{define "students"}}
+<label>Связанные студенты (для студента и родителя):</label>
+<input type="hidden" name="studentIds" value="">
+<select name="studentIds" multiple size="6">
+{{$user := .User}}
+{{range .Groups}}
+	<optgroup label="{{.Group.Name}}">
+	{{range .Students}}
+		<option value="{{.ID.Hex}}"{{if and $user ($user.LinkedTo .ID)}} selected{{end}}>{{.Name}}</option>
+	{{end}}
+	</optgroup>
+{{end}}
+</select>
+{{end}}`
+
+	type linkData struct {
+		Groups []groupStudents
+		User   *models.User
+	}
+	data := struct {
+		Users  []models.User
+		Names  map[primitive.ObjectID]string
+		Groups []groupStudents
+		Self   primitive.ObjectID
+	}{
+		Users:  users,
+		Names:  names,
+		Groups: groups,
+		Self:   currentUser(r).ID,
+	}
+
+	funcs := template.FuncMap{
+		"roles": func() []models.Role { return models.Roles },
+		// link — данные для выбора студентов в форме пользователя
+		"link": func(user interface{}) linkData {
+			u, _ := user.(models.User)
+			d := linkData{Groups: groups}
+			if user != nil {
+				d.User = &u
+			}
+			return d
+		},
+	}
+	t := template.Must(template.New("users").Funcs(funcs).Parse(tmpl))
+	t.Execute(w, data)
+}
@@ -2,41 +2,126 @@
 package main
 
 import (
+	"electronic-diary/config"
 	"electronic-diary/db"
 	"electronic-diary/handlers"
+	"errors"
+	"flag"
 	"log"
 	"net/http"
 	"os"
 )
 
 func main() {
-	// Проверка --reset
-	reset := false
-	for _, arg := range os.Args {
-		if arg == "--reset" {
-			reset = true
-			break
+	cfg, err := config.Load(os.Args[1:], os.Getenv)
+	if errors.Is(err, flag.ErrHelp) {
+		return
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+	if cfg.PrintConfig {
+		if err := cfg.Print(os.Stdout); err != nil {
+			log.Fatal("Не удалось вывести конфигурацию:", err)
 		}
+		return
 	}
 
-	db.Connect()
-	db.InitCollections()
+	var store db.Store
+	switch cfg.Storage {
+	case "mongo":
+		mongoStore, err := db.NewMongoStore(cfg.Mongo.URI, cfg.Mongo.Database)
+		if err != nil {
+			log.Fatal(err)
+		}
+		store = mongoStore
+	case "sqlite":
+		sqliteStore, err := db.NewSQLiteStore(cfg.SQLite.Path)
+		if err != nil {
+			log.Fatal(err)
+		}
+		store = sqliteStore
+	case "memory":
+		store = db.NewMemoryStore()
+		log.Println("🧠 Используется хранилище в памяти — данные не сохранятся после перезапуска")
+	}
+	defer store.Close()
 
-	if reset {
-		db.ResetData()
+	if cfg.Reset {
+		if err := store.Reset(); err != nil {
+			log.Fatal("Не удалось удалить данные:", err)
+		}
+	}
+	if err := db.EnsureTerm(store); err != nil {
+		log.Fatal("Не удалось создать учебный период:", err)
+	}
+	if cfg.Seed {
+		if err := db.SeedData(store); err != nil {
+			log.Fatal("Не удалось создать начальные данные:", err)
+		}
 	}
-	db.SeedData()
+	if err := db.EnsureGradingScales(store); err != nil {
+		log.Fatal("Не удалось создать шкалы оценок:", err)
+	}
+	if err := db.EnsureAdmin(store, cfg.Admin.Login, cfg.Admin.Password); err != nil {
+		log.Fatal("Не удалось создать администратора:", err)
+	}
+
+	srv := handlers.NewServer(store)
+
+	// Регистрируем маршруты. Protect проверяет сессию и роль;
+	// что именно видит студент или родитель, решает сам обработчик.
+	http.HandleFunc("/login", srv.LoginHandler)
+	http.HandleFunc("/logout", srv.LogoutHandler)
+
+	http.HandleFunc("/", srv.Protect(handlers.AnyUser, srv.HomeHandler))
+	http.HandleFunc("/portal", srv.Protect(handlers.AnyUser, srv.PortalHandler))
+	http.HandleFunc("/portal/", srv.Protect(handlers.AnyUser, srv.PortalHandler))
+
+	http.HandleFunc("/api/term", srv.Protect(handlers.AnyUser, srv.SelectTermHandler))
+
+	http.HandleFunc("/group/", srv.Protect(handlers.StaffOnly, srv.GroupHandler))
+	http.HandleFunc("/student/", srv.Protect(handlers.StaffOnly, srv.StudentHandler))
+	http.HandleFunc("/api/student/", srv.Protect(handlers.StaffOnly, srv.UpdateStudentHandler))
+	http.HandleFunc("/lesson/", srv.Protect(handlers.StaffOnly, srv.LessonHandler))
+	http.HandleFunc("/api/lessons", srv.Protect(handlers.StaffOnly, srv.LessonsAPIHandler))
+	http.HandleFunc("/api/lessons/", srv.Protect(handlers.StaffOnly, srv.LessonsAPIHandler))
+	http.HandleFunc("/api/gradebook", srv.Protect(handlers.StaffOnly, srv.GradebookAPIHandler))
+	http.HandleFunc("/discipline/", srv.Protect(handlers.StaffOnly, srv.DisciplineHandler))
+	http.HandleFunc("/assessment/", srv.Protect(handlers.StaffOnly, srv.AssessmentHandler))
+	http.HandleFunc("/api/assessments", srv.Protect(handlers.StaffOnly, srv.AssessmentsAPIHandler))
+	http.HandleFunc("/api/assessments/", srv.Protect(handlers.StaffOnly, srv.AssessmentsAPIHandler))
+
+	http.HandleFunc("/api/groups", srv.Protect(handlers.AdminWrites, srv.GroupsAPIHandler))
+	http.HandleFunc("/api/groups/", srv.Protect(handlers.AdminWrites, srv.GroupsAPIHandler))
+	http.HandleFunc("/api/students", srv.Protect(handlers.AdminWrites, srv.StudentsAPIHandler))
+	http.HandleFunc("/api/students/", srv.Protect(handlers.AdminWrites, srv.StudentsAPIHandler))
+	http.HandleFunc("/api/disciplines", srv.Protect(handlers.AdminWrites, srv.DisciplinesAPIHandler))
+	http.HandleFunc("/api/disciplines/", srv.Protect(handlers.AdminWrites, srv.DisciplinesAPIHandler))
+	http.HandleFunc("/terms", srv.Protect(handlers.AdminWrites, srv.TermsHandler))
+	http.HandleFunc("/api/terms", srv.Protect(handlers.AdminWrites, srv.TermsAPIHandler))
+	http.HandleFunc("/api/terms/", srv.Protect(handlers.AdminWrites, srv.TermsAPIHandler))
+	http.HandleFunc("/api/years", srv.Protect(handlers.AdminWrites, srv.YearsAPIHandler))
+	http.HandleFunc("/scales", srv.Protect(handlers.AdminWrites, srv.GradingScalesHandler))
+	http.HandleFunc("/api/grading-scales", srv.Protect(handlers.AdminWrites, srv.GradingScalesAPIHandler))
+	http.HandleFunc("/api/grading-scales/", srv.Protect(handlers.AdminWrites, srv.GradingScalesAPIHandler))
+
+	http.HandleFunc("/api/reset-dynamic", srv.Protect(handlers.AdminOnly, srv.ResetDynamicHandler))
+	http.HandleFunc("/users", srv.Protect(handlers.AdminOnly, srv.UsersHandler))
+	http.HandleFunc("/audit", srv.Protect(handlers.AdminOnly, srv.AuditHandler))
+	http.HandleFunc("/import/", srv.Protect(handlers.AdminOnly, srv.ImportHandler))
+	http.HandleFunc("/resets", srv.Protect(handlers.AdminOnly, srv.ResetsHandler))
+	http.HandleFunc("/api/resets/", srv.Protect(handlers.AdminOnly, srv.ResetsAPIHandler))
+	http.HandleFunc("/api/users", srv.Protect(handlers.AdminOnly, srv.UsersAPIHandler))
+	http.HandleFunc("/api/users/", srv.Protect(handlers.AdminOnly, srv.UsersAPIHandler))
 
-	// Регистрируем маршруты
-	http.HandleFunc("/", handlers.HomeHandler)
-	http.HandleFunc("/group/", handlers.GroupHandler)
-	http.HandleFunc("/student/", handlers.StudentHandler)
-	http.HandleFunc("/api/student/", handlers.UpdateStudentHandler)
-	http.HandleFunc("/api/reset-dynamic", handlers.ResetDynamicHandler)
+	// API v1 проверяет сессию сам, чтобы ошибки шли в его JSON-формате
+	http.HandleFunc("/api/v1/", srv.APIv1Handler)
+	http.HandleFunc("/api/docs", handlers.APIDocsHandler)
 
 	// Статические файлы (CSS/JS)
-	http.Handle("/static/", http.StripPrefix("/static/", http.FileServer(http.Dir("static/"))))
+	http.Handle("/static/", http.StripPrefix("/static/", http.FileServer(http.Dir(cfg.StaticDir))))
 
-	log.Println("🚀 Сервер запущен на http://localhost:8080")
-	log.Fatal(http.ListenAndServe(":8080", nil))	
+	log.Println("🚀 Сервер запущен на", cfg.Addr)
+	log.Fatal(http.ListenAndServe(cfg.Addr, nil))
 }
\ No newline at end of file
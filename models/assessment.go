@@ -0,0 +1,35 @@
+// models/assessment.go
+package models
+
+import (
+	"math"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// WeightedScore сводит баллы студента за работы дисциплины в оценку 0–100,
+// которая хранится в StudentDisciplineData.Score. Каждая работа даёт долю
+// points/MaxPoints, доли усредняются с весами работ. Работы, за которые
+// студент ещё не оценён, не учитываются; ok == false, если оценок нет совсем.
+func WeightedScore(assessments []Assessment, points map[primitive.ObjectID]float64) (score int, ok bool) {
+	var sum, weights float64
+	for _, a := range assessments {
+		p, marked := points[a.ID]
+		if !marked || !finite(p) || !finite(a.MaxPoints) || !finite(a.Weight) ||
+			a.MaxPoints <= 0 || a.Weight <= 0 {
+			continue
+		}
+		sum += a.Weight * math.Min(p/a.MaxPoints, 1)
+		weights += a.Weight
+	}
+	if weights == 0 {
+		return 0, false
+	}
+	return int(math.Round(sum / weights * 100)), true
+}
+
+// finite — не NaN и не бесконечность: с такими значениями сравнения
+// всегда ложны и проверки диапазона их не отсекают.
+func finite(v float64) bool {
+	return !math.IsNaN(v) && !math.IsInf(v, 0)
+}
@@ -0,0 +1,39 @@
+// models/attendance.go
+package models
+
+type AttendanceStatus string
+
+const (
+	AttendancePresent AttendanceStatus = "present"
+	AttendanceLate    AttendanceStatus = "late"
+	AttendanceAbsent  AttendanceStatus = "absent"
+	AttendanceExcused AttendanceStatus = "excused"
+)
+
+// AttendanceStatuses — все допустимые отметки в порядке показа в журнале.
+var AttendanceStatuses = []AttendanceStatus{AttendancePresent, AttendanceLate, AttendanceAbsent, AttendanceExcused}
+
+func (s AttendanceStatus) Valid() bool {
+	for _, st := range AttendanceStatuses {
+		if s == st {
+			return true
+		}
+	}
+	return false
+}
+
+// CountAttendance выводит счётчики StudentDisciplineData из отметок:
+// занятия с уважительной причиной не учитываются вовсе, опоздание
+// считается посещением.
+func CountAttendance(statuses []AttendanceStatus) (total, attended int) {
+	for _, s := range statuses {
+		switch s {
+		case AttendancePresent, AttendanceLate:
+			total++
+			attended++
+		case AttendanceAbsent:
+			total++
+		}
+	}
+	return total, attended
+}
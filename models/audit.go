@@ -0,0 +1,64 @@
+// models/audit.go
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Сущности журнала изменений.
+const (
+	AuditStudent = "student" // комментарий студента
+	AuditRecord  = "record"  // запись студента по дисциплине
+	AuditDiary   = "diary"   // сброс данных и его отмена
+	AuditLesson  = "lesson"  // отметка посещаемости на занятии
+	AuditWork    = "work"    // баллы за оцениваемую работу
+)
+
+// AuditEntry — одно изменённое поле. Журнал только пополняется:
+// записи не меняются и не удаляются.
+type AuditEntry struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	At        time.Time          `bson:"at" json:"at"`
+	ActorID   primitive.ObjectID `bson:"actorId" json:"actorId"`
+	ActorName string             `bson:"actorName" json:"actorName"`
+	Entity    string             `bson:"entity" json:"entity"`
+	// EntityID — студент, запись или снимок сброса
+	EntityID     primitive.ObjectID  `bson:"entityId" json:"entityId"`
+	StudentID    *primitive.ObjectID `bson:"studentId,omitempty" json:"studentId"`
+	DisciplineID *primitive.ObjectID `bson:"disciplineId,omitempty" json:"disciplineId"`
+	Field        string              `bson:"field" json:"field"`
+	OldValue     string              `bson:"oldValue" json:"oldValue"`
+	NewValue     string              `bson:"newValue" json:"newValue"`
+}
+
+// AuditFilter — условия выборки журнала; пустые поля не ограничивают.
+// Записи отдаются от новых к старым, не больше Limit.
+type AuditFilter struct {
+	ActorID      *primitive.ObjectID
+	StudentID    *primitive.ObjectID
+	DisciplineID *primitive.ObjectID
+	Field        string
+	From, To     time.Time
+	Limit        int
+}
+
+// Match проверяет запись на соответствие фильтру (без учёта Limit).
+func (f AuditFilter) Match(e AuditEntry) bool {
+	switch {
+	case f.ActorID != nil && e.ActorID != *f.ActorID:
+		return false
+	case f.StudentID != nil && (e.StudentID == nil || *e.StudentID != *f.StudentID):
+		return false
+	case f.DisciplineID != nil && (e.DisciplineID == nil || *e.DisciplineID != *f.DisciplineID):
+		return false
+	case f.Field != "" && e.Field != f.Field:
+		return false
+	case !f.From.IsZero() && e.At.Before(f.From):
+		return false
+	case !f.To.IsZero() && !e.At.Before(f.To):
+		return false
+	}
+	return true
+}
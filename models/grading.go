@@ -0,0 +1,173 @@
+// models/grading.go
+package models
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// GradingScale — шкала перевода балла 0–100 в оценку.
+// Встроенные шкалы отличаются непустым Key и не удаляются.
+type GradingScale struct {
+	ID    primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	Key   string             `bson:"key,omitempty" json:"key,omitempty"`
+	Name  string             `bson:"name" json:"name"`
+	Bands []GradeBand        `bson:"bands" json:"bands"`
+}
+
+// GradeBand — оценка Label ставится за балл не ниже Min.
+// Class — CSS-класс цвета оценки (grade-1 … grade-5).
+type GradeBand struct {
+	Min   int    `bson:"min" json:"min"`
+	Label string `bson:"label" json:"label"`
+	Class string `bson:"class" json:"class"`
+}
+
+// Grade находит оценку за балл. Полосы просматриваются от старшей
+// к младшей; балл ниже всех порогов получает младшую оценку.
+func (sc GradingScale) Grade(score int) GradeBand {
+	bands := sc.sortedBands()
+	for _, b := range bands {
+		if score >= b.Min {
+			return b
+		}
+	}
+	if len(bands) == 0 {
+		return GradeBand{Label: "—"}
+	}
+	return bands[len(bands)-1]
+}
+
+// Table — оценки за все баллы 0–100. По ней страницы показывают
+// предпросмотр оценки в браузере, не дублируя правила шкалы в JS.
+func (sc GradingScale) Table() []GradeBand {
+	table := make([]GradeBand, 101)
+	for score := range table {
+		table[score] = sc.Grade(score)
+	}
+	return table
+}
+
+func (sc GradingScale) sortedBands() []GradeBand {
+	bands := append([]GradeBand(nil), sc.Bands...)
+	sort.SliceStable(bands, func(i, j int) bool { return bands[i].Min > bands[j].Min })
+	return bands
+}
+
+// Validate проверяет шкалу и упорядочивает полосы от старшей к младшей.
+func (sc *GradingScale) Validate() error {
+	sc.Name = strings.TrimSpace(sc.Name)
+	if sc.Name == "" {
+		return errors.New("название шкалы не может быть пустым")
+	}
+	if len(sc.Bands) == 0 {
+		return errors.New("в шкале должна быть хотя бы одна оценка")
+	}
+	seen := make(map[int]bool, len(sc.Bands))
+	hasZero := false
+	for i := range sc.Bands {
+		b := &sc.Bands[i]
+		b.Label = strings.TrimSpace(b.Label)
+		if b.Label == "" {
+			return errors.New("у каждой оценки должно быть название")
+		}
+		if b.Min < 0 || b.Min > 100 {
+			return fmt.Errorf("порог оценки %q должен быть от 0 до 100", b.Label)
+		}
+		if b.Class != "" && !validGradeClass(b.Class) {
+			return fmt.Errorf("неизвестный цвет оценки %q", b.Class)
+		}
+		if seen[b.Min] {
+			return fmt.Errorf("порог %d встречается дважды", b.Min)
+		}
+		seen[b.Min] = true
+		hasZero = hasZero || b.Min == 0
+	}
+	if !hasZero {
+		return errors.New("нужна оценка с порогом 0 — для самых низких баллов")
+	}
+	sc.Bands = sc.sortedBands()
+
+	// Цвет, если не задан, подбираем по месту оценки: старшая — grade-5,
+	// младшая — grade-1
+	for i := range sc.Bands {
+		if sc.Bands[i].Class != "" {
+			continue
+		}
+		rank := 5
+		if n := len(sc.Bands); n > 1 {
+			rank = 5 - int(math.Round(float64(i)*4/float64(n-1)))
+		}
+		sc.Bands[i].Class = fmt.Sprintf("grade-%d", rank)
+	}
+	return nil
+}
+
+func validGradeClass(class string) bool {
+	for rank := 1; rank <= 5; rank++ {
+		if class == fmt.Sprintf("grade-%d", rank) {
+			return true
+		}
+	}
+	return false
+}
+
+// FivePointScale — пятибалльная шкала, которой дневник пользовался
+// до появления настраиваемых шкал. Применяется, если шкала не назначена.
+func FivePointScale() GradingScale {
+	return GradingScale{
+		Key:  "five-point",
+		Name: "Пятибалльная",
+		Bands: []GradeBand{
+			{Min: 80, Label: "5", Class: "grade-5"},
+			{Min: 60, Label: "4", Class: "grade-4"},
+			{Min: 40, Label: "3", Class: "grade-3"},
+			{Min: 20, Label: "2", Class: "grade-2"},
+			{Min: 0, Label: "1", Class: "grade-1"},
+		},
+	}
+}
+
+// BuiltinScales — шкалы, которые создаются в пустом хранилище.
+func BuiltinScales() []GradingScale {
+	return []GradingScale{
+		FivePointScale(),
+		{
+			Key:  "pass-fail",
+			Name: "Зачёт / незачёт",
+			Bands: []GradeBand{
+				{Min: 40, Label: "Зачёт", Class: "grade-5"},
+				{Min: 0, Label: "Незачёт", Class: "grade-1"},
+			},
+		},
+		{
+			Key:  "letters",
+			Name: "Буквенная A–F",
+			Bands: []GradeBand{
+				{Min: 90, Label: "A", Class: "grade-5"},
+				{Min: 80, Label: "B", Class: "grade-4"},
+				{Min: 70, Label: "C", Class: "grade-3"},
+				{Min: 60, Label: "D", Class: "grade-2"},
+				{Min: 0, Label: "F", Class: "grade-1"},
+			},
+		},
+		{
+			Key:  "ects",
+			Name: "ECTS",
+			Bands: []GradeBand{
+				{Min: 90, Label: "A", Class: "grade-5"},
+				{Min: 82, Label: "B", Class: "grade-4"},
+				{Min: 74, Label: "C", Class: "grade-4"},
+				{Min: 64, Label: "D", Class: "grade-3"},
+				{Min: 60, Label: "E", Class: "grade-3"},
+				{Min: 35, Label: "FX", Class: "grade-2"},
+				{Min: 0, Label: "F", Class: "grade-1"},
+			},
+		},
+	}
+}
@@ -1,11 +1,19 @@
 // models/models.go
 package models
 
-import "go.mongodb.org/mongo-driver/bson/primitive"
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
 
 type Group struct {
-	ID   primitive.ObjectID `bson:"_id,omitempty" json:"id"`
-	Name string             `bson:"name" json:"name"`
+	ID       primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	Name     string             `bson:"name" json:"name"`
+	Slug     string             `bson:"slug" json:"slug"`
+	Archived bool               `bson:"archived" json:"archived"`
+	// Шкала оценок группы; nil — пятибалльная
+	GradingScaleID *primitive.ObjectID `bson:"gradingScaleId,omitempty" json:"gradingScaleId"`
 }
 
 type Student struct {
@@ -13,19 +21,69 @@ type Student struct {
 	Name     string             `bson:"name" json:"name"`
 	GroupID  primitive.ObjectID `bson:"groupId" json:"groupId"`
 	Comments string             `bson:"comments" json:"comments"`
+	// Version растёт при каждом изменении; по нему ловятся одновременные правки
+	Version int `bson:"version" json:"version"`
 }
 
 type Discipline struct {
-	ID      primitive.ObjectID `bson:"_id,omitempty" json:"id"`
-	Name    string             `bson:"name" json:"name"`
-	GroupID primitive.ObjectID `bson:"groupId" json:"groupId"`
+	ID       primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	Name     string             `bson:"name" json:"name"`
+	GroupID  primitive.ObjectID `bson:"groupId" json:"groupId"`
+	Position int                `bson:"position" json:"position"`
+	Retired  bool               `bson:"retired" json:"retired"`
+	// Своя шкала дисциплины; nil — шкала группы
+	GradingScaleID *primitive.ObjectID `bson:"gradingScaleId,omitempty" json:"gradingScaleId"`
+	// Преподаватель дисциплины; только он (и администратор) меняет её записи
+	TeacherID *primitive.ObjectID `bson:"teacherId,omitempty" json:"teacherId"`
 }
 
 type StudentDisciplineData struct {
 	ID               primitive.ObjectID `bson:"_id,omitempty" json:"id"`
 	StudentID        primitive.ObjectID `bson:"studentId" json:"studentId"`
 	DisciplineID     primitive.ObjectID `bson:"disciplineId" json:"disciplineId"`
+	TermID           primitive.ObjectID `bson:"termId" json:"termId"`
 	Score            int                `bson:"score" json:"score"`
 	TotalClasses     int                `bson:"totalClasses" json:"totalClasses"`
 	AttendedClasses  int                `bson:"attendedClasses" json:"attendedClasses"`
+	// Version растёт при каждой ручной правке; пересчёт по журналу и работам
+	// его не трогает — эти поля форма не перезаписывает
+	Version int `bson:"version" json:"version"`
+}
+
+// Lesson — проведённое занятие по дисциплине в группе.
+type Lesson struct {
+	ID           primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	GroupID      primitive.ObjectID `bson:"groupId" json:"groupId"`
+	DisciplineID primitive.ObjectID `bson:"disciplineId" json:"disciplineId"`
+	TermID       primitive.ObjectID `bson:"termId" json:"termId"`
+	Date         time.Time          `bson:"date" json:"date"`
+	Topic        string             `bson:"topic" json:"topic"`
+}
+
+// AttendanceMark — отметка студента на занятии.
+type AttendanceMark struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	LessonID  primitive.ObjectID `bson:"lessonId" json:"lessonId"`
+	StudentID primitive.ObjectID `bson:"studentId" json:"studentId"`
+	Status    AttendanceStatus   `bson:"status" json:"status"`
+}
+
+// Assessment — оцениваемая работа по дисциплине: лабораторная, тест, экзамен.
+type Assessment struct {
+	ID           primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	GroupID      primitive.ObjectID `bson:"groupId" json:"groupId"`
+	DisciplineID primitive.ObjectID `bson:"disciplineId" json:"disciplineId"`
+	TermID       primitive.ObjectID `bson:"termId" json:"termId"`
+	Name         string             `bson:"name" json:"name"`
+	Date         time.Time          `bson:"date" json:"date"`
+	MaxPoints    float64            `bson:"maxPoints" json:"maxPoints"`
+	Weight       float64            `bson:"weight" json:"weight"`
+}
+
+// AssessmentMark — баллы студента за работу.
+type AssessmentMark struct {
+	ID           primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	AssessmentID primitive.ObjectID `bson:"assessmentId" json:"assessmentId"`
+	StudentID    primitive.ObjectID `bson:"studentId" json:"studentId"`
+	Points       float64            `bson:"points" json:"points"`
 }
\ No newline at end of file
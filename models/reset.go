@@ -0,0 +1,40 @@
+// models/reset.go
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ResetScope — что обнуляет сброс. Пустая область — весь дневник; группа —
+// комментарии, счётчики, отметки и баллы её студентов; дисциплина — только
+// счётчики, отметки и баллы по ней (комментарии студентов не трогаются).
+type ResetScope struct {
+	GroupID      *primitive.ObjectID `bson:"groupId,omitempty" json:"groupId"`
+	DisciplineID *primitive.ObjectID `bson:"disciplineId,omitempty" json:"disciplineId"`
+}
+
+// StudentComment — комментарий студента на момент сброса.
+type StudentComment struct {
+	StudentID primitive.ObjectID `bson:"studentId" json:"studentId"`
+	Comments  string             `bson:"comments" json:"comments"`
+}
+
+// ResetSnapshot — данные, которые обнулил сброс. Из снимка сброс можно
+// отменить, пока это не сделано однажды (RestoredAt пуст).
+type ResetSnapshot struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	At        time.Time          `bson:"at" json:"at"`
+	ActorID   primitive.ObjectID `bson:"actorId" json:"actorId"`
+	ActorName string             `bson:"actorName" json:"actorName"`
+	Scope     ResetScope         `bson:"scope" json:"scope"`
+	// ScopeName — подпись области для истории сбросов: группа или дисциплина
+	// могут быть позже переименованы или удалены
+	ScopeName  string                  `bson:"scopeName" json:"scopeName"`
+	Comments   []StudentComment        `bson:"comments" json:"comments"`
+	Records    []StudentDisciplineData `bson:"records" json:"records"`
+	Attendance []AttendanceMark        `bson:"attendance" json:"attendance"`
+	Marks      []AssessmentMark        `bson:"marks" json:"marks"`
+	RestoredAt *time.Time              `bson:"restoredAt,omitempty" json:"restoredAt"`
+}
@@ -0,0 +1,71 @@
+// models/term.go
+package models
+
+import (
+	"strconv"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// AcademicYear — учебный год, например «2025/2026».
+type AcademicYear struct {
+	ID   primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	Name string             `bson:"name" json:"name"`
+}
+
+// Term — учебный период (семестр, четверть) внутри года. Записи студентов,
+// занятия и работы относятся к периоду. Закрытый период заморожен: его
+// оценки и посещаемость можно только смотреть.
+type Term struct {
+	ID     primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	YearID primitive.ObjectID `bson:"yearId" json:"yearId"`
+	Name   string             `bson:"name" json:"name"`
+	Start  time.Time          `bson:"start" json:"start"`
+	End    time.Time          `bson:"end" json:"end"`
+	Closed bool               `bson:"closed" json:"closed"`
+}
+
+// CurrentTerm выбирает период по умолчанию: открытый период, в который
+// попадает now, иначе последний по дате начала открытый, иначе последний
+// вообще. terms упорядочены по дате начала; nil — периодов нет.
+func CurrentTerm(terms []Term, now time.Time) *Term {
+	var latestOpen *Term
+	for i := range terms {
+		t := &terms[i]
+		if t.Closed {
+			continue
+		}
+		if !now.Before(t.Start) && now.Before(t.End.AddDate(0, 0, 1)) {
+			return t
+		}
+		latestOpen = t
+	}
+	if latestOpen != nil {
+		return latestOpen
+	}
+	if len(terms) > 0 {
+		return &terms[len(terms)-1]
+	}
+	return nil
+}
+
+// DefaultTerm — год и семестр, в который попадает now: осенний с сентября
+// по январь, весенний с февраля по август.
+func DefaultTerm(now time.Time) (year AcademicYear, term Term) {
+	y := now.Year()
+	if now.Month() < time.September {
+		y--
+	}
+	year.Name = strconv.Itoa(y) + "/" + strconv.Itoa(y+1)
+	if now.Month() >= time.September || now.Month() == time.January {
+		term.Name = "Осенний семестр"
+		term.Start = time.Date(y, time.September, 1, 0, 0, 0, 0, time.UTC)
+		term.End = time.Date(y+1, time.January, 31, 0, 0, 0, 0, time.UTC)
+	} else {
+		term.Name = "Весенний семестр"
+		term.Start = time.Date(y+1, time.February, 1, 0, 0, 0, 0, time.UTC)
+		term.End = time.Date(y+1, time.August, 31, 0, 0, 0, 0, time.UTC)
+	}
+	return year, term
+}
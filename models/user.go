@@ -0,0 +1,92 @@
+// models/user.go
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"golang.org/x/crypto/bcrypt"
+)
+
+type Role string
+
+const (
+	RoleAdmin   Role = "admin"
+	RoleTeacher Role = "teacher"
+	RoleStudent Role = "student"
+	RoleParent  Role = "parent"
+)
+
+// Roles — все роли в порядке показа в формах.
+var Roles = []Role{RoleAdmin, RoleTeacher, RoleStudent, RoleParent}
+
+func (r Role) Valid() bool {
+	for _, role := range Roles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
+// Staff — администраторы и преподаватели работают с группами целиком;
+// студенты и родители видят только связанные с ними записи.
+func (r Role) Staff() bool {
+	return r == RoleAdmin || r == RoleTeacher
+}
+
+func (r Role) Title() string {
+	switch r {
+	case RoleAdmin:
+		return "Администратор"
+	case RoleTeacher:
+		return "Преподаватель"
+	case RoleStudent:
+		return "Студент"
+	case RoleParent:
+		return "Родитель"
+	}
+	return string(r)
+}
+
+// User — учётная запись. StudentIDs — связанные студенты:
+// для студента это его собственная запись, для родителя — дети.
+type User struct {
+	ID           primitive.ObjectID   `bson:"_id,omitempty" json:"id"`
+	Login        string               `bson:"login" json:"login"`
+	Name         string               `bson:"name" json:"name"`
+	Role         Role                 `bson:"role" json:"role"`
+	PasswordHash string               `bson:"passwordHash" json:"-"`
+	StudentIDs   []primitive.ObjectID `bson:"studentIds" json:"studentIds"`
+}
+
+// SetPassword сохраняет bcrypt-хеш пароля.
+func (u *User) SetPassword(password string) error {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+	u.PasswordHash = string(hash)
+	return nil
+}
+
+func (u *User) CheckPassword(password string) bool {
+	return bcrypt.CompareHashAndPassword([]byte(u.PasswordHash), []byte(password)) == nil
+}
+
+// LinkedTo — связан ли пользователь со студентом.
+func (u *User) LinkedTo(studentID primitive.ObjectID) bool {
+	for _, id := range u.StudentIDs {
+		if id == studentID {
+			return true
+		}
+	}
+	return false
+}
+
+// Session — вход пользователя; Token хранится в cookie.
+type Session struct {
+	Token     string             `bson:"_id" json:"-"`
+	UserID    primitive.ObjectID `bson:"userId" json:"userId"`
+	ExpiresAt time.Time          `bson:"expiresAt" json:"expiresAt"`
+}
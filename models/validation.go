@@ -0,0 +1,35 @@
+// models/validation.go
+package models
+
+import (
+	"sort"
+	"strings"
+)
+
+// FieldErrors — ошибки по полям: JSON-имя поля → сообщение.
+type FieldErrors map[string]string
+
+func (e FieldErrors) Error() string {
+	msgs := make([]string, 0, len(e))
+	for field, msg := range e {
+		msgs = append(msgs, field+": "+msg)
+	}
+	sort.Strings(msgs)
+	return strings.Join(msgs, "; ")
+}
+
+// Validate проверяет счётчики записи: балл 0–100, занятий не меньше нуля,
+// посещено не больше, чем проведено. Пустой результат — запись корректна.
+func (d *StudentDisciplineData) Validate() FieldErrors {
+	errs := FieldErrors{}
+	if d.Score < 0 || d.Score > 100 {
+		errs["score"] = "балл — от 0 до 100"
+	}
+	if d.TotalClasses < 0 {
+		errs["totalClasses"] = "число занятий не может быть отрицательным"
+	}
+	if d.AttendedClasses < 0 || d.AttendedClasses > d.TotalClasses {
+		errs["attendedClasses"] = "посещено — от 0 до числа занятий"
+	}
+	return errs
+}
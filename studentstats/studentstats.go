@@ -0,0 +1,111 @@
+// studentstats/studentstats.go
+package studentstats
+
+import "electronic-diary/models"
+
+// Итоги студента за период по записям его дисциплин. Пакет ничего не
+// читает из хранилища: записи передаёт вызывающий, и порядок записей
+// определяет порядок дисциплин в результате.
+
+// Summary — итоги студента по записям дисциплин.
+type Summary struct {
+	// Count — сколько записей учтено (пустые не учитываются)
+	Count int
+	// GPA — средний балл по учтённым записям; 0, если их нет
+	GPA             float64
+	AttendedClasses int
+	TotalClasses    int
+	// Лучшие и худшие дисциплины: при равенстве — все равные в порядке
+	// входных записей. Дисциплины без занятий в посещаемость не входят.
+	BestScore       []models.StudentDisciplineData
+	WorstScore      []models.StudentDisciplineData
+	BestAttendance  []models.StudentDisciplineData
+	WorstAttendance []models.StudentDisciplineData
+}
+
+// Empty — в записи нет ни балла, ни занятий: такие записи создаются
+// заранее для всех студентов и в итоги не входят.
+func Empty(d models.StudentDisciplineData) bool {
+	return d.Score == 0 && d.TotalClasses == 0
+}
+
+// HasAttendance — были ли занятия хотя бы по одной дисциплине.
+func (s Summary) HasAttendance() bool {
+	return s.TotalClasses > 0
+}
+
+// AttendanceRate — посещаемость по всем занятиям, в процентах; 0, если
+// занятий не было.
+func (s Summary) AttendanceRate() float64 {
+	if s.TotalClasses == 0 {
+		return 0
+	}
+	return float64(s.AttendedClasses) / float64(s.TotalClasses) * 100
+}
+
+// Compute считает итоги по записям.
+func Compute(records []models.StudentDisciplineData) Summary {
+	var s Summary
+	total := 0
+	for _, d := range records {
+		if Empty(d) {
+			continue
+		}
+		s.Count++
+		total += d.Score
+		s.BestScore = keep(s.BestScore, d, compareScore(d, s.BestScore), 1)
+		s.WorstScore = keep(s.WorstScore, d, compareScore(d, s.WorstScore), -1)
+
+		if d.TotalClasses == 0 {
+			continue
+		}
+		s.AttendedClasses += d.AttendedClasses
+		s.TotalClasses += d.TotalClasses
+		s.BestAttendance = keep(s.BestAttendance, d, compareAttendance(d, s.BestAttendance), 1)
+		s.WorstAttendance = keep(s.WorstAttendance, d, compareAttendance(d, s.WorstAttendance), -1)
+	}
+	if s.Count > 0 {
+		s.GPA = float64(total) / float64(s.Count)
+	}
+	return s
+}
+
+// keep обновляет список лидеров: cmp — результат сравнения записи с
+// лидерами (1 — больше, 0 — равна, -1 — меньше), want — какое
+// направление ищем.
+func keep(leaders []models.StudentDisciplineData, d models.StudentDisciplineData, cmp, want int) []models.StudentDisciplineData {
+	switch {
+	case len(leaders) == 0 || cmp == want:
+		return []models.StudentDisciplineData{d}
+	case cmp == 0:
+		return append(leaders, d)
+	}
+	return leaders
+}
+
+func compareScore(d models.StudentDisciplineData, leaders []models.StudentDisciplineData) int {
+	if len(leaders) == 0 {
+		return 0
+	}
+	return compare(d.Score, leaders[0].Score)
+}
+
+// compareAttendance сравнивает доли посещённых занятий без деления:
+// 1/3 и 2/6 равны точно, а не с точностью до float.
+func compareAttendance(d models.StudentDisciplineData, leaders []models.StudentDisciplineData) int {
+	if len(leaders) == 0 {
+		return 0
+	}
+	l := leaders[0]
+	return compare(d.AttendedClasses*l.TotalClasses, l.AttendedClasses*d.TotalClasses)
+}
+
+func compare(a, b int) int {
+	switch {
+	case a > b:
+		return 1
+	case a < b:
+		return -1
+	}
+	return 0
+}
@@ -0,0 +1,247 @@
+// studentstats/studentstats_test.go
+package studentstats
+
+import (
+	"electronic-diary/models"
+	"math"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Дисциплины тестов: записи ссылаются на них по номеру.
+var disciplines = []primitive.ObjectID{
+	primitive.NewObjectID(),
+	primitive.NewObjectID(),
+	primitive.NewObjectID(),
+	primitive.NewObjectID(),
+}
+
+func record(disc, score, attended, total int) models.StudentDisciplineData {
+	return models.StudentDisciplineData{
+		DisciplineID:    disciplines[disc],
+		Score:           score,
+		AttendedClasses: attended,
+		TotalClasses:    total,
+	}
+}
+
+// indexes — номера дисциплин записей, чтобы сравнивать результат
+// с ожиданием без ObjectID.
+func indexes(records []models.StudentDisciplineData) []int {
+	list := []int{}
+	for _, d := range records {
+		for i, id := range disciplines {
+			if d.DisciplineID == id {
+				list = append(list, i)
+			}
+		}
+	}
+	return list
+}
+
+func equal(a, b []int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestCompute(t *testing.T) {
+	tests := []struct {
+		name            string
+		records         []models.StudentDisciplineData
+		count           int
+		gpa             float64
+		rate            float64
+		hasAttendance   bool
+		bestScore       []int
+		worstScore      []int
+		bestAttendance  []int
+		worstAttendance []int
+	}{
+		{
+			name:            "нет записей",
+			bestScore:       []int{},
+			worstScore:      []int{},
+			bestAttendance:  []int{},
+			worstAttendance: []int{},
+		},
+		{
+			name:            "только пустые записи",
+			records:         []models.StudentDisciplineData{record(0, 0, 0, 0), record(1, 0, 0, 0)},
+			bestScore:       []int{},
+			worstScore:      []int{},
+			bestAttendance:  []int{},
+			worstAttendance: []int{},
+		},
+		{
+			name:            "одна запись",
+			records:         []models.StudentDisciplineData{record(0, 75, 3, 4)},
+			count:           1,
+			gpa:             75,
+			rate:            75,
+			hasAttendance:   true,
+			bestScore:       []int{0},
+			worstScore:      []int{0},
+			bestAttendance:  []int{0},
+			worstAttendance: []int{0},
+		},
+		{
+			name: "разные баллы и посещаемость",
+			records: []models.StudentDisciplineData{
+				record(0, 60, 9, 10),
+				record(1, 90, 5, 10),
+				record(2, 40, 10, 10),
+			},
+			count:           3,
+			gpa:             190.0 / 3,
+			rate:            80,
+			hasAttendance:   true,
+			bestScore:       []int{1},
+			worstScore:      []int{2},
+			bestAttendance:  []int{2},
+			worstAttendance: []int{1},
+		},
+		{
+			name: "равные баллы — все в порядке записей",
+			records: []models.StudentDisciplineData{
+				record(2, 80, 1, 2),
+				record(0, 50, 1, 2),
+				record(1, 80, 1, 2),
+				record(3, 50, 1, 2),
+			},
+			count:           4,
+			gpa:             65,
+			rate:            50,
+			hasAttendance:   true,
+			bestScore:       []int{2, 1},
+			worstScore:      []int{0, 3},
+			bestAttendance:  []int{2, 0, 1, 3},
+			worstAttendance: []int{2, 0, 1, 3},
+		},
+		{
+			name: "равные доли посещаемости с разным числом занятий",
+			records: []models.StudentDisciplineData{
+				record(0, 70, 1, 3),
+				record(1, 70, 2, 6),
+				record(2, 70, 3, 3),
+			},
+			count:           3,
+			gpa:             70,
+			rate:            50,
+			hasAttendance:   true,
+			bestScore:       []int{0, 1, 2},
+			worstScore:      []int{0, 1, 2},
+			bestAttendance:  []int{2},
+			worstAttendance: []int{0, 1},
+		},
+		{
+			name: "дисциплина без занятий не входит в посещаемость",
+			records: []models.StudentDisciplineData{
+				record(0, 85, 0, 0),
+				record(1, 60, 0, 4),
+				record(2, 70, 2, 4),
+			},
+			count:           3,
+			gpa:             215.0 / 3,
+			rate:            25,
+			hasAttendance:   true,
+			bestScore:       []int{0},
+			worstScore:      []int{1},
+			bestAttendance:  []int{2},
+			worstAttendance: []int{1},
+		},
+		{
+			name: "занятий не было ни по одной дисциплине",
+			records: []models.StudentDisciplineData{
+				record(0, 30, 0, 0),
+				record(1, 45, 0, 0),
+			},
+			count:           2,
+			gpa:             37.5,
+			bestScore:       []int{1},
+			worstScore:      []int{0},
+			bestAttendance:  []int{},
+			worstAttendance: []int{},
+		},
+		{
+			name: "пустая запись не считается худшей",
+			records: []models.StudentDisciplineData{
+				record(0, 0, 0, 0),
+				record(1, 55, 4, 5),
+				record(2, 0, 0, 5),
+			},
+			count:           2,
+			gpa:             27.5,
+			rate:            40,
+			hasAttendance:   true,
+			bestScore:       []int{1},
+			worstScore:      []int{2},
+			bestAttendance:  []int{1},
+			worstAttendance: []int{2},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := Compute(tt.records)
+			if s.Count != tt.count {
+				t.Errorf("Count = %d, ждали %d", s.Count, tt.count)
+			}
+			if math.Abs(s.GPA-tt.gpa) > 1e-9 {
+				t.Errorf("GPA = %v, ждали %v", s.GPA, tt.gpa)
+			}
+			if math.Abs(s.AttendanceRate()-tt.rate) > 1e-9 {
+				t.Errorf("AttendanceRate = %v, ждали %v", s.AttendanceRate(), tt.rate)
+			}
+			if s.HasAttendance() != tt.hasAttendance {
+				t.Errorf("HasAttendance = %v, ждали %v", s.HasAttendance(), tt.hasAttendance)
+			}
+			for _, c := range []struct {
+				field string
+				got   []models.StudentDisciplineData
+				want  []int
+			}{
+				{"BestScore", s.BestScore, tt.bestScore},
+				{"WorstScore", s.WorstScore, tt.worstScore},
+				{"BestAttendance", s.BestAttendance, tt.bestAttendance},
+				{"WorstAttendance", s.WorstAttendance, tt.worstAttendance},
+			} {
+				if got := indexes(c.got); !equal(got, c.want) {
+					t.Errorf("%s = %v, ждали %v", c.field, got, c.want)
+				}
+			}
+		})
+	}
+}
+
+// TestComputeDeterministic — результат не зависит от того, сколько раз
+// его считать, и лидеры не ссылаются на общую переменную цикла.
+func TestComputeDeterministic(t *testing.T) {
+	records := []models.StudentDisciplineData{
+		record(0, 70, 2, 4),
+		record(1, 90, 4, 4),
+		record(2, 90, 1, 4),
+		record(3, 20, 4, 4),
+	}
+	first := Compute(records)
+	for i := 0; i < 100; i++ {
+		s := Compute(records)
+		if !equal(indexes(s.BestScore), indexes(first.BestScore)) ||
+			!equal(indexes(s.WorstAttendance), indexes(first.WorstAttendance)) {
+			t.Fatalf("результат изменился на попытке %d", i)
+		}
+	}
+	if got := indexes(first.BestScore); !equal(got, []int{1, 2}) {
+		t.Errorf("BestScore = %v, ждали [1 2]", got)
+	}
+	if first.BestScore[0].Score != 90 || first.WorstScore[0].Score != 20 {
+		t.Errorf("лидеры хранят чужие значения: %+v, %+v", first.BestScore[0], first.WorstScore[0])
+	}
+}
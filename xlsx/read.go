@@ -0,0 +1,217 @@
+// xlsx/read.go
+package xlsx
+
+import (
+	"archive/zip"
+	"encoding/xml"
+	"errors"
+	"io"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// ErrNoSheet — в книге нет ни одного листа.
+var ErrNoSheet = errors.New("в книге нет листов")
+
+// Размер листа Excel. Строки и колонки дальше этих пределов не
+// принимаются: иначе номер строки из файла в несколько сотен байт
+// заставил бы выделить память под сотни миллионов пустых строк.
+const (
+	maxRows    = 1048576
+	maxColumns = 16384
+)
+
+// Read читает значения первого листа книги: строки по порядку, пустые
+// ячейки — пустые строки. Числа возвращаются так, как записаны в файле
+// (например, «61» или «61.5»); оформление и формулы не учитываются.
+func Read(r io.ReaderAt, size int64) ([][]string, error) {
+	z, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, err
+	}
+	files := make(map[string]*zip.File, len(z.File))
+	for _, f := range z.File {
+		files[f.Name] = f
+	}
+
+	sheet, err := firstSheet(files)
+	if err != nil {
+		return nil, err
+	}
+	var shared []string
+	if f, ok := files["xl/sharedStrings.xml"]; ok {
+		if shared, err = sharedStrings(f); err != nil {
+			return nil, err
+		}
+	}
+	f, ok := files[sheet]
+	if !ok {
+		return nil, ErrNoSheet
+	}
+	return sheetRows(f, shared)
+}
+
+// firstSheet находит файл первого листа по workbook.xml и его связям.
+func firstSheet(files map[string]*zip.File) (string, error) {
+	var wb struct {
+		Sheets []struct {
+			RID string `xml:"http://schemas.openxmlformats.org/officeDocument/2006/relationships id,attr"`
+		} `xml:"sheets>sheet"`
+	}
+	if err := decodeFile(files, "xl/workbook.xml", &wb); err != nil {
+		return "", err
+	}
+	if len(wb.Sheets) == 0 {
+		return "", ErrNoSheet
+	}
+	var rels struct {
+		Rels []struct {
+			ID     string `xml:"Id,attr"`
+			Target string `xml:"Target,attr"`
+		} `xml:"Relationship"`
+	}
+	if err := decodeFile(files, "xl/_rels/workbook.xml.rels", &rels); err != nil {
+		return "", err
+	}
+	for _, rel := range rels.Rels {
+		if rel.ID != wb.Sheets[0].RID {
+			continue
+		}
+		if strings.HasPrefix(rel.Target, "/") {
+			return strings.TrimPrefix(rel.Target, "/"), nil
+		}
+		return path.Join("xl", rel.Target), nil
+	}
+	return "", ErrNoSheet
+}
+
+// richText — строка ячейки: простой текст или набор фрагментов.
+type richText struct {
+	T    string `xml:"t"`
+	Runs []struct {
+		T string `xml:"t"`
+	} `xml:"r"`
+}
+
+func (t richText) String() string {
+	if len(t.Runs) == 0 {
+		return t.T
+	}
+	var b strings.Builder
+	for _, r := range t.Runs {
+		b.WriteString(r.T)
+	}
+	return b.String()
+}
+
+func sharedStrings(f *zip.File) ([]string, error) {
+	var sst struct {
+		Items []richText `xml:"si"`
+	}
+	if err := decode(f, &sst); err != nil {
+		return nil, err
+	}
+	list := make([]string, len(sst.Items))
+	for i, si := range sst.Items {
+		list[i] = si.String()
+	}
+	return list, nil
+}
+
+func sheetRows(f *zip.File, shared []string) ([][]string, error) {
+	var ws struct {
+		Rows []struct {
+			R     int `xml:"r,attr"`
+			Cells []struct {
+				R      string   `xml:"r,attr"`
+				T      string   `xml:"t,attr"`
+				V      string   `xml:"v"`
+				Inline richText `xml:"is"`
+			} `xml:"c"`
+		} `xml:"sheetData>row"`
+	}
+	if err := decode(f, &ws); err != nil {
+		return nil, err
+	}
+
+	var rows [][]string
+	for _, row := range ws.Rows {
+		// Пустые строки Excel не записывает — восстанавливаем их по номеру
+		n := row.R
+		if n == 0 {
+			n = len(rows) + 1
+		}
+		if n < 0 || n > maxRows {
+			return nil, errors.New("строка " + strconv.Itoa(n) + " за пределами листа")
+		}
+		for len(rows) < n-1 {
+			rows = append(rows, nil)
+		}
+		var values []string
+		for j, c := range row.Cells {
+			col := j
+			if c.R != "" {
+				var err error
+				if col, err = columnIndex(c.R); err != nil {
+					return nil, err
+				}
+			}
+			if col >= maxColumns {
+				return nil, errors.New("строка " + strconv.Itoa(n) + ": ячеек больше, чем колонок в листе")
+			}
+			for len(values) <= col {
+				values = append(values, "")
+			}
+			switch c.T {
+			case "s":
+				idx, err := strconv.Atoi(c.V)
+				if err != nil || idx < 0 || idx >= len(shared) {
+					return nil, errors.New("ячейка " + c.R + ": неверная ссылка на строку")
+				}
+				values[col] = shared[idx]
+			case "inlineStr":
+				values[col] = c.Inline.String()
+			default:
+				values[col] = c.V
+			}
+		}
+		rows = append(rows, values)
+	}
+	return rows, nil
+}
+
+// columnIndex — номер колонки (с нуля) по адресу ячейки вида «AB12».
+func columnIndex(ref string) (int, error) {
+	col := 0
+	for _, r := range ref {
+		if r < 'A' || r > 'Z' {
+			break
+		}
+		col = col*26 + int(r-'A'+1)
+		if col > maxColumns {
+			return 0, errors.New("ячейка " + ref + ": колонка за пределами листа")
+		}
+	}
+	if col == 0 {
+		return 0, errors.New("ячейка " + ref + ": неверный адрес")
+	}
+	return col - 1, nil
+}
+
+func decodeFile(files map[string]*zip.File, name string, v interface{}) error {
+	f, ok := files[name]
+	if !ok {
+		return errors.New("в книге нет " + name)
+	}
+	return decode(f, v)
+}
+
+func decode(f *zip.File, v interface{}) error {
+	rc, err := f.Open()
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+	return xml.NewDecoder(rc).Decode(v)
+}
@@ -0,0 +1,150 @@
+// xlsx/xlsx.go
+package xlsx
+
+import (
+	"archive/zip"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Книга из одного листа без оформления — этого хватает для выгрузки
+// и импорта ведомости. Файлы книги собираются и разбираются по
+// спецификации Office Open XML вручную, чтобы не тянуть в проект
+// библиотеку для Excel.
+
+const contentTypes = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">
+<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>
+<Default Extension="xml" ContentType="application/xml"/>
+<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>
+<Override PartName="/xl/worksheets/sheet1.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>
+</Types>`
+
+const rootRels = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">
+<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/>
+</Relationships>`
+
+const workbookRels = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">
+<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/>
+</Relationships>`
+
+const workbook = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">
+<sheets><sheet name="%s" sheetId="1" r:id="rId1"/></sheets>
+</workbook>`
+
+// maxSheetName — предел длины имени листа в Excel.
+const maxSheetName = 31
+
+// Write пишет книгу с одним листом sheet. Значения int и float64
+// становятся числами, остальное — строками (через fmt.Sprint).
+func Write(w io.Writer, sheet string, rows [][]interface{}) error {
+	z := zip.NewWriter(w)
+	files := []struct {
+		name string
+		body string
+	}{
+		{"[Content_Types].xml", contentTypes},
+		{"_rels/.rels", rootRels},
+		{"xl/workbook.xml", fmt.Sprintf(workbook, escape(sheetName(sheet)))},
+		{"xl/_rels/workbook.xml.rels", workbookRels},
+		{"xl/worksheets/sheet1.xml", worksheet(rows)},
+	}
+	for _, f := range files {
+		fw, err := z.Create(f.name)
+		if err != nil {
+			return err
+		}
+		if _, err := io.WriteString(fw, f.body); err != nil {
+			return err
+		}
+	}
+	return z.Close()
+}
+
+func worksheet(rows [][]interface{}) string {
+	var b strings.Builder
+	b.WriteString(`<?xml version="1.0" encoding="UTF-8" standalone="yes"?>`)
+	b.WriteString(`<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>`)
+	for i, row := range rows {
+		fmt.Fprintf(&b, `<row r="%d">`, i+1)
+		for j, v := range row {
+			ref := cellRef(j, i)
+			switch v := v.(type) {
+			case nil:
+				continue
+			case int:
+				fmt.Fprintf(&b, `<c r="%s"><v>%d</v></c>`, ref, v)
+			case float64:
+				fmt.Fprintf(&b, `<c r="%s"><v>%s</v></c>`, ref, strconv.FormatFloat(v, 'f', -1, 64))
+			default:
+				fmt.Fprintf(&b, `<c r="%s" t="inlineStr"><is><t xml:space="preserve">%s</t></is></c>`, ref, escape(fmt.Sprint(v)))
+			}
+		}
+		b.WriteString(`</row>`)
+	}
+	b.WriteString(`</sheetData></worksheet>`)
+	return b.String()
+}
+
+// cellRef — адрес ячейки в нотации A1; col и row считаются с нуля.
+func cellRef(col, row int) string {
+	name := ""
+	for col++; col > 0; col = (col - 1) / 26 {
+		name = string(rune('A'+(col-1)%26)) + name
+	}
+	return name + strconv.Itoa(row+1)
+}
+
+// sheetName убирает из имени листа символы, которые Excel не принимает,
+// и обрезает его до maxSheetName.
+func sheetName(name string) string {
+	name = strings.Map(func(r rune) rune {
+		if strings.ContainsRune(`[]:*?/\`, r) {
+			return '_'
+		}
+		return r
+	}, name)
+	if r := []rune(name); len(r) > maxSheetName {
+		name = string(r[:maxSheetName])
+	}
+	if name == "" {
+		name = "Лист1"
+	}
+	return name
+}
+
+// escape готовит текст для XML: убирает символы, запрещённые в XML 1.0
+// (управляющие, суррогаты, U+FFFE и U+FFFF), с которыми Excel не откроет
+// книгу, и экранирует разметку.
+func escape(s string) string {
+	s = strings.Map(func(r rune) rune {
+		if !xmlChar(r) {
+			return -1
+		}
+		return r
+	}, s)
+	var b strings.Builder
+	xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
+
+// xmlChar — допустим ли символ в XML 1.0 (production Char спецификации).
+func xmlChar(r rune) bool {
+	switch {
+	case r == '\t' || r == '\n' || r == '\r':
+		return true
+	case r >= 0x20 && r <= 0xD7FF:
+		return true
+	case r >= 0xE000 && r <= 0xFFFD:
+		return true
+	case r >= 0x10000 && r <= 0x10FFFF:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,216 @@
+// xlsx/xlsx_test.go
+package xlsx
+
+import (
+	"archive/zip"
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// book собирает книгу из готового листа sheet1.xml и, если shared не
+// пустой, таблицы строк — так можно проверить разбор файлов, которые
+// Write не создаёт.
+func book(t *testing.T, sheet, shared string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	z := zip.NewWriter(&buf)
+	files := map[string]string{
+		"[Content_Types].xml":        contentTypes,
+		"_rels/.rels":                rootRels,
+		"xl/workbook.xml":            strings.Replace(workbook, "%s", "Лист1", 1),
+		"xl/_rels/workbook.xml.rels": workbookRels,
+		"xl/worksheets/sheet1.xml": `<?xml version="1.0" encoding="UTF-8"?>` +
+			`<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>` +
+			sheet + `</sheetData></worksheet>`,
+	}
+	if shared != "" {
+		files["xl/sharedStrings.xml"] = `<?xml version="1.0" encoding="UTF-8"?>` +
+			`<sst xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">` + shared + `</sst>`
+	}
+	for name, body := range files {
+		fw, err := z.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := fw.Write([]byte(body)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := z.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestWriteRead(t *testing.T) {
+	tests := []struct {
+		name string
+		rows [][]interface{}
+		want [][]string
+	}{
+		{
+			name: "пустой лист",
+			rows: nil,
+			want: nil,
+		},
+		{
+			name: "числа и строки",
+			rows: [][]interface{}{
+				{"Студент", "Баллы", "Посещаемость"},
+				{"Иванов Иван", 61, 87.5},
+			},
+			want: [][]string{
+				{"Студент", "Баллы", "Посещаемость"},
+				{"Иванов Иван", "61", "87.5"},
+			},
+		},
+		{
+			name: "пропуски в строке",
+			rows: [][]interface{}{{"a", nil, "c"}, {nil, nil, 3}},
+			want: [][]string{{"a", "", "c"}, {"", "", "3"}},
+		},
+		{
+			name: "разметка экранируется",
+			rows: [][]interface{}{{`<b>&"Кавычки"</b>`, "  пробелы  "}},
+			want: [][]string{{`<b>&"Кавычки"</b>`, "  пробелы  "}},
+		},
+		{
+			name: "запрещённые в XML символы убираются",
+			rows: [][]interface{}{{"Пет\x00ров\x1b", "a\uFFFEb"}},
+			want: [][]string{{"Петров", "ab"}},
+		},
+		{
+			name: "колонки дальше Z",
+			rows: [][]interface{}{{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16, 17, 18, 19, 20, 21, 22, 23, 24, 25, 26, 27, 28}},
+			want: [][]string{{"1", "2", "3", "4", "5", "6", "7", "8", "9", "10", "11", "12", "13", "14", "15", "16", "17", "18", "19", "20", "21", "22", "23", "24", "25", "26", "27", "28"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := Write(&buf, "ИС-21 [весна]", tt.rows); err != nil {
+				t.Fatalf("Write: %v", err)
+			}
+			got, err := Read(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+			if err != nil {
+				t.Fatalf("Read: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("прочитано %q, ждали %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRead(t *testing.T) {
+	tests := []struct {
+		name   string
+		sheet  string
+		shared string
+		want   [][]string
+		err    string
+	}{
+		{
+			name:   "общие строки и пропущенные строки листа",
+			sheet:  `<row r="1"><c r="A1" t="s"><v>0</v></c></row><row r="3"><c r="B3" t="s"><v>1</v></c></row>`,
+			shared: `<si><t>Группа</t></si><si><r><t>Ива</t></r><r><t>нов</t></r></si>`,
+			want:   [][]string{{"Группа"}, nil, {"", "Иванов"}},
+		},
+		{
+			name:  "ячейки и строки без адреса",
+			sheet: `<row><c><v>1</v></c><c t="inlineStr"><is><t>два</t></is></c></row><row><c><v>3</v></c></row>`,
+			want:  [][]string{{"1", "два"}, {"3"}},
+		},
+		{
+			name:  "последняя строка листа",
+			sheet: `<row r="1048576"><c r="XFD1048576"><v>1</v></c></row>`,
+		},
+		{
+			name:  "адрес без колонки",
+			sheet: `<row r="1"><c r="a1"><v>1</v></c></row>`,
+			err:   "неверный адрес",
+		},
+		{
+			name:  "адрес из одних цифр",
+			sheet: `<row r="1"><c r="12"><v>1</v></c></row>`,
+			err:   "неверный адрес",
+		},
+		{
+			name:  "колонка за пределами листа",
+			sheet: `<row r="1"><c r="XFE1"><v>1</v></c></row>`,
+			err:   "колонка за пределами листа",
+		},
+		{
+			name:  "огромная колонка",
+			sheet: `<row r="1"><c r="ZZZZZZZZZZZZZZZ1"><v>1</v></c></row>`,
+			err:   "колонка за пределами листа",
+		},
+		{
+			name:  "строка за пределами листа",
+			sheet: `<row r="400000000"><c r="A400000000"><v>1</v></c></row>`,
+			err:   "за пределами листа",
+		},
+		{
+			name:  "отрицательный номер строки",
+			sheet: `<row r="-5"><c r="A1"><v>1</v></c></row>`,
+			err:   "за пределами листа",
+		},
+		{
+			name:   "ссылка на несуществующую общую строку",
+			sheet:  `<row r="1"><c r="A1" t="s"><v>7</v></c></row>`,
+			shared: `<si><t>одна</t></si>`,
+			err:    "неверная ссылка на строку",
+		},
+		{
+			name:  "общая строка без таблицы строк",
+			sheet: `<row r="1"><c r="A1" t="s"><v>0</v></c></row>`,
+			err:   "неверная ссылка на строку",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := book(t, tt.sheet, tt.shared)
+			got, err := Read(bytes.NewReader(data), int64(len(data)))
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Fatalf("ошибка %v, ждали «%s»", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Read: %v", err)
+			}
+			if tt.want != nil && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("прочитано %q, ждали %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReadNotWorkbook(t *testing.T) {
+	data := []byte("Студент;Баллы\n")
+	if _, err := Read(bytes.NewReader(data), int64(len(data))); err == nil {
+		t.Error("CSV прочитан как книга")
+	}
+	data = book(t, "", "")
+	rows, err := Read(bytes.NewReader(data), int64(len(data)))
+	if err != nil || len(rows) != 0 {
+		t.Errorf("пустой лист: %q, %v", rows, err)
+	}
+}
+
+func TestSheetName(t *testing.T) {
+	tests := []struct{ in, want string }{
+		{"ИС-21", "ИС-21"},
+		{"a/b\\c[d]:e*f?", "a_b_c_d__e_f_"},
+		{"", "Лист1"},
+		{strings.Repeat("я", 40), strings.Repeat("я", maxSheetName)},
+	}
+	for _, tt := range tests {
+		if got := sheetName(tt.in); got != tt.want {
+			t.Errorf("sheetName(%q) = %q, ждали %q", tt.in, got, tt.want)
+		}
+	}
+}